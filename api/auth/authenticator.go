@@ -0,0 +1,110 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package auth
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors returned when a request is rejected.
+var (
+	ErrUnauthenticated  = errors.New("missing or unknown API key")
+	ErrPermissionDenied = errors.New("method not allowed for API key")
+)
+
+// Authenticator checks the API keys presented by clients against the keys it
+// was created with, and the method allowlists of those keys.
+type Authenticator struct {
+	keys map[[sha256.Size]byte]grant
+}
+
+// grant is what a key gives access to. Keys are looked up by the hash of their
+// token, so that the time taken by the lookup reveals nothing about the tokens.
+type grant struct {
+	name    string
+	all     bool
+	methods map[string]struct{}
+}
+
+// NewAuthenticator creates a new authenticator which accepts the given keys.
+// Each key needs a unique token and at least one allowed method.
+func NewAuthenticator(keys ...Key) (*Authenticator, error) {
+
+	a := Authenticator{
+		keys: make(map[[sha256.Size]byte]grant, len(keys)),
+	}
+
+	for _, key := range keys {
+		if key.Token == "" {
+			return nil, fmt.Errorf("missing token for key (%s)", key.Name)
+		}
+		if len(key.Methods) == 0 {
+			return nil, fmt.Errorf("missing allowed methods for key (%s)", key.Name)
+		}
+		hash := sha256.Sum256([]byte(key.Token))
+		_, ok := a.keys[hash]
+		if ok {
+			return nil, fmt.Errorf("duplicate token for key (%s)", key.Name)
+		}
+		g := grant{
+			name:    key.Name,
+			methods: make(map[string]struct{}, len(key.Methods)),
+		}
+		for _, method := range key.Methods {
+			if method == AllMethods {
+				g.all = true
+				continue
+			}
+			g.methods[methodName(method)] = struct{}{}
+		}
+		a.keys[hash] = g
+	}
+
+	return &a, nil
+}
+
+// Authorize checks that the given token belongs to a known key which is
+// allowed to call the given method. The method can be given either by name or
+// as a full GRPC method, such as `/API/GetHeader`. On success, it returns the
+// name of the key.
+func (a *Authenticator) Authorize(token string, method string) (string, error) {
+
+	if token == "" {
+		return "", ErrUnauthenticated
+	}
+	g, ok := a.keys[sha256.Sum256([]byte(token))]
+	if !ok {
+		return "", ErrUnauthenticated
+	}
+	if g.all {
+		return g.name, nil
+	}
+	name := methodName(method)
+	_, ok = g.methods[name]
+	if !ok {
+		return g.name, fmt.Errorf("%w (key: %s, method: %s)", ErrPermissionDenied, g.name, name)
+	}
+
+	return g.name, nil
+}
+
+// methodName strips the service from a full GRPC method, so that methods can
+// be listed by name only.
+func methodName(method string) string {
+	return method[strings.LastIndex(method, "/")+1:]
+}
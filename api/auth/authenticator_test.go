@@ -0,0 +1,125 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/api/auth"
+)
+
+func TestNewAuthenticator(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := auth.NewAuthenticator(
+			auth.Key{Name: "rosetta", Token: "token1", Methods: []string{"GetHeader"}},
+			auth.Key{Name: "admin", Token: "token2", Methods: []string{auth.AllMethods}},
+		)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("handles missing token", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := auth.NewAuthenticator(auth.Key{Name: "rosetta", Methods: []string{"GetHeader"}})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles missing methods", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := auth.NewAuthenticator(auth.Key{Name: "rosetta", Token: "token1"})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles duplicate token", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := auth.NewAuthenticator(
+			auth.Key{Name: "rosetta", Token: "token1", Methods: []string{"GetHeader"}},
+			auth.Key{Name: "admin", Token: "token1", Methods: []string{auth.AllMethods}},
+		)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestAuthenticator_Authorize(t *testing.T) {
+	authenticate, err := auth.NewAuthenticator(
+		auth.Key{Name: "rosetta", Token: "token1", Methods: []string{"GetHeader", "/API/GetEvents"}},
+		auth.Key{Name: "admin", Token: "token2", Methods: []string{auth.AllMethods}},
+	)
+	require.NoError(t, err)
+
+	t.Run("allows listed methods by name", func(t *testing.T) {
+		t.Parallel()
+
+		name, err := authenticate.Authorize("token1", "GetHeader")
+
+		require.NoError(t, err)
+		assert.Equal(t, "rosetta", name)
+	})
+
+	t.Run("allows listed methods by full method", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := authenticate.Authorize("token1", "/API/GetHeader")
+		assert.NoError(t, err)
+
+		_, err = authenticate.Authorize("token1", "/API/GetEvents")
+		assert.NoError(t, err)
+	})
+
+	t.Run("allows all methods with wildcard", func(t *testing.T) {
+		t.Parallel()
+
+		name, err := authenticate.Authorize("token2", "/API/GetIndexStats")
+
+		require.NoError(t, err)
+		assert.Equal(t, "admin", name)
+	})
+
+	t.Run("rejects unlisted methods", func(t *testing.T) {
+		t.Parallel()
+
+		name, err := authenticate.Authorize("token1", "/API/GetIndexStats")
+
+		assert.ErrorIs(t, err, auth.ErrPermissionDenied)
+		assert.Equal(t, "rosetta", name)
+	})
+
+	t.Run("rejects unknown token", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := authenticate.Authorize("token3", "/API/GetHeader")
+
+		assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+	})
+
+	t.Run("rejects missing token", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := authenticate.Authorize("", "/API/GetHeader")
+
+		assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+	})
+}
@@ -0,0 +1,107 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/tags"
+)
+
+// Headers in which clients can present their API key, either as a bearer token
+// in the authorization header, or as is in the API key header.
+const (
+	HeaderAuthorization = "authorization"
+	HeaderAPIKey        = "x-api-key"
+)
+
+// TagKey is the tag of a call which holds the name of the API key it
+// presented, once it has been authorized.
+const TagKey = "auth.key"
+
+const bearer = "bearer "
+
+// ExtractToken returns the token presented in the given values of the
+// authorization and API key headers, or an empty string if there is none.
+func ExtractToken(authorization string, apiKey string) string {
+	if len(authorization) > len(bearer) && strings.EqualFold(authorization[:len(bearer)], bearer) {
+		return strings.TrimSpace(authorization[len(bearer):])
+	}
+	return strings.TrimSpace(apiKey)
+}
+
+// UnaryServerInterceptor returns a GRPC interceptor which rejects unary calls
+// that do not present an API key allowed to call their method.
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		err := a.check(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a GRPC interceptor which rejects streams that
+// do not present an API key allowed to call their method.
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := a.check(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// check authorizes the call with the API key found in the incoming metadata of
+// the given context, and tags the call with the name of the key, so that it
+// shows up in the logs.
+func (a *Authenticator) check(ctx context.Context, method string) error {
+
+	var authorization, apiKey string
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok {
+		authorization = first(md.Get(HeaderAuthorization))
+		apiKey = first(md.Get(HeaderAPIKey))
+	}
+
+	name, err := a.Authorize(ExtractToken(authorization, apiKey), method)
+	if name != "" {
+		tags.Extract(ctx).Set(TagKey, name)
+	}
+	if errors.Is(err, ErrPermissionDenied) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return nil
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,107 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package auth_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/optakt/flow-dps/api/auth"
+)
+
+func TestExtractToken(t *testing.T) {
+	assert.Equal(t, "token1", auth.ExtractToken("Bearer token1", ""))
+	assert.Equal(t, "token1", auth.ExtractToken("bearer token1", "token2"))
+	assert.Equal(t, "token2", auth.ExtractToken("", "token2"))
+	assert.Equal(t, "token2", auth.ExtractToken("Basic dXNlcjpwYXNz", "token2"))
+	assert.Empty(t, auth.ExtractToken("", ""))
+}
+
+func TestAuthenticator_UnaryServerInterceptor(t *testing.T) {
+	authenticate, err := auth.NewAuthenticator(
+		auth.Key{Name: "rosetta", Token: "token1", Methods: []string{"GetHeader"}},
+	)
+	require.NoError(t, err)
+	interceptor := authenticate.UnaryServerInterceptor()
+
+	handler := func(context.Context, interface{}) (interface{}, error) {
+		return "response", nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/API/GetHeader"}
+
+	t.Run("nominal case with bearer token", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(auth.HeaderAuthorization, "Bearer token1"))
+		res, err := interceptor(ctx, "request", info, handler)
+
+		require.NoError(t, err)
+		assert.Equal(t, "response", res)
+	})
+
+	t.Run("nominal case with API key", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(auth.HeaderAPIKey, "token1"))
+		res, err := interceptor(ctx, "request", info, handler)
+
+		require.NoError(t, err)
+		assert.Equal(t, "response", res)
+	})
+
+	t.Run("handles missing metadata", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := interceptor(context.Background(), "request", info, handler)
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("handles unknown token", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(auth.HeaderAuthorization, "Bearer token2"))
+		_, err := interceptor(ctx, "request", info, handler)
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("handles unlisted method", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(auth.HeaderAuthorization, "Bearer token1"))
+		_, err := interceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/API/GetEvents"}, handler)
+
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
+
+func TestToken(t *testing.T) {
+	token := auth.NewToken("token1", true)
+
+	md, err := token.GetRequestMetadata(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, "token1", auth.ExtractToken(md[auth.HeaderAuthorization], ""))
+	assert.True(t, token.RequireTransportSecurity())
+	assert.False(t, auth.NewToken("token1", false).RequireTransportSecurity())
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// AllMethods is the entry of a method allowlist which allows a key to call any
+// method of the DPS API.
+const AllMethods = "*"
+
+// Key is an API key, which clients present as a bearer token in order to call
+// the methods of the DPS API on its allowlist. Methods are listed by name,
+// such as `GetHeader`.
+type Key struct {
+	Name    string   `json:"name" yaml:"name"`
+	Token   string   `json:"token" yaml:"token"`
+	Methods []string `json:"methods" yaml:"methods"`
+}
+
+// Keyring is the content of a file listing the API keys accepted by a server.
+type Keyring struct {
+	Keys []Key `json:"keys" yaml:"keys"`
+}
+
+// ReadKeys reads the API keys from the keyring file at the given path. The
+// format of the file is chosen based on its extension, which should be one of
+// `.json`, `.yaml` or `.yml`.
+func ReadKeys(path string) ([]Key, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read keyring file: %w", err)
+	}
+
+	var keyring Keyring
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".json":
+		err = json.Unmarshal(data, &keyring)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, &keyring)
+	default:
+		return nil, fmt.Errorf("unsupported keyring extension (%s)", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not decode keyring: %w", err)
+	}
+
+	return keyring.Keys, nil
+}
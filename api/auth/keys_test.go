@@ -0,0 +1,75 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package auth_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/api/auth"
+)
+
+func TestReadKeys(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "keys.yaml")
+	err := os.WriteFile(yamlPath, []byte("keys:\n  - name: rosetta\n    token: token1\n    methods: [GetHeader, GetEvents]\n"), 0600)
+	require.NoError(t, err)
+	jsonPath := filepath.Join(dir, "keys.json")
+	err = os.WriteFile(jsonPath, []byte(`{"keys": [{"name": "admin", "token": "token2", "methods": ["*"]}]}`), 0600)
+	require.NoError(t, err)
+	invalidPath := filepath.Join(dir, "keys.txt")
+	err = os.WriteFile(invalidPath, []byte("token1"), 0600)
+	require.NoError(t, err)
+
+	t.Run("nominal case with YAML", func(t *testing.T) {
+		t.Parallel()
+
+		keys, err := auth.ReadKeys(yamlPath)
+
+		require.NoError(t, err)
+		want := []auth.Key{{Name: "rosetta", Token: "token1", Methods: []string{"GetHeader", "GetEvents"}}}
+		assert.Equal(t, want, keys)
+	})
+
+	t.Run("nominal case with JSON", func(t *testing.T) {
+		t.Parallel()
+
+		keys, err := auth.ReadKeys(jsonPath)
+
+		require.NoError(t, err)
+		want := []auth.Key{{Name: "admin", Token: "token2", Methods: []string{auth.AllMethods}}}
+		assert.Equal(t, want, keys)
+	})
+
+	t.Run("handles unsupported extension", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := auth.ReadKeys(invalidPath)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles missing file", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := auth.ReadKeys(filepath.Join(dir, "missing.yaml"))
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ServerTLS builds the TLS configuration of a server from the certificate and
+// private key files at the given paths. When a client CA file is given, the
+// server requires clients to present a certificate signed by one of its
+// certificate authorities, which enables mutual TLS.
+func ServerTLS(certFile string, keyFile string, clientCAFile string) (*tls.Config, error) {
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load server certificate: %w", err)
+	}
+
+	cfg := tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if clientCAFile != "" {
+		pool, err := loadPool(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate authorities: %w", err)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return &cfg, nil
+}
+
+// ClientTLS builds the TLS configuration of a client. When a CA file is given,
+// server certificates are verified against its certificate authorities instead
+// of those of the system. When a certificate and private key file are given,
+// the client presents them to servers which require mutual TLS. The server name
+// overrides the name used to verify server certificates, if it is not empty.
+func ClientTLS(caFile string, certFile string, keyFile string, serverName string) (*tls.Config, error) {
+
+	cfg := tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+	if caFile != "" {
+		pool, err := loadPool(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load certificate authorities: %w", err)
+		}
+		cfg.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return &cfg, nil
+}
+
+func loadPool(path string) (*x509.CertPool, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read certificate file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	ok := pool.AppendCertsFromPEM(data)
+	if !ok {
+		return nil, errors.New("no valid certificate found in file")
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,193 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/api/auth"
+)
+
+func TestTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := writeCertificate(t, dir, "ca", nil, nil)
+	writeCertificate(t, dir, "server", ca, caKey)
+	writeCertificate(t, dir, "client", ca, caKey)
+	writeCertificate(t, dir, "other", nil, nil)
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	t.Run("nominal case with TLS", func(t *testing.T) {
+		t.Parallel()
+
+		server, err := auth.ServerTLS(path("server.crt"), path("server.key"), "")
+		require.NoError(t, err)
+		client, err := auth.ClientTLS(path("ca.crt"), "", "", "localhost")
+		require.NoError(t, err)
+
+		assert.NoError(t, handshake(server, client))
+	})
+
+	t.Run("nominal case with mutual TLS", func(t *testing.T) {
+		t.Parallel()
+
+		server, err := auth.ServerTLS(path("server.crt"), path("server.key"), path("ca.crt"))
+		require.NoError(t, err)
+		client, err := auth.ClientTLS(path("ca.crt"), path("client.crt"), path("client.key"), "localhost")
+		require.NoError(t, err)
+
+		assert.NoError(t, handshake(server, client))
+	})
+
+	t.Run("handles missing client certificate with mutual TLS", func(t *testing.T) {
+		t.Parallel()
+
+		server, err := auth.ServerTLS(path("server.crt"), path("server.key"), path("ca.crt"))
+		require.NoError(t, err)
+		client, err := auth.ClientTLS(path("ca.crt"), "", "", "localhost")
+		require.NoError(t, err)
+
+		assert.Error(t, handshake(server, client))
+	})
+
+	t.Run("handles unknown server certificate authority", func(t *testing.T) {
+		t.Parallel()
+
+		server, err := auth.ServerTLS(path("server.crt"), path("server.key"), "")
+		require.NoError(t, err)
+		client, err := auth.ClientTLS(path("other.crt"), "", "", "localhost")
+		require.NoError(t, err)
+
+		assert.Error(t, handshake(server, client))
+	})
+
+	t.Run("handles missing server certificate", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := auth.ServerTLS(path("missing.crt"), path("server.key"), "")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles invalid certificate authorities", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := auth.ServerTLS(path("server.crt"), path("server.key"), path("server.key"))
+		assert.Error(t, err)
+
+		_, err = auth.ClientTLS(path("client.key"), "", "", "")
+		assert.Error(t, err)
+	})
+}
+
+// handshake runs a TLS handshake between the given server and client
+// configurations over a loopback connection, and returns the error of the
+// server side if there is one, or that of the client side otherwise.
+func handshake(server *tls.Config, client *tls.Config) error {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	failed := make(chan error, 1)
+	go func() {
+		conn, err := tls.Dial("tcp", listener.Addr().String(), client)
+		if err == nil {
+			_ = conn.SetDeadline(time.Now().Add(time.Second))
+			_, err = conn.Read(make([]byte, 1))
+			_ = conn.Close()
+		}
+		failed <- err
+	}()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(time.Second))
+	err = tls.Server(conn, server).Handshake()
+	if err != nil {
+		return err
+	}
+	_ = conn.Close()
+
+	err = <-failed
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// writeCertificate writes a certificate and its private key as PEM files in the
+// given directory. The certificate is self-signed when no parent is given.
+func writeCertificate(t *testing.T, dir string, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent = &template
+		parentKey = key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	err = os.WriteFile(filepath.Join(dir, name+".crt"), certPEM, 0600)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	err = os.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0600)
+	require.NoError(t, err)
+
+	return cert, key
+}
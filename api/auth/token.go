@@ -0,0 +1,53 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package auth
+
+import (
+	"context"
+)
+
+// Token is a set of GRPC per-call credentials, which presents an API key as a
+// bearer token on every call made by a client.
+type Token struct {
+	token  string
+	secure bool
+}
+
+// NewToken creates new credentials for the given API key. Unless the secure
+// flag is unset, the token is only sent over connections secured by TLS, so
+// that it can not be intercepted.
+func NewToken(token string, secure bool) *Token {
+
+	t := Token{
+		token:  token,
+		secure: secure,
+	}
+
+	return &t
+}
+
+// GetRequestMetadata implements the `credentials.PerRPCCredentials` interface.
+func (t *Token) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	md := map[string]string{
+		HeaderAuthorization: "Bearer " + t.token,
+	}
+	return md, nil
+}
+
+// RequireTransportSecurity implements the `credentials.PerRPCCredentials`
+// interface.
+func (t *Token) RequireTransportSecurity() bool {
+	return t.secure
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package cache
+
+import (
+	"fmt"
+
+	"github.com/dgraph-io/ristretto"
+)
+
+// minCounters is the minimum number of counters used to track the frequency of
+// cache entries, so that small caches still get enough of them.
+const minCounters = 1000
+
+// Cache is a size-bounded cache for the encoded responses of the DPS API. It
+// is meant for data that never changes once indexed, such as headers or
+// transactions, so that popular entities can be served without reading and
+// decoding them from the index again.
+type Cache struct {
+	cfg   Config
+	cache *ristretto.Cache
+}
+
+// NewCache creates a new response cache, backed by a Ristretto cache.
+func NewCache(options ...Option) (*Cache, error) {
+
+	cfg := DefaultConfig
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	if cfg.Size == 0 {
+		return nil, fmt.Errorf("invalid cache size (%d), must be greater than zero", cfg.Size)
+	}
+
+	// Ristretto recommends keeping ten times as many counters as items in the
+	// cache when full. Assuming an average response size of 1 kilobyte, this
+	// is what we get; small caches would end up without any counters, so we
+	// keep a minimum number of them.
+	counters := int64(cfg.Size) / 1000 * 10
+	if counters < minCounters {
+		counters = minCounters
+	}
+	cache, err := ristretto.NewCache(&ristretto.Config{
+		NumCounters: counters,
+		MaxCost:     int64(cfg.Size),
+		BufferItems: 64,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize cache: %w", err)
+	}
+
+	c := Cache{
+		cfg:   cfg,
+		cache: cache,
+	}
+
+	return &c, nil
+}
+
+// Get returns the cached response data for the given method and request key.
+func (c *Cache) Get(method string, key []byte) ([]byte, bool) {
+	data, ok := c.cache.Get(entry(method, key))
+	if !ok {
+		return nil, false
+	}
+	return data.([]byte), true
+}
+
+// Set caches the response data for the given method and request key. As the
+// cache buffers its writes, the data might not be available right away, and it
+// might not be cached at all if the cache is under contention or if the data
+// is too big.
+func (c *Cache) Set(method string, key []byte, data []byte) {
+	cost := uint64(len(method) + len(key) + len(data))
+	if cost > c.cfg.MaxItem {
+		return
+	}
+	_ = c.cache.Set(entry(method, key), data, int64(cost))
+}
+
+// Close stops the background processing of the cache and releases its memory.
+func (c *Cache) Close() {
+	c.cache.Close()
+}
+
+func entry(method string, key []byte) string {
+	return method + "/" + string(key)
+}
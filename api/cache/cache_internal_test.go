@@ -0,0 +1,96 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestCache(t *testing.T) {
+	key := []byte{0x01, 0x02}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := NewCache()
+		require.NoError(t, err)
+		defer c.Close()
+
+		_, ok := c.Get("GetHeader", key)
+		assert.False(t, ok)
+
+		c.Set("GetHeader", key, mocks.GenericBytes)
+		c.cache.Wait()
+
+		data, ok := c.Get("GetHeader", key)
+		require.True(t, ok)
+		assert.Equal(t, mocks.GenericBytes, data)
+	})
+
+	t.Run("separates methods", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := NewCache()
+		require.NoError(t, err)
+		defer c.Close()
+
+		c.Set("GetTransaction", key, mocks.GenericBytes)
+		c.cache.Wait()
+
+		_, ok := c.Get("GetResult", key)
+		assert.False(t, ok)
+	})
+
+	t.Run("skips items over maximum size", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := NewCache(WithMaxItem(uint64(len(mocks.GenericBytes))))
+		require.NoError(t, err)
+		defer c.Close()
+
+		c.Set("GetHeader", key, mocks.GenericBytes)
+		c.cache.Wait()
+
+		_, ok := c.Get("GetHeader", key)
+		assert.False(t, ok)
+	})
+
+	t.Run("supports small size", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := NewCache(WithSize(999))
+		require.NoError(t, err)
+		defer c.Close()
+
+		c.Set("GetHeader", key, mocks.GenericBytes)
+		c.cache.Wait()
+
+		data, ok := c.Get("GetHeader", key)
+		require.True(t, ok)
+		assert.Equal(t, mocks.GenericBytes, data)
+	})
+
+	t.Run("handles invalid size", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewCache(WithSize(0))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package cache
+
+// DefaultConfig is the default configuration for the response cache.
+var DefaultConfig = Config{
+	Size:    100_000_000, // ~100 MB
+	MaxItem: 1_000_000,   // ~1 MB
+}
+
+// Config contains optional parameters for the response cache.
+type Config struct {
+	Size    uint64
+	MaxItem uint64
+}
+
+// Option is an option that can be given to the response cache to configure
+// optional parameters on initialization.
+type Option func(*Config)
+
+// WithSize sets the maximum total size in bytes of the responses kept in the
+// cache.
+func WithSize(size uint64) Option {
+	return func(cfg *Config) {
+		cfg.Size = size
+	}
+}
+
+// WithMaxItem sets the maximum size in bytes of a single response for it to be
+// kept in the cache. Bigger responses are never cached, so that a few of them
+// can not evict many smaller and more popular ones.
+func WithMaxItem(size uint64) Option {
+	return func(cfg *Config) {
+		cfg.MaxItem = size
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package cache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// MetricsCache wraps the response cache and records metrics for its hits and
+// misses.
+type MetricsCache struct {
+	cache *Cache
+
+	hits   *prometheus.CounterVec
+	misses *prometheus.CounterVec
+}
+
+// NewMetricsCache creates a response cache that records the number of hits and
+// misses per method as Prometheus metrics.
+func NewMetricsCache(cache *Cache) *MetricsCache {
+
+	hitsOpts := prometheus.CounterOpts{
+		Name: "response_cache_hits",
+		Help: "number of responses served from the response cache per method",
+	}
+	hits := promauto.NewCounterVec(hitsOpts, []string{"method"})
+
+	missesOpts := prometheus.CounterOpts{
+		Name: "response_cache_misses",
+		Help: "number of responses missing from the response cache per method",
+	}
+	misses := promauto.NewCounterVec(missesOpts, []string{"method"})
+
+	m := MetricsCache{
+		cache: cache,
+
+		hits:   hits,
+		misses: misses,
+	}
+
+	return &m
+}
+
+// Get returns the cached response data for the given method and request key,
+// and records whether it was found.
+func (m *MetricsCache) Get(method string, key []byte) ([]byte, bool) {
+	data, ok := m.cache.Get(method, key)
+	if ok {
+		m.hits.WithLabelValues(method).Inc()
+	} else {
+		m.misses.WithLabelValues(method).Inc()
+	}
+	return data, ok
+}
+
+// Set caches the response data for the given method and request key.
+func (m *MetricsCache) Set(method string, key []byte, data []byte) {
+	m.cache.Set(method, key, data)
+}
@@ -27,13 +27,18 @@ type APIClient interface {
 	GetRegisterValues(ctx context.Context, in *GetRegisterValuesRequest, opts ...grpc.CallOption) (*GetRegisterValuesResponse, error)
 	GetCollection(ctx context.Context, in *GetCollectionRequest, opts ...grpc.CallOption) (*GetCollectionResponse, error)
 	ListCollectionsForHeight(ctx context.Context, in *ListCollectionsForHeightRequest, opts ...grpc.CallOption) (*ListCollectionsForHeightResponse, error)
+	GetHeightForCollection(ctx context.Context, in *GetHeightForCollectionRequest, opts ...grpc.CallOption) (*GetHeightForCollectionResponse, error)
 	GetGuarantee(ctx context.Context, in *GetGuaranteeRequest, opts ...grpc.CallOption) (*GetGuaranteeResponse, error)
 	GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*GetTransactionResponse, error)
 	GetHeightForTransaction(ctx context.Context, in *GetHeightForTransactionRequest, opts ...grpc.CallOption) (*GetHeightForTransactionResponse, error)
 	ListTransactionsForHeight(ctx context.Context, in *ListTransactionsForHeightRequest, opts ...grpc.CallOption) (*ListTransactionsForHeightResponse, error)
 	GetResult(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*GetResultResponse, error)
+	ListResultsForHeight(ctx context.Context, in *ListResultsForHeightRequest, opts ...grpc.CallOption) (*ListResultsForHeightResponse, error)
 	GetSeal(ctx context.Context, in *GetSealRequest, opts ...grpc.CallOption) (*GetSealResponse, error)
 	ListSealsForHeight(ctx context.Context, in *ListSealsForHeightRequest, opts ...grpc.CallOption) (*ListSealsForHeightResponse, error)
+	GetHeightForSeal(ctx context.Context, in *GetHeightForSealRequest, opts ...grpc.CallOption) (*GetHeightForSealResponse, error)
+	GetBlock(ctx context.Context, in *GetBlockRequest, opts ...grpc.CallOption) (*GetBlockResponse, error)
+	GetIndexStats(ctx context.Context, in *GetIndexStatsRequest, opts ...grpc.CallOption) (*GetIndexStatsResponse, error)
 }
 
 type aPIClient struct {
@@ -125,6 +130,15 @@ func (c *aPIClient) ListCollectionsForHeight(ctx context.Context, in *ListCollec
 	return out, nil
 }
 
+func (c *aPIClient) GetHeightForCollection(ctx context.Context, in *GetHeightForCollectionRequest, opts ...grpc.CallOption) (*GetHeightForCollectionResponse, error) {
+	out := new(GetHeightForCollectionResponse)
+	err := c.cc.Invoke(ctx, "/API/GetHeightForCollection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *aPIClient) GetGuarantee(ctx context.Context, in *GetGuaranteeRequest, opts ...grpc.CallOption) (*GetGuaranteeResponse, error) {
 	out := new(GetGuaranteeResponse)
 	err := c.cc.Invoke(ctx, "/API/GetGuarantee", in, out, opts...)
@@ -170,6 +184,15 @@ func (c *aPIClient) GetResult(ctx context.Context, in *GetResultRequest, opts ..
 	return out, nil
 }
 
+func (c *aPIClient) ListResultsForHeight(ctx context.Context, in *ListResultsForHeightRequest, opts ...grpc.CallOption) (*ListResultsForHeightResponse, error) {
+	out := new(ListResultsForHeightResponse)
+	err := c.cc.Invoke(ctx, "/API/ListResultsForHeight", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *aPIClient) GetSeal(ctx context.Context, in *GetSealRequest, opts ...grpc.CallOption) (*GetSealResponse, error) {
 	out := new(GetSealResponse)
 	err := c.cc.Invoke(ctx, "/API/GetSeal", in, out, opts...)
@@ -188,6 +211,33 @@ func (c *aPIClient) ListSealsForHeight(ctx context.Context, in *ListSealsForHeig
 	return out, nil
 }
 
+func (c *aPIClient) GetHeightForSeal(ctx context.Context, in *GetHeightForSealRequest, opts ...grpc.CallOption) (*GetHeightForSealResponse, error) {
+	out := new(GetHeightForSealResponse)
+	err := c.cc.Invoke(ctx, "/API/GetHeightForSeal", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) GetBlock(ctx context.Context, in *GetBlockRequest, opts ...grpc.CallOption) (*GetBlockResponse, error) {
+	out := new(GetBlockResponse)
+	err := c.cc.Invoke(ctx, "/API/GetBlock", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) GetIndexStats(ctx context.Context, in *GetIndexStatsRequest, opts ...grpc.CallOption) (*GetIndexStatsResponse, error) {
+	out := new(GetIndexStatsResponse)
+	err := c.cc.Invoke(ctx, "/API/GetIndexStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // APIServer is the server API for API service.
 // All implementations should embed UnimplementedAPIServer
 // for forward compatibility
@@ -201,13 +251,18 @@ type APIServer interface {
 	GetRegisterValues(context.Context, *GetRegisterValuesRequest) (*GetRegisterValuesResponse, error)
 	GetCollection(context.Context, *GetCollectionRequest) (*GetCollectionResponse, error)
 	ListCollectionsForHeight(context.Context, *ListCollectionsForHeightRequest) (*ListCollectionsForHeightResponse, error)
+	GetHeightForCollection(context.Context, *GetHeightForCollectionRequest) (*GetHeightForCollectionResponse, error)
 	GetGuarantee(context.Context, *GetGuaranteeRequest) (*GetGuaranteeResponse, error)
 	GetTransaction(context.Context, *GetTransactionRequest) (*GetTransactionResponse, error)
 	GetHeightForTransaction(context.Context, *GetHeightForTransactionRequest) (*GetHeightForTransactionResponse, error)
 	ListTransactionsForHeight(context.Context, *ListTransactionsForHeightRequest) (*ListTransactionsForHeightResponse, error)
 	GetResult(context.Context, *GetResultRequest) (*GetResultResponse, error)
+	ListResultsForHeight(context.Context, *ListResultsForHeightRequest) (*ListResultsForHeightResponse, error)
 	GetSeal(context.Context, *GetSealRequest) (*GetSealResponse, error)
 	ListSealsForHeight(context.Context, *ListSealsForHeightRequest) (*ListSealsForHeightResponse, error)
+	GetHeightForSeal(context.Context, *GetHeightForSealRequest) (*GetHeightForSealResponse, error)
+	GetBlock(context.Context, *GetBlockRequest) (*GetBlockResponse, error)
+	GetIndexStats(context.Context, *GetIndexStatsRequest) (*GetIndexStatsResponse, error)
 }
 
 // UnimplementedAPIServer should be embedded to have forward compatible implementations.
@@ -241,6 +296,9 @@ func (UnimplementedAPIServer) GetCollection(context.Context, *GetCollectionReque
 func (UnimplementedAPIServer) ListCollectionsForHeight(context.Context, *ListCollectionsForHeightRequest) (*ListCollectionsForHeightResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListCollectionsForHeight not implemented")
 }
+func (UnimplementedAPIServer) GetHeightForCollection(context.Context, *GetHeightForCollectionRequest) (*GetHeightForCollectionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHeightForCollection not implemented")
+}
 func (UnimplementedAPIServer) GetGuarantee(context.Context, *GetGuaranteeRequest) (*GetGuaranteeResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetGuarantee not implemented")
 }
@@ -256,12 +314,24 @@ func (UnimplementedAPIServer) ListTransactionsForHeight(context.Context, *ListTr
 func (UnimplementedAPIServer) GetResult(context.Context, *GetResultRequest) (*GetResultResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetResult not implemented")
 }
+func (UnimplementedAPIServer) ListResultsForHeight(context.Context, *ListResultsForHeightRequest) (*ListResultsForHeightResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListResultsForHeight not implemented")
+}
 func (UnimplementedAPIServer) GetSeal(context.Context, *GetSealRequest) (*GetSealResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetSeal not implemented")
 }
 func (UnimplementedAPIServer) ListSealsForHeight(context.Context, *ListSealsForHeightRequest) (*ListSealsForHeightResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListSealsForHeight not implemented")
 }
+func (UnimplementedAPIServer) GetHeightForSeal(context.Context, *GetHeightForSealRequest) (*GetHeightForSealResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHeightForSeal not implemented")
+}
+func (UnimplementedAPIServer) GetBlock(context.Context, *GetBlockRequest) (*GetBlockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlock not implemented")
+}
+func (UnimplementedAPIServer) GetIndexStats(context.Context, *GetIndexStatsRequest) (*GetIndexStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetIndexStats not implemented")
+}
 
 // UnsafeAPIServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to APIServer will
@@ -436,6 +506,24 @@ func _API_ListCollectionsForHeight_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _API_GetHeightForCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHeightForCollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).GetHeightForCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/GetHeightForCollection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).GetHeightForCollection(ctx, req.(*GetHeightForCollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _API_GetGuarantee_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetGuaranteeRequest)
 	if err := dec(in); err != nil {
@@ -526,6 +614,24 @@ func _API_GetResult_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _API_ListResultsForHeight_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListResultsForHeightRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).ListResultsForHeight(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/ListResultsForHeight",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).ListResultsForHeight(ctx, req.(*ListResultsForHeightRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _API_GetSeal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetSealRequest)
 	if err := dec(in); err != nil {
@@ -562,6 +668,60 @@ func _API_ListSealsForHeight_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _API_GetHeightForSeal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHeightForSealRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).GetHeightForSeal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/GetHeightForSeal",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).GetHeightForSeal(ctx, req.(*GetHeightForSealRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _API_GetBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).GetBlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/GetBlock",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).GetBlock(ctx, req.(*GetBlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _API_GetIndexStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetIndexStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).GetIndexStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/GetIndexStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).GetIndexStats(ctx, req.(*GetIndexStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // API_ServiceDesc is the grpc.ServiceDesc for API service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -605,6 +765,10 @@ var API_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListCollectionsForHeight",
 			Handler:    _API_ListCollectionsForHeight_Handler,
 		},
+		{
+			MethodName: "GetHeightForCollection",
+			Handler:    _API_GetHeightForCollection_Handler,
+		},
 		{
 			MethodName: "GetGuarantee",
 			Handler:    _API_GetGuarantee_Handler,
@@ -625,6 +789,10 @@ var API_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetResult",
 			Handler:    _API_GetResult_Handler,
 		},
+		{
+			MethodName: "ListResultsForHeight",
+			Handler:    _API_ListResultsForHeight_Handler,
+		},
 		{
 			MethodName: "GetSeal",
 			Handler:    _API_GetSeal_Handler,
@@ -633,6 +801,18 @@ var API_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListSealsForHeight",
 			Handler:    _API_ListSealsForHeight_Handler,
 		},
+		{
+			MethodName: "GetHeightForSeal",
+			Handler:    _API_GetHeightForSeal_Handler,
+		},
+		{
+			MethodName: "GetBlock",
+			Handler:    _API_GetBlock_Handler,
+		},
+		{
+			MethodName: "GetIndexStats",
+			Handler:    _API_GetIndexStats_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api.proto",
@@ -0,0 +1,214 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v2"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// Names of the fields of the `Block` message, which can be used in the field
+// mask of a `GetBlock` request.
+const (
+	FieldHeader       = "header"
+	FieldCollections  = "collections"
+	FieldGuarantees   = "guarantees"
+	FieldTransactions = "transactions"
+	FieldResults      = "results"
+	FieldEvents       = "events"
+	FieldSeals        = "seals"
+)
+
+// MaskFromFields converts the field mask of a `GetBlock` request into a block
+// mask. An empty field mask selects all the fields of the block.
+func MaskFromFields(fields *fieldmaskpb.FieldMask) (dps.BlockMask, error) {
+
+	paths := fields.GetPaths()
+	if len(paths) == 0 {
+		return dps.FullBlock, nil
+	}
+
+	var mask dps.BlockMask
+	for _, path := range paths {
+		switch path {
+		case FieldHeader:
+			mask.Header = true
+		case FieldCollections:
+			mask.Collections = true
+		case FieldGuarantees:
+			mask.Guarantees = true
+		case FieldTransactions:
+			mask.Transactions = true
+		case FieldResults:
+			mask.Results = true
+		case FieldEvents:
+			mask.Events = true
+		case FieldSeals:
+			mask.Seals = true
+		default:
+			return dps.BlockMask{}, fmt.Errorf("unknown block field (%s)", path)
+		}
+	}
+
+	return mask, nil
+}
+
+// FieldsFromMask converts a block mask into the field mask of a `GetBlock`
+// request.
+func FieldsFromMask(mask dps.BlockMask) *fieldmaskpb.FieldMask {
+
+	var paths []string
+	if mask.Header {
+		paths = append(paths, FieldHeader)
+	}
+	if mask.Collections {
+		paths = append(paths, FieldCollections)
+	}
+	if mask.Guarantees {
+		paths = append(paths, FieldGuarantees)
+	}
+	if mask.Transactions {
+		paths = append(paths, FieldTransactions)
+	}
+	if mask.Results {
+		paths = append(paths, FieldResults)
+	}
+	if mask.Events {
+		paths = append(paths, FieldEvents)
+	}
+	if mask.Seals {
+		paths = append(paths, FieldSeals)
+	}
+
+	return &fieldmaskpb.FieldMask{Paths: paths}
+}
+
+// assembler implements the `dps.BlockReader` interface for index readers that
+// can not read whole blocks at once, by assembling them from the entities read
+// through the individual methods of the index reader.
+type assembler struct {
+	index dps.Reader
+}
+
+// Block returns the data selected by the given mask for the finalized block at
+// the given height.
+func (a *assembler) Block(height uint64, mask dps.BlockMask) (*dps.Block, error) {
+
+	header, err := a.index.Header(height)
+	if err != nil {
+		return nil, fmt.Errorf("could not get header: %w", err)
+	}
+
+	block := dps.Block{
+		Height:  height,
+		BlockID: header.ID(),
+	}
+	if mask.Header {
+		block.Header = header
+	}
+
+	if mask.Collections || mask.Guarantees {
+		collIDs, err := a.index.CollectionsByHeight(height)
+		if err != nil {
+			return nil, fmt.Errorf("could not list collections: %w", err)
+		}
+		for _, collID := range collIDs {
+			if mask.Collections {
+				collection, err := a.index.Collection(collID)
+				if err != nil {
+					return nil, fmt.Errorf("could not get collection (id: %x): %w", collID, err)
+				}
+				block.Collections = append(block.Collections, collection)
+			}
+			// Only the collections of the block payload have a guarantee, which
+			// is not the case for the system collection of the block.
+			if mask.Guarantees {
+				guarantee, err := a.index.Guarantee(collID)
+				if errors.Is(err, badger.ErrKeyNotFound) || errors.Is(err, dps.ErrNotFound) {
+					continue
+				}
+				if err != nil {
+					return nil, fmt.Errorf("could not get guarantee (id: %x): %w", collID, err)
+				}
+				block.Guarantees = append(block.Guarantees, guarantee)
+			}
+		}
+	}
+
+	if mask.Transactions || mask.Results {
+		txIDs, err := a.index.TransactionsByHeight(height)
+		if err != nil {
+			return nil, fmt.Errorf("could not list transactions: %w", err)
+		}
+		for _, txID := range txIDs {
+			if mask.Transactions {
+				transaction, err := a.index.Transaction(txID)
+				if err != nil {
+					return nil, fmt.Errorf("could not get transaction (id: %x): %w", txID, err)
+				}
+				block.Transactions = append(block.Transactions, transaction)
+			}
+			if mask.Results {
+				result, err := a.index.Result(txID)
+				if err != nil {
+					return nil, fmt.Errorf("could not get transaction result (id: %x): %w", txID, err)
+				}
+				block.Results = append(block.Results, result)
+			}
+		}
+	}
+
+	if mask.Events {
+		block.Events, err = a.index.Events(height)
+		if err != nil {
+			return nil, fmt.Errorf("could not get events: %w", err)
+		}
+	}
+
+	if mask.Seals {
+		sealIDs, err := a.index.SealsByHeight(height)
+		if err != nil {
+			return nil, fmt.Errorf("could not list seals: %w", err)
+		}
+		for _, sealID := range sealIDs {
+			seal, err := a.index.Seal(sealID)
+			if err != nil {
+				return nil, fmt.Errorf("could not get seal (id: %x): %w", sealID, err)
+			}
+			block.Seals = append(block.Seals, seal)
+		}
+	}
+
+	return &block, nil
+}
+
+// BlockByID returns the data selected by the given mask for the finalized
+// block with the given ID.
+func (a *assembler) BlockByID(blockID flow.Identifier, mask dps.BlockMask) (*dps.Block, error) {
+
+	height, err := a.index.HeightForBlock(blockID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get height for block: %w", err)
+	}
+
+	return a.Block(height, mask)
+}
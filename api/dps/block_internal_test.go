@@ -0,0 +1,122 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestMaskFromFields(t *testing.T) {
+	t.Run("empty mask selects all fields", func(t *testing.T) {
+		got, err := MaskFromFields(nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, dps.FullBlock, got)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		mask := dps.BlockMask{Collections: true, Results: true, Events: true}
+
+		got, err := MaskFromFields(FieldsFromMask(mask))
+
+		require.NoError(t, err)
+		assert.Equal(t, mask, got)
+	})
+
+	t.Run("handles unknown field", func(t *testing.T) {
+		_, err := MaskFromFields(&fieldmaskpb.FieldMask{Paths: []string{FieldHeader, "payload"}})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestAssembler(t *testing.T) {
+	collIDs := mocks.GenericCollectionIDs(5)
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			return mocks.GenericHeight, nil
+		}
+		// The last collection has no guarantee, like the system collection.
+		index.GuaranteeFunc = func(collID flow.Identifier) (*flow.CollectionGuarantee, error) {
+			if collID == collIDs[4] {
+				return nil, fmt.Errorf("could not retrieve guarantee: %w", badger.ErrKeyNotFound)
+			}
+			return mocks.GenericGuarantee(0), nil
+		}
+
+		a := assembler{index: index}
+		got, err := a.BlockByID(mocks.GenericHeader.ID(), dps.FullBlock)
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, got.Height)
+		assert.Equal(t, mocks.GenericHeader.ID(), got.BlockID)
+		assert.Equal(t, mocks.GenericHeader, got.Header)
+		assert.Len(t, got.Collections, 5)
+		assert.Len(t, got.Guarantees, 4)
+		assert.Len(t, got.Transactions, len(got.Results))
+		assert.NotEmpty(t, got.Events)
+		assert.Len(t, got.Seals, 5)
+	})
+
+	t.Run("only reads selected fields", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.EventsFunc = func(uint64, ...flow.EventType) ([]flow.Event, error) {
+			t.Error("events should not be read")
+			return nil, nil
+		}
+		index.CollectionsByHeightFunc = func(uint64) ([]flow.Identifier, error) {
+			t.Error("collections should not be read")
+			return nil, nil
+		}
+
+		a := assembler{index: index}
+		got, err := a.Block(mocks.GenericHeight, dps.BlockMask{Seals: true})
+
+		require.NoError(t, err)
+		assert.Nil(t, got.Header)
+		assert.Len(t, got.Seals, 5)
+	})
+
+	t.Run("handles index failure", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.SealFunc = func(flow.Identifier) (*flow.Seal, error) {
+			return nil, mocks.GenericError
+		}
+
+		a := assembler{index: index}
+		_, err := a.Block(mocks.GenericHeight, dps.FullBlock)
+
+		assert.ErrorIs(t, err, mocks.GenericError)
+	})
+}
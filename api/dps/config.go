@@ -0,0 +1,65 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+import (
+	"github.com/optakt/flow-dps/service/stats"
+)
+
+// DefaultConfig is the default configuration for the Server.
+var DefaultConfig = Config{
+	Stats: nil,
+	Cache: nil,
+}
+
+// Config contains optional parameters for the Server.
+type Config struct {
+	Stats Collector
+	Cache Cache
+}
+
+// Collector represents something that can collect the statistics of an index.
+type Collector interface {
+	Collect() (*stats.Report, error)
+}
+
+// Cache represents a cache for the encoded responses of the server.
+type Cache interface {
+	Get(method string, key []byte) ([]byte, bool)
+	Set(method string, key []byte, data []byte)
+}
+
+// Option is an option that can be given to the server to configure optional
+// parameters on initialization.
+type Option func(*Config)
+
+// WithStats sets the collector used to serve the statistics of the index. The
+// `GetIndexStats` method fails when no collector is set, as collecting them
+// requires walking through the whole index.
+func WithStats(collect Collector) Option {
+	return func(cfg *Config) {
+		cfg.Stats = collect
+	}
+}
+
+// WithCache sets the cache used to serve the codec-encoded headers, collections,
+// guarantees, transactions, results, seals and blocks, which never change once
+// they are indexed. Without a cache, they are read from the index for each
+// request.
+func WithCache(cache Cache) Option {
+	return func(cfg *Config) {
+		cfg.Cache = cache
+	}
+}
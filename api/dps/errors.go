@@ -0,0 +1,140 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// codeErrors maps the GRPC status codes returned by the DPS API to the typed
+// errors they stand for, so that errors can be matched with `errors.Is` on
+// both sides of the API.
+var codeErrors = map[codes.Code]error{
+	codes.InvalidArgument:    dps.ErrInvalidArgument,
+	codes.NotFound:           dps.ErrNotFound,
+	codes.OutOfRange:         dps.ErrOutOfRange,
+	codes.FailedPrecondition: dps.ErrNotIncluded,
+	codes.Unavailable:        dps.ErrUnavailable,
+}
+
+// apiError is an error of the DPS API, along with its GRPC status code. It is
+// returned as is by the server, so that the GRPC server uses its code for the
+// response, and wraps the original error, so that callers of the server can
+// still inspect it.
+type apiError struct {
+	code codes.Code
+	err  error
+}
+
+// Error implements the `error` interface.
+func (a *apiError) Error() string {
+	return a.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (a *apiError) Unwrap() error {
+	return a.err
+}
+
+// Is returns whether the target is the typed error matching the status code.
+func (a *apiError) Is(target error) bool {
+	sentinel, ok := codeErrors[a.code]
+	return ok && target == sentinel
+}
+
+// GRPCStatus returns the GRPC status of the error. If the wrapped error is
+// itself a GRPC status error, its status is kept as is, along with its details.
+func (a *apiError) GRPCStatus() *status.Status {
+	st, ok := status.FromError(a.err)
+	if ok {
+		return st
+	}
+	return status.New(a.code, a.err.Error())
+}
+
+// badRequest returns the error for a request that is not valid.
+func badRequest(err error) error {
+	return &apiError{code: codes.InvalidArgument, err: fmt.Errorf("bad request: %w", err)}
+}
+
+// fail returns the error for a request that failed, with a status code based
+// on the cause of the failure.
+func fail(err error) error {
+	var invalid validator.ValidationErrors
+	code := codes.Internal
+	switch {
+	case errors.As(err, &invalid), errors.Is(err, dps.ErrInvalidArgument):
+		code = codes.InvalidArgument
+	case errors.Is(err, badger.ErrKeyNotFound), errors.Is(err, dps.ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, dps.ErrOutOfRange):
+		code = codes.OutOfRange
+	case errors.Is(err, dps.ErrNotIncluded):
+		code = codes.FailedPrecondition
+	case errors.Is(err, dps.ErrUnavailable):
+		code = codes.Unavailable
+	}
+	return &apiError{code: code, err: err}
+}
+
+// failAt returns the error for a request at the given height that failed. When
+// the data is missing because the height is outside of the indexed range, the
+// request fails with an out of range error instead of a not found error.
+func (s *Server) failAt(height uint64, err error) error {
+	if !errors.Is(err, badger.ErrKeyNotFound) {
+		return fail(err)
+	}
+	first, last, ok := s.heights()
+	if ok && (height < first || height > last) {
+		return fail(fmt.Errorf("invalid height: %w (given: %d, first: %d, last: %d)", dps.ErrOutOfRange, height, first, last))
+	}
+	return fail(err)
+}
+
+// heights returns the first and last indexed heights, if they can be read.
+func (s *Server) heights() (uint64, uint64, bool) {
+	first, err := s.index.First()
+	if err != nil {
+		return 0, 0, false
+	}
+	last, err := s.index.Last()
+	if err != nil {
+		return 0, 0, false
+	}
+	return first, last, true
+}
+
+// fromStatus converts an error returned by a DPS API client into an error that
+// matches the typed error for its status code, if there is one. The GRPC
+// status of the error remains available, for example for its details.
+func fromStatus(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	_, ok = codeErrors[st.Code()]
+	if !ok {
+		return err
+	}
+	return &apiError{code: st.Code(), err: err}
+}
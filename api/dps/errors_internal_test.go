@@ -0,0 +1,194 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestServer_StatusCodes(t *testing.T) {
+	notFound := fmt.Errorf("could not get value: %w", badger.ErrKeyNotFound)
+
+	tests := []struct {
+		name string
+
+		height    uint64
+		encoding  Encoding
+		mockErr   error
+		withStats bool
+
+		wantCode codes.Code
+		wantErr  error
+	}{
+		{
+			name:     "invalid argument for bad request",
+			height:   mocks.GenericHeight,
+			encoding: Encoding(42),
+			wantCode: codes.InvalidArgument,
+			wantErr:  dps.ErrInvalidArgument,
+		},
+		{
+			name:     "not found for missing key in indexed range",
+			height:   mocks.GenericHeight,
+			mockErr:  notFound,
+			wantCode: codes.NotFound,
+			wantErr:  badger.ErrKeyNotFound,
+		},
+		{
+			name:     "out of range for missing key outside indexed range",
+			height:   mocks.GenericHeight + 1,
+			mockErr:  notFound,
+			wantCode: codes.OutOfRange,
+			wantErr:  dps.ErrOutOfRange,
+		},
+		{
+			name:     "failed precondition for data not included",
+			height:   mocks.GenericHeight,
+			mockErr:  fmt.Errorf("headers are %w", dps.ErrNotIncluded),
+			wantCode: codes.FailedPrecondition,
+			wantErr:  dps.ErrNotIncluded,
+		},
+		{
+			name:     "unavailable for data not yet available",
+			height:   mocks.GenericHeight,
+			mockErr:  dps.ErrUnavailable,
+			wantCode: codes.Unavailable,
+			wantErr:  dps.ErrUnavailable,
+		},
+		{
+			name:     "internal for other errors",
+			height:   mocks.GenericHeight,
+			mockErr:  mocks.GenericError,
+			wantCode: codes.Internal,
+			wantErr:  mocks.GenericError,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			index := mocks.BaselineReader(t)
+			index.HeaderFunc = func(uint64) (*flow.Header, error) {
+				return nil, test.mockErr
+			}
+			s := NewServer(index, zbor.NewCodec())
+
+			req := GetHeaderRequest{
+				Height:   test.height,
+				Encoding: test.encoding,
+			}
+			_, err := s.GetHeader(context.Background(), &req)
+
+			require.Error(t, err)
+			assert.Equal(t, test.wantCode, status.Code(err))
+			assert.ErrorIs(t, err, test.wantErr)
+		})
+	}
+
+	t.Run("unimplemented for disabled statistics", func(t *testing.T) {
+		t.Parallel()
+
+		s := NewServer(mocks.BaselineReader(t), zbor.NewCodec())
+
+		_, err := s.GetIndexStats(context.Background(), &GetIndexStatsRequest{})
+
+		assert.Equal(t, codes.Unimplemented, status.Code(err))
+	})
+}
+
+func TestFromStatus(t *testing.T) {
+	t.Run("matches typed errors", func(t *testing.T) {
+		t.Parallel()
+
+		for code, sentinel := range codeErrors {
+			err := fromStatus(status.Error(code, "failure"))
+
+			assert.ErrorIs(t, err, sentinel)
+			assert.Equal(t, code, status.Code(err))
+		}
+	})
+
+	t.Run("keeps other errors as is", func(t *testing.T) {
+		t.Parallel()
+
+		exhausted := status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		assert.Equal(t, exhausted, fromStatus(exhausted))
+		assert.Equal(t, mocks.GenericError, fromStatus(mocks.GenericError))
+	})
+}
+
+func TestIndex_StatusErrors(t *testing.T) {
+	listener := bufconn.Listen(1024 * 1024)
+	defer listener.Close()
+
+	reader := mocks.BaselineReader(t)
+	reader.TransactionFunc = func(flow.Identifier) (*flow.TransactionBody, error) {
+		return nil, fmt.Errorf("could not get value: %w", badger.ErrKeyNotFound)
+	}
+	reader.CommitFunc = func(uint64) (flow.StateCommitment, error) {
+		return flow.DummyStateCommitment, fmt.Errorf("could not get value: %w", badger.ErrKeyNotFound)
+	}
+	reader.ValuesFunc = func(uint64, []ledger.Path) ([]ledger.Value, error) {
+		return nil, mocks.GenericError
+	}
+
+	gsvr := grpc.NewServer()
+	RegisterAPIServer(gsvr, NewServer(reader, zbor.NewCodec()))
+	go func() {
+		_ = gsvr.Serve(listener)
+	}()
+	defer gsvr.Stop()
+
+	dialer := func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}
+	conn, err := grpc.Dial("bufnet", grpc.WithContextDialer(dialer), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	index := IndexFromAPI(NewAPIClient(conn), zbor.NewCodec())
+
+	_, err = index.Transaction(mocks.GenericTransactionIDs(1)[0])
+	assert.ErrorIs(t, err, dps.ErrNotFound)
+
+	_, err = index.Commit(mocks.GenericHeight + 1)
+	assert.ErrorIs(t, err, dps.ErrOutOfRange)
+
+	_, err = index.Values(mocks.GenericHeight, mocks.GenericLedgerPaths(1))
+	assert.False(t, errors.Is(err, dps.ErrNotFound))
+	var se interface{ GRPCStatus() *status.Status }
+	require.True(t, errors.As(err, &se))
+	assert.Equal(t, codes.Internal, se.GRPCStatus().Code())
+}
@@ -0,0 +1,315 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/spork"
+)
+
+// Federation implements the `index.Reader` interface on top of the GRPC APIs
+// of multiple DPS servers, each of them serving the index of a different
+// spork. Calls for a given height are routed to the server of the spork that
+// contains it, while lookups by identifier are tried on each spork in turn,
+// starting with the most recent one.
+type Federation struct {
+	sporks  *spork.Registry
+	indexes map[uint64]dps.Reader // indexes by first height of their spork
+}
+
+// FederationFromAPIs creates a new federated index reader for the sporks of
+// the given registry. The connect function is called once for each spork, in
+// order to get the GRPC API client for its DPS server.
+func FederationFromAPIs(sporks *spork.Registry, codec dps.Codec, connect func(spork.Spork) (APIClient, error)) (*Federation, error) {
+	return NewFederation(sporks, func(spork spork.Spork) (dps.Reader, error) {
+		client, err := connect(spork)
+		if err != nil {
+			return nil, err
+		}
+		return IndexFromAPI(client, codec), nil
+	})
+}
+
+// NewFederation creates a new federated index reader for the sporks of the
+// given registry. The connect function is called once for each spork, in order
+// to get the index reader for its DPS server, which allows wrapping the
+// readers, for example to add caching or retries.
+func NewFederation(sporks *spork.Registry, connect func(spork.Spork) (dps.Reader, error)) (*Federation, error) {
+
+	indexes := make(map[uint64]dps.Reader)
+	for _, spork := range sporks.Sporks() {
+		index, err := connect(spork)
+		if err != nil {
+			return nil, fmt.Errorf("could not connect to spork %s API: %w", spork.Name, err)
+		}
+		indexes[spork.First] = index
+	}
+
+	f := Federation{
+		sporks:  sporks,
+		indexes: indexes,
+	}
+
+	return &f, nil
+}
+
+// First returns the height of the first finalized block that was indexed on
+// the oldest spork.
+func (f *Federation) First() (uint64, error) {
+	oldest := f.sporks.Sporks()[0]
+	return f.indexes[oldest.First].First()
+}
+
+// Last returns the height of the last finalized block that was indexed on the
+// most recent spork.
+func (f *Federation) Last() (uint64, error) {
+	latest := f.sporks.Latest()
+	return f.indexes[latest.First].Last()
+}
+
+// HeightForBlock returns the height of the given blockID.
+func (f *Federation) HeightForBlock(blockID flow.Identifier) (uint64, error) {
+	var height uint64
+	err := f.lookup(func(index dps.Reader) error {
+		var err error
+		height, err = index.HeightForBlock(blockID)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("could not look up height for block (block: %x): %w", blockID, err)
+	}
+	return height, nil
+}
+
+// HeightForTransaction returns the height of the given transaction ID.
+func (f *Federation) HeightForTransaction(txID flow.Identifier) (uint64, error) {
+	var height uint64
+	err := f.lookup(func(index dps.Reader) error {
+		var err error
+		height, err = index.HeightForTransaction(txID)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("could not look up height for transaction (tx: %x): %w", txID, err)
+	}
+	return height, nil
+}
+
+// HeightForCollection returns the height of the given collection ID.
+func (f *Federation) HeightForCollection(collID flow.Identifier) (uint64, error) {
+	var height uint64
+	err := f.lookup(func(index dps.Reader) error {
+		var err error
+		height, err = index.HeightForCollection(collID)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("could not look up height for collection (collection: %x): %w", collID, err)
+	}
+	return height, nil
+}
+
+// HeightForSeal returns the height of the given seal ID.
+func (f *Federation) HeightForSeal(sealID flow.Identifier) (uint64, error) {
+	var height uint64
+	err := f.lookup(func(index dps.Reader) error {
+		var err error
+		height, err = index.HeightForSeal(sealID)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("could not look up height for seal (seal: %x): %w", sealID, err)
+	}
+	return height, nil
+}
+
+// Commit returns the commitment of the execution state as it was after the
+// execution of the finalized block at the given height.
+func (f *Federation) Commit(height uint64) (flow.StateCommitment, error) {
+	index, err := f.route(height)
+	if err != nil {
+		return flow.DummyStateCommitment, err
+	}
+	return index.Commit(height)
+}
+
+// Header returns the header for the finalized block at the given height.
+func (f *Federation) Header(height uint64) (*flow.Header, error) {
+	index, err := f.route(height)
+	if err != nil {
+		return nil, err
+	}
+	return index.Header(height)
+}
+
+// Events returns the events of all transactions that were part of the
+// finalized block at the given height. It can optionally filter them by event
+// type; if no event types are given, all events are returned.
+func (f *Federation) Events(height uint64, types ...flow.EventType) ([]flow.Event, error) {
+	index, err := f.route(height)
+	if err != nil {
+		return nil, err
+	}
+	return index.Events(height, types...)
+}
+
+// Values returns the Ledger values of the execution state at the given paths
+// as they were after the execution of the finalized block at the given height.
+func (f *Federation) Values(height uint64, paths []ledger.Path) ([]ledger.Value, error) {
+	index, err := f.route(height)
+	if err != nil {
+		return nil, err
+	}
+	return index.Values(height, paths)
+}
+
+// Collection returns the collection with the given ID.
+func (f *Federation) Collection(collID flow.Identifier) (*flow.LightCollection, error) {
+	var collection *flow.LightCollection
+	err := f.lookup(func(index dps.Reader) error {
+		var err error
+		collection, err = index.Collection(collID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not look up collection (collection: %x): %w", collID, err)
+	}
+	return collection, nil
+}
+
+// Guarantee returns the collection guarantee for the given collection ID.
+func (f *Federation) Guarantee(collID flow.Identifier) (*flow.CollectionGuarantee, error) {
+	var guarantee *flow.CollectionGuarantee
+	err := f.lookup(func(index dps.Reader) error {
+		var err error
+		guarantee, err = index.Guarantee(collID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not look up guarantee (collection: %x): %w", collID, err)
+	}
+	return guarantee, nil
+}
+
+// Transaction returns the transaction with the given ID.
+func (f *Federation) Transaction(txID flow.Identifier) (*flow.TransactionBody, error) {
+	var transaction *flow.TransactionBody
+	err := f.lookup(func(index dps.Reader) error {
+		var err error
+		transaction, err = index.Transaction(txID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not look up transaction (tx: %x): %w", txID, err)
+	}
+	return transaction, nil
+}
+
+// Seal returns the seal with the given ID.
+func (f *Federation) Seal(sealID flow.Identifier) (*flow.Seal, error) {
+	var seal *flow.Seal
+	err := f.lookup(func(index dps.Reader) error {
+		var err error
+		seal, err = index.Seal(sealID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not look up seal (seal: %x): %w", sealID, err)
+	}
+	return seal, nil
+}
+
+// Result returns the result for a given transaction ID.
+func (f *Federation) Result(txID flow.Identifier) (*flow.TransactionResult, error) {
+	var result *flow.TransactionResult
+	err := f.lookup(func(index dps.Reader) error {
+		var err error
+		result, err = index.Result(txID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not look up transaction result (tx: %x): %w", txID, err)
+	}
+	return result, nil
+}
+
+// CollectionsByHeight returns the collection IDs within the given block.
+func (f *Federation) CollectionsByHeight(height uint64) ([]flow.Identifier, error) {
+	index, err := f.route(height)
+	if err != nil {
+		return nil, err
+	}
+	return index.CollectionsByHeight(height)
+}
+
+// TransactionsByHeight returns the transaction IDs within the given block.
+func (f *Federation) TransactionsByHeight(height uint64) ([]flow.Identifier, error) {
+	index, err := f.route(height)
+	if err != nil {
+		return nil, err
+	}
+	return index.TransactionsByHeight(height)
+}
+
+// SealsByHeight returns the seal IDs at the given height.
+func (f *Federation) SealsByHeight(height uint64) ([]flow.Identifier, error) {
+	index, err := f.route(height)
+	if err != nil {
+		return nil, err
+	}
+	return index.SealsByHeight(height)
+}
+
+// ResultsByHeight returns the transaction results within the given block.
+func (f *Federation) ResultsByHeight(height uint64) ([]*flow.TransactionResult, error) {
+	index, err := f.route(height)
+	if err != nil {
+		return nil, err
+	}
+	return index.ResultsByHeight(height)
+}
+
+// route returns the index of the spork that contains the given height.
+func (f *Federation) route(height uint64) (dps.Reader, error) {
+	spork, err := f.sporks.Spork(height)
+	if err != nil {
+		return nil, fmt.Errorf("could not route height to spork: %w", err)
+	}
+	return f.indexes[spork.First], nil
+}
+
+// lookup executes the given operation on the index of each spork, starting
+// with the most recent one, until it succeeds. If it fails on all sporks, the
+// errors of each spork are returned as a multi-error.
+func (f *Federation) lookup(op func(index dps.Reader) error) error {
+	sporks := f.sporks.Sporks()
+	var errs error
+	for i := len(sporks) - 1; i >= 0; i-- {
+		spork := sporks[i]
+		err := op(f.indexes[spork.First])
+		if err == nil {
+			return nil
+		}
+		errs = multierror.Append(errs, fmt.Errorf("spork %s: %w", spork.Name, err))
+	}
+	return errs
+}
@@ -0,0 +1,232 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/spork"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestFederationFromAPIs(t *testing.T) {
+	sporks := testSporks(t)
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		var connected []string
+		federation, err := FederationFromAPIs(sporks, mocks.BaselineCodec(t), func(s spork.Spork) (APIClient, error) {
+			connected = append(connected, s.Name)
+			return &apiMock{}, nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"first", "second", "third"}, connected)
+		assert.Len(t, federation.indexes, 3)
+	})
+
+	t.Run("handles connection failure", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := FederationFromAPIs(sporks, mocks.BaselineCodec(t), func(spork.Spork) (APIClient, error) {
+			return nil, mocks.GenericError
+		})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestNewFederation(t *testing.T) {
+	sporks := testSporks(t)
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		readers := make(map[string]dps.Reader)
+		federation, err := NewFederation(sporks, func(s spork.Spork) (dps.Reader, error) {
+			reader := mocks.BaselineReader(t)
+			readers[s.Name] = reader
+			return reader, nil
+		})
+
+		require.NoError(t, err)
+		require.Len(t, federation.indexes, 3)
+		for _, s := range sporks.Sporks() {
+			assert.Same(t, readers[s.Name], federation.indexes[s.First])
+		}
+	})
+
+	t.Run("handles connection failure", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewFederation(sporks, func(spork.Spork) (dps.Reader, error) {
+			return nil, mocks.GenericError
+		})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestFederation_First(t *testing.T) {
+	oldest := mocks.BaselineReader(t)
+	oldest.FirstFunc = func() (uint64, error) {
+		return 1, nil
+	}
+
+	federation := testFederation(t, oldest, mocks.BaselineReader(t), mocks.BaselineReader(t))
+
+	got, err := federation.First()
+
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), got)
+}
+
+func TestFederation_Last(t *testing.T) {
+	latest := mocks.BaselineReader(t)
+	latest.LastFunc = func() (uint64, error) {
+		return 250, nil
+	}
+
+	federation := testFederation(t, mocks.BaselineReader(t), mocks.BaselineReader(t), latest)
+
+	got, err := federation.Last()
+
+	require.NoError(t, err)
+	assert.Equal(t, uint64(250), got)
+}
+
+func TestFederation_Header(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		called := make(map[string]uint64)
+		readers := make([]*mocks.Reader, 0, 3)
+		for _, name := range []string{"first", "second", "third"} {
+			name := name
+			reader := mocks.BaselineReader(t)
+			reader.HeaderFunc = func(height uint64) (*flow.Header, error) {
+				called[name] = height
+				return mocks.GenericHeader, nil
+			}
+			readers = append(readers, reader)
+		}
+
+		federation := testFederation(t, readers[0], readers[1], readers[2])
+
+		_, err := federation.Header(100)
+		require.NoError(t, err)
+		_, err = federation.Header(101)
+		require.NoError(t, err)
+		_, err = federation.Header(1_000_000)
+		require.NoError(t, err)
+
+		want := map[string]uint64{
+			"first":  100,
+			"second": 101,
+			"third":  1_000_000,
+		}
+		assert.Equal(t, want, called)
+	})
+
+	t.Run("handles height outside of sporks", func(t *testing.T) {
+		t.Parallel()
+
+		federation := testFederation(t, mocks.BaselineReader(t), mocks.BaselineReader(t), mocks.BaselineReader(t))
+
+		_, err := federation.Header(0)
+
+		assert.ErrorIs(t, err, spork.ErrNotFound)
+	})
+}
+
+func TestFederation_HeightForTransaction(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		var visited []uint64
+		readers := make([]*mocks.Reader, 0, 3)
+		for i, height := range []uint64{50, 150, 0} {
+			i := uint64(i)
+			height := height
+			reader := mocks.BaselineReader(t)
+			reader.HeightForTransactionFunc = func(flow.Identifier) (uint64, error) {
+				visited = append(visited, i)
+				if height == 0 {
+					return 0, mocks.GenericError
+				}
+				return height, nil
+			}
+			readers = append(readers, reader)
+		}
+
+		federation := testFederation(t, readers[0], readers[1], readers[2])
+
+		got, err := federation.HeightForTransaction(mocks.GenericTransaction(0).ID())
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(150), got)
+		assert.Equal(t, []uint64{2, 1}, visited)
+	})
+
+	t.Run("handles transaction missing from all sporks", func(t *testing.T) {
+		t.Parallel()
+
+		reader := mocks.BaselineReader(t)
+		reader.HeightForTransactionFunc = func(flow.Identifier) (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		federation := testFederation(t, reader, reader, reader)
+
+		_, err := federation.HeightForTransaction(mocks.GenericTransaction(0).ID())
+
+		assert.ErrorIs(t, err, mocks.GenericError)
+	})
+}
+
+func testSporks(t *testing.T) *spork.Registry {
+	t.Helper()
+
+	sporks, err := spork.New(
+		spork.Spork{Name: "first", API: "first:5005", First: 1, Last: 100},
+		spork.Spork{Name: "second", API: "second:5005", First: 101, Last: 200},
+		spork.Spork{Name: "third", API: "third:5005", First: 201},
+	)
+	require.NoError(t, err)
+
+	return sporks
+}
+
+func testFederation(t *testing.T, first, second, third dps.Reader) *Federation {
+	t.Helper()
+
+	f := Federation{
+		sporks: testSporks(t),
+		indexes: map[uint64]dps.Reader{
+			1:   first,
+			101: second,
+			201: third,
+		},
+	}
+
+	return &f
+}
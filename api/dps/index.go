@@ -48,11 +48,17 @@ func IndexFromAPI(client APIClient, codec dps.Codec) *Index {
 
 // First returns the height of the first finalized block that was indexed.
 func (i *Index) First() (uint64, error) {
+	return i.FirstContext(context.Background())
+}
+
+// FirstContext is like First, but uses the given context for the call to the
+// DPS API.
+func (i *Index) FirstContext(ctx context.Context) (uint64, error) {
 
 	req := GetFirstRequest{}
-	res, err := i.client.GetFirst(context.Background(), &req)
+	res, err := i.client.GetFirst(ctx, &req)
 	if err != nil {
-		return 0, fmt.Errorf("could not get first height: %w", err)
+		return 0, fmt.Errorf("could not get first height: %w", fromStatus(err))
 	}
 
 	return res.Height, nil
@@ -60,11 +66,17 @@ func (i *Index) First() (uint64, error) {
 
 // Last returns the height of the last finalized block that was indexed.
 func (i *Index) Last() (uint64, error) {
+	return i.LastContext(context.Background())
+}
+
+// LastContext is like Last, but uses the given context for the call to the DPS
+// API.
+func (i *Index) LastContext(ctx context.Context) (uint64, error) {
 
 	req := GetLastRequest{}
-	res, err := i.client.GetLast(context.Background(), &req)
+	res, err := i.client.GetLast(ctx, &req)
 	if err != nil {
-		return 0, fmt.Errorf("could not get last height: %w", err)
+		return 0, fmt.Errorf("could not get last height: %w", fromStatus(err))
 	}
 
 	return res.Height, nil
@@ -72,13 +84,19 @@ func (i *Index) Last() (uint64, error) {
 
 // HeightForBlock returns the height of the given blockID.
 func (i *Index) HeightForBlock(blockID flow.Identifier) (uint64, error) {
+	return i.HeightForBlockContext(context.Background(), blockID)
+}
+
+// HeightForBlockContext is like HeightForBlock, but uses the given context for
+// the call to the DPS API.
+func (i *Index) HeightForBlockContext(ctx context.Context, blockID flow.Identifier) (uint64, error) {
 
 	req := GetHeightForBlockRequest{
 		BlockID: blockID[:],
 	}
-	res, err := i.client.GetHeightForBlock(context.Background(), &req)
+	res, err := i.client.GetHeightForBlock(ctx, &req)
 	if err != nil {
-		return 0, fmt.Errorf("could not get height: %w", err)
+		return 0, fmt.Errorf("could not get height: %w", fromStatus(err))
 	}
 
 	return res.Height, nil
@@ -87,13 +105,19 @@ func (i *Index) HeightForBlock(blockID flow.Identifier) (uint64, error) {
 // Commit returns the commitment of the execution state as it was after the
 // execution of the finalized block at the given height.
 func (i *Index) Commit(height uint64) (flow.StateCommitment, error) {
+	return i.CommitContext(context.Background(), height)
+}
+
+// CommitContext is like Commit, but uses the given context for the call to the
+// DPS API.
+func (i *Index) CommitContext(ctx context.Context, height uint64) (flow.StateCommitment, error) {
 
 	req := GetCommitRequest{
 		Height: height,
 	}
-	res, err := i.client.GetCommit(context.Background(), &req)
+	res, err := i.client.GetCommit(ctx, &req)
 	if err != nil {
-		return flow.DummyStateCommitment, fmt.Errorf("could not get commit: %w", err)
+		return flow.DummyStateCommitment, fmt.Errorf("could not get commit: %w", fromStatus(err))
 	}
 
 	commit, err := flow.ToStateCommitment(res.Commit)
@@ -106,13 +130,19 @@ func (i *Index) Commit(height uint64) (flow.StateCommitment, error) {
 
 // Header returns the header for the finalized block at the given height.
 func (i *Index) Header(height uint64) (*flow.Header, error) {
+	return i.HeaderContext(context.Background(), height)
+}
+
+// HeaderContext is like Header, but uses the given context for the call to the
+// DPS API.
+func (i *Index) HeaderContext(ctx context.Context, height uint64) (*flow.Header, error) {
 
 	req := GetHeaderRequest{
 		Height: height,
 	}
-	res, err := i.client.GetHeader(context.Background(), &req)
+	res, err := i.client.GetHeader(ctx, &req)
 	if err != nil {
-		return nil, fmt.Errorf("could not get header: %w", err)
+		return nil, fmt.Errorf("could not get header: %w", fromStatus(err))
 	}
 
 	var header flow.Header
@@ -129,14 +159,20 @@ func (i *Index) Header(height uint64) (*flow.Header, error) {
 // For compatibility with existing Flow execution node code, a path that is not
 // found within the indexed execution state returns a nil value without error.
 func (i *Index) Values(height uint64, paths []ledger.Path) ([]ledger.Value, error) {
+	return i.ValuesContext(context.Background(), height, paths)
+}
+
+// ValuesContext is like Values, but uses the given context for the call to the
+// DPS API.
+func (i *Index) ValuesContext(ctx context.Context, height uint64, paths []ledger.Path) ([]ledger.Value, error) {
 
 	req := GetRegisterValuesRequest{
 		Height: height,
 		Paths:  convert.PathsToBytes(paths),
 	}
-	res, err := i.client.GetRegisterValues(context.Background(), &req)
+	res, err := i.client.GetRegisterValues(ctx, &req)
 	if err != nil {
-		return nil, fmt.Errorf("could not get registers: %w", err)
+		return nil, fmt.Errorf("could not get registers: %w", fromStatus(err))
 	}
 
 	values := convert.BytesToValues(res.Values)
@@ -146,13 +182,19 @@ func (i *Index) Values(height uint64, paths []ledger.Path) ([]ledger.Value, erro
 
 // Collection returns the collection with the given ID.
 func (i *Index) Collection(collID flow.Identifier) (*flow.LightCollection, error) {
+	return i.CollectionContext(context.Background(), collID)
+}
+
+// CollectionContext is like Collection, but uses the given context for the call
+// to the DPS API.
+func (i *Index) CollectionContext(ctx context.Context, collID flow.Identifier) (*flow.LightCollection, error) {
 
 	req := GetCollectionRequest{
 		CollectionID: collID[:],
 	}
-	res, err := i.client.GetCollection(context.Background(), &req)
+	res, err := i.client.GetCollection(ctx, &req)
 	if err != nil {
-		return nil, fmt.Errorf("could not get collection: %w", err)
+		return nil, fmt.Errorf("could not get collection: %w", fromStatus(err))
 	}
 
 	var collection flow.LightCollection
@@ -166,13 +208,19 @@ func (i *Index) Collection(collID flow.Identifier) (*flow.LightCollection, error
 
 // CollectionsByHeight returns the transaction IDs within the given block.
 func (i *Index) CollectionsByHeight(height uint64) ([]flow.Identifier, error) {
+	return i.CollectionsByHeightContext(context.Background(), height)
+}
+
+// CollectionsByHeightContext is like CollectionsByHeight, but uses the given
+// context for the call to the DPS API.
+func (i *Index) CollectionsByHeightContext(ctx context.Context, height uint64) ([]flow.Identifier, error) {
 
 	req := ListCollectionsForHeightRequest{
 		Height: height,
 	}
-	res, err := i.client.ListCollectionsForHeight(context.Background(), &req)
+	res, err := i.client.ListCollectionsForHeight(ctx, &req)
 	if err != nil {
-		return nil, fmt.Errorf("could not get transactions: %w", err)
+		return nil, fmt.Errorf("could not get transactions: %w", fromStatus(err))
 	}
 
 	collIDs := make([]flow.Identifier, 0, len(res.CollectionIDs))
@@ -185,13 +233,19 @@ func (i *Index) CollectionsByHeight(height uint64) ([]flow.Identifier, error) {
 
 // Guarantee returns the collection guarantee for the given collection ID.
 func (i *Index) Guarantee(collID flow.Identifier) (*flow.CollectionGuarantee, error) {
+	return i.GuaranteeContext(context.Background(), collID)
+}
+
+// GuaranteeContext is like Guarantee, but uses the given context for the call
+// to the DPS API.
+func (i *Index) GuaranteeContext(ctx context.Context, collID flow.Identifier) (*flow.CollectionGuarantee, error) {
 
 	req := GetGuaranteeRequest{
 		CollectionID: collID[:],
 	}
-	res, err := i.client.GetGuarantee(context.Background(), &req)
+	res, err := i.client.GetGuarantee(ctx, &req)
 	if err != nil {
-		return nil, fmt.Errorf("could not get guarantee: %w", err)
+		return nil, fmt.Errorf("could not get guarantee: %w", fromStatus(err))
 	}
 
 	var guarantee flow.CollectionGuarantee
@@ -205,13 +259,19 @@ func (i *Index) Guarantee(collID flow.Identifier) (*flow.CollectionGuarantee, er
 
 // Transaction returns the transaction with the given ID.
 func (i *Index) Transaction(txID flow.Identifier) (*flow.TransactionBody, error) {
+	return i.TransactionContext(context.Background(), txID)
+}
+
+// TransactionContext is like Transaction, but uses the given context for the
+// call to the DPS API.
+func (i *Index) TransactionContext(ctx context.Context, txID flow.Identifier) (*flow.TransactionBody, error) {
 
 	req := GetTransactionRequest{
 		TransactionID: txID[:],
 	}
-	res, err := i.client.GetTransaction(context.Background(), &req)
+	res, err := i.client.GetTransaction(ctx, &req)
 	if err != nil {
-		return nil, fmt.Errorf("could not get transaction: %w", err)
+		return nil, fmt.Errorf("could not get transaction: %w", fromStatus(err))
 	}
 
 	var transaction flow.TransactionBody
@@ -225,13 +285,59 @@ func (i *Index) Transaction(txID flow.Identifier) (*flow.TransactionBody, error)
 
 // HeightForTransaction returns the height of the given transaction ID.
 func (i *Index) HeightForTransaction(txID flow.Identifier) (uint64, error) {
+	return i.HeightForTransactionContext(context.Background(), txID)
+}
+
+// HeightForTransactionContext is like HeightForTransaction, but uses the given
+// context for the call to the DPS API.
+func (i *Index) HeightForTransactionContext(ctx context.Context, txID flow.Identifier) (uint64, error) {
 
 	req := GetHeightForTransactionRequest{
 		TransactionID: txID[:],
 	}
-	res, err := i.client.GetHeightForTransaction(context.Background(), &req)
+	res, err := i.client.GetHeightForTransaction(ctx, &req)
+	if err != nil {
+		return 0, fmt.Errorf("could not get height: %w", fromStatus(err))
+	}
+
+	return res.Height, nil
+}
+
+// HeightForCollection returns the height of the given collection ID.
+func (i *Index) HeightForCollection(collID flow.Identifier) (uint64, error) {
+	return i.HeightForCollectionContext(context.Background(), collID)
+}
+
+// HeightForCollectionContext is like HeightForCollection, but uses the given
+// context for the call to the DPS API.
+func (i *Index) HeightForCollectionContext(ctx context.Context, collID flow.Identifier) (uint64, error) {
+
+	req := GetHeightForCollectionRequest{
+		CollectionID: collID[:],
+	}
+	res, err := i.client.GetHeightForCollection(ctx, &req)
+	if err != nil {
+		return 0, fmt.Errorf("could not get height: %w", fromStatus(err))
+	}
+
+	return res.Height, nil
+}
+
+// HeightForSeal returns the height of the given seal ID.
+func (i *Index) HeightForSeal(sealID flow.Identifier) (uint64, error) {
+	return i.HeightForSealContext(context.Background(), sealID)
+}
+
+// HeightForSealContext is like HeightForSeal, but uses the given context for
+// the call to the DPS API.
+func (i *Index) HeightForSealContext(ctx context.Context, sealID flow.Identifier) (uint64, error) {
+
+	req := GetHeightForSealRequest{
+		SealID: sealID[:],
+	}
+	res, err := i.client.GetHeightForSeal(ctx, &req)
 	if err != nil {
-		return 0, fmt.Errorf("could not get height: %w", err)
+		return 0, fmt.Errorf("could not get height: %w", fromStatus(err))
 	}
 
 	return res.Height, nil
@@ -239,13 +345,19 @@ func (i *Index) HeightForTransaction(txID flow.Identifier) (uint64, error) {
 
 // TransactionsByHeight returns the transaction IDs within the given block.
 func (i *Index) TransactionsByHeight(height uint64) ([]flow.Identifier, error) {
+	return i.TransactionsByHeightContext(context.Background(), height)
+}
+
+// TransactionsByHeightContext is like TransactionsByHeight, but uses the given
+// context for the call to the DPS API.
+func (i *Index) TransactionsByHeightContext(ctx context.Context, height uint64) ([]flow.Identifier, error) {
 
 	req := ListTransactionsForHeightRequest{
 		Height: height,
 	}
-	res, err := i.client.ListTransactionsForHeight(context.Background(), &req)
+	res, err := i.client.ListTransactionsForHeight(ctx, &req)
 	if err != nil {
-		return nil, fmt.Errorf("could not get transactions: %w", err)
+		return nil, fmt.Errorf("could not get transactions: %w", fromStatus(err))
 	}
 
 	txIDs := make([]flow.Identifier, 0, len(res.TransactionIDs))
@@ -258,13 +370,19 @@ func (i *Index) TransactionsByHeight(height uint64) ([]flow.Identifier, error) {
 
 // Result returns the result for a given transaction ID.
 func (i *Index) Result(txID flow.Identifier) (*flow.TransactionResult, error) {
+	return i.ResultContext(context.Background(), txID)
+}
+
+// ResultContext is like Result, but uses the given context for the call to the
+// DPS API.
+func (i *Index) ResultContext(ctx context.Context, txID flow.Identifier) (*flow.TransactionResult, error) {
 
 	req := GetResultRequest{
 		TransactionID: txID[:],
 	}
-	res, err := i.client.GetResult(context.Background(), &req)
+	res, err := i.client.GetResult(ctx, &req)
 	if err != nil {
-		return nil, fmt.Errorf("could not get transaction result: %w", err)
+		return nil, fmt.Errorf("could not get transaction result: %w", fromStatus(err))
 	}
 
 	var result flow.TransactionResult
@@ -280,15 +398,21 @@ func (i *Index) Result(txID flow.Identifier) (*flow.TransactionResult, error) {
 // finalized block at the given height. It can optionally filter them by event
 // type; if no event types are given, all events are returned.
 func (i *Index) Events(height uint64, types ...flow.EventType) ([]flow.Event, error) {
+	return i.EventsContext(context.Background(), height, types...)
+}
+
+// EventsContext is like Events, but uses the given context for the call to the
+// DPS API.
+func (i *Index) EventsContext(ctx context.Context, height uint64, types ...flow.EventType) ([]flow.Event, error) {
 	tt := convert.TypesToStrings(types)
 
 	req := GetEventsRequest{
 		Height: height,
 		Types:  tt,
 	}
-	res, err := i.client.GetEvents(context.Background(), &req)
+	res, err := i.client.GetEvents(ctx, &req)
 	if err != nil {
-		return nil, fmt.Errorf("could not get events: %w", err)
+		return nil, fmt.Errorf("could not get events: %w", fromStatus(err))
 	}
 
 	var events []flow.Event
@@ -302,13 +426,19 @@ func (i *Index) Events(height uint64, types ...flow.EventType) ([]flow.Event, er
 
 // Seal returns the seal with the given ID.
 func (i *Index) Seal(sealID flow.Identifier) (*flow.Seal, error) {
+	return i.SealContext(context.Background(), sealID)
+}
+
+// SealContext is like Seal, but uses the given context for the call to the DPS
+// API.
+func (i *Index) SealContext(ctx context.Context, sealID flow.Identifier) (*flow.Seal, error) {
 
 	req := GetSealRequest{
 		SealID: sealID[:],
 	}
-	res, err := i.client.GetSeal(context.Background(), &req)
+	res, err := i.client.GetSeal(ctx, &req)
 	if err != nil {
-		return nil, fmt.Errorf("could not get seal: %w", err)
+		return nil, fmt.Errorf("could not get seal: %w", fromStatus(err))
 	}
 
 	var seal flow.Seal
@@ -322,13 +452,19 @@ func (i *Index) Seal(sealID flow.Identifier) (*flow.Seal, error) {
 
 // SealsByHeight returns the seal IDs at the given height.
 func (i *Index) SealsByHeight(height uint64) ([]flow.Identifier, error) {
+	return i.SealsByHeightContext(context.Background(), height)
+}
+
+// SealsByHeightContext is like SealsByHeight, but uses the given context for
+// the call to the DPS API.
+func (i *Index) SealsByHeightContext(ctx context.Context, height uint64) ([]flow.Identifier, error) {
 
 	req := ListSealsForHeightRequest{
 		Height: height,
 	}
-	res, err := i.client.ListSealsForHeight(context.Background(), &req)
+	res, err := i.client.ListSealsForHeight(ctx, &req)
 	if err != nil {
-		return nil, fmt.Errorf("could not get seals: %w", err)
+		return nil, fmt.Errorf("could not get seals: %w", fromStatus(err))
 	}
 
 	sealIDs := make([]flow.Identifier, 0, len(res.SealIDs))
@@ -338,3 +474,95 @@ func (i *Index) SealsByHeight(height uint64) ([]flow.Identifier, error) {
 
 	return sealIDs, nil
 }
+
+// ResultsByHeight returns the transaction results within the given block.
+func (i *Index) ResultsByHeight(height uint64) ([]*flow.TransactionResult, error) {
+	return i.ResultsByHeightContext(context.Background(), height)
+}
+
+// ResultsByHeightContext is like ResultsByHeight, but uses the given context
+// for the call to the DPS API.
+func (i *Index) ResultsByHeightContext(ctx context.Context, height uint64) ([]*flow.TransactionResult, error) {
+
+	req := ListResultsForHeightRequest{
+		Height: height,
+	}
+	res, err := i.client.ListResultsForHeight(ctx, &req)
+	if err != nil {
+		return nil, fmt.Errorf("could not get transaction results: %w", fromStatus(err))
+	}
+
+	var results []*flow.TransactionResult
+	err = i.codec.Unmarshal(res.Data, &results)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode transaction results: %w", err)
+	}
+
+	return results, nil
+}
+
+// Block returns the data selected by the given mask for the finalized block at
+// the given height. The mask must select at least one field.
+func (i *Index) Block(height uint64, mask dps.BlockMask) (*dps.Block, error) {
+	return i.BlockContext(context.Background(), height, mask)
+}
+
+// BlockContext is like Block, but uses the given context for the call to the
+// DPS API.
+func (i *Index) BlockContext(ctx context.Context, height uint64, mask dps.BlockMask) (*dps.Block, error) {
+
+	// An empty field mask selects the whole block on the DPS API, so an empty
+	// block mask has to be rejected here rather than sent as is.
+	if mask == (dps.BlockMask{}) {
+		return nil, fmt.Errorf("block mask does not select any fields")
+	}
+
+	req := GetBlockRequest{
+		Selector: &GetBlockRequest_Height{Height: height},
+		Fields:   FieldsFromMask(mask),
+	}
+	res, err := i.client.GetBlock(ctx, &req)
+	if err != nil {
+		return nil, fmt.Errorf("could not get block: %w", fromStatus(err))
+	}
+
+	var block dps.Block
+	err = i.codec.Unmarshal(res.Data, &block)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode block: %w", err)
+	}
+
+	return &block, nil
+}
+
+// BlockByID returns the data selected by the given mask for the finalized block
+// with the given ID. The mask must select at least one field.
+func (i *Index) BlockByID(blockID flow.Identifier, mask dps.BlockMask) (*dps.Block, error) {
+	return i.BlockByIDContext(context.Background(), blockID, mask)
+}
+
+// BlockByIDContext is like BlockByID, but uses the given context for the call
+// to the DPS API.
+func (i *Index) BlockByIDContext(ctx context.Context, blockID flow.Identifier, mask dps.BlockMask) (*dps.Block, error) {
+
+	if mask == (dps.BlockMask{}) {
+		return nil, fmt.Errorf("block mask does not select any fields")
+	}
+
+	req := GetBlockRequest{
+		Selector: &GetBlockRequest_BlockID{BlockID: blockID[:]},
+		Fields:   FieldsFromMask(mask),
+	}
+	res, err := i.client.GetBlock(ctx, &req)
+	if err != nil {
+		return nil, fmt.Errorf("could not get block: %w", fromStatus(err))
+	}
+
+	var block dps.Block
+	err = i.codec.Unmarshal(res.Data, &block)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode block: %w", err)
+	}
+
+	return &block, nil
+}
@@ -24,6 +24,7 @@ import (
 	"google.golang.org/grpc"
 
 	"github.com/optakt/flow-dps/models/convert"
+	"github.com/optakt/flow-dps/models/dps"
 	"github.com/optakt/flow-dps/testing/mocks"
 )
 
@@ -803,6 +804,295 @@ func TestIndex_ListSealsForHeight(t *testing.T) {
 	})
 }
 
+func TestIndex_HeightForCollection(t *testing.T) {
+	collID := mocks.GenericCollectionIDs(1)[0]
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		index := Index{
+			client: &apiMock{
+				GetHeightForCollectionFunc: func(_ context.Context, in *GetHeightForCollectionRequest, _ ...grpc.CallOption) (*GetHeightForCollectionResponse, error) {
+					assert.Equal(t, collID[:], in.CollectionID)
+
+					return &GetHeightForCollectionResponse{
+						CollectionID: collID[:],
+						Height:       mocks.GenericHeight,
+					}, nil
+				},
+			},
+		}
+
+		got, err := index.HeightForCollection(collID)
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, got)
+	})
+
+	t.Run("handles index failures", func(t *testing.T) {
+		t.Parallel()
+
+		index := Index{
+			client: &apiMock{
+				GetHeightForCollectionFunc: func(context.Context, *GetHeightForCollectionRequest, ...grpc.CallOption) (*GetHeightForCollectionResponse, error) {
+					return nil, mocks.GenericError
+				},
+			},
+		}
+
+		_, err := index.HeightForCollection(collID)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestIndex_ListResultsForHeight(t *testing.T) {
+	results := mocks.GenericResults(4)
+
+	data, err := cbor.Marshal(results)
+	require.NoError(t, err)
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		codec := mocks.BaselineCodec(t)
+		codec.UnmarshalFunc = cbor.Unmarshal
+
+		index := Index{
+			codec: codec,
+			client: &apiMock{
+				ListResultsForHeightFunc: func(_ context.Context, in *ListResultsForHeightRequest, _ ...grpc.CallOption) (*ListResultsForHeightResponse, error) {
+					assert.Equal(t, mocks.GenericHeight, in.Height)
+
+					return &ListResultsForHeightResponse{
+						Height: mocks.GenericHeight,
+						Data:   data,
+					}, nil
+				},
+			},
+		}
+
+		got, err := index.ResultsByHeight(mocks.GenericHeight)
+
+		require.NoError(t, err)
+		assert.Equal(t, results, got)
+	})
+
+	t.Run("handles index failures", func(t *testing.T) {
+		t.Parallel()
+
+		index := Index{
+			client: &apiMock{
+				ListResultsForHeightFunc: func(context.Context, *ListResultsForHeightRequest, ...grpc.CallOption) (*ListResultsForHeightResponse, error) {
+					return nil, mocks.GenericError
+				},
+			},
+		}
+
+		_, err := index.ResultsByHeight(mocks.GenericHeight)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestIndex_HeightForSeal(t *testing.T) {
+	sealID := mocks.GenericSealIDs(1)[0]
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		index := Index{
+			client: &apiMock{
+				GetHeightForSealFunc: func(_ context.Context, in *GetHeightForSealRequest, _ ...grpc.CallOption) (*GetHeightForSealResponse, error) {
+					assert.Equal(t, sealID[:], in.SealID)
+
+					return &GetHeightForSealResponse{
+						SealID: sealID[:],
+						Height: mocks.GenericHeight,
+					}, nil
+				},
+			},
+		}
+
+		got, err := index.HeightForSeal(sealID)
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, got)
+	})
+
+	t.Run("handles index failures", func(t *testing.T) {
+		t.Parallel()
+
+		index := Index{
+			client: &apiMock{
+				GetHeightForSealFunc: func(context.Context, *GetHeightForSealRequest, ...grpc.CallOption) (*GetHeightForSealResponse, error) {
+					return nil, mocks.GenericError
+				},
+			},
+		}
+
+		_, err := index.HeightForSeal(sealID)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestIndex_Block(t *testing.T) {
+	block := dps.Block{Seals: mocks.GenericSeals(2)}
+	mask := dps.BlockMask{Seals: true}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := cbor.Marshal(block)
+		require.NoError(t, err)
+
+		codec := mocks.BaselineCodec(t)
+		codec.UnmarshalFunc = cbor.Unmarshal
+
+		index := Index{
+			codec: codec,
+			client: &apiMock{
+				GetBlockFunc: func(_ context.Context, in *GetBlockRequest, _ ...grpc.CallOption) (*GetBlockResponse, error) {
+					assert.Equal(t, mocks.GenericHeight, in.GetHeight())
+					assert.Equal(t, []string{FieldSeals}, in.Fields.Paths)
+
+					return &GetBlockResponse{Data: data}, nil
+				},
+			},
+		}
+
+		got, err := index.Block(mocks.GenericHeight, mask)
+
+		require.NoError(t, err)
+		assert.Equal(t, &block, got)
+	})
+
+	t.Run("handles empty masks", func(t *testing.T) {
+		t.Parallel()
+
+		index := Index{
+			codec: mocks.BaselineCodec(t),
+			client: &apiMock{
+				GetBlockFunc: func(context.Context, *GetBlockRequest, ...grpc.CallOption) (*GetBlockResponse, error) {
+					t.Error("block should not be requested with an empty mask")
+					return nil, mocks.GenericError
+				},
+			},
+		}
+
+		_, err := index.Block(mocks.GenericHeight, dps.BlockMask{})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles index failures", func(t *testing.T) {
+		t.Parallel()
+
+		index := Index{
+			codec: mocks.BaselineCodec(t),
+			client: &apiMock{
+				GetBlockFunc: func(context.Context, *GetBlockRequest, ...grpc.CallOption) (*GetBlockResponse, error) {
+					return nil, mocks.GenericError
+				},
+			},
+		}
+
+		_, err := index.Block(mocks.GenericHeight, mask)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestIndex_BlockByID(t *testing.T) {
+	blockID := mocks.GenericHeader.ID()
+	block := dps.Block{Seals: mocks.GenericSeals(2)}
+	mask := dps.BlockMask{Seals: true}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := cbor.Marshal(block)
+		require.NoError(t, err)
+
+		codec := mocks.BaselineCodec(t)
+		codec.UnmarshalFunc = cbor.Unmarshal
+
+		index := Index{
+			codec: codec,
+			client: &apiMock{
+				GetBlockFunc: func(_ context.Context, in *GetBlockRequest, _ ...grpc.CallOption) (*GetBlockResponse, error) {
+					assert.Equal(t, blockID[:], in.GetBlockID())
+					assert.Equal(t, []string{FieldSeals}, in.Fields.Paths)
+
+					return &GetBlockResponse{Data: data}, nil
+				},
+			},
+		}
+
+		got, err := index.BlockByID(blockID, mask)
+
+		require.NoError(t, err)
+		assert.Equal(t, &block, got)
+	})
+
+	t.Run("handles empty masks", func(t *testing.T) {
+		t.Parallel()
+
+		index := Index{
+			codec: mocks.BaselineCodec(t),
+			client: &apiMock{
+				GetBlockFunc: func(context.Context, *GetBlockRequest, ...grpc.CallOption) (*GetBlockResponse, error) {
+					t.Error("block should not be requested with an empty mask")
+					return nil, mocks.GenericError
+				},
+			},
+		}
+
+		_, err := index.BlockByID(blockID, dps.BlockMask{})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles index failures", func(t *testing.T) {
+		t.Parallel()
+
+		index := Index{
+			codec: mocks.BaselineCodec(t),
+			client: &apiMock{
+				GetBlockFunc: func(context.Context, *GetBlockRequest, ...grpc.CallOption) (*GetBlockResponse, error) {
+					return nil, mocks.GenericError
+				},
+			},
+		}
+
+		_, err := index.BlockByID(blockID, mask)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestIndex_Context(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+
+	var got context.Context
+	index := Index{
+		codec: mocks.BaselineCodec(t),
+		client: &apiMock{
+			GetHeaderFunc: func(ctx context.Context, in *GetHeaderRequest, _ ...grpc.CallOption) (*GetHeaderResponse, error) {
+				got = ctx
+				return &GetHeaderResponse{Height: in.Height, Data: mocks.GenericBytes}, nil
+			},
+		},
+	}
+
+	_, err := index.HeaderContext(ctx, mocks.GenericHeight)
+
+	require.NoError(t, err)
+	assert.Equal(t, "value", got.Value(key{}))
+}
+
 type apiMock struct {
 	GetFirstFunc                  func(ctx context.Context, in *GetFirstRequest, opts ...grpc.CallOption) (*GetFirstResponse, error)
 	GetLastFunc                   func(ctx context.Context, in *GetLastRequest, opts ...grpc.CallOption) (*GetLastResponse, error)
@@ -820,6 +1110,11 @@ type apiMock struct {
 	GetResultFunc                 func(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*GetResultResponse, error)
 	GetSealFunc                   func(ctx context.Context, in *GetSealRequest, opts ...grpc.CallOption) (*GetSealResponse, error)
 	ListSealsForHeightFunc        func(ctx context.Context, in *ListSealsForHeightRequest, opts ...grpc.CallOption) (*ListSealsForHeightResponse, error)
+	GetHeightForCollectionFunc    func(ctx context.Context, in *GetHeightForCollectionRequest, opts ...grpc.CallOption) (*GetHeightForCollectionResponse, error)
+	GetHeightForSealFunc          func(ctx context.Context, in *GetHeightForSealRequest, opts ...grpc.CallOption) (*GetHeightForSealResponse, error)
+	ListResultsForHeightFunc      func(ctx context.Context, in *ListResultsForHeightRequest, opts ...grpc.CallOption) (*ListResultsForHeightResponse, error)
+	GetBlockFunc                  func(ctx context.Context, in *GetBlockRequest, opts ...grpc.CallOption) (*GetBlockResponse, error)
+	GetIndexStatsFunc             func(ctx context.Context, in *GetIndexStatsRequest, opts ...grpc.CallOption) (*GetIndexStatsResponse, error)
 }
 
 func (a *apiMock) GetFirst(ctx context.Context, in *GetFirstRequest, opts ...grpc.CallOption) (*GetFirstResponse, error) {
@@ -885,3 +1180,23 @@ func (a *apiMock) GetSeal(ctx context.Context, in *GetSealRequest, opts ...grpc.
 func (a *apiMock) ListSealsForHeight(ctx context.Context, in *ListSealsForHeightRequest, opts ...grpc.CallOption) (*ListSealsForHeightResponse, error) {
 	return a.ListSealsForHeightFunc(ctx, in, opts...)
 }
+
+func (a *apiMock) GetHeightForCollection(ctx context.Context, in *GetHeightForCollectionRequest, opts ...grpc.CallOption) (*GetHeightForCollectionResponse, error) {
+	return a.GetHeightForCollectionFunc(ctx, in, opts...)
+}
+
+func (a *apiMock) GetHeightForSeal(ctx context.Context, in *GetHeightForSealRequest, opts ...grpc.CallOption) (*GetHeightForSealResponse, error) {
+	return a.GetHeightForSealFunc(ctx, in, opts...)
+}
+
+func (a *apiMock) ListResultsForHeight(ctx context.Context, in *ListResultsForHeightRequest, opts ...grpc.CallOption) (*ListResultsForHeightResponse, error) {
+	return a.ListResultsForHeightFunc(ctx, in, opts...)
+}
+
+func (a *apiMock) GetBlock(ctx context.Context, in *GetBlockRequest, opts ...grpc.CallOption) (*GetBlockResponse, error) {
+	return a.GetBlockFunc(ctx, in, opts...)
+}
+
+func (a *apiMock) GetIndexStats(ctx context.Context, in *GetIndexStatsRequest, opts ...grpc.CallOption) (*GetIndexStatsResponse, error) {
+	return a.GetIndexStatsFunc(ctx, in, opts...)
+}
@@ -0,0 +1,473 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+import (
+	"fmt"
+
+	"github.com/onflow/flow-go/crypto"
+	"github.com/onflow/flow-go/model/flow"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/optakt/flow-dps/models/convert"
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// HeaderToProto converts a Flow block header into its typed protobuf message.
+func HeaderToProto(header *flow.Header) *Header {
+	return &Header{
+		ChainID:            header.ChainID.String(),
+		ParentID:           convert.IDToHash(header.ParentID),
+		Height:             header.Height,
+		PayloadHash:        convert.IDToHash(header.PayloadHash),
+		Timestamp:          timestamppb.New(header.Timestamp),
+		View:               header.View,
+		ParentVoterIDs:     idsToHashes(header.ParentVoterIDs),
+		ParentVoterSigData: header.ParentVoterSigData,
+		ProposerID:         convert.IDToHash(header.ProposerID),
+		ProposerSigData:    header.ProposerSigData,
+	}
+}
+
+// ProtoToHeader converts a typed protobuf message into a Flow block header.
+func ProtoToHeader(msg *Header) (*flow.Header, error) {
+
+	parentID, err := hashToID(msg.ParentID)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert parent ID: %w", err)
+	}
+	payloadHash, err := hashToID(msg.PayloadHash)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert payload hash: %w", err)
+	}
+	voterIDs, err := hashesToIDs(msg.ParentVoterIDs)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert parent voter IDs: %w", err)
+	}
+	proposerID, err := hashToID(msg.ProposerID)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert proposer ID: %w", err)
+	}
+
+	header := flow.Header{
+		ChainID:            flow.ChainID(msg.ChainID),
+		ParentID:           parentID,
+		Height:             msg.Height,
+		PayloadHash:        payloadHash,
+		Timestamp:          msg.Timestamp.AsTime(),
+		View:               msg.View,
+		ParentVoterIDs:     voterIDs,
+		ParentVoterSigData: msg.ParentVoterSigData,
+		ProposerID:         proposerID,
+		ProposerSigData:    msg.ProposerSigData,
+	}
+
+	return &header, nil
+}
+
+// EventsToProto converts Flow events into their typed protobuf messages.
+func EventsToProto(events []flow.Event) []*Event {
+	msgs := make([]*Event, 0, len(events))
+	for _, event := range events {
+		msg := Event{
+			Type:             string(event.Type),
+			TransactionID:    convert.IDToHash(event.TransactionID),
+			TransactionIndex: event.TransactionIndex,
+			EventIndex:       event.EventIndex,
+			Payload:          event.Payload,
+		}
+		msgs = append(msgs, &msg)
+	}
+	return msgs
+}
+
+// ProtoToEvents converts typed protobuf messages into Flow events.
+func ProtoToEvents(msgs []*Event) ([]flow.Event, error) {
+	var events []flow.Event
+	for _, msg := range msgs {
+		txID, err := hashToID(msg.TransactionID)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert transaction ID: %w", err)
+		}
+		event := flow.Event{
+			Type:             flow.EventType(msg.Type),
+			TransactionID:    txID,
+			TransactionIndex: msg.TransactionIndex,
+			EventIndex:       msg.EventIndex,
+			Payload:          msg.Payload,
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// CollectionToProto converts a Flow light collection into its typed protobuf
+// message.
+func CollectionToProto(collection *flow.LightCollection) *Collection {
+	return &Collection{
+		TransactionIDs: idsToHashes(collection.Transactions),
+	}
+}
+
+// ProtoToCollection converts a typed protobuf message into a Flow light
+// collection.
+func ProtoToCollection(msg *Collection) (*flow.LightCollection, error) {
+
+	txIDs, err := hashesToIDs(msg.TransactionIDs)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert transaction IDs: %w", err)
+	}
+
+	collection := flow.LightCollection{
+		Transactions: txIDs,
+	}
+
+	return &collection, nil
+}
+
+// GuaranteeToProto converts a Flow collection guarantee into its typed
+// protobuf message.
+func GuaranteeToProto(guarantee *flow.CollectionGuarantee) *Guarantee {
+	return &Guarantee{
+		CollectionID:     convert.IDToHash(guarantee.CollectionID),
+		ReferenceBlockID: convert.IDToHash(guarantee.ReferenceBlockID),
+		SignerIDs:        idsToHashes(guarantee.SignerIDs),
+		Signature:        guarantee.Signature,
+	}
+}
+
+// ProtoToGuarantee converts a typed protobuf message into a Flow collection
+// guarantee.
+func ProtoToGuarantee(msg *Guarantee) (*flow.CollectionGuarantee, error) {
+
+	collID, err := hashToID(msg.CollectionID)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert collection ID: %w", err)
+	}
+	blockID, err := hashToID(msg.ReferenceBlockID)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert reference block ID: %w", err)
+	}
+	signerIDs, err := hashesToIDs(msg.SignerIDs)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert signer IDs: %w", err)
+	}
+
+	guarantee := flow.CollectionGuarantee{
+		CollectionID:     collID,
+		ReferenceBlockID: blockID,
+		SignerIDs:        signerIDs,
+		Signature:        crypto.Signature(msg.Signature),
+	}
+
+	return &guarantee, nil
+}
+
+// TransactionToProto converts a Flow transaction body into its typed protobuf
+// message.
+func TransactionToProto(tx *flow.TransactionBody) *Transaction {
+
+	authorizers := make([][]byte, 0, len(tx.Authorizers))
+	for _, authorizer := range tx.Authorizers {
+		authorizers = append(authorizers, authorizer.Bytes())
+	}
+
+	return &Transaction{
+		ReferenceBlockID: convert.IDToHash(tx.ReferenceBlockID),
+		Script:           tx.Script,
+		Arguments:        tx.Arguments,
+		GasLimit:         tx.GasLimit,
+		ProposalKey: &ProposalKey{
+			Address:        tx.ProposalKey.Address.Bytes(),
+			KeyIndex:       tx.ProposalKey.KeyIndex,
+			SequenceNumber: tx.ProposalKey.SequenceNumber,
+		},
+		Payer:              tx.Payer.Bytes(),
+		Authorizers:        authorizers,
+		PayloadSignatures:  signaturesToProto(tx.PayloadSignatures),
+		EnvelopeSignatures: signaturesToProto(tx.EnvelopeSignatures),
+	}
+}
+
+// ProtoToTransaction converts a typed protobuf message into a Flow transaction
+// body.
+func ProtoToTransaction(msg *Transaction) (*flow.TransactionBody, error) {
+
+	blockID, err := hashToID(msg.ReferenceBlockID)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert reference block ID: %w", err)
+	}
+
+	var authorizers []flow.Address
+	for _, authorizer := range msg.Authorizers {
+		authorizers = append(authorizers, flow.BytesToAddress(authorizer))
+	}
+
+	tx := flow.TransactionBody{
+		ReferenceBlockID:   blockID,
+		Script:             msg.Script,
+		Arguments:          msg.Arguments,
+		GasLimit:           msg.GasLimit,
+		Payer:              flow.BytesToAddress(msg.Payer),
+		Authorizers:        authorizers,
+		PayloadSignatures:  protoToSignatures(msg.PayloadSignatures),
+		EnvelopeSignatures: protoToSignatures(msg.EnvelopeSignatures),
+	}
+	if msg.ProposalKey != nil {
+		tx.ProposalKey = flow.ProposalKey{
+			Address:        flow.BytesToAddress(msg.ProposalKey.Address),
+			KeyIndex:       msg.ProposalKey.KeyIndex,
+			SequenceNumber: msg.ProposalKey.SequenceNumber,
+		}
+	}
+
+	return &tx, nil
+}
+
+// ResultToProto converts a Flow transaction result into its typed protobuf
+// message.
+func ResultToProto(result *flow.TransactionResult) *TransactionResult {
+	return &TransactionResult{
+		TransactionID:   convert.IDToHash(result.TransactionID),
+		ErrorMessage:    result.ErrorMessage,
+		ComputationUsed: result.ComputationUsed,
+	}
+}
+
+// ProtoToResult converts a typed protobuf message into a Flow transaction
+// result.
+func ProtoToResult(msg *TransactionResult) (*flow.TransactionResult, error) {
+
+	txID, err := hashToID(msg.TransactionID)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert transaction ID: %w", err)
+	}
+
+	result := flow.TransactionResult{
+		TransactionID:   txID,
+		ErrorMessage:    msg.ErrorMessage,
+		ComputationUsed: msg.ComputationUsed,
+	}
+
+	return &result, nil
+}
+
+// SealToProto converts a Flow seal into its typed protobuf message.
+func SealToProto(seal *flow.Seal) *Seal {
+
+	sigs := make([]*AggregatedSignature, 0, len(seal.AggregatedApprovalSigs))
+	for _, sig := range seal.AggregatedApprovalSigs {
+		verifierSigs := make([][]byte, 0, len(sig.VerifierSignatures))
+		for _, verifierSig := range sig.VerifierSignatures {
+			verifierSigs = append(verifierSigs, verifierSig)
+		}
+		msg := AggregatedSignature{
+			VerifierSignatures: verifierSigs,
+			SignerIDs:          idsToHashes(sig.SignerIDs),
+		}
+		sigs = append(sigs, &msg)
+	}
+
+	return &Seal{
+		BlockID:                convert.IDToHash(seal.BlockID),
+		ResultID:               convert.IDToHash(seal.ResultID),
+		FinalState:             convert.CommitToHash(seal.FinalState),
+		AggregatedApprovalSigs: sigs,
+	}
+}
+
+// ProtoToSeal converts a typed protobuf message into a Flow seal.
+func ProtoToSeal(msg *Seal) (*flow.Seal, error) {
+
+	blockID, err := hashToID(msg.BlockID)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert block ID: %w", err)
+	}
+	resultID, err := hashToID(msg.ResultID)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert result ID: %w", err)
+	}
+	commit, err := flow.ToStateCommitment(msg.FinalState)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert final state: %w", err)
+	}
+
+	var sigs []flow.AggregatedSignature
+	for _, sig := range msg.AggregatedApprovalSigs {
+		var verifierSigs []crypto.Signature
+		for _, verifierSig := range sig.VerifierSignatures {
+			verifierSigs = append(verifierSigs, verifierSig)
+		}
+		signerIDs, err := hashesToIDs(sig.SignerIDs)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert signer IDs: %w", err)
+		}
+		aggregated := flow.AggregatedSignature{
+			VerifierSignatures: verifierSigs,
+			SignerIDs:          signerIDs,
+		}
+		sigs = append(sigs, aggregated)
+	}
+
+	seal := flow.Seal{
+		BlockID:                blockID,
+		ResultID:               resultID,
+		FinalState:             commit,
+		AggregatedApprovalSigs: sigs,
+	}
+
+	return &seal, nil
+}
+
+// BlockToProto converts the fields of an indexed block into its typed protobuf
+// message. Fields that are not set in the block are left empty.
+func BlockToProto(block *dps.Block) *Block {
+
+	msg := Block{
+		Events: EventsToProto(block.Events),
+	}
+	if block.Header != nil {
+		msg.Header = HeaderToProto(block.Header)
+	}
+	for _, collection := range block.Collections {
+		msg.Collections = append(msg.Collections, CollectionToProto(collection))
+	}
+	for _, guarantee := range block.Guarantees {
+		msg.Guarantees = append(msg.Guarantees, GuaranteeToProto(guarantee))
+	}
+	for _, transaction := range block.Transactions {
+		msg.Transactions = append(msg.Transactions, TransactionToProto(transaction))
+	}
+	for _, result := range block.Results {
+		msg.Results = append(msg.Results, ResultToProto(result))
+	}
+	for _, seal := range block.Seals {
+		msg.Seals = append(msg.Seals, SealToProto(seal))
+	}
+
+	return &msg
+}
+
+// ProtoToBlock converts a typed protobuf message into the fields of an indexed
+// block. The height and ID of the block are not part of the message, and are
+// left for the caller to set.
+func ProtoToBlock(msg *Block) (*dps.Block, error) {
+
+	var block dps.Block
+	if msg.Header != nil {
+		header, err := ProtoToHeader(msg.Header)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert header: %w", err)
+		}
+		block.Header = header
+	}
+	for _, m := range msg.Collections {
+		collection, err := ProtoToCollection(m)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert collection: %w", err)
+		}
+		block.Collections = append(block.Collections, collection)
+	}
+	for _, m := range msg.Guarantees {
+		guarantee, err := ProtoToGuarantee(m)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert guarantee: %w", err)
+		}
+		block.Guarantees = append(block.Guarantees, guarantee)
+	}
+	for _, m := range msg.Transactions {
+		transaction, err := ProtoToTransaction(m)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert transaction: %w", err)
+		}
+		block.Transactions = append(block.Transactions, transaction)
+	}
+	for _, m := range msg.Results {
+		result, err := ProtoToResult(m)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert transaction result: %w", err)
+		}
+		block.Results = append(block.Results, result)
+	}
+	events, err := ProtoToEvents(msg.Events)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert events: %w", err)
+	}
+	block.Events = events
+	for _, m := range msg.Seals {
+		seal, err := ProtoToSeal(m)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert seal: %w", err)
+		}
+		block.Seals = append(block.Seals, seal)
+	}
+
+	return &block, nil
+}
+
+func signaturesToProto(sigs []flow.TransactionSignature) []*TransactionSignature {
+	msgs := make([]*TransactionSignature, 0, len(sigs))
+	for _, sig := range sigs {
+		msg := TransactionSignature{
+			Address:     sig.Address.Bytes(),
+			SignerIndex: int64(sig.SignerIndex),
+			KeyIndex:    sig.KeyIndex,
+			Signature:   sig.Signature,
+		}
+		msgs = append(msgs, &msg)
+	}
+	return msgs
+}
+
+func protoToSignatures(msgs []*TransactionSignature) []flow.TransactionSignature {
+	var sigs []flow.TransactionSignature
+	for _, msg := range msgs {
+		sig := flow.TransactionSignature{
+			Address:     flow.BytesToAddress(msg.Address),
+			SignerIndex: int(msg.SignerIndex),
+			KeyIndex:    msg.KeyIndex,
+			Signature:   msg.Signature,
+		}
+		sigs = append(sigs, sig)
+	}
+	return sigs
+}
+
+func idsToHashes(ids []flow.Identifier) [][]byte {
+	hashes := make([][]byte, 0, len(ids))
+	for _, id := range ids {
+		hashes = append(hashes, convert.IDToHash(id))
+	}
+	return hashes
+}
+
+func hashesToIDs(hashes [][]byte) ([]flow.Identifier, error) {
+	var ids []flow.Identifier
+	for _, hash := range hashes {
+		id, err := hashToID(hash)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func hashToID(hash []byte) (flow.Identifier, error) {
+	if len(hash) != len(flow.ZeroID) {
+		return flow.ZeroID, fmt.Errorf("invalid identifier length (%d)", len(hash))
+	}
+	return flow.HashToID(hash), nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/crypto"
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestHeaderConversion(t *testing.T) {
+	header := *mocks.GenericHeader
+	header.ParentVoterIDs = mocks.GenericBlockIDs(2)
+	header.ParentVoterSigData = mocks.GenericBytes
+
+	got, err := ProtoToHeader(HeaderToProto(&header))
+
+	require.NoError(t, err)
+	assert.Equal(t, header.ID(), got.ID())
+	assert.True(t, header.Timestamp.Equal(got.Timestamp))
+
+	msg := HeaderToProto(&header)
+	msg.ParentID = mocks.GenericBytes
+	_, err = ProtoToHeader(msg)
+	assert.Error(t, err)
+}
+
+func TestEventsConversion(t *testing.T) {
+	events := mocks.GenericEvents(4)
+
+	got, err := ProtoToEvents(EventsToProto(events))
+
+	require.NoError(t, err)
+	assert.Equal(t, events, got)
+
+	msgs := EventsToProto(events)
+	msgs[1].TransactionID = mocks.GenericBytes
+	_, err = ProtoToEvents(msgs)
+	assert.Error(t, err)
+}
+
+func TestCollectionConversion(t *testing.T) {
+	collection := mocks.GenericCollection(0)
+
+	got, err := ProtoToCollection(CollectionToProto(collection))
+
+	require.NoError(t, err)
+	assert.Equal(t, collection, got)
+}
+
+func TestGuaranteeConversion(t *testing.T) {
+	guarantee := mocks.GenericGuarantee(0)
+	guarantee.SignerIDs = mocks.GenericBlockIDs(3)
+	guarantee.Signature = crypto.Signature(mocks.GenericBytes)
+
+	got, err := ProtoToGuarantee(GuaranteeToProto(guarantee))
+
+	require.NoError(t, err)
+	assert.Equal(t, guarantee, got)
+}
+
+func TestTransactionConversion(t *testing.T) {
+	tx := mocks.GenericTransaction(0)
+	tx.Arguments = [][]byte{mocks.GenericBytes}
+	tx.Authorizers = mocks.GenericAddresses(2)
+	tx.ProposalKey = flow.ProposalKey{
+		Address:        mocks.GenericAddress(0),
+		KeyIndex:       1,
+		SequenceNumber: 2,
+	}
+	tx.PayloadSignatures = []flow.TransactionSignature{{
+		Address:     mocks.GenericAddress(1),
+		SignerIndex: 3,
+		KeyIndex:    4,
+		Signature:   mocks.GenericBytes,
+	}}
+
+	got, err := ProtoToTransaction(TransactionToProto(tx))
+
+	require.NoError(t, err)
+	assert.Equal(t, tx.ID(), got.ID())
+	assert.Equal(t, tx.PayloadSignatures, got.PayloadSignatures)
+}
+
+func TestResultConversion(t *testing.T) {
+	result := mocks.GenericResult(0)
+
+	got, err := ProtoToResult(ResultToProto(result))
+
+	require.NoError(t, err)
+	assert.Equal(t, result, got)
+}
+
+func TestSealConversion(t *testing.T) {
+	seal := mocks.GenericSeal(0)
+	seal.AggregatedApprovalSigs = []flow.AggregatedSignature{{
+		VerifierSignatures: []crypto.Signature{mocks.GenericBytes},
+		SignerIDs:          mocks.GenericBlockIDs(1),
+	}}
+
+	got, err := ProtoToSeal(SealToProto(seal))
+
+	require.NoError(t, err)
+	assert.Equal(t, seal, got)
+
+	msg := SealToProto(seal)
+	msg.FinalState = mocks.GenericBytes
+	_, err = ProtoToSeal(msg)
+	assert.Error(t, err)
+}
+
+func TestBlockConversion(t *testing.T) {
+	block := dps.Block{
+		Header:       mocks.GenericHeader,
+		Collections:  mocks.GenericCollections(2),
+		Guarantees:   mocks.GenericGuarantees(2),
+		Transactions: mocks.GenericTransactions(2),
+		Results:      mocks.GenericResults(2),
+		Events:       mocks.GenericEvents(2),
+		Seals:        mocks.GenericSeals(2),
+	}
+
+	got, err := ProtoToBlock(BlockToProto(&block))
+
+	require.NoError(t, err)
+	assert.Equal(t, block.Header.ID(), got.Header.ID())
+	assert.Equal(t, block.Collections, got.Collections)
+	assert.Equal(t, block.Guarantees, got.Guarantees)
+	assert.Len(t, got.Transactions, 2)
+	assert.Equal(t, block.Results, got.Results)
+	assert.Equal(t, block.Events, got.Events)
+	assert.Equal(t, block.Seals, got.Seals)
+
+	got, err = ProtoToBlock(BlockToProto(&dps.Block{Seals: block.Seals}))
+
+	require.NoError(t, err)
+	assert.Nil(t, got.Header)
+	assert.Empty(t, got.Collections)
+	assert.Equal(t, block.Seals, got.Seals)
+}
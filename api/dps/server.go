@@ -16,9 +16,12 @@ package dps
 
 import (
 	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
 
 	"github.com/go-playground/validator/v10"
+	"google.golang.org/grpc/codes"
 
 	"github.com/onflow/flow-go/model/flow"
 
@@ -31,6 +34,7 @@ import (
 // This is generally an on-disk interface, but could be a GRPC-based index as
 // well, in which case there is a double redirection.
 type Server struct {
+	cfg   Config
 	index dps.Reader
 	codec dps.Codec
 
@@ -39,9 +43,15 @@ type Server struct {
 
 // NewServer creates a new server, using the provided index reader as a backend
 // for data retrieval.
-func NewServer(index dps.Reader, codec dps.Codec) *Server {
+func NewServer(index dps.Reader, codec dps.Codec, options ...Option) *Server {
+
+	cfg := DefaultConfig
+	for _, option := range options {
+		option(&cfg)
+	}
 
 	s := Server{
+		cfg:      cfg,
 		index:    index,
 		codec:    codec,
 		validate: validator.New(),
@@ -55,7 +65,7 @@ func (s *Server) GetFirst(_ context.Context, _ *GetFirstRequest) (*GetFirstRespo
 
 	height, err := s.index.First()
 	if err != nil {
-		return nil, fmt.Errorf("could not get first height: %w", err)
+		return nil, fail(fmt.Errorf("could not get first height: %w", err))
 	}
 
 	res := GetFirstResponse{
@@ -70,7 +80,7 @@ func (s *Server) GetLast(_ context.Context, _ *GetLastRequest) (*GetLastResponse
 
 	height, err := s.index.Last()
 	if err != nil {
-		return nil, fmt.Errorf("could not get last height: %w", err)
+		return nil, fail(fmt.Errorf("could not get last height: %w", err))
 	}
 
 	res := GetLastResponse{
@@ -86,13 +96,13 @@ func (s *Server) GetHeightForBlock(_ context.Context, req *GetHeightForBlockRequ
 
 	err := s.validate.Struct(req)
 	if err != nil {
-		return nil, fmt.Errorf("bad request: %w", err)
+		return nil, badRequest(err)
 	}
 
 	blockID := flow.HashToID(req.BlockID)
 	height, err := s.index.HeightForBlock(blockID)
 	if err != nil {
-		return nil, fmt.Errorf("could not get height for block: %w", err)
+		return nil, fail(fmt.Errorf("could not get height for block: %w", err))
 	}
 
 	res := GetHeightForBlockResponse{
@@ -108,12 +118,12 @@ func (s *Server) GetCommit(_ context.Context, req *GetCommitRequest) (*GetCommit
 
 	err := s.validate.Struct(req)
 	if err != nil {
-		return nil, fmt.Errorf("bad request: %w", err)
+		return nil, badRequest(err)
 	}
 
 	commit, err := s.index.Commit(req.Height)
 	if err != nil {
-		return nil, fmt.Errorf("could not get commit: %w", err)
+		return nil, s.failAt(req.Height, fmt.Errorf("could not get commit: %w", err))
 	}
 
 	res := GetCommitResponse{
@@ -129,46 +139,73 @@ func (s *Server) GetHeader(_ context.Context, req *GetHeaderRequest) (*GetHeader
 
 	err := s.validate.Struct(req)
 	if err != nil {
-		return nil, fmt.Errorf("bad request: %w", err)
+		return nil, badRequest(err)
 	}
 
-	header, err := s.index.Header(req.Height)
+	typed, err := protobufEncoding(req.Encoding)
 	if err != nil {
-		return nil, fmt.Errorf("could not get header: %w", err)
+		return nil, badRequest(err)
+	}
+
+	res := GetHeaderResponse{
+		Height: req.Height,
+	}
+
+	key := heightKey(req.Height)
+	data, ok := s.cached("GetHeader", key, typed)
+	if ok {
+		res.Data = data
+		return &res, nil
 	}
 
-	data, err := s.codec.Marshal(header)
+	header, err := s.index.Header(req.Height)
 	if err != nil {
-		return nil, fmt.Errorf("could not encode header: %w", err)
+		return nil, s.failAt(req.Height, fmt.Errorf("could not get header: %w", err))
 	}
 
-	res := GetHeaderResponse{
-		Height: req.Height,
-		Data:   data,
+	if typed {
+		res.Header = HeaderToProto(header)
+		return &res, nil
 	}
 
+	data, err = s.codec.Marshal(header)
+	if err != nil {
+		return nil, fail(fmt.Errorf("could not encode header: %w", err))
+	}
+	s.cache("GetHeader", key, data)
+	res.Data = data
+
 	return &res, nil
 }
 
 // GetEvents implements the `GetEvents` method of the generated GRPC server.
 func (s *Server) GetEvents(_ context.Context, req *GetEventsRequest) (*GetEventsResponse, error) {
 
-	types := convert.StringsToTypes(req.Types)
-	events, err := s.index.Events(req.Height, types...)
+	typed, err := protobufEncoding(req.Encoding)
 	if err != nil {
-		return nil, fmt.Errorf("could not get events: %w", err)
+		return nil, badRequest(err)
 	}
 
-	data, err := s.codec.Marshal(events)
+	types := convert.StringsToTypes(req.Types)
+	events, err := s.index.Events(req.Height, types...)
 	if err != nil {
-		return nil, fmt.Errorf("could not encode events: %w", err)
+		return nil, s.failAt(req.Height, fmt.Errorf("could not get events: %w", err))
 	}
 
 	res := GetEventsResponse{
 		Height: req.Height,
 		Types:  req.Types,
-		Data:   data,
 	}
+	if typed {
+		res.Events = EventsToProto(events)
+		return &res, nil
+	}
+
+	data, err := s.codec.Marshal(events)
+	if err != nil {
+		return nil, fail(fmt.Errorf("could not encode events: %w", err))
+	}
+	res.Data = data
 
 	return &res, nil
 }
@@ -179,17 +216,17 @@ func (s *Server) GetRegisterValues(_ context.Context, req *GetRegisterValuesRequ
 
 	err := s.validate.Struct(req)
 	if err != nil {
-		return nil, fmt.Errorf("bad request: %w", err)
+		return nil, badRequest(err)
 	}
 
 	paths, err := convert.BytesToPaths(req.Paths)
 	if err != nil {
-		return nil, fmt.Errorf("could not convert paths: %w", err)
+		return nil, badRequest(fmt.Errorf("could not convert paths: %w", err))
 	}
 
 	values, err := s.index.Values(req.Height, paths)
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve values: %w", err)
+		return nil, s.failAt(req.Height, fmt.Errorf("could not retrieve values: %w", err))
 	}
 
 	res := GetRegisterValuesResponse{
@@ -207,24 +244,41 @@ func (s *Server) GetCollection(_ context.Context, req *GetCollectionRequest) (*G
 
 	err := s.validate.Struct(req)
 	if err != nil {
-		return nil, fmt.Errorf("bad request: %w", err)
+		return nil, badRequest(err)
+	}
+
+	typed, err := protobufEncoding(req.Encoding)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	res := GetCollectionResponse{
+		CollectionID: req.CollectionID,
+	}
+
+	data, ok := s.cached("GetCollection", req.CollectionID, typed)
+	if ok {
+		res.Data = data
+		return &res, nil
 	}
 
 	collID := flow.HashToID(req.CollectionID)
 	collection, err := s.index.Collection(collID)
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve collection: %w", err)
+		return nil, fail(fmt.Errorf("could not retrieve collection: %w", err))
 	}
 
-	data, err := s.codec.Marshal(collection)
-	if err != nil {
-		return nil, fmt.Errorf("could not encode collection: %w", err)
+	if typed {
+		res.Collection = CollectionToProto(collection)
+		return &res, nil
 	}
 
-	res := GetCollectionResponse{
-		CollectionID: req.CollectionID,
-		Data:         data,
+	data, err = s.codec.Marshal(collection)
+	if err != nil {
+		return nil, fail(fmt.Errorf("could not encode collection: %w", err))
 	}
+	s.cache("GetCollection", req.CollectionID, data)
+	res.Data = data
 
 	return &res, nil
 }
@@ -235,11 +289,11 @@ func (s *Server) ListCollectionsForHeight(_ context.Context, req *ListCollection
 
 	err := s.validate.Struct(req)
 	if err != nil {
-		return nil, fmt.Errorf("bad request: %w", err)
+		return nil, badRequest(err)
 	}
 	collIDs, err := s.index.CollectionsByHeight(req.Height)
 	if err != nil {
-		return nil, fmt.Errorf("could not list collections by height: %w", err)
+		return nil, s.failAt(req.Height, fmt.Errorf("could not list collections by height: %w", err))
 	}
 
 	rawIDs := make([][]byte, 0, len(collIDs))
@@ -255,31 +309,71 @@ func (s *Server) ListCollectionsForHeight(_ context.Context, req *ListCollection
 	return &res, nil
 }
 
-// GetGuarantee implements the `GetGuarantee` method of the generated GRPC
+// GetHeightForCollection implements the `GetHeightForCollection` method of the generated GRPC
 // server.
-func (s *Server) GetGuarantee(_ context.Context, req *GetGuaranteeRequest) (*GetGuaranteeResponse, error) {
+func (s *Server) GetHeightForCollection(_ context.Context, req *GetHeightForCollectionRequest) (*GetHeightForCollectionResponse, error) {
 
 	err := s.validate.Struct(req)
 	if err != nil {
-		return nil, fmt.Errorf("bad request: %w", err)
+		return nil, badRequest(err)
 	}
 
 	collID := flow.HashToID(req.CollectionID)
-	guarantee, err := s.index.Guarantee(collID)
+	height, err := s.index.HeightForCollection(collID)
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve guarantee: %w", err)
+		return nil, fail(fmt.Errorf("could not get height for collection: %w", err))
+	}
+
+	res := GetHeightForCollectionResponse{
+		CollectionID: req.CollectionID,
+		Height:       height,
 	}
 
-	data, err := s.codec.Marshal(guarantee)
+	return &res, nil
+}
+
+// GetGuarantee implements the `GetGuarantee` method of the generated GRPC
+// server.
+func (s *Server) GetGuarantee(_ context.Context, req *GetGuaranteeRequest) (*GetGuaranteeResponse, error) {
+
+	err := s.validate.Struct(req)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	typed, err := protobufEncoding(req.Encoding)
 	if err != nil {
-		return nil, fmt.Errorf("could not encode guarantee: %w", err)
+		return nil, badRequest(err)
 	}
 
 	res := GetGuaranteeResponse{
 		CollectionID: req.CollectionID,
-		Data:         data,
 	}
 
+	data, ok := s.cached("GetGuarantee", req.CollectionID, typed)
+	if ok {
+		res.Data = data
+		return &res, nil
+	}
+
+	collID := flow.HashToID(req.CollectionID)
+	guarantee, err := s.index.Guarantee(collID)
+	if err != nil {
+		return nil, fail(fmt.Errorf("could not retrieve guarantee: %w", err))
+	}
+
+	if typed {
+		res.Guarantee = GuaranteeToProto(guarantee)
+		return &res, nil
+	}
+
+	data, err = s.codec.Marshal(guarantee)
+	if err != nil {
+		return nil, fail(fmt.Errorf("could not encode guarantee: %w", err))
+	}
+	s.cache("GetGuarantee", req.CollectionID, data)
+	res.Data = data
+
 	return &res, nil
 }
 
@@ -289,24 +383,41 @@ func (s *Server) GetTransaction(_ context.Context, req *GetTransactionRequest) (
 
 	err := s.validate.Struct(req)
 	if err != nil {
-		return nil, fmt.Errorf("bad request: %w", err)
+		return nil, badRequest(err)
+	}
+
+	typed, err := protobufEncoding(req.Encoding)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	res := GetTransactionResponse{
+		TransactionID: req.TransactionID,
+	}
+
+	data, ok := s.cached("GetTransaction", req.TransactionID, typed)
+	if ok {
+		res.Data = data
+		return &res, nil
 	}
 
 	txID := flow.HashToID(req.TransactionID)
 	transaction, err := s.index.Transaction(txID)
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve transaction: %w", err)
+		return nil, fail(fmt.Errorf("could not retrieve transaction: %w", err))
 	}
 
-	data, err := s.codec.Marshal(transaction)
-	if err != nil {
-		return nil, fmt.Errorf("could not encode transaction: %w", err)
+	if typed {
+		res.Transaction = TransactionToProto(transaction)
+		return &res, nil
 	}
 
-	res := GetTransactionResponse{
-		TransactionID: req.TransactionID,
-		Data:          data,
+	data, err = s.codec.Marshal(transaction)
+	if err != nil {
+		return nil, fail(fmt.Errorf("could not encode transaction: %w", err))
 	}
+	s.cache("GetTransaction", req.TransactionID, data)
+	res.Data = data
 
 	return &res, nil
 }
@@ -317,13 +428,13 @@ func (s *Server) GetHeightForTransaction(_ context.Context, req *GetHeightForTra
 
 	err := s.validate.Struct(req)
 	if err != nil {
-		return nil, fmt.Errorf("bad request: %w", err)
+		return nil, badRequest(err)
 	}
 
 	txID := flow.HashToID(req.TransactionID)
 	height, err := s.index.HeightForTransaction(txID)
 	if err != nil {
-		return nil, fmt.Errorf("could not get height for transaction: %w", err)
+		return nil, fail(fmt.Errorf("could not get height for transaction: %w", err))
 	}
 
 	res := GetHeightForTransactionResponse{
@@ -340,12 +451,12 @@ func (s *Server) ListTransactionsForHeight(_ context.Context, req *ListTransacti
 
 	err := s.validate.Struct(req)
 	if err != nil {
-		return nil, fmt.Errorf("bad request: %w", err)
+		return nil, badRequest(err)
 	}
 
 	txIDs, err := s.index.TransactionsByHeight(req.Height)
 	if err != nil {
-		return nil, fmt.Errorf("could not list transactions by height: %w", err)
+		return nil, s.failAt(req.Height, fmt.Errorf("could not list transactions by height: %w", err))
 	}
 
 	transactionIDs := make([][]byte, 0, len(txIDs))
@@ -367,24 +478,81 @@ func (s *Server) GetResult(_ context.Context, req *GetResultRequest) (*GetResult
 
 	err := s.validate.Struct(req)
 	if err != nil {
-		return nil, fmt.Errorf("bad request: %w", err)
+		return nil, badRequest(err)
+	}
+
+	typed, err := protobufEncoding(req.Encoding)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	res := GetResultResponse{
+		TransactionID: req.TransactionID,
+	}
+
+	data, ok := s.cached("GetResult", req.TransactionID, typed)
+	if ok {
+		res.Data = data
+		return &res, nil
 	}
 
 	txID := flow.HashToID(req.TransactionID)
 	result, err := s.index.Result(txID)
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve transaction result: %w", err)
+		return nil, fail(fmt.Errorf("could not retrieve transaction result: %w", err))
 	}
 
-	data, err := s.codec.Marshal(result)
+	if typed {
+		res.Result = ResultToProto(result)
+		return &res, nil
+	}
+
+	data, err = s.codec.Marshal(result)
 	if err != nil {
-		return nil, fmt.Errorf("could not encode transaction result: %w", err)
+		return nil, fail(fmt.Errorf("could not encode transaction result: %w", err))
 	}
+	s.cache("GetResult", req.TransactionID, data)
+	res.Data = data
 
-	res := GetResultResponse{
-		TransactionID: req.TransactionID,
-		Data:          data,
+	return &res, nil
+}
+
+// ListResultsForHeight implements the `ListResultsForHeight` method of the generated GRPC
+// server.
+func (s *Server) ListResultsForHeight(_ context.Context, req *ListResultsForHeightRequest) (*ListResultsForHeightResponse, error) {
+
+	err := s.validate.Struct(req)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	typed, err := protobufEncoding(req.Encoding)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	results, err := s.index.ResultsByHeight(req.Height)
+	if err != nil {
+		return nil, s.failAt(req.Height, fmt.Errorf("could not list transaction results by height: %w", err))
+	}
+
+	res := ListResultsForHeightResponse{
+		Height: req.Height,
+	}
+
+	if typed {
+		res.Results = make([]*TransactionResult, 0, len(results))
+		for _, result := range results {
+			res.Results = append(res.Results, ResultToProto(result))
+		}
+		return &res, nil
+	}
+
+	data, err := s.codec.Marshal(results)
+	if err != nil {
+		return nil, fail(fmt.Errorf("could not encode transaction results: %w", err))
 	}
+	res.Data = data
 
 	return &res, nil
 }
@@ -395,24 +563,41 @@ func (s *Server) GetSeal(_ context.Context, req *GetSealRequest) (*GetSealRespon
 
 	err := s.validate.Struct(req)
 	if err != nil {
-		return nil, fmt.Errorf("bad request: %w", err)
+		return nil, badRequest(err)
+	}
+
+	typed, err := protobufEncoding(req.Encoding)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	res := GetSealResponse{
+		SealID: req.SealID,
+	}
+
+	data, ok := s.cached("GetSeal", req.SealID, typed)
+	if ok {
+		res.Data = data
+		return &res, nil
 	}
 
 	sealID := flow.HashToID(req.SealID)
 	seal, err := s.index.Seal(sealID)
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve seal: %w", err)
+		return nil, fail(fmt.Errorf("could not retrieve seal: %w", err))
 	}
 
-	data, err := s.codec.Marshal(seal)
-	if err != nil {
-		return nil, fmt.Errorf("could not encode seal: %w", err)
+	if typed {
+		res.Seal = SealToProto(seal)
+		return &res, nil
 	}
 
-	res := GetSealResponse{
-		SealID: req.SealID,
-		Data:   data,
+	data, err = s.codec.Marshal(seal)
+	if err != nil {
+		return nil, fail(fmt.Errorf("could not encode seal: %w", err))
 	}
+	s.cache("GetSeal", req.SealID, data)
+	res.Data = data
 
 	return &res, nil
 }
@@ -423,12 +608,12 @@ func (s *Server) ListSealsForHeight(_ context.Context, req *ListSealsForHeightRe
 
 	err := s.validate.Struct(req)
 	if err != nil {
-		return nil, fmt.Errorf("bad request: %w", err)
+		return nil, badRequest(err)
 	}
 
 	sealIDs, err := s.index.SealsByHeight(req.Height)
 	if err != nil {
-		return nil, fmt.Errorf("could not list seals by height: %w", err)
+		return nil, s.failAt(req.Height, fmt.Errorf("could not list seals by height: %w", err))
 	}
 
 	sIDs := make([][]byte, 0, len(sealIDs))
@@ -443,3 +628,216 @@ func (s *Server) ListSealsForHeight(_ context.Context, req *ListSealsForHeightRe
 
 	return &res, nil
 }
+
+// GetHeightForSeal implements the `GetHeightForSeal` method of the generated GRPC
+// server.
+func (s *Server) GetHeightForSeal(_ context.Context, req *GetHeightForSealRequest) (*GetHeightForSealResponse, error) {
+
+	err := s.validate.Struct(req)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	sealID := flow.HashToID(req.SealID)
+	height, err := s.index.HeightForSeal(sealID)
+	if err != nil {
+		return nil, fail(fmt.Errorf("could not get height for seal: %w", err))
+	}
+
+	res := GetHeightForSealResponse{
+		SealID: req.SealID,
+		Height: height,
+	}
+
+	return &res, nil
+}
+
+// GetBlock implements the `GetBlock` method of the generated GRPC server.
+func (s *Server) GetBlock(_ context.Context, req *GetBlockRequest) (*GetBlockResponse, error) {
+
+	var key []byte
+	switch selector := req.Selector.(type) {
+	case *GetBlockRequest_Height:
+		key = heightKey(selector.Height)
+	case *GetBlockRequest_BlockID:
+		if len(selector.BlockID) != len(flow.ZeroID) {
+			return nil, badRequest(fmt.Errorf("invalid block ID length (%d)", len(selector.BlockID)))
+		}
+		key = selector.BlockID
+	default:
+		return nil, badRequest(errors.New("one of height and block ID is required"))
+	}
+
+	typed, err := protobufEncoding(req.Encoding)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	mask, err := MaskFromFields(req.Fields)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	// Blocks are cached by height or by ID, along with the fields that were
+	// requested, as the response differs for each of them.
+	key = append(append([]byte{}, key...), maskKey(mask))
+	data, ok := s.cached("GetBlock", key, typed)
+	if ok {
+		var block dps.Block
+		err = s.codec.Unmarshal(data, &block)
+		if err != nil {
+			return nil, fail(fmt.Errorf("could not decode cached block: %w", err))
+		}
+		res := GetBlockResponse{
+			Height:  block.Height,
+			BlockID: block.BlockID[:],
+			Data:    data,
+		}
+		return &res, nil
+	}
+
+	var block *dps.Block
+	blocks := s.blocks()
+	switch selector := req.Selector.(type) {
+	case *GetBlockRequest_Height:
+		block, err = blocks.Block(selector.Height, mask)
+		if err != nil {
+			return nil, s.failAt(selector.Height, fmt.Errorf("could not get block: %w", err))
+		}
+	case *GetBlockRequest_BlockID:
+		block, err = blocks.BlockByID(flow.HashToID(selector.BlockID), mask)
+		if err != nil {
+			return nil, fail(fmt.Errorf("could not get block: %w", err))
+		}
+	}
+
+	res := GetBlockResponse{
+		Height:  block.Height,
+		BlockID: block.BlockID[:],
+	}
+	if typed {
+		res.Block = BlockToProto(block)
+		return &res, nil
+	}
+
+	data, err = s.codec.Marshal(block)
+	if err != nil {
+		return nil, fail(fmt.Errorf("could not encode block: %w", err))
+	}
+	s.cache("GetBlock", key, data)
+	res.Data = data
+
+	return &res, nil
+}
+
+// GetIndexStats implements the `GetIndexStats` method of the generated GRPC
+// server.
+func (s *Server) GetIndexStats(_ context.Context, _ *GetIndexStatsRequest) (*GetIndexStatsResponse, error) {
+
+	if s.cfg.Stats == nil {
+		return nil, &apiError{code: codes.Unimplemented, err: errors.New("index statistics are not enabled")}
+	}
+
+	report, err := s.cfg.Stats.Collect()
+	if err != nil {
+		return nil, fail(fmt.Errorf("could not collect index statistics: %w", err))
+	}
+
+	prefixes := make([]*PrefixStats, 0, len(report.Prefixes))
+	for _, prefix := range report.Prefixes {
+		dictionaries := make([]*DictionaryStats, 0, len(prefix.Dictionaries))
+		for _, dictionary := range prefix.Dictionaries {
+			dictionaries = append(dictionaries, &DictionaryStats{
+				Version:           uint32(dictionary.Version),
+				Dictionary:        dictionary.Dictionary,
+				Values:            dictionary.Values,
+				CompressedBytes:   dictionary.CompressedBytes,
+				UncompressedBytes: dictionary.UncompressedBytes,
+				Ratio:             dictionary.Ratio,
+			})
+		}
+		p := PrefixStats{
+			Prefix:            uint32(prefix.Prefix),
+			Name:              prefix.Name,
+			Keys:              prefix.Keys,
+			KeyBytes:          prefix.KeyBytes,
+			CompressedBytes:   prefix.CompressedBytes,
+			UncompressedBytes: prefix.UncompressedBytes,
+			Invalid:           prefix.Invalid,
+			Dictionaries:      dictionaries,
+		}
+		if prefix.Coverage != nil {
+			p.HasCoverage = true
+			p.FirstHeight = prefix.Coverage.First
+			p.LastHeight = prefix.Coverage.Last
+		}
+		prefixes = append(prefixes, &p)
+	}
+
+	res := GetIndexStatsResponse{
+		Prefixes: prefixes,
+	}
+
+	return &res, nil
+}
+
+// blocks returns the reader used to read whole blocks. Index readers which can
+// not read all the data of a block at once have their blocks assembled from
+// the individual entities they read.
+func (s *Server) blocks() dps.BlockReader {
+	blocks, ok := s.index.(dps.BlockReader)
+	if ok {
+		return blocks
+	}
+	return &assembler{index: s.index}
+}
+
+// cached returns the cached codec-encoded data of the entity with the given key
+// for the given method. Responses with typed protobuf entities are never
+// cached, as they are not encoded by the server.
+func (s *Server) cached(method string, key []byte, typed bool) ([]byte, bool) {
+	if s.cfg.Cache == nil || typed {
+		return nil, false
+	}
+	return s.cfg.Cache.Get(method, key)
+}
+
+// cache caches the codec-encoded data of the entity with the given key for the
+// given method.
+func (s *Server) cache(method string, key []byte, data []byte) {
+	if s.cfg.Cache == nil {
+		return
+	}
+	s.cfg.Cache.Set(method, key, data)
+}
+
+// heightKey returns the cache key of an entity at the given height.
+func heightKey(height uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, height)
+	return key
+}
+
+// maskKey returns the cache key part for the fields selected by a block mask.
+func maskKey(mask dps.BlockMask) byte {
+	var key byte
+	for i, selected := range []bool{mask.Header, mask.Collections, mask.Guarantees, mask.Transactions, mask.Results, mask.Events, mask.Seals} {
+		if selected {
+			key |= 1 << i
+		}
+	}
+	return key
+}
+
+// protobufEncoding returns whether the entities of a response should be set in
+// its typed protobuf fields, rather than encoded with the codec of the server.
+func protobufEncoding(encoding Encoding) (bool, error) {
+	switch encoding {
+	case Encoding_ENCODING_CODEC:
+		return false, nil
+	case Encoding_ENCODING_PROTOBUF:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown encoding (%d)", encoding)
+	}
+}
@@ -21,11 +21,16 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
 	"github.com/onflow/flow-go/ledger"
 	"github.com/onflow/flow-go/model/flow"
 
 	"github.com/optakt/flow-dps/models/convert"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/stats"
+	"github.com/optakt/flow-dps/service/storage"
 	"github.com/optakt/flow-dps/testing/mocks"
 )
 
@@ -33,9 +38,12 @@ func TestNewServer(t *testing.T) {
 	index := mocks.BaselineReader(t)
 	codec := mocks.BaselineCodec(t)
 
-	s := NewServer(index, codec)
+	collector := &statsMock{}
+
+	s := NewServer(index, codec, WithStats(collector))
 
 	assert.NotNil(t, s)
+	assert.Equal(t, collector, s.cfg.Stats)
 	assert.NotNil(t, s.codec)
 	assert.Equal(t, index, s.index)
 	assert.Equal(t, codec, s.codec)
@@ -727,6 +735,74 @@ func TestServer_ListCollectionsForHeight(t *testing.T) {
 	}
 }
 
+func TestServer_GetHeightForCollection(t *testing.T) {
+	collID := mocks.GenericCollectionIDs(1)[0]
+	tests := []struct {
+		name string
+
+		req *GetHeightForCollectionRequest
+
+		mockErr error
+
+		checkErr require.ErrorAssertionFunc
+	}{
+		{
+			name: "nominal case",
+
+			req: &GetHeightForCollectionRequest{
+				CollectionID: mocks.ByteSlice(collID),
+			},
+
+			checkErr: require.NoError,
+		},
+		{
+			name: "handles invalid collection ID",
+
+			req: &GetHeightForCollectionRequest{
+				CollectionID: mocks.GenericBytes,
+			},
+
+			checkErr: require.Error,
+		},
+		{
+			name: "handles index failure",
+
+			req: &GetHeightForCollectionRequest{
+				CollectionID: mocks.ByteSlice(collID),
+			},
+
+			mockErr: mocks.GenericError,
+
+			checkErr: require.Error,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			index := mocks.BaselineReader(t)
+			index.HeightForCollectionFunc = func(flow.Identifier) (uint64, error) {
+				return mocks.GenericHeight, test.mockErr
+			}
+
+			s := Server{
+				index:    index,
+				validate: validator.New(),
+			}
+
+			gotRes, gotErr := s.GetHeightForCollection(context.Background(), test.req)
+
+			test.checkErr(t, gotErr)
+			if gotErr == nil {
+				assert.Equal(t, mocks.GenericHeight, gotRes.Height)
+				assert.Equal(t, collID[:], gotRes.CollectionID)
+			}
+		})
+	}
+}
+
 func TestServer_GetGuarantee(t *testing.T) {
 	guarantee := mocks.GenericGuarantee(0)
 	tests := []struct {
@@ -1081,6 +1157,72 @@ func TestServer_GetResult(t *testing.T) {
 	}
 }
 
+func TestServer_ListResultsForHeight(t *testing.T) {
+	tests := []struct {
+		name string
+
+		req *ListResultsForHeightRequest
+
+		mockErr error
+
+		checkErr require.ErrorAssertionFunc
+	}{
+		{
+			name: "nominal case",
+
+			req: &ListResultsForHeightRequest{
+				Height: mocks.GenericHeight,
+			},
+
+			checkErr: require.NoError,
+		},
+		{
+			name: "handles missing height",
+
+			req: &ListResultsForHeightRequest{},
+
+			checkErr: require.Error,
+		},
+		{
+			name: "handles index failure",
+
+			req: &ListResultsForHeightRequest{
+				Height: mocks.GenericHeight,
+			},
+
+			mockErr: mocks.GenericError,
+
+			checkErr: require.Error,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			index := mocks.BaselineReader(t)
+			index.ResultsByHeightFunc = func(uint64) ([]*flow.TransactionResult, error) {
+				return mocks.GenericResults(4), test.mockErr
+			}
+
+			s := Server{
+				codec:    mocks.BaselineCodec(t),
+				index:    index,
+				validate: validator.New(),
+			}
+
+			gotRes, gotErr := s.ListResultsForHeight(context.Background(), test.req)
+
+			test.checkErr(t, gotErr)
+			if gotErr == nil {
+				assert.Equal(t, mocks.GenericHeight, gotRes.Height)
+				assert.NotEmpty(t, gotRes.Data)
+			}
+		})
+	}
+}
+
 func TestServer_GetSeal(t *testing.T) {
 	seal := mocks.GenericSeal(0)
 	tests := []struct {
@@ -1218,3 +1360,512 @@ func TestServer_ListSealsForHeight(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_GetHeightForSeal(t *testing.T) {
+	sealID := mocks.GenericSealIDs(1)[0]
+	tests := []struct {
+		name string
+
+		req *GetHeightForSealRequest
+
+		mockErr error
+
+		checkErr require.ErrorAssertionFunc
+	}{
+		{
+			name: "nominal case",
+
+			req: &GetHeightForSealRequest{
+				SealID: mocks.ByteSlice(sealID),
+			},
+
+			checkErr: require.NoError,
+		},
+		{
+			name: "handles invalid seal ID",
+
+			req: &GetHeightForSealRequest{
+				SealID: mocks.GenericBytes,
+			},
+
+			checkErr: require.Error,
+		},
+		{
+			name: "handles index failure",
+
+			req: &GetHeightForSealRequest{
+				SealID: mocks.ByteSlice(sealID),
+			},
+
+			mockErr: mocks.GenericError,
+
+			checkErr: require.Error,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			index := mocks.BaselineReader(t)
+			index.HeightForSealFunc = func(flow.Identifier) (uint64, error) {
+				return mocks.GenericHeight, test.mockErr
+			}
+
+			s := Server{
+				index:    index,
+				validate: validator.New(),
+			}
+
+			gotRes, gotErr := s.GetHeightForSeal(context.Background(), test.req)
+
+			test.checkErr(t, gotErr)
+			if gotErr == nil {
+				assert.Equal(t, mocks.GenericHeight, gotRes.Height)
+				assert.Equal(t, sealID[:], gotRes.SealID)
+			}
+		})
+	}
+}
+
+func TestServer_GetBlock(t *testing.T) {
+	blockID := mocks.GenericHeader.ID()
+	tests := []struct {
+		name string
+
+		req *GetBlockRequest
+
+		mockErr error
+
+		wantHeight uint64
+		wantMask   dps.BlockMask
+		checkErr   require.ErrorAssertionFunc
+	}{
+		{
+			name: "nominal case by height",
+
+			req: &GetBlockRequest{
+				Selector: &GetBlockRequest_Height{Height: mocks.GenericHeight},
+			},
+
+			wantHeight: mocks.GenericHeight,
+			wantMask:   dps.FullBlock,
+			checkErr:   require.NoError,
+		},
+		{
+			name: "nominal case by block ID",
+
+			req: &GetBlockRequest{
+				Selector: &GetBlockRequest_BlockID{BlockID: blockID[:]},
+			},
+
+			wantHeight: mocks.GenericHeight,
+			wantMask:   dps.FullBlock,
+			checkErr:   require.NoError,
+		},
+		{
+			name: "nominal case with field mask",
+
+			req: &GetBlockRequest{
+				Selector: &GetBlockRequest_Height{Height: mocks.GenericHeight},
+				Fields:   &fieldmaskpb.FieldMask{Paths: []string{FieldHeader, FieldSeals}},
+			},
+
+			wantHeight: mocks.GenericHeight,
+			wantMask:   dps.BlockMask{Header: true, Seals: true},
+			checkErr:   require.NoError,
+		},
+		{
+			name: "nominal case at height zero",
+
+			req: &GetBlockRequest{
+				Selector: &GetBlockRequest_Height{Height: 0},
+			},
+
+			wantHeight: 0,
+			wantMask:   dps.FullBlock,
+			checkErr:   require.NoError,
+		},
+		{
+			name: "handles missing height and block ID",
+
+			req: &GetBlockRequest{},
+
+			checkErr: require.Error,
+		},
+		{
+			name: "handles invalid block ID",
+
+			req: &GetBlockRequest{
+				Selector: &GetBlockRequest_BlockID{BlockID: mocks.GenericBytes},
+			},
+
+			checkErr: require.Error,
+		},
+		{
+			name: "handles unknown field",
+
+			req: &GetBlockRequest{
+				Selector: &GetBlockRequest_Height{Height: mocks.GenericHeight},
+				Fields:   &fieldmaskpb.FieldMask{Paths: []string{"payload"}},
+			},
+
+			checkErr: require.Error,
+		},
+		{
+			name: "handles index failure",
+
+			req: &GetBlockRequest{
+				Selector: &GetBlockRequest_Height{Height: mocks.GenericHeight},
+			},
+			mockErr: mocks.GenericError,
+
+			checkErr: require.Error,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotMask dps.BlockMask
+			index := blockReader{
+				Reader: mocks.BaselineReader(t),
+				BlockFunc: func(height uint64, mask dps.BlockMask) (*dps.Block, error) {
+					gotMask = mask
+					return &dps.Block{Height: height, BlockID: blockID}, test.mockErr
+				},
+			}
+
+			s := Server{
+				codec:    mocks.BaselineCodec(t),
+				index:    index,
+				validate: validator.New(),
+			}
+
+			gotRes, gotErr := s.GetBlock(context.Background(), test.req)
+
+			test.checkErr(t, gotErr)
+			if gotErr == nil {
+				assert.Equal(t, test.wantHeight, gotRes.Height)
+				assert.Equal(t, blockID[:], gotRes.BlockID)
+				assert.NotEmpty(t, gotRes.Data)
+				assert.Equal(t, test.wantMask, gotMask)
+			}
+		})
+	}
+}
+
+func TestServer_GetIndexStats(t *testing.T) {
+	report := &stats.Report{
+		Prefixes: []*stats.Prefix{
+			{
+				Prefix:            storage.PrefixHeader,
+				Name:              "header",
+				Keys:              2,
+				KeyBytes:          18,
+				CompressedBytes:   200,
+				UncompressedBytes: 600,
+				Coverage:          &stats.Coverage{First: 1, Last: 2},
+				Dictionaries: []*stats.Dictionary{
+					{Version: 2, Dictionary: "generic", Values: 2, CompressedBytes: 200, UncompressedBytes: 600, Ratio: 3},
+				},
+			},
+			{
+				Prefix:            storage.PrefixLast,
+				Name:              "last",
+				Keys:              1,
+				KeyBytes:          1,
+				CompressedBytes:   19,
+				UncompressedBytes: 9,
+				Dictionaries:      []*stats.Dictionary{},
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+
+		mockStats Collector
+		mockErr   error
+
+		checkErr require.ErrorAssertionFunc
+	}{
+		{
+			name: "nominal case",
+
+			mockStats: &statsMock{},
+
+			checkErr: require.NoError,
+		},
+		{
+			name: "handles stats not enabled",
+
+			checkErr: require.Error,
+		},
+		{
+			name: "handles collector failure",
+
+			mockStats: &statsMock{},
+			mockErr:   mocks.GenericError,
+
+			checkErr: require.Error,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, ok := test.mockStats.(*statsMock)
+			if ok {
+				mock.CollectFunc = func() (*stats.Report, error) {
+					return report, test.mockErr
+				}
+			}
+
+			s := Server{
+				cfg:      Config{Stats: test.mockStats},
+				codec:    mocks.BaselineCodec(t),
+				index:    mocks.BaselineReader(t),
+				validate: validator.New(),
+			}
+
+			gotRes, gotErr := s.GetIndexStats(context.Background(), &GetIndexStatsRequest{})
+
+			test.checkErr(t, gotErr)
+			if gotErr != nil {
+				return
+			}
+
+			require.Len(t, gotRes.Prefixes, 2)
+			header := gotRes.Prefixes[0]
+			assert.Equal(t, uint32(storage.PrefixHeader), header.Prefix)
+			assert.Equal(t, "header", header.Name)
+			assert.Equal(t, uint64(2), header.Keys)
+			assert.Equal(t, uint64(600), header.UncompressedBytes)
+			assert.True(t, header.HasCoverage)
+			assert.Equal(t, uint64(1), header.FirstHeight)
+			assert.Equal(t, uint64(2), header.LastHeight)
+			require.Len(t, header.Dictionaries, 1)
+			assert.Equal(t, "generic", header.Dictionaries[0].Dictionary)
+			assert.Equal(t, float64(3), header.Dictionaries[0].Ratio)
+
+			last := gotRes.Prefixes[1]
+			assert.False(t, last.HasCoverage)
+			assert.Empty(t, last.Dictionaries)
+		})
+	}
+}
+
+type statsMock struct {
+	CollectFunc func() (*stats.Report, error)
+}
+
+func (s *statsMock) Collect() (*stats.Report, error) {
+	return s.CollectFunc()
+}
+
+func TestServer_ProtobufEncoding(t *testing.T) {
+	codec := mocks.BaselineCodec(t)
+	codec.MarshalFunc = func(interface{}) ([]byte, error) {
+		t.Error("codec should not be used with protobuf encoding")
+		return nil, nil
+	}
+
+	s := Server{
+		codec:    codec,
+		index:    mocks.BaselineReader(t),
+		validate: validator.New(),
+	}
+
+	ctx := context.Background()
+	protobuf := Encoding_ENCODING_PROTOBUF
+	collID := mocks.GenericCollectionIDs(1)[0]
+	txID := mocks.GenericTransactionIDs(1)[0]
+	sealID := mocks.GenericSealIDs(1)[0]
+
+	t.Run("header", func(t *testing.T) {
+		res, err := s.GetHeader(ctx, &GetHeaderRequest{Height: mocks.GenericHeight, Encoding: protobuf})
+
+		require.NoError(t, err)
+		assert.Empty(t, res.Data)
+		assert.True(t, proto.Equal(HeaderToProto(mocks.GenericHeader), res.Header))
+	})
+
+	t.Run("events", func(t *testing.T) {
+		res, err := s.GetEvents(ctx, &GetEventsRequest{Height: mocks.GenericHeight, Encoding: protobuf})
+
+		require.NoError(t, err)
+		assert.Empty(t, res.Data)
+		assert.Len(t, res.Events, 4)
+	})
+
+	t.Run("collection", func(t *testing.T) {
+		res, err := s.GetCollection(ctx, &GetCollectionRequest{CollectionID: collID[:], Encoding: protobuf})
+
+		require.NoError(t, err)
+		assert.Empty(t, res.Data)
+		assert.True(t, proto.Equal(CollectionToProto(mocks.GenericCollection(0)), res.Collection))
+	})
+
+	t.Run("guarantee", func(t *testing.T) {
+		res, err := s.GetGuarantee(ctx, &GetGuaranteeRequest{CollectionID: collID[:], Encoding: protobuf})
+
+		require.NoError(t, err)
+		assert.Empty(t, res.Data)
+		assert.True(t, proto.Equal(GuaranteeToProto(mocks.GenericGuarantee(0)), res.Guarantee))
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		res, err := s.GetTransaction(ctx, &GetTransactionRequest{TransactionID: txID[:], Encoding: protobuf})
+
+		require.NoError(t, err)
+		assert.Empty(t, res.Data)
+		assert.True(t, proto.Equal(TransactionToProto(mocks.GenericTransaction(0)), res.Transaction))
+	})
+
+	t.Run("result", func(t *testing.T) {
+		res, err := s.GetResult(ctx, &GetResultRequest{TransactionID: txID[:], Encoding: protobuf})
+
+		require.NoError(t, err)
+		assert.Empty(t, res.Data)
+		assert.True(t, proto.Equal(ResultToProto(mocks.GenericResult(0)), res.Result))
+	})
+
+	t.Run("seal", func(t *testing.T) {
+		res, err := s.GetSeal(ctx, &GetSealRequest{SealID: sealID[:], Encoding: protobuf})
+
+		require.NoError(t, err)
+		assert.Empty(t, res.Data)
+		assert.True(t, proto.Equal(SealToProto(mocks.GenericSeal(0)), res.Seal))
+	})
+
+	t.Run("results for height", func(t *testing.T) {
+		res, err := s.ListResultsForHeight(ctx, &ListResultsForHeightRequest{Height: mocks.GenericHeight, Encoding: protobuf})
+
+		require.NoError(t, err)
+		assert.Empty(t, res.Data)
+		assert.Len(t, res.Results, 4)
+	})
+
+	t.Run("block", func(t *testing.T) {
+		res, err := s.GetBlock(ctx, &GetBlockRequest{Selector: &GetBlockRequest_Height{Height: mocks.GenericHeight}, Encoding: protobuf})
+
+		require.NoError(t, err)
+		assert.Empty(t, res.Data)
+		assert.True(t, proto.Equal(HeaderToProto(mocks.GenericHeader), res.Block.Header))
+		assert.Len(t, res.Block.Seals, 5)
+	})
+
+	t.Run("unknown encoding", func(t *testing.T) {
+		_, err := s.GetHeader(ctx, &GetHeaderRequest{Height: mocks.GenericHeight, Encoding: Encoding(7)})
+
+		assert.Error(t, err)
+	})
+
+}
+
+type responseCache map[string][]byte
+
+func (r responseCache) Get(method string, key []byte) ([]byte, bool) {
+	data, ok := r[method+"/"+string(key)]
+	return data, ok
+}
+
+func (r responseCache) Set(method string, key []byte, data []byte) {
+	r[method+"/"+string(key)] = data
+}
+
+func TestServer_ResponseCache(t *testing.T) {
+	ctx := context.Background()
+	txID := mocks.GenericTransactionIDs(1)[0]
+
+	t.Run("caches encoded responses", func(t *testing.T) {
+		t.Parallel()
+
+		reads := 0
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			reads++
+			return mocks.GenericHeader, nil
+		}
+		index.TransactionFunc = func(flow.Identifier) (*flow.TransactionBody, error) {
+			reads++
+			return mocks.GenericTransaction(0), nil
+		}
+
+		cache := responseCache{}
+		s := NewServer(index, mocks.BaselineCodec(t), WithCache(cache))
+
+		for i := 0; i < 3; i++ {
+			header, err := s.GetHeader(ctx, &GetHeaderRequest{Height: mocks.GenericHeight})
+			require.NoError(t, err)
+			assert.Equal(t, mocks.GenericBytes, header.Data)
+			assert.Equal(t, mocks.GenericHeight, header.Height)
+
+			tx, err := s.GetTransaction(ctx, &GetTransactionRequest{TransactionID: txID[:]})
+			require.NoError(t, err)
+			assert.Equal(t, mocks.GenericBytes, tx.Data)
+			assert.Equal(t, txID[:], tx.TransactionID)
+		}
+
+		assert.Equal(t, 2, reads)
+		assert.Len(t, cache, 2)
+	})
+
+	t.Run("does not cache typed responses", func(t *testing.T) {
+		t.Parallel()
+
+		cache := responseCache{}
+		cache.Set("GetHeader", heightKey(mocks.GenericHeight), mocks.GenericBytes)
+		s := NewServer(mocks.BaselineReader(t), mocks.BaselineCodec(t), WithCache(cache))
+
+		res, err := s.GetHeader(ctx, &GetHeaderRequest{Height: mocks.GenericHeight, Encoding: Encoding_ENCODING_PROTOBUF})
+
+		require.NoError(t, err)
+		assert.Empty(t, res.Data)
+		assert.NotNil(t, res.Header)
+		assert.Len(t, cache, 1)
+	})
+
+	t.Run("does not cache failures", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.SealFunc = func(flow.Identifier) (*flow.Seal, error) {
+			return nil, mocks.GenericError
+		}
+
+		cache := responseCache{}
+		s := NewServer(index, mocks.BaselineCodec(t), WithCache(cache))
+
+		sealID := mocks.GenericSealIDs(1)[0]
+		_, err := s.GetSeal(ctx, &GetSealRequest{SealID: sealID[:]})
+
+		assert.Error(t, err)
+		assert.Empty(t, cache)
+	})
+}
+
+// blockReader extends the mock index reader with the ability to read whole
+// blocks, which it does by block ID through the reader's height lookup.
+type blockReader struct {
+	*mocks.Reader
+	BlockFunc func(height uint64, mask dps.BlockMask) (*dps.Block, error)
+}
+
+func (b blockReader) Block(height uint64, mask dps.BlockMask) (*dps.Block, error) {
+	return b.BlockFunc(height, mask)
+}
+
+func (b blockReader) BlockByID(blockID flow.Identifier, mask dps.BlockMask) (*dps.Block, error) {
+	height, err := b.HeightForBlock(blockID)
+	if err != nil {
+		return nil, err
+	}
+	return b.BlockFunc(height, mask)
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package gateway
+
+import (
+	"time"
+)
+
+// DefaultConfig is the default configuration for the Gateway.
+var DefaultConfig = Config{
+	Authorizer: nil,
+	Throttler:  nil,
+}
+
+// Config contains optional parameters for the Gateway.
+type Config struct {
+	Authorizer Authorizer
+	Throttler  Throttler
+}
+
+// Authorizer represents something that can check whether a token allows
+// calling a method of the DPS API.
+type Authorizer interface {
+	Authorize(token string, method string) (string, error)
+}
+
+// Throttler represents something that can decide whether a client is allowed
+// to call a method of the DPS API right now.
+type Throttler interface {
+	Allow(client string, method string) (bool, time.Duration)
+}
+
+// Option is an option that can be given to the gateway to configure optional
+// parameters on initialization.
+type Option func(*Config)
+
+// WithAuthorizer sets the authorizer used to check the API keys presented by
+// clients. Each endpoint is checked against the DPS API method it calls, so
+// that keys have the same permissions on the gateway as on the GRPC API. All
+// requests are accepted when no authorizer is set.
+func WithAuthorizer(authorize Authorizer) Option {
+	return func(cfg *Config) {
+		cfg.Authorizer = authorize
+	}
+}
+
+// WithThrottler sets the throttler used to rate limit clients. Clients are
+// identified by API key when an authorizer is set, and by address otherwise,
+// and each endpoint is limited as the DPS API method it calls. Clients which
+// exceed their rate limit receive a `429 Too Many Requests` response, with a
+// `Retry-After` header.
+func WithThrottler(throttle Throttler) Option {
+	return func(cfg *Config) {
+		cfg.Throttler = throttle
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/optakt/flow-dps/api/auth"
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// errorResponse is the body of responses for failed requests.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// respond writes the given value as the JSON body of the response.
+func respond(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// respondError writes an error response with the given status and message.
+func respondError(w http.ResponseWriter, status int, message string) {
+	respond(w, status, errorResponse{Error: message})
+}
+
+// fail writes an error response for an error returned by the DPS API, with a
+// status code that depends on the cause of the error.
+func fail(w http.ResponseWriter, err error) {
+	respondError(w, statusCode(err), err.Error())
+}
+
+func statusCode(err error) int {
+	var invalid validator.ValidationErrors
+	switch {
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, auth.ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.As(err, &invalid), errors.Is(err, dps.ErrInvalidArgument):
+		return http.StatusBadRequest
+	case errors.Is(err, badger.ErrKeyNotFound), errors.Is(err, dps.ErrNotFound), errors.Is(err, dps.ErrOutOfRange), errors.Is(err, dps.ErrNotIncluded):
+		return http.StatusNotFound
+	case errors.Is(err, dps.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	case status.Code(err) == codes.Unimplemented:
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
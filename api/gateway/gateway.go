@@ -0,0 +1,159 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package gateway
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/optakt/flow-dps/api/auth"
+	api "github.com/optakt/flow-dps/api/dps"
+	"github.com/optakt/flow-dps/api/limit"
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// Gateway exposes the methods of the DPS API as REST endpoints. It calls the
+// DPS API server directly, and decodes the data it returns into plain JSON, so
+// that it can be used without the codec of the DPS.
+type Gateway struct {
+	cfg    Config
+	server api.APIServer
+	codec  dps.Codec
+	routes []route
+}
+
+// handler is the function called for a request on a route, with the values of
+// the route's parameters by name.
+type handler func(w http.ResponseWriter, r *http.Request, params map[string]string)
+
+// route is a REST endpoint. Segments of its path which are enclosed in curly
+// braces are parameters, which match any value. The method is the name of
+// the DPS API method called by the route's handler.
+type route struct {
+	segments []string
+	method   string
+	handle   handler
+}
+
+// NewGateway creates a new HTTP/JSON gateway on top of the given DPS API
+// server, which uses the given codec to decode the data it returns.
+func NewGateway(server api.APIServer, codec dps.Codec, options ...Option) *Gateway {
+
+	cfg := DefaultConfig
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	g := Gateway{
+		cfg:    cfg,
+		server: server,
+		codec:  codec,
+	}
+
+	g.add("/v1/first", "GetFirst", g.First)
+	g.add("/v1/last", "GetLast", g.Last)
+	g.add("/v1/blocks/{blockID}/height", "GetHeightForBlock", g.HeightForBlock)
+	g.add("/v1/blocks/{blockID}", "GetBlock", g.Block)
+	g.add("/v1/heights/{height}/block", "GetBlock", g.BlockAtHeight)
+	g.add("/v1/heights/{height}/commit", "GetCommit", g.Commit)
+	g.add("/v1/heights/{height}/header", "GetHeader", g.Header)
+	g.add("/v1/heights/{height}/events", "GetEvents", g.Events)
+	g.add("/v1/heights/{height}/registers", "GetRegisterValues", g.RegisterValues)
+	g.add("/v1/heights/{height}/collections", "ListCollectionsForHeight", g.CollectionsForHeight)
+	g.add("/v1/heights/{height}/transactions", "ListTransactionsForHeight", g.TransactionsForHeight)
+	g.add("/v1/heights/{height}/results", "ListResultsForHeight", g.ResultsForHeight)
+	g.add("/v1/heights/{height}/seals", "ListSealsForHeight", g.SealsForHeight)
+	g.add("/v1/collections/{collectionID}", "GetCollection", g.Collection)
+	g.add("/v1/collections/{collectionID}/height", "GetHeightForCollection", g.HeightForCollection)
+	g.add("/v1/guarantees/{collectionID}", "GetGuarantee", g.Guarantee)
+	g.add("/v1/transactions/{transactionID}", "GetTransaction", g.Transaction)
+	g.add("/v1/transactions/{transactionID}/height", "GetHeightForTransaction", g.HeightForTransaction)
+	g.add("/v1/results/{transactionID}", "GetResult", g.Result)
+	g.add("/v1/seals/{sealID}", "GetSeal", g.Seal)
+	g.add("/v1/seals/{sealID}/height", "GetHeightForSeal", g.HeightForSeal)
+	g.add("/v1/stats", "GetIndexStats", g.IndexStats)
+
+	return &g
+}
+
+// ServeHTTP implements the `http.Handler` interface. It routes requests to the
+// endpoint matching their path.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	segments := split(r.URL.Path)
+	for _, route := range g.routes {
+		params, ok := route.match(segments)
+		if !ok {
+			continue
+		}
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		var key string
+		if g.cfg.Authorizer != nil {
+			token := auth.ExtractToken(r.Header.Get(auth.HeaderAuthorization), r.Header.Get(auth.HeaderAPIKey))
+			name, err := g.cfg.Authorizer.Authorize(token, route.method)
+			if err != nil {
+				fail(w, err)
+				return
+			}
+			key = name
+		}
+		if g.cfg.Throttler != nil {
+			ok, delay := g.cfg.Throttler.Allow(limit.ClientFor(key, r.RemoteAddr), route.method)
+			if !ok {
+				w.Header().Set("Retry-After", limit.RetryAfter(delay))
+				respondError(w, http.StatusTooManyRequests, "rate limit exceeded")
+				return
+			}
+		}
+		route.handle(w, r, params)
+		return
+	}
+
+	respondError(w, http.StatusNotFound, "unknown endpoint")
+}
+
+func (g *Gateway) add(path string, method string, handle handler) {
+	r := route{
+		segments: split(path),
+		method:   method,
+		handle:   handle,
+	}
+	g.routes = append(g.routes, r)
+}
+
+func (r route) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, segment := range r.segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params[segment[1:len(segment)-1]] = segments[i]
+			continue
+		}
+		if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func split(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
@@ -0,0 +1,370 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package gateway_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/api/auth"
+	api "github.com/optakt/flow-dps/api/dps"
+	"github.com/optakt/flow-dps/api/gateway"
+	"github.com/optakt/flow-dps/api/limit"
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestGateway(t *testing.T) {
+	reader := mocks.BaselineReader(t)
+	reader.ValuesFunc = func(_ uint64, paths []ledger.Path) ([]ledger.Value, error) {
+		return mocks.GenericLedgerValues(len(paths)), nil
+	}
+	server := api.NewServer(reader, zbor.NewCodec())
+	gw := gateway.NewGateway(server, zbor.NewCodec())
+
+	blockID := mocks.GenericBlockIDs(1)[0]
+	collID := mocks.GenericCollectionIDs(1)[0]
+	txID := mocks.GenericTransactionIDs(1)[0]
+	sealID := mocks.GenericSealIDs(1)[0]
+	path := mocks.GenericLedgerPath(0)
+
+	tests := []struct {
+		name  string
+		path  string
+		check func(t *testing.T, body map[string]interface{})
+	}{
+		{
+			name: "first",
+			path: "/v1/first",
+			check: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, float64(mocks.GenericHeight), body["height"])
+			},
+		},
+		{
+			name: "last",
+			path: "/v1/last",
+			check: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, float64(mocks.GenericHeight), body["height"])
+			},
+		},
+		{
+			name: "height for block",
+			path: fmt.Sprintf("/v1/blocks/%x/height", blockID),
+			check: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, blockID.String(), body["block_id"])
+				assert.Equal(t, float64(mocks.GenericHeight), body["height"])
+			},
+		},
+		{
+			name: "block",
+			path: fmt.Sprintf("/v1/blocks/%x", blockID),
+			check: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, float64(mocks.GenericHeight), body["height"])
+				assert.NotNil(t, body["header"])
+				assert.Len(t, body["collections"], 5)
+				assert.Len(t, body["seals"], 5)
+				assert.NotEmpty(t, body["transactions"])
+				assert.NotEmpty(t, body["events"])
+			},
+		},
+		{
+			name: "block at height with fields",
+			path: "/v1/heights/42/block?field=header,seals",
+			check: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, mocks.GenericHeader.ID().String(), body["block_id"])
+				assert.NotNil(t, body["header"])
+				assert.Len(t, body["seals"], 5)
+				assert.NotContains(t, body, "collections")
+				assert.NotContains(t, body, "events")
+			},
+		},
+		{
+			name: "commit",
+			path: "/v1/heights/42/commit",
+			check: func(t *testing.T, body map[string]interface{}) {
+				commit := mocks.GenericCommit(0)
+				assert.Equal(t, hex.EncodeToString(commit[:]), body["commit"])
+			},
+		},
+		{
+			name: "header",
+			path: "/v1/heights/42/header",
+			check: func(t *testing.T, body map[string]interface{}) {
+				header := body["header"].(map[string]interface{})
+				assert.Equal(t, mocks.GenericHeader.ChainID.String(), header["ChainID"])
+				assert.Equal(t, float64(mocks.GenericHeader.Height), header["Height"])
+			},
+		},
+		{
+			name: "events",
+			path: "/v1/heights/42/events?type=a,b&type=c",
+			check: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, []interface{}{"a", "b", "c"}, body["types"])
+				events := body["events"].([]interface{})
+				require.Len(t, events, 4)
+				event := events[0].(map[string]interface{})
+				assert.IsType(t, map[string]interface{}{}, event["Payload"])
+			},
+		},
+		{
+			name: "register values",
+			path: fmt.Sprintf("/v1/heights/42/registers?path=%x", path[:]),
+			check: func(t *testing.T, body map[string]interface{}) {
+				registers := body["registers"].([]interface{})
+				require.Len(t, registers, 1)
+				register := registers[0].(map[string]interface{})
+				value := mocks.GenericLedgerValue(0)
+				assert.Equal(t, hex.EncodeToString(path[:]), register["path"])
+				assert.Equal(t, hex.EncodeToString(value), register["value"])
+			},
+		},
+		{
+			name: "collection",
+			path: fmt.Sprintf("/v1/collections/%x", collID),
+			check: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, collID.String(), body["collection_id"])
+				assert.NotNil(t, body["collection"])
+			},
+		},
+		{
+			name: "collections for height",
+			path: "/v1/heights/42/collections",
+			check: func(t *testing.T, body map[string]interface{}) {
+				assert.Len(t, body["collection_ids"], 5)
+			},
+		},
+		{
+			name: "height for collection",
+			path: fmt.Sprintf("/v1/collections/%x/height", collID),
+			check: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, collID.String(), body["collection_id"])
+				assert.Equal(t, float64(mocks.GenericHeight), body["height"])
+			},
+		},
+		{
+			name: "guarantee",
+			path: fmt.Sprintf("/v1/guarantees/%x", collID),
+			check: func(t *testing.T, body map[string]interface{}) {
+				assert.NotNil(t, body["guarantee"])
+			},
+		},
+		{
+			name: "transaction",
+			path: fmt.Sprintf("/v1/transactions/%x", txID),
+			check: func(t *testing.T, body map[string]interface{}) {
+				tx := body["transaction"].(map[string]interface{})
+				assert.Equal(t, string(mocks.GenericTransaction(0).Script), tx["Script"])
+			},
+		},
+		{
+			name: "height for transaction",
+			path: fmt.Sprintf("/v1/transactions/%x/height", txID),
+			check: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, float64(mocks.GenericHeight), body["height"])
+			},
+		},
+		{
+			name: "transactions for height",
+			path: "/v1/heights/42/transactions",
+			check: func(t *testing.T, body map[string]interface{}) {
+				assert.NotEmpty(t, body["transaction_ids"])
+			},
+		},
+		{
+			name: "result",
+			path: fmt.Sprintf("/v1/results/%x", txID),
+			check: func(t *testing.T, body map[string]interface{}) {
+				result := body["result"].(map[string]interface{})
+				assert.Equal(t, mocks.GenericResult(0).TransactionID.String(), result["TransactionID"])
+			},
+		},
+		{
+			name: "results for height",
+			path: "/v1/heights/42/results",
+			check: func(t *testing.T, body map[string]interface{}) {
+				results := body["results"].([]interface{})
+				require.Len(t, results, 4)
+				result := results[0].(map[string]interface{})
+				assert.Equal(t, mocks.GenericResult(0).TransactionID.String(), result["TransactionID"])
+			},
+		},
+		{
+			name: "seal",
+			path: fmt.Sprintf("/v1/seals/%x", sealID),
+			check: func(t *testing.T, body map[string]interface{}) {
+				seal := body["seal"].(map[string]interface{})
+				commit := mocks.GenericSeal(0).FinalState
+				assert.Equal(t, hex.EncodeToString(commit[:]), seal["FinalState"])
+			},
+		},
+		{
+			name: "height for seal",
+			path: fmt.Sprintf("/v1/seals/%x/height", sealID),
+			check: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, sealID.String(), body["seal_id"])
+				assert.Equal(t, float64(mocks.GenericHeight), body["height"])
+			},
+		},
+		{
+			name: "seals for height",
+			path: "/v1/heights/42/seals",
+			check: func(t *testing.T, body map[string]interface{}) {
+				assert.Len(t, body["seal_ids"], 5)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			gw.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			var body map[string]interface{}
+			err := json.Unmarshal(rec.Body.Bytes(), &body)
+			require.NoError(t, err)
+			test.check(t, body)
+		})
+	}
+}
+
+func TestGateway_Errors(t *testing.T) {
+	reader := mocks.BaselineReader(t)
+	reader.HeaderFunc = func(uint64) (*flow.Header, error) {
+		return nil, fmt.Errorf("could not get value: %w", badger.ErrKeyNotFound)
+	}
+	reader.ValuesFunc = func(uint64, []ledger.Path) ([]ledger.Value, error) {
+		return nil, mocks.GenericError
+	}
+	server := api.NewServer(reader, zbor.NewCodec())
+	gw := gateway.NewGateway(server, zbor.NewCodec())
+
+	path := mocks.GenericLedgerPath(0)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{name: "unknown endpoint", method: http.MethodGet, path: "/v1/unknown", status: http.StatusNotFound},
+		{name: "wrong method", method: http.MethodPost, path: "/v1/first", status: http.StatusMethodNotAllowed},
+		{name: "invalid height", method: http.MethodGet, path: "/v1/heights/abc/header", status: http.StatusBadRequest},
+		{name: "invalid identifier", method: http.MethodGet, path: "/v1/seals/abc", status: http.StatusBadRequest},
+		{name: "unknown block field", method: http.MethodGet, path: "/v1/heights/42/block?field=payload", status: http.StatusBadRequest},
+		{name: "invalid path", method: http.MethodGet, path: "/v1/heights/42/registers?path=xyz", status: http.StatusBadRequest},
+		{name: "failed validation", method: http.MethodGet, path: "/v1/heights/42/registers?path=abcd", status: http.StatusBadRequest},
+		{name: "internal error", method: http.MethodGet, path: fmt.Sprintf("/v1/heights/42/registers?path=%x", path[:]), status: http.StatusInternalServerError},
+		{name: "not found", method: http.MethodGet, path: "/v1/heights/42/header", status: http.StatusNotFound},
+		{name: "stats not enabled", method: http.MethodGet, path: "/v1/stats", status: http.StatusNotImplemented},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, test.path, nil)
+			gw.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.status, rec.Code)
+
+			var body map[string]string
+			err := json.Unmarshal(rec.Body.Bytes(), &body)
+			require.NoError(t, err)
+			assert.NotEmpty(t, body["error"])
+		})
+	}
+}
+
+func TestGateway_Authorizer(t *testing.T) {
+	reader := mocks.BaselineReader(t)
+	server := api.NewServer(reader, zbor.NewCodec())
+	authenticate, err := auth.NewAuthenticator(
+		auth.Key{Name: "rosetta", Token: "token1", Methods: []string{"GetFirst", "GetLast"}},
+	)
+	require.NoError(t, err)
+	gw := gateway.NewGateway(server, zbor.NewCodec(), gateway.WithAuthorizer(authenticate))
+
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		status  int
+	}{
+		{name: "bearer token", path: "/v1/first", headers: map[string]string{"Authorization": "Bearer token1"}, status: http.StatusOK},
+		{name: "API key", path: "/v1/last", headers: map[string]string{"X-API-Key": "token1"}, status: http.StatusOK},
+		{name: "missing token", path: "/v1/first", status: http.StatusUnauthorized},
+		{name: "unknown token", path: "/v1/first", headers: map[string]string{"Authorization": "Bearer token2"}, status: http.StatusUnauthorized},
+		{name: "unlisted method", path: "/v1/heights/42/commit", headers: map[string]string{"Authorization": "Bearer token1"}, status: http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+			gw.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.status, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestGateway_Throttler(t *testing.T) {
+	reader := mocks.BaselineReader(t)
+	server := api.NewServer(reader, zbor.NewCodec())
+	limiter := limit.NewLimiter(limit.WithLimit(limit.ClassLookups, 1, 1))
+	gw := gateway.NewGateway(server, zbor.NewCodec(), gateway.WithThrottler(limiter))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/first", nil)
+	gw.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	gw.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/v1/first", nil)
+	req.RemoteAddr = "192.0.2.2:4242"
+	gw.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
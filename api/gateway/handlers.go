@@ -0,0 +1,606 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package gateway
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	api "github.com/optakt/flow-dps/api/dps"
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// First handles requests for the first indexed height.
+func (g *Gateway) First(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+
+	res, err := g.server.GetFirst(r.Context(), &api.GetFirstRequest{})
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	respond(w, http.StatusOK, heightResponse{Height: res.Height})
+}
+
+// Last handles requests for the last indexed height.
+func (g *Gateway) Last(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+
+	res, err := g.server.GetLast(r.Context(), &api.GetLastRequest{})
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	respond(w, http.StatusOK, heightResponse{Height: res.Height})
+}
+
+// HeightForBlock handles requests for the height of a block.
+func (g *Gateway) HeightForBlock(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	blockID, err := parseID(params["blockID"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := api.GetHeightForBlockRequest{
+		BlockID: blockID[:],
+	}
+	res, err := g.server.GetHeightForBlock(r.Context(), &req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	respond(w, http.StatusOK, blockHeightResponse{BlockID: blockID, Height: res.Height})
+}
+
+// Commit handles requests for the state commitment at a height.
+func (g *Gateway) Commit(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	height, err := parseHeight(params["height"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := api.GetCommitRequest{
+		Height: height,
+	}
+	res, err := g.server.GetCommit(r.Context(), &req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	respond(w, http.StatusOK, commitResponse{Height: res.Height, Commit: hex.EncodeToString(res.Commit)})
+}
+
+// Header handles requests for the block header at a height.
+func (g *Gateway) Header(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	height, err := parseHeight(params["height"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := api.GetHeaderRequest{
+		Height: height,
+	}
+	res, err := g.server.GetHeader(r.Context(), &req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	var header flow.Header
+	err = g.codec.Unmarshal(res.Data, &header)
+	if err != nil {
+		fail(w, fmt.Errorf("could not decode header: %w", err))
+		return
+	}
+
+	respond(w, http.StatusOK, headerResponse{Height: res.Height, Header: &header})
+}
+
+// Events handles requests for the events at a height. The event types can be
+// given as repeated or comma-separated `type` query parameters.
+func (g *Gateway) Events(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	height, err := parseHeight(params["height"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := api.GetEventsRequest{
+		Height: height,
+		Types:  parseList(r, "type"),
+	}
+	res, err := g.server.GetEvents(r.Context(), &req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	var events []flow.Event
+	err = g.codec.Unmarshal(res.Data, &events)
+	if err != nil {
+		fail(w, fmt.Errorf("could not decode events: %w", err))
+		return
+	}
+
+	decoded := make([]event, 0, len(events))
+	for _, e := range events {
+		decoded = append(decoded, newEvent(e))
+	}
+
+	respond(w, http.StatusOK, eventsResponse{Height: res.Height, Types: req.Types, Events: decoded})
+}
+
+// RegisterValues handles requests for the values of registers at a height.
+// The register paths are given as repeated or comma-separated `path` query
+// parameters, encoded as hexadecimal strings.
+func (g *Gateway) RegisterValues(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	height, err := parseHeight(params["height"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	encoded := parseList(r, "path")
+	paths := make([][]byte, 0, len(encoded))
+	for _, path := range encoded {
+		decoded, err := hex.DecodeString(path)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("invalid register path (%s): %s", path, err))
+			return
+		}
+		paths = append(paths, decoded)
+	}
+
+	req := api.GetRegisterValuesRequest{
+		Height: height,
+		Paths:  paths,
+	}
+	res, err := g.server.GetRegisterValues(r.Context(), &req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	if len(res.Values) != len(res.Paths) {
+		fail(w, fmt.Errorf("mismatching number of register values (%d) and paths (%d)", len(res.Values), len(res.Paths)))
+		return
+	}
+
+	registers := make([]register, 0, len(res.Values))
+	for i, value := range res.Values {
+		reg := register{
+			Path:  hex.EncodeToString(res.Paths[i]),
+			Value: hex.EncodeToString(value),
+		}
+		registers = append(registers, reg)
+	}
+
+	respond(w, http.StatusOK, registersResponse{Height: res.Height, Registers: registers})
+}
+
+// Collection handles requests for a collection.
+func (g *Gateway) Collection(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	collID, err := parseID(params["collectionID"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := api.GetCollectionRequest{
+		CollectionID: collID[:],
+	}
+	res, err := g.server.GetCollection(r.Context(), &req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	var collection flow.LightCollection
+	err = g.codec.Unmarshal(res.Data, &collection)
+	if err != nil {
+		fail(w, fmt.Errorf("could not decode collection: %w", err))
+		return
+	}
+
+	respond(w, http.StatusOK, collectionResponse{CollectionID: collID, Collection: &collection})
+}
+
+// CollectionsForHeight handles requests for the collection IDs at a height.
+func (g *Gateway) CollectionsForHeight(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	height, err := parseHeight(params["height"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := api.ListCollectionsForHeightRequest{
+		Height: height,
+	}
+	res, err := g.server.ListCollectionsForHeight(r.Context(), &req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	respond(w, http.StatusOK, collectionsResponse{Height: res.Height, CollectionIDs: toIDs(res.CollectionIDs)})
+}
+
+// HeightForCollection handles requests for the height of a collection.
+func (g *Gateway) HeightForCollection(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	collID, err := parseID(params["collectionID"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := api.GetHeightForCollectionRequest{
+		CollectionID: collID[:],
+	}
+	res, err := g.server.GetHeightForCollection(r.Context(), &req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	respond(w, http.StatusOK, collectionHeightResponse{CollectionID: collID, Height: res.Height})
+}
+
+// Guarantee handles requests for the guarantee of a collection.
+func (g *Gateway) Guarantee(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	collID, err := parseID(params["collectionID"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := api.GetGuaranteeRequest{
+		CollectionID: collID[:],
+	}
+	res, err := g.server.GetGuarantee(r.Context(), &req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	var guarantee flow.CollectionGuarantee
+	err = g.codec.Unmarshal(res.Data, &guarantee)
+	if err != nil {
+		fail(w, fmt.Errorf("could not decode guarantee: %w", err))
+		return
+	}
+
+	respond(w, http.StatusOK, guaranteeResponse{CollectionID: collID, Guarantee: &guarantee})
+}
+
+// Transaction handles requests for a transaction.
+func (g *Gateway) Transaction(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	txID, err := parseID(params["transactionID"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := api.GetTransactionRequest{
+		TransactionID: txID[:],
+	}
+	res, err := g.server.GetTransaction(r.Context(), &req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	var tx flow.TransactionBody
+	err = g.codec.Unmarshal(res.Data, &tx)
+	if err != nil {
+		fail(w, fmt.Errorf("could not decode transaction: %w", err))
+		return
+	}
+
+	respond(w, http.StatusOK, transactionResponse{TransactionID: txID, Transaction: newTransaction(&tx)})
+}
+
+// HeightForTransaction handles requests for the height of a transaction.
+func (g *Gateway) HeightForTransaction(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	txID, err := parseID(params["transactionID"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := api.GetHeightForTransactionRequest{
+		TransactionID: txID[:],
+	}
+	res, err := g.server.GetHeightForTransaction(r.Context(), &req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	respond(w, http.StatusOK, transactionHeightResponse{TransactionID: txID, Height: res.Height})
+}
+
+// TransactionsForHeight handles requests for the transaction IDs at a height.
+func (g *Gateway) TransactionsForHeight(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	height, err := parseHeight(params["height"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := api.ListTransactionsForHeightRequest{
+		Height: height,
+	}
+	res, err := g.server.ListTransactionsForHeight(r.Context(), &req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	respond(w, http.StatusOK, transactionsResponse{Height: res.Height, TransactionIDs: toIDs(res.TransactionIDs)})
+}
+
+// Result handles requests for the result of a transaction.
+func (g *Gateway) Result(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	txID, err := parseID(params["transactionID"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := api.GetResultRequest{
+		TransactionID: txID[:],
+	}
+	res, err := g.server.GetResult(r.Context(), &req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	var result flow.TransactionResult
+	err = g.codec.Unmarshal(res.Data, &result)
+	if err != nil {
+		fail(w, fmt.Errorf("could not decode transaction result: %w", err))
+		return
+	}
+
+	respond(w, http.StatusOK, resultResponse{TransactionID: txID, Result: &result})
+}
+
+// ResultsForHeight handles requests for the transaction results at a height.
+func (g *Gateway) ResultsForHeight(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	height, err := parseHeight(params["height"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := api.ListResultsForHeightRequest{
+		Height: height,
+	}
+	res, err := g.server.ListResultsForHeight(r.Context(), &req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	var results []*flow.TransactionResult
+	err = g.codec.Unmarshal(res.Data, &results)
+	if err != nil {
+		fail(w, fmt.Errorf("could not decode transaction results: %w", err))
+		return
+	}
+
+	respond(w, http.StatusOK, resultsResponse{Height: res.Height, Results: results})
+}
+
+// Seal handles requests for a seal.
+func (g *Gateway) Seal(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	sealID, err := parseID(params["sealID"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := api.GetSealRequest{
+		SealID: sealID[:],
+	}
+	res, err := g.server.GetSeal(r.Context(), &req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	var seal flow.Seal
+	err = g.codec.Unmarshal(res.Data, &seal)
+	if err != nil {
+		fail(w, fmt.Errorf("could not decode seal: %w", err))
+		return
+	}
+
+	respond(w, http.StatusOK, sealResponse{SealID: sealID, Seal: newSeal(&seal)})
+}
+
+// SealsForHeight handles requests for the seal IDs at a height.
+func (g *Gateway) SealsForHeight(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	height, err := parseHeight(params["height"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := api.ListSealsForHeightRequest{
+		Height: height,
+	}
+	res, err := g.server.ListSealsForHeight(r.Context(), &req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	respond(w, http.StatusOK, sealsResponse{Height: res.Height, SealIDs: toIDs(res.SealIDs)})
+}
+
+// HeightForSeal handles requests for the height of a seal.
+func (g *Gateway) HeightForSeal(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	sealID, err := parseID(params["sealID"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := api.GetHeightForSealRequest{
+		SealID: sealID[:],
+	}
+	res, err := g.server.GetHeightForSeal(r.Context(), &req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	respond(w, http.StatusOK, sealHeightResponse{SealID: sealID, Height: res.Height})
+}
+
+// BlockAtHeight handles requests for the finalized block at a height. The
+// fields of the block can be selected with repeated or comma-separated `field`
+// query parameters; all fields are returned by default.
+func (g *Gateway) BlockAtHeight(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	height, err := parseHeight(params["height"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := api.GetBlockRequest{
+		Selector: &api.GetBlockRequest_Height{Height: height},
+		Fields:   &fieldmaskpb.FieldMask{Paths: parseList(r, "field")},
+	}
+	g.block(w, r, &req)
+}
+
+// Block handles requests for a finalized block by ID. The fields of the block
+// can be selected like for `BlockAtHeight`.
+func (g *Gateway) Block(w http.ResponseWriter, r *http.Request, params map[string]string) {
+
+	blockID, err := parseID(params["blockID"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := api.GetBlockRequest{
+		Selector: &api.GetBlockRequest_BlockID{BlockID: blockID[:]},
+		Fields:   &fieldmaskpb.FieldMask{Paths: parseList(r, "field")},
+	}
+	g.block(w, r, &req)
+}
+
+// IndexStats handles requests for the index statistics.
+func (g *Gateway) IndexStats(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+
+	res, err := g.server.GetIndexStats(r.Context(), &api.GetIndexStatsRequest{})
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	respond(w, http.StatusOK, newReport(res.Prefixes))
+}
+
+func (g *Gateway) block(w http.ResponseWriter, r *http.Request, req *api.GetBlockRequest) {
+
+	res, err := g.server.GetBlock(r.Context(), req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	var block dps.Block
+	err = g.codec.Unmarshal(res.Data, &block)
+	if err != nil {
+		fail(w, fmt.Errorf("could not decode block: %w", err))
+		return
+	}
+
+	respond(w, http.StatusOK, newBlock(&block))
+}
+
+func parseHeight(value string) (uint64, error) {
+	height, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid height (%s): %w", value, err)
+	}
+	return height, nil
+}
+
+func parseID(value string) (flow.Identifier, error) {
+	id, err := flow.HexStringToIdentifier(value)
+	if err != nil {
+		return flow.ZeroID, fmt.Errorf("invalid identifier (%s): %w", value, err)
+	}
+	return id, nil
+}
+
+// parseList returns the values of a query parameter, which can be repeated or
+// contain comma-separated values.
+func parseList(r *http.Request, name string) []string {
+	var values []string
+	for _, param := range r.URL.Query()[name] {
+		for _, value := range strings.Split(param, ",") {
+			if value == "" {
+				continue
+			}
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func toIDs(raw [][]byte) []flow.Identifier {
+	ids := make([]flow.Identifier, 0, len(raw))
+	for _, id := range raw {
+		ids = append(ids, flow.HashToID(id))
+	}
+	return ids
+}
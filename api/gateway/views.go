@@ -0,0 +1,279 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package gateway
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	api "github.com/optakt/flow-dps/api/dps"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/stats"
+)
+
+// The decoded Flow entities keep the field names of their Flow types, while
+// the envelopes that wrap them use snake case, like the rest of the DPS JSON
+// output. Values which would otherwise be opaque bytes, such as state
+// commitments, register paths and values, are encoded as hexadecimal strings.
+
+type heightResponse struct {
+	Height uint64 `json:"height"`
+}
+
+type blockHeightResponse struct {
+	BlockID flow.Identifier `json:"block_id"`
+	Height  uint64          `json:"height"`
+}
+
+type commitResponse struct {
+	Height uint64 `json:"height"`
+	Commit string `json:"commit"`
+}
+
+type headerResponse struct {
+	Height uint64       `json:"height"`
+	Header *flow.Header `json:"header"`
+}
+
+type eventsResponse struct {
+	Height uint64   `json:"height"`
+	Types  []string `json:"types"`
+	Events []event  `json:"events"`
+}
+
+type registersResponse struct {
+	Height    uint64     `json:"height"`
+	Registers []register `json:"registers"`
+}
+
+type register struct {
+	Path  string `json:"path"`
+	Value string `json:"value"`
+}
+
+type collectionResponse struct {
+	CollectionID flow.Identifier       `json:"collection_id"`
+	Collection   *flow.LightCollection `json:"collection"`
+}
+
+type collectionsResponse struct {
+	Height        uint64            `json:"height"`
+	CollectionIDs []flow.Identifier `json:"collection_ids"`
+}
+
+type collectionHeightResponse struct {
+	CollectionID flow.Identifier `json:"collection_id"`
+	Height       uint64          `json:"height"`
+}
+
+type guaranteeResponse struct {
+	CollectionID flow.Identifier           `json:"collection_id"`
+	Guarantee    *flow.CollectionGuarantee `json:"guarantee"`
+}
+
+type transactionResponse struct {
+	TransactionID flow.Identifier `json:"transaction_id"`
+	Transaction   transaction     `json:"transaction"`
+}
+
+type transactionHeightResponse struct {
+	TransactionID flow.Identifier `json:"transaction_id"`
+	Height        uint64          `json:"height"`
+}
+
+type transactionsResponse struct {
+	Height         uint64            `json:"height"`
+	TransactionIDs []flow.Identifier `json:"transaction_ids"`
+}
+
+type resultResponse struct {
+	TransactionID flow.Identifier         `json:"transaction_id"`
+	Result        *flow.TransactionResult `json:"result"`
+}
+
+type resultsResponse struct {
+	Height  uint64                    `json:"height"`
+	Results []*flow.TransactionResult `json:"results"`
+}
+
+type sealResponse struct {
+	SealID flow.Identifier `json:"seal_id"`
+	Seal   seal            `json:"seal"`
+}
+
+type sealsResponse struct {
+	Height  uint64            `json:"height"`
+	SealIDs []flow.Identifier `json:"seal_ids"`
+}
+
+type sealHeightResponse struct {
+	SealID flow.Identifier `json:"seal_id"`
+	Height uint64          `json:"height"`
+}
+
+// blockResponse omits the fields of the block which were not selected by the
+// field mask of the request.
+type blockResponse struct {
+	Height       uint64                      `json:"height"`
+	BlockID      flow.Identifier             `json:"block_id"`
+	Header       *flow.Header                `json:"header,omitempty"`
+	Collections  []*flow.LightCollection     `json:"collections,omitempty"`
+	Guarantees   []*flow.CollectionGuarantee `json:"guarantees,omitempty"`
+	Transactions []transaction               `json:"transactions,omitempty"`
+	Results      []*flow.TransactionResult   `json:"results,omitempty"`
+	Events       []event                     `json:"events,omitempty"`
+	Seals        []seal                      `json:"seals,omitempty"`
+}
+
+// event is a Flow event with its JSON-CDC payload embedded as JSON.
+type event struct {
+	Type             flow.EventType
+	TransactionID    flow.Identifier
+	TransactionIndex uint32
+	EventIndex       uint32
+	Payload          json.RawMessage
+}
+
+// transaction is a Flow transaction body with its script as text and its
+// JSON-CDC arguments embedded as JSON.
+type transaction struct {
+	ReferenceBlockID   flow.Identifier
+	Script             string
+	Arguments          []json.RawMessage
+	GasLimit           uint64
+	ProposalKey        flow.ProposalKey
+	Payer              flow.Address
+	Authorizers        []flow.Address
+	PayloadSignatures  []flow.TransactionSignature
+	EnvelopeSignatures []flow.TransactionSignature
+}
+
+// seal is a Flow seal with its final state commitment as hexadecimal string.
+type seal struct {
+	BlockID                flow.Identifier
+	ResultID               flow.Identifier
+	FinalState             string
+	AggregatedApprovalSigs []flow.AggregatedSignature
+}
+
+func newEvent(e flow.Event) event {
+	return event{
+		Type:             e.Type,
+		TransactionID:    e.TransactionID,
+		TransactionIndex: e.TransactionIndex,
+		EventIndex:       e.EventIndex,
+		Payload:          embed(e.Payload),
+	}
+}
+
+func newTransaction(tx *flow.TransactionBody) transaction {
+	args := make([]json.RawMessage, 0, len(tx.Arguments))
+	for _, arg := range tx.Arguments {
+		args = append(args, embed(arg))
+	}
+	return transaction{
+		ReferenceBlockID:   tx.ReferenceBlockID,
+		Script:             string(tx.Script),
+		Arguments:          args,
+		GasLimit:           tx.GasLimit,
+		ProposalKey:        tx.ProposalKey,
+		Payer:              tx.Payer,
+		Authorizers:        tx.Authorizers,
+		PayloadSignatures:  tx.PayloadSignatures,
+		EnvelopeSignatures: tx.EnvelopeSignatures,
+	}
+}
+
+func newSeal(s *flow.Seal) seal {
+	return seal{
+		BlockID:                s.BlockID,
+		ResultID:               s.ResultID,
+		FinalState:             hex.EncodeToString(s.FinalState[:]),
+		AggregatedApprovalSigs: s.AggregatedApprovalSigs,
+	}
+}
+
+func newBlock(b *dps.Block) blockResponse {
+	block := blockResponse{
+		Height:      b.Height,
+		BlockID:     b.BlockID,
+		Header:      b.Header,
+		Collections: b.Collections,
+		Guarantees:  b.Guarantees,
+		Results:     b.Results,
+	}
+	for _, tx := range b.Transactions {
+		block.Transactions = append(block.Transactions, newTransaction(tx))
+	}
+	for _, e := range b.Events {
+		block.Events = append(block.Events, newEvent(e))
+	}
+	for _, s := range b.Seals {
+		block.Seals = append(block.Seals, newSeal(s))
+	}
+	return block
+}
+
+// embed returns the given JSON-CDC encoded value as raw JSON, so that it is
+// embedded in the response as is. Values which are not valid JSON are embedded
+// as base64-encoded string instead, like any other byte slice.
+func embed(data []byte) json.RawMessage {
+	if json.Valid(data) {
+		return data
+	}
+	encoded, _ := json.Marshal(data)
+	return encoded
+}
+
+// newReport converts the index statistics of the DPS API back into the report
+// they were created from.
+func newReport(prefixes []*api.PrefixStats) *stats.Report {
+	report := stats.Report{
+		Prefixes: make([]*stats.Prefix, 0, len(prefixes)),
+	}
+	for _, p := range prefixes {
+		prefix := stats.Prefix{
+			Prefix:            uint8(p.Prefix),
+			Name:              p.Name,
+			Keys:              p.Keys,
+			KeyBytes:          p.KeyBytes,
+			CompressedBytes:   p.CompressedBytes,
+			UncompressedBytes: p.UncompressedBytes,
+			Invalid:           p.Invalid,
+			Dictionaries:      make([]*stats.Dictionary, 0, len(p.Dictionaries)),
+		}
+		if p.HasCoverage {
+			prefix.Coverage = &stats.Coverage{
+				First: p.FirstHeight,
+				Last:  p.LastHeight,
+			}
+		}
+		for _, d := range p.Dictionaries {
+			dictionary := stats.Dictionary{
+				Version:           uint8(d.Version),
+				Dictionary:        d.Dictionary,
+				Values:            d.Values,
+				CompressedBytes:   d.CompressedBytes,
+				UncompressedBytes: d.UncompressedBytes,
+				Ratio:             d.Ratio,
+			}
+			prefix.Dictionaries = append(prefix.Dictionaries, &dictionary)
+		}
+		report.Prefixes = append(report.Prefixes, &prefix)
+	}
+	return &report
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package health_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/optakt/flow-dps/api/health"
+)
+
+func TestBypassUnary(t *testing.T) {
+	reject := func(context.Context, interface{}, *grpc.UnaryServerInfo, grpc.UnaryHandler) (interface{}, error) {
+		return nil, status.Error(codes.Unauthenticated, "rejected")
+	}
+	handler := func(context.Context, interface{}) (interface{}, error) {
+		return "response", nil
+	}
+	interceptor := health.BypassUnary(reject)
+
+	t.Run("skips interceptor for health checks", func(t *testing.T) {
+		t.Parallel()
+
+		info := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+		res, err := interceptor(context.Background(), "request", info, handler)
+
+		require.NoError(t, err)
+		assert.Equal(t, "response", res)
+	})
+
+	t.Run("applies interceptor for other calls", func(t *testing.T) {
+		t.Parallel()
+
+		info := &grpc.UnaryServerInfo{FullMethod: "/API/GetLast"}
+		_, err := interceptor(context.Background(), "request", info, handler)
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func TestBypassStream(t *testing.T) {
+	reject := func(interface{}, grpc.ServerStream, *grpc.StreamServerInfo, grpc.StreamHandler) error {
+		return status.Error(codes.Unauthenticated, "rejected")
+	}
+	handler := func(interface{}, grpc.ServerStream) error {
+		return nil
+	}
+	interceptor := health.BypassStream(reject)
+
+	t.Run("skips interceptor for health watches", func(t *testing.T) {
+		t.Parallel()
+
+		info := &grpc.StreamServerInfo{FullMethod: "/grpc.health.v1.Health/Watch"}
+		err := interceptor(nil, nil, info, handler)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("applies interceptor for other streams", func(t *testing.T) {
+		t.Parallel()
+
+		info := &grpc.StreamServerInfo{FullMethod: "/API/Other"}
+		err := interceptor(nil, nil, info, handler)
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package health
+
+import (
+	"time"
+)
+
+// DefaultConfig is the default configuration for the Monitor.
+var DefaultConfig = Config{
+	Interval: 5 * time.Second,
+	Services: []string{""},
+	Follower: nil,
+	MaxLag:   0,
+}
+
+// Config contains optional parameters for the Monitor.
+type Config struct {
+	Interval time.Duration
+	Services []string
+	Follower Follower
+	MaxLag   uint64
+}
+
+// Follower represents something that follows the finalized height of the
+// chain, such as the consensus tracker of a live index.
+type Follower interface {
+	Finalized() uint64
+}
+
+// Option is an option that can be given to the monitor to configure optional
+// parameters on initialization.
+type Option func(*Config)
+
+// WithInterval sets the interval at which the monitor checks the index.
+func WithInterval(interval time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.Interval = interval
+	}
+}
+
+// WithServices sets the names of the GRPC services whose serving status is
+// set by the monitor, on top of the overall status of the server.
+func WithServices(services ...string) Option {
+	return func(cfg *Config) {
+		cfg.Services = append([]string{""}, services...)
+	}
+}
+
+// WithMaxLag makes the monitor report the index as not serving whenever its
+// last indexed height lags the finalized height of the given follower by more
+// than the given number of blocks.
+func WithMaxLag(follower Follower, lag uint64) Option {
+	return func(cfg *Config) {
+		cfg.Follower = follower
+		cfg.MaxLag = lag
+	}
+}
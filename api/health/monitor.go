@@ -0,0 +1,156 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package health
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Index represents something that provides the range of indexed heights.
+type Index interface {
+	First() (uint64, error)
+	Last() (uint64, error)
+}
+
+// Setter represents something that sets the serving status of GRPC services,
+// such as the standard GRPC health server.
+type Setter interface {
+	SetServingStatus(service string, status grpc_health_v1.HealthCheckResponse_ServingStatus)
+}
+
+// Monitor periodically checks whether an index is ready to serve requests, and
+// reports the result as the serving status of the GRPC health service. An index
+// is ready once it has a valid first and last height and, optionally, as long as
+// its last height does not lag too far behind the finalized height.
+type Monitor struct {
+	log   zerolog.Logger
+	cfg   Config
+	index Index
+	set   Setter
+
+	serving *bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewMonitor creates a new health monitor for the given index, which reports
+// its status to the given setter. Until its first check, the index is reported
+// as not serving.
+func NewMonitor(log zerolog.Logger, index Index, set Setter, options ...Option) *Monitor {
+
+	cfg := DefaultConfig
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	m := Monitor{
+		log:   log.With().Str("component", "health_monitor").Logger(),
+		cfg:   cfg,
+		index: index,
+		set:   set,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	m.report(grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	return &m
+}
+
+// Check checks whether the index is ready to serve requests, updates the
+// serving status accordingly and returns the reason why it is not ready, if
+// it is not.
+func (m *Monitor) Check() error {
+
+	err := m.check()
+	serving := err == nil
+	if m.serving == nil || *m.serving != serving {
+		if serving {
+			m.log.Info().Msg("index ready to serve requests")
+		} else {
+			m.log.Warn().Err(err).Msg("index not ready to serve requests")
+		}
+	}
+	m.serving = &serving
+
+	if !serving {
+		m.report(grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+		return err
+	}
+
+	m.report(grpc_health_v1.HealthCheckResponse_SERVING)
+	return nil
+}
+
+// Run checks the index at the configured interval, until the monitor is
+// stopped.
+func (m *Monitor) Run() {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	_ = m.Check()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			_ = m.Check()
+		}
+	}
+}
+
+// Stop stops the monitor and waits for it to finish.
+func (m *Monitor) Stop() error {
+	close(m.stop)
+	<-m.done
+	return nil
+}
+
+func (m *Monitor) check() error {
+
+	first, err := m.index.First()
+	if err != nil {
+		return fmt.Errorf("could not get first height: %w", err)
+	}
+	last, err := m.index.Last()
+	if err != nil {
+		return fmt.Errorf("could not get last height: %w", err)
+	}
+	if last < first {
+		return fmt.Errorf("invalid height range (first: %d, last: %d)", first, last)
+	}
+
+	if m.cfg.Follower == nil || m.cfg.MaxLag == 0 {
+		return nil
+	}
+	finalized := m.cfg.Follower.Finalized()
+	if finalized > last && finalized-last > m.cfg.MaxLag {
+		return fmt.Errorf("index lagging behind finalized height (last: %d, finalized: %d, max lag: %d)", last, finalized, m.cfg.MaxLag)
+	}
+
+	return nil
+}
+
+func (m *Monitor) report(status grpc_health_v1.HealthCheckResponse_ServingStatus) {
+	for _, service := range m.cfg.Services {
+		m.set.SetServingStatus(service, status)
+	}
+}
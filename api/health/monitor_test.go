@@ -0,0 +1,185 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package health_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/optakt/flow-dps/api/health"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+type setter struct {
+	mutex    sync.Mutex
+	statuses map[string]grpc_health_v1.HealthCheckResponse_ServingStatus
+}
+
+func (s *setter) SetServingStatus(service string, status grpc_health_v1.HealthCheckResponse_ServingStatus) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.statuses == nil {
+		s.statuses = make(map[string]grpc_health_v1.HealthCheckResponse_ServingStatus)
+	}
+	s.statuses[service] = status
+}
+
+func (s *setter) Status(service string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.statuses[service]
+}
+
+type follower uint64
+
+func (f follower) Finalized() uint64 {
+	return uint64(f)
+}
+
+func TestMonitor(t *testing.T) {
+	serving := grpc_health_v1.HealthCheckResponse_SERVING
+	notServing := grpc_health_v1.HealthCheckResponse_NOT_SERVING
+
+	t.Run("not serving before first check", func(t *testing.T) {
+		t.Parallel()
+
+		set := &setter{}
+		_ = health.NewMonitor(mocks.NoopLogger, mocks.BaselineReader(t), set, health.WithServices("API"))
+
+		assert.Equal(t, notServing, set.Status(""))
+		assert.Equal(t, notServing, set.Status("API"))
+	})
+
+	t.Run("serving with valid heights", func(t *testing.T) {
+		t.Parallel()
+
+		set := &setter{}
+		monitor := health.NewMonitor(mocks.NoopLogger, mocks.BaselineReader(t), set, health.WithServices("API"))
+
+		err := monitor.Check()
+
+		require.NoError(t, err)
+		assert.Equal(t, serving, set.Status(""))
+		assert.Equal(t, serving, set.Status("API"))
+	})
+
+	t.Run("not serving without first height", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.FirstFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+		set := &setter{}
+		monitor := health.NewMonitor(mocks.NoopLogger, index, set)
+
+		err := monitor.Check()
+
+		assert.ErrorIs(t, err, mocks.GenericError)
+		assert.Equal(t, notServing, set.Status(""))
+	})
+
+	t.Run("not serving without last height", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+		set := &setter{}
+		monitor := health.NewMonitor(mocks.NoopLogger, index, set)
+
+		err := monitor.Check()
+
+		assert.ErrorIs(t, err, mocks.GenericError)
+		assert.Equal(t, notServing, set.Status(""))
+	})
+
+	t.Run("not serving when lagging too far behind", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return 100, nil
+		}
+		set := &setter{}
+		monitor := health.NewMonitor(mocks.NoopLogger, index, set, health.WithMaxLag(follower(111), 10))
+
+		err := monitor.Check()
+
+		assert.Error(t, err)
+		assert.Equal(t, notServing, set.Status(""))
+	})
+
+	t.Run("serving when lagging within bounds", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return 100, nil
+		}
+		set := &setter{}
+		monitor := health.NewMonitor(mocks.NoopLogger, index, set, health.WithMaxLag(follower(110), 10))
+
+		err := monitor.Check()
+
+		require.NoError(t, err)
+		assert.Equal(t, serving, set.Status(""))
+	})
+
+	t.Run("recovers once index is ready", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+		set := &setter{}
+		monitor := health.NewMonitor(mocks.NoopLogger, index, set)
+
+		err := monitor.Check()
+		require.Error(t, err)
+		assert.Equal(t, notServing, set.Status(""))
+
+		index.LastFunc = func() (uint64, error) {
+			return mocks.GenericHeight, nil
+		}
+
+		err = monitor.Check()
+		require.NoError(t, err)
+		assert.Equal(t, serving, set.Status(""))
+	})
+
+	t.Run("checks on interval until stopped", func(t *testing.T) {
+		t.Parallel()
+
+		set := &setter{}
+		monitor := health.NewMonitor(mocks.NoopLogger, mocks.BaselineReader(t), set, health.WithInterval(time.Millisecond))
+
+		go monitor.Run()
+
+		assert.Eventually(t, func() bool {
+			return set.Status("") == serving
+		}, time.Second, time.Millisecond)
+
+		err := monitor.Stop()
+		assert.NoError(t, err)
+	})
+}
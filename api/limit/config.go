@@ -0,0 +1,89 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package limit
+
+import (
+	"time"
+)
+
+// Classes of methods of the DPS API, which are limited separately. Register
+// reads are much more expensive to serve than other lookups, so they usually
+// warrant a lower limit.
+const (
+	ClassLookups   = Class("lookups")
+	ClassRegisters = Class("registers")
+)
+
+// Class is a class of methods which share the same rate limit.
+type Class string
+
+// Limit is the rate limit of a class of methods for each client. The rate is
+// the sustained number of calls per second, and the burst is the number of
+// calls a client can make at once after being idle. A rate of zero disables
+// rate limiting for the class.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// DefaultConfig is the default configuration for the Limiter.
+var DefaultConfig = Config{
+	Limits: map[Class]Limit{
+		ClassLookups:   {Rate: 100, Burst: 200},
+		ClassRegisters: {Rate: 20, Burst: 40},
+	},
+	Methods: map[string]Class{
+		"GetRegisterValues": ClassRegisters,
+	},
+	IdleTimeout: 10 * time.Minute,
+}
+
+// Config contains optional parameters for the Limiter. Methods which are not
+// given a class belong to the lookups class.
+type Config struct {
+	Limits      map[Class]Limit
+	Methods     map[string]Class
+	IdleTimeout time.Duration
+}
+
+// Option is an option that can be given to the limiter to configure optional
+// parameters on initialization.
+type Option func(*Config)
+
+// WithLimit sets the rate limit for the given class of methods, with the rate
+// as a number of calls per second. A rate of zero disables rate limiting for
+// the class.
+func WithLimit(class Class, rate float64, burst int) Option {
+	return func(cfg *Config) {
+		cfg.Limits[class] = Limit{Rate: rate, Burst: burst}
+	}
+}
+
+// WithMethodClass assigns the method with the given name, such as
+// `GetIndexStats`, to the given class of methods.
+func WithMethodClass(method string, class Class) Option {
+	return func(cfg *Config) {
+		cfg.Methods[method] = class
+	}
+}
+
+// WithIdleTimeout sets the time after which the state of idle clients is
+// dropped, so that memory use does not grow with the number of clients seen
+// over time.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.IdleTimeout = timeout
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package limit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/tags"
+
+	"github.com/optakt/flow-dps/api/auth"
+)
+
+// HeaderRetryAfter is the header in which rejected calls receive the number of
+// seconds after which they can be retried.
+const HeaderRetryAfter = "retry-after"
+
+// Throttler represents something that can decide whether a client is allowed
+// to call a method right now.
+type Throttler interface {
+	Allow(client string, method string) (bool, time.Duration)
+}
+
+// UnaryServerInterceptor returns a GRPC interceptor which rejects unary calls
+// from clients that exceeded their rate limit for the called method. It should
+// come after the authentication interceptor, if any, so that authenticated
+// clients are limited per API key instead of per address.
+func UnaryServerInterceptor(throttle Throttler) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ok, delay := throttle.Allow(Client(ctx), info.FullMethod)
+		if !ok {
+			_ = grpc.SetHeader(ctx, retryAfter(delay))
+			return nil, reject(info.FullMethod, delay)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a GRPC interceptor which rejects streams from
+// clients that exceeded their rate limit for the called method.
+func StreamServerInterceptor(throttle Throttler) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ok, delay := throttle.Allow(Client(stream.Context()), info.FullMethod)
+		if !ok {
+			_ = stream.SetHeader(retryAfter(delay))
+			return reject(info.FullMethod, delay)
+		}
+		return handler(srv, stream)
+	}
+}
+
+// Client returns the identity of the client of a call, which is the name of
+// its API key if it was authenticated, or the host of its address otherwise.
+func Client(ctx context.Context) string {
+
+	key := tags.Extract(ctx).Values()[auth.TagKey]
+	var address string
+	p, ok := peer.FromContext(ctx)
+	if ok && p.Addr != nil {
+		address = p.Addr.String()
+	}
+
+	return ClientFor(key, address)
+}
+
+// ClientFor returns the identity of a client with the given API key name and
+// address. Clients with an API key are identified by its name, so that a key
+// shares its rate limits across addresses; others are identified by the host
+// of their address.
+func ClientFor(key string, address string) string {
+	if key != "" {
+		return "key:" + key
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	return host
+}
+
+// RetryDelay returns the delay after which a call rejected with the given error
+// can be retried, if the error contains one.
+func RetryDelay(err error) (time.Duration, bool) {
+
+	var se interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &se) {
+		return 0, false
+	}
+	for _, detail := range se.GRPCStatus().Details() {
+		info, ok := detail.(*errdetails.RetryInfo)
+		if ok && info.RetryDelay != nil {
+			return info.RetryDelay.AsDuration(), true
+		}
+	}
+
+	return 0, false
+}
+
+// RetryAfter returns the number of whole seconds after which a call rejected
+// with the given delay can be retried, as used in retry headers.
+func RetryAfter(delay time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(delay.Seconds())))
+}
+
+func retryAfter(delay time.Duration) metadata.MD {
+	return metadata.Pairs(HeaderRetryAfter, RetryAfter(delay))
+}
+
+// reject returns the error for a call that exceeded its rate limit. Besides a
+// message, it contains the delay after which the call can be retried as
+// details, so that clients can back off accordingly.
+func reject(method string, delay time.Duration) error {
+	st := status.New(codes.ResourceExhausted, fmt.Sprintf("rate limit exceeded (method: %s, retry after: %s)", method, delay))
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package limit_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/tags"
+
+	"github.com/optakt/flow-dps/api/auth"
+	"github.com/optakt/flow-dps/api/limit"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+type throttler struct {
+	client string
+	method string
+	ok     bool
+	delay  time.Duration
+}
+
+func (t *throttler) Allow(client string, method string) (bool, time.Duration) {
+	t.client = client
+	t.method = method
+	return t.ok, t.delay
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	handler := func(context.Context, interface{}) (interface{}, error) {
+		return "response", nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/API/GetRegisterValues"}
+	addr := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 4242}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		throttle := &throttler{ok: true}
+		res, err := limit.UnaryServerInterceptor(throttle)(ctx, "request", info, handler)
+
+		require.NoError(t, err)
+		assert.Equal(t, "response", res)
+		assert.Equal(t, "192.0.2.1", throttle.client)
+		assert.Equal(t, info.FullMethod, throttle.method)
+	})
+
+	t.Run("rejects throttled calls with retry delay", func(t *testing.T) {
+		t.Parallel()
+
+		throttle := &throttler{ok: false, delay: 1500 * time.Millisecond}
+		_, err := limit.UnaryServerInterceptor(throttle)(ctx, "request", info, handler)
+
+		require.Error(t, err)
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		delay, ok := limit.RetryDelay(err)
+		require.True(t, ok)
+		assert.Equal(t, 1500*time.Millisecond, delay)
+	})
+}
+
+func TestClient(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 4242}
+
+	t.Run("identifies clients by host", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+
+		assert.Equal(t, "192.0.2.1", limit.Client(ctx))
+	})
+
+	t.Run("identifies authenticated clients by key", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+		ctx = tags.SetInContext(ctx, tags.NewTags().Set(auth.TagKey, "rosetta"))
+
+		assert.Equal(t, "key:rosetta", limit.Client(ctx))
+	})
+
+	t.Run("handles missing peer", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Empty(t, limit.Client(context.Background()))
+	})
+}
+
+func TestRetryDelay(t *testing.T) {
+	_, ok := limit.RetryDelay(status.Error(codes.ResourceExhausted, "rate limit exceeded"))
+	assert.False(t, ok)
+
+	_, ok = limit.RetryDelay(mocks.GenericError)
+	assert.False(t, ok)
+
+	assert.Equal(t, "2", limit.RetryAfter(1500*time.Millisecond))
+	assert.Equal(t, "1", limit.RetryAfter(time.Second))
+}
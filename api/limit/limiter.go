@@ -0,0 +1,153 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package limit
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Limiter applies per-client rate limits on the methods of the DPS API, with a
+// separate token bucket for each client and class of methods.
+type Limiter struct {
+	cfg Config
+
+	mutex   *sync.Mutex
+	buckets map[bucket]*state
+	clients map[string]uint // number of buckets per client
+	swept   time.Time
+}
+
+type bucket struct {
+	client string
+	class  Class
+}
+
+type state struct {
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+// NewLimiter creates a new limiter with the given options.
+func NewLimiter(options ...Option) *Limiter {
+
+	cfg := Config{
+		Limits:      make(map[Class]Limit, len(DefaultConfig.Limits)),
+		Methods:     make(map[string]Class, len(DefaultConfig.Methods)),
+		IdleTimeout: DefaultConfig.IdleTimeout,
+	}
+	for class, limit := range DefaultConfig.Limits {
+		cfg.Limits[class] = limit
+	}
+	for method, class := range DefaultConfig.Methods {
+		cfg.Methods[method] = class
+	}
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	l := Limiter{
+		cfg:     cfg,
+		mutex:   &sync.Mutex{},
+		buckets: make(map[bucket]*state),
+		clients: make(map[string]uint),
+		swept:   time.Now(),
+	}
+
+	return &l
+}
+
+// Class returns the class of the given method, which can be given either by
+// name or as a full GRPC method, such as `/API/GetHeader`.
+func (l *Limiter) Class(method string) Class {
+	class, ok := l.cfg.Methods[method[strings.LastIndex(method, "/")+1:]]
+	if !ok {
+		return ClassLookups
+	}
+	return class
+}
+
+// Allow consumes a call to the given method from the rate limit of the given
+// client. When the client exceeded its rate limit, the call is not allowed and
+// the returned delay is the time until it would be.
+func (l *Limiter) Allow(client string, method string) (bool, time.Duration) {
+	return l.allow(time.Now(), client, method)
+}
+
+// Clients returns the number of clients the limiter currently keeps track of.
+func (l *Limiter) Clients() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return len(l.clients)
+}
+
+func (l *Limiter) allow(now time.Time, client string, method string) (bool, time.Duration) {
+
+	class := l.Class(method)
+	limit, ok := l.cfg.Limits[class]
+	if !ok || limit.Rate <= 0 {
+		return true, 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.sweep(now)
+
+	b := bucket{client: client, class: class}
+	s, ok := l.buckets[b]
+	if !ok {
+		burst := limit.Burst
+		if burst < 1 {
+			burst = 1
+		}
+		s = &state{limiter: rate.NewLimiter(rate.Limit(limit.Rate), burst)}
+		l.buckets[b] = s
+		l.clients[client]++
+	}
+	s.seen = now
+
+	reservation := s.limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+
+	return true, 0
+}
+
+// sweep drops the buckets of clients that have been idle for longer than the
+// idle timeout. It only goes through the buckets once per idle timeout.
+func (l *Limiter) sweep(now time.Time) {
+	if l.cfg.IdleTimeout <= 0 || now.Sub(l.swept) < l.cfg.IdleTimeout {
+		return
+	}
+	for b, s := range l.buckets {
+		if now.Sub(s.seen) < l.cfg.IdleTimeout {
+			continue
+		}
+		delete(l.buckets, b)
+		l.clients[b.client]--
+		if l.clients[b.client] == 0 {
+			delete(l.clients, b.client)
+		}
+	}
+	l.swept = now
+}
@@ -0,0 +1,130 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_Class(t *testing.T) {
+	limiter := NewLimiter(WithMethodClass("GetIndexStats", "stats"))
+
+	assert.Equal(t, ClassRegisters, limiter.Class("GetRegisterValues"))
+	assert.Equal(t, ClassRegisters, limiter.Class("/API/GetRegisterValues"))
+	assert.Equal(t, ClassLookups, limiter.Class("/API/GetHeader"))
+	assert.Equal(t, Class("stats"), limiter.Class("/API/GetIndexStats"))
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Now()
+
+	t.Run("allows burst then throttles", func(t *testing.T) {
+		t.Parallel()
+
+		limiter := NewLimiter(WithLimit(ClassLookups, 10, 3))
+
+		for i := 0; i < 3; i++ {
+			ok, _ := limiter.allow(now, "client", "/API/GetHeader")
+			assert.True(t, ok)
+		}
+		ok, delay := limiter.allow(now, "client", "/API/GetHeader")
+		assert.False(t, ok)
+		assert.Equal(t, 100*time.Millisecond, delay)
+
+		ok, _ = limiter.allow(now.Add(100*time.Millisecond), "client", "/API/GetHeader")
+		assert.True(t, ok)
+	})
+
+	t.Run("rejected calls do not consume the rate limit", func(t *testing.T) {
+		t.Parallel()
+
+		limiter := NewLimiter(WithLimit(ClassLookups, 10, 1))
+
+		ok, _ := limiter.allow(now, "client", "/API/GetHeader")
+		assert.True(t, ok)
+		for i := 0; i < 5; i++ {
+			ok, _ = limiter.allow(now, "client", "/API/GetHeader")
+			assert.False(t, ok)
+		}
+
+		ok, _ = limiter.allow(now.Add(100*time.Millisecond), "client", "/API/GetHeader")
+		assert.True(t, ok)
+	})
+
+	t.Run("limits clients separately", func(t *testing.T) {
+		t.Parallel()
+
+		limiter := NewLimiter(WithLimit(ClassLookups, 1, 1))
+
+		ok, _ := limiter.allow(now, "client1", "/API/GetHeader")
+		assert.True(t, ok)
+		ok, _ = limiter.allow(now, "client2", "/API/GetHeader")
+		assert.True(t, ok)
+		ok, _ = limiter.allow(now, "client1", "/API/GetHeader")
+		assert.False(t, ok)
+	})
+
+	t.Run("limits classes separately", func(t *testing.T) {
+		t.Parallel()
+
+		limiter := NewLimiter(WithLimit(ClassLookups, 1, 1), WithLimit(ClassRegisters, 1, 1))
+
+		ok, _ := limiter.allow(now, "client", "/API/GetHeader")
+		assert.True(t, ok)
+		ok, _ = limiter.allow(now, "client", "/API/GetRegisterValues")
+		assert.True(t, ok)
+		ok, _ = limiter.allow(now, "client", "/API/GetRegisterValues")
+		assert.False(t, ok)
+	})
+
+	t.Run("zero rate disables limit", func(t *testing.T) {
+		t.Parallel()
+
+		limiter := NewLimiter(WithLimit(ClassRegisters, 0, 0))
+
+		for i := 0; i < 100; i++ {
+			ok, _ := limiter.allow(now, "client", "/API/GetRegisterValues")
+			assert.True(t, ok)
+		}
+		assert.Zero(t, limiter.Clients())
+	})
+
+	t.Run("does not modify default config", func(t *testing.T) {
+		t.Parallel()
+
+		_ = NewLimiter(WithLimit(ClassLookups, 1, 1), WithMethodClass("GetHeader", ClassRegisters))
+
+		assert.Equal(t, Limit{Rate: 100, Burst: 200}, DefaultConfig.Limits[ClassLookups])
+		assert.NotContains(t, DefaultConfig.Methods, "GetHeader")
+	})
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	now := time.Now()
+	limiter := NewLimiter(WithIdleTimeout(time.Minute))
+	limiter.swept = now
+
+	_, _ = limiter.allow(now, "client1", "/API/GetHeader")
+	_, _ = limiter.allow(now, "client1", "/API/GetRegisterValues")
+	_, _ = limiter.allow(now.Add(30*time.Second), "client2", "/API/GetHeader")
+	assert.Equal(t, 2, limiter.Clients())
+
+	_, _ = limiter.allow(now.Add(time.Minute), "client2", "/API/GetHeader")
+	assert.Equal(t, 1, limiter.Clients())
+	assert.Len(t, limiter.buckets, 1)
+}
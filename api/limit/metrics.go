@@ -0,0 +1,88 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package limit
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// MetricsLimiter wraps the limiter and records metrics for the calls it allows
+// and throttles.
+type MetricsLimiter struct {
+	limiter *Limiter
+
+	allowed   *prometheus.CounterVec
+	throttled *prometheus.CounterVec
+	delay     *prometheus.CounterVec
+	clients   prometheus.Gauge
+}
+
+// NewMetricsLimiter creates a limiter that records the number of allowed and
+// throttled calls per class of methods as Prometheus metrics.
+func NewMetricsLimiter(limiter *Limiter) *MetricsLimiter {
+
+	allowedOpts := prometheus.CounterOpts{
+		Name: "rate_limit_allowed_calls",
+		Help: "number of calls allowed by the rate limiter per class of methods",
+	}
+	allowed := promauto.NewCounterVec(allowedOpts, []string{"class"})
+
+	throttledOpts := prometheus.CounterOpts{
+		Name: "rate_limit_throttled_calls",
+		Help: "number of calls rejected by the rate limiter per class of methods",
+	}
+	throttled := promauto.NewCounterVec(throttledOpts, []string{"class"})
+
+	delayOpts := prometheus.CounterOpts{
+		Name: "rate_limit_retry_delay_seconds",
+		Help: "retry delays given to rejected calls per class of methods",
+	}
+	delay := promauto.NewCounterVec(delayOpts, []string{"class"})
+
+	clientsOpts := prometheus.GaugeOpts{
+		Name: "rate_limit_clients",
+		Help: "number of clients tracked by the rate limiter",
+	}
+	clients := promauto.NewGauge(clientsOpts)
+
+	m := MetricsLimiter{
+		limiter: limiter,
+
+		allowed:   allowed,
+		throttled: throttled,
+		delay:     delay,
+		clients:   clients,
+	}
+
+	return &m
+}
+
+// Allow consumes a call to the given method from the rate limit of the given
+// client, and records whether it was allowed.
+func (m *MetricsLimiter) Allow(client string, method string) (bool, time.Duration) {
+	ok, delay := m.limiter.Allow(client, method)
+	class := string(m.limiter.Class(method))
+	if ok {
+		m.allowed.WithLabelValues(class).Inc()
+	} else {
+		m.throttled.WithLabelValues(class).Inc()
+		m.delay.WithLabelValues(class).Add(delay.Seconds())
+	}
+	m.clients.Set(float64(m.limiter.Clients()))
+	return ok, delay
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package remote
+
+import (
+	"time"
+)
+
+// DefaultConfig is the default configuration for the remote reader.
+var DefaultConfig = Config{
+	Timeout:    10 * time.Second,
+	Retries:    5,
+	MinBackoff: 100 * time.Millisecond,
+	MaxBackoff: 5 * time.Second,
+	CacheSize:  100_000,
+}
+
+// Config contains optional parameters for the remote reader.
+type Config struct {
+	Timeout    time.Duration
+	Retries    uint
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	CacheSize  uint64
+}
+
+// Option is an option that can be given to the remote reader to configure
+// optional parameters on initialization.
+type Option func(*Config)
+
+// WithTimeout sets the timeout for each call to the DPS API. A timeout of zero
+// means calls never time out.
+func WithTimeout(timeout time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.Timeout = timeout
+	}
+}
+
+// WithRetries sets how many times a call is retried when the DPS API is
+// unavailable, before giving up.
+func WithRetries(retries uint) Option {
+	return func(cfg *Config) {
+		cfg.Retries = retries
+	}
+}
+
+// WithBackoff sets the delay before the first retry of a call, which doubles
+// with each retry until it reaches the given maximum.
+func WithBackoff(min time.Duration, max time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.MinBackoff = min
+		cfg.MaxBackoff = max
+	}
+}
+
+// WithCacheSize sets the maximum number of results kept in the cache. A size
+// of zero disables caching.
+func WithCacheSize(size uint64) Option {
+	return func(cfg *Config) {
+		cfg.CacheSize = size
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package remote
+
+import (
+	"context"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+)
+
+// Index represents an index reader that accepts a context for each of its
+// calls, such as the GRPC API index reader.
+type Index interface {
+	FirstContext(ctx context.Context) (uint64, error)
+	LastContext(ctx context.Context) (uint64, error)
+
+	HeightForBlockContext(ctx context.Context, blockID flow.Identifier) (uint64, error)
+	HeightForTransactionContext(ctx context.Context, txID flow.Identifier) (uint64, error)
+	HeightForCollectionContext(ctx context.Context, collID flow.Identifier) (uint64, error)
+	HeightForSealContext(ctx context.Context, sealID flow.Identifier) (uint64, error)
+
+	CommitContext(ctx context.Context, height uint64) (flow.StateCommitment, error)
+	HeaderContext(ctx context.Context, height uint64) (*flow.Header, error)
+	EventsContext(ctx context.Context, height uint64, types ...flow.EventType) ([]flow.Event, error)
+	ValuesContext(ctx context.Context, height uint64, paths []ledger.Path) ([]ledger.Value, error)
+
+	CollectionContext(ctx context.Context, collID flow.Identifier) (*flow.LightCollection, error)
+	GuaranteeContext(ctx context.Context, collID flow.Identifier) (*flow.CollectionGuarantee, error)
+	TransactionContext(ctx context.Context, txID flow.Identifier) (*flow.TransactionBody, error)
+	SealContext(ctx context.Context, sealID flow.Identifier) (*flow.Seal, error)
+	ResultContext(ctx context.Context, txID flow.Identifier) (*flow.TransactionResult, error)
+
+	CollectionsByHeightContext(ctx context.Context, height uint64) ([]flow.Identifier, error)
+	TransactionsByHeightContext(ctx context.Context, height uint64) ([]flow.Identifier, error)
+	SealsByHeightContext(ctx context.Context, height uint64) ([]flow.Identifier, error)
+	ResultsByHeightContext(ctx context.Context, height uint64) ([]*flow.TransactionResult, error)
+}
@@ -0,0 +1,368 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package remote
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// Reader implements the `dps.Reader` interface on top of an index reader for a
+// remote DPS API. Each call is bounded by a timeout and retried with an
+// exponential backoff when the API is unavailable. The results of all calls
+// except `First`, `Last` and `Values` never change once indexed, and are kept
+// in a cache, so they should not be modified by callers.
+type Reader struct {
+	cfg   Config
+	index Index
+	cache *ristretto.Cache
+}
+
+// NewReader creates a new remote reader on top of the given index.
+func NewReader(index Index, options ...Option) (*Reader, error) {
+
+	cfg := DefaultConfig
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	// Ristretto recommends keeping ten times as many counters as items in the
+	// cache when full; as we count each result as one item, this is easy.
+	var cache *ristretto.Cache
+	if cfg.CacheSize > 0 {
+		var err error
+		cache, err = ristretto.NewCache(&ristretto.Config{
+			NumCounters: int64(cfg.CacheSize) * 10,
+			MaxCost:     int64(cfg.CacheSize),
+			BufferItems: 64,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not initialize cache: %w", err)
+		}
+	}
+
+	r := Reader{
+		cfg:   cfg,
+		index: index,
+		cache: cache,
+	}
+
+	return &r, nil
+}
+
+// First returns the height of the first finalized block that was indexed.
+func (r *Reader) First() (uint64, error) {
+	var height uint64
+	err := r.retry(func(ctx context.Context) error {
+		var err error
+		height, err = r.index.FirstContext(ctx)
+		return err
+	})
+	return height, err
+}
+
+// Last returns the height of the last finalized block that was indexed.
+func (r *Reader) Last() (uint64, error) {
+	var height uint64
+	err := r.retry(func(ctx context.Context) error {
+		var err error
+		height, err = r.index.LastContext(ctx)
+		return err
+	})
+	return height, err
+}
+
+// HeightForBlock returns the height of the given blockID.
+func (r *Reader) HeightForBlock(blockID flow.Identifier) (uint64, error) {
+	value, err := r.cached(idKey("height_for_block", blockID), func(ctx context.Context) (interface{}, error) {
+		return r.index.HeightForBlockContext(ctx, blockID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return value.(uint64), nil
+}
+
+// HeightForTransaction returns the height of the given transaction ID.
+func (r *Reader) HeightForTransaction(txID flow.Identifier) (uint64, error) {
+	value, err := r.cached(idKey("height_for_transaction", txID), func(ctx context.Context) (interface{}, error) {
+		return r.index.HeightForTransactionContext(ctx, txID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return value.(uint64), nil
+}
+
+// HeightForCollection returns the height of the given collection ID.
+func (r *Reader) HeightForCollection(collID flow.Identifier) (uint64, error) {
+	value, err := r.cached(idKey("height_for_collection", collID), func(ctx context.Context) (interface{}, error) {
+		return r.index.HeightForCollectionContext(ctx, collID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return value.(uint64), nil
+}
+
+// HeightForSeal returns the height of the given seal ID.
+func (r *Reader) HeightForSeal(sealID flow.Identifier) (uint64, error) {
+	value, err := r.cached(idKey("height_for_seal", sealID), func(ctx context.Context) (interface{}, error) {
+		return r.index.HeightForSealContext(ctx, sealID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return value.(uint64), nil
+}
+
+// Commit returns the commitment of the execution state as it was after the
+// execution of the finalized block at the given height.
+func (r *Reader) Commit(height uint64) (flow.StateCommitment, error) {
+	value, err := r.cached(heightKey("commit", height), func(ctx context.Context) (interface{}, error) {
+		return r.index.CommitContext(ctx, height)
+	})
+	if err != nil {
+		return flow.DummyStateCommitment, err
+	}
+	return value.(flow.StateCommitment), nil
+}
+
+// Header returns the header for the finalized block at the given height.
+func (r *Reader) Header(height uint64) (*flow.Header, error) {
+	value, err := r.cached(heightKey("header", height), func(ctx context.Context) (interface{}, error) {
+		return r.index.HeaderContext(ctx, height)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*flow.Header), nil
+}
+
+// Events returns the events of all transactions that were part of the
+// finalized block at the given height. It can optionally filter them by event
+// type; if no event types are given, all events are returned.
+func (r *Reader) Events(height uint64, types ...flow.EventType) ([]flow.Event, error) {
+	names := make([]string, 0, len(types))
+	for _, typ := range types {
+		names = append(names, string(typ))
+	}
+	key := heightKey("events", height) + "/" + strings.Join(names, ",")
+	value, err := r.cached(key, func(ctx context.Context) (interface{}, error) {
+		return r.index.EventsContext(ctx, height, types...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]flow.Event), nil
+}
+
+// Values returns the Ledger values of the execution state at the given paths
+// as they were after the execution of the finalized block at the given height.
+// They are not cached, as script invokers already cache the registers they
+// read.
+func (r *Reader) Values(height uint64, paths []ledger.Path) ([]ledger.Value, error) {
+	var values []ledger.Value
+	err := r.retry(func(ctx context.Context) error {
+		var err error
+		values, err = r.index.ValuesContext(ctx, height, paths)
+		return err
+	})
+	return values, err
+}
+
+// Collection returns the collection with the given ID.
+func (r *Reader) Collection(collID flow.Identifier) (*flow.LightCollection, error) {
+	value, err := r.cached(idKey("collection", collID), func(ctx context.Context) (interface{}, error) {
+		return r.index.CollectionContext(ctx, collID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*flow.LightCollection), nil
+}
+
+// Guarantee returns the collection guarantee for the given collection ID.
+func (r *Reader) Guarantee(collID flow.Identifier) (*flow.CollectionGuarantee, error) {
+	value, err := r.cached(idKey("guarantee", collID), func(ctx context.Context) (interface{}, error) {
+		return r.index.GuaranteeContext(ctx, collID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*flow.CollectionGuarantee), nil
+}
+
+// Transaction returns the transaction with the given ID.
+func (r *Reader) Transaction(txID flow.Identifier) (*flow.TransactionBody, error) {
+	value, err := r.cached(idKey("transaction", txID), func(ctx context.Context) (interface{}, error) {
+		return r.index.TransactionContext(ctx, txID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*flow.TransactionBody), nil
+}
+
+// Seal returns the seal with the given ID.
+func (r *Reader) Seal(sealID flow.Identifier) (*flow.Seal, error) {
+	value, err := r.cached(idKey("seal", sealID), func(ctx context.Context) (interface{}, error) {
+		return r.index.SealContext(ctx, sealID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*flow.Seal), nil
+}
+
+// Result returns the result for a given transaction ID.
+func (r *Reader) Result(txID flow.Identifier) (*flow.TransactionResult, error) {
+	value, err := r.cached(idKey("result", txID), func(ctx context.Context) (interface{}, error) {
+		return r.index.ResultContext(ctx, txID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*flow.TransactionResult), nil
+}
+
+// CollectionsByHeight returns the collection IDs within the given block.
+func (r *Reader) CollectionsByHeight(height uint64) ([]flow.Identifier, error) {
+	value, err := r.cached(heightKey("collections", height), func(ctx context.Context) (interface{}, error) {
+		return r.index.CollectionsByHeightContext(ctx, height)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]flow.Identifier), nil
+}
+
+// TransactionsByHeight returns the transaction IDs within the given block.
+func (r *Reader) TransactionsByHeight(height uint64) ([]flow.Identifier, error) {
+	value, err := r.cached(heightKey("transactions", height), func(ctx context.Context) (interface{}, error) {
+		return r.index.TransactionsByHeightContext(ctx, height)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]flow.Identifier), nil
+}
+
+// SealsByHeight returns the seal IDs at the given height.
+func (r *Reader) SealsByHeight(height uint64) ([]flow.Identifier, error) {
+	value, err := r.cached(heightKey("seals", height), func(ctx context.Context) (interface{}, error) {
+		return r.index.SealsByHeightContext(ctx, height)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]flow.Identifier), nil
+}
+
+// ResultsByHeight returns the transaction results within the given block.
+func (r *Reader) ResultsByHeight(height uint64) ([]*flow.TransactionResult, error) {
+	value, err := r.cached(heightKey("results", height), func(ctx context.Context) (interface{}, error) {
+		return r.index.ResultsByHeightContext(ctx, height)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]*flow.TransactionResult), nil
+}
+
+// cached returns the cached result for the given key, or retrieves it with
+// retries and caches it.
+func (r *Reader) cached(key string, retrieve func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+
+	if r.cache != nil {
+		value, ok := r.cache.Get(key)
+		if ok {
+			return value, nil
+		}
+	}
+
+	var value interface{}
+	err := r.retry(func(ctx context.Context) error {
+		var err error
+		value, err = retrieve(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if r.cache != nil {
+		_ = r.cache.Set(key, value, 1)
+	}
+
+	return value, nil
+}
+
+// retry executes the given call, bounded by the configured timeout, and
+// retries it with an exponential backoff as long as the API is unavailable.
+func (r *Reader) retry(call func(ctx context.Context) error) error {
+
+	backoff := r.cfg.MinBackoff
+	for attempt := uint(0); ; attempt++ {
+
+		err := r.attempt(call)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, dps.ErrUnavailable) {
+			return err
+		}
+		if attempt >= r.cfg.Retries {
+			return fmt.Errorf("could not complete call after %d attempts: %w", attempt+1, err)
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > r.cfg.MaxBackoff {
+			backoff = r.cfg.MaxBackoff
+		}
+	}
+}
+
+func (r *Reader) attempt(call func(ctx context.Context) error) error {
+	ctx := context.Background()
+	if r.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.cfg.Timeout)
+		defer cancel()
+	}
+	return call(ctx)
+}
+
+func heightKey(prefix string, height uint64) string {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, height)
+	return prefix + "/" + string(key)
+}
+
+func idKey(prefix string, id flow.Identifier) string {
+	return prefix + "/" + string(id[:])
+}
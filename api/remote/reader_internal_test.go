@@ -0,0 +1,357 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package remote
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+
+	api "github.com/optakt/flow-dps/api/dps"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+var (
+	_ Index      = (*api.Index)(nil)
+	_ dps.Reader = (*Reader)(nil)
+)
+
+// index wraps a mock reader into a context-accepting index, and counts the
+// calls made to it.
+type index struct {
+	reader *mocks.Reader
+
+	mutex *sync.Mutex
+	calls map[string]int
+	ctx   context.Context
+}
+
+func newIndex(t *testing.T) *index {
+	return &index{
+		reader: mocks.BaselineReader(t),
+		mutex:  &sync.Mutex{},
+		calls:  make(map[string]int),
+	}
+}
+
+func (i *index) call(ctx context.Context, name string) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.calls[name]++
+	i.ctx = ctx
+}
+
+func (i *index) count(name string) int {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return i.calls[name]
+}
+
+func (i *index) FirstContext(ctx context.Context) (uint64, error) {
+	i.call(ctx, "First")
+	return i.reader.First()
+}
+
+func (i *index) LastContext(ctx context.Context) (uint64, error) {
+	i.call(ctx, "Last")
+	return i.reader.Last()
+}
+
+func (i *index) HeightForBlockContext(ctx context.Context, blockID flow.Identifier) (uint64, error) {
+	i.call(ctx, "HeightForBlock")
+	return i.reader.HeightForBlock(blockID)
+}
+
+func (i *index) HeightForTransactionContext(ctx context.Context, txID flow.Identifier) (uint64, error) {
+	i.call(ctx, "HeightForTransaction")
+	return i.reader.HeightForTransaction(txID)
+}
+
+func (i *index) HeightForCollectionContext(ctx context.Context, collID flow.Identifier) (uint64, error) {
+	i.call(ctx, "HeightForCollection")
+	return i.reader.HeightForCollection(collID)
+}
+
+func (i *index) HeightForSealContext(ctx context.Context, sealID flow.Identifier) (uint64, error) {
+	i.call(ctx, "HeightForSeal")
+	return i.reader.HeightForSeal(sealID)
+}
+
+func (i *index) CommitContext(ctx context.Context, height uint64) (flow.StateCommitment, error) {
+	i.call(ctx, "Commit")
+	return i.reader.Commit(height)
+}
+
+func (i *index) HeaderContext(ctx context.Context, height uint64) (*flow.Header, error) {
+	i.call(ctx, "Header")
+	return i.reader.Header(height)
+}
+
+func (i *index) EventsContext(ctx context.Context, height uint64, types ...flow.EventType) ([]flow.Event, error) {
+	i.call(ctx, "Events")
+	return i.reader.Events(height, types...)
+}
+
+func (i *index) ValuesContext(ctx context.Context, height uint64, paths []ledger.Path) ([]ledger.Value, error) {
+	i.call(ctx, "Values")
+	return i.reader.Values(height, paths)
+}
+
+func (i *index) CollectionContext(ctx context.Context, collID flow.Identifier) (*flow.LightCollection, error) {
+	i.call(ctx, "Collection")
+	return i.reader.Collection(collID)
+}
+
+func (i *index) GuaranteeContext(ctx context.Context, collID flow.Identifier) (*flow.CollectionGuarantee, error) {
+	i.call(ctx, "Guarantee")
+	return i.reader.Guarantee(collID)
+}
+
+func (i *index) TransactionContext(ctx context.Context, txID flow.Identifier) (*flow.TransactionBody, error) {
+	i.call(ctx, "Transaction")
+	return i.reader.Transaction(txID)
+}
+
+func (i *index) SealContext(ctx context.Context, sealID flow.Identifier) (*flow.Seal, error) {
+	i.call(ctx, "Seal")
+	return i.reader.Seal(sealID)
+}
+
+func (i *index) ResultContext(ctx context.Context, txID flow.Identifier) (*flow.TransactionResult, error) {
+	i.call(ctx, "Result")
+	return i.reader.Result(txID)
+}
+
+func (i *index) CollectionsByHeightContext(ctx context.Context, height uint64) ([]flow.Identifier, error) {
+	i.call(ctx, "CollectionsByHeight")
+	return i.reader.CollectionsByHeight(height)
+}
+
+func (i *index) TransactionsByHeightContext(ctx context.Context, height uint64) ([]flow.Identifier, error) {
+	i.call(ctx, "TransactionsByHeight")
+	return i.reader.TransactionsByHeight(height)
+}
+
+func (i *index) SealsByHeightContext(ctx context.Context, height uint64) ([]flow.Identifier, error) {
+	i.call(ctx, "SealsByHeight")
+	return i.reader.SealsByHeight(height)
+}
+
+func (i *index) ResultsByHeightContext(ctx context.Context, height uint64) ([]*flow.TransactionResult, error) {
+	i.call(ctx, "ResultsByHeight")
+	return i.reader.ResultsByHeight(height)
+}
+
+func TestNewReader(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		r, err := NewReader(newIndex(t), WithTimeout(time.Second), WithRetries(2), WithBackoff(time.Millisecond, time.Second), WithCacheSize(10))
+
+		require.NoError(t, err)
+		assert.Equal(t, time.Second, r.cfg.Timeout)
+		assert.Equal(t, uint(2), r.cfg.Retries)
+		assert.Equal(t, time.Millisecond, r.cfg.MinBackoff)
+		assert.Equal(t, time.Second, r.cfg.MaxBackoff)
+		assert.NotNil(t, r.cache)
+	})
+
+	t.Run("without cache", func(t *testing.T) {
+		t.Parallel()
+
+		r, err := NewReader(newIndex(t), WithCacheSize(0))
+
+		require.NoError(t, err)
+		assert.Nil(t, r.cache)
+	})
+}
+
+func TestReader_Retry(t *testing.T) {
+	unavailable := fmt.Errorf("could not get header: %w", dps.ErrUnavailable)
+
+	t.Run("retries while unavailable", func(t *testing.T) {
+		t.Parallel()
+
+		index := newIndex(t)
+		failures := 2
+		index.reader.HeaderFunc = func(uint64) (*flow.Header, error) {
+			if failures > 0 {
+				failures--
+				return nil, unavailable
+			}
+			return mocks.GenericHeader, nil
+		}
+		r, err := NewReader(index, WithBackoff(time.Millisecond, time.Millisecond))
+		require.NoError(t, err)
+
+		header, err := r.Header(mocks.GenericHeight)
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeader, header)
+		assert.Equal(t, 3, index.count("Header"))
+	})
+
+	t.Run("gives up after retries", func(t *testing.T) {
+		t.Parallel()
+
+		index := newIndex(t)
+		index.reader.LastFunc = func() (uint64, error) {
+			return 0, unavailable
+		}
+		r, err := NewReader(index, WithRetries(3), WithBackoff(time.Millisecond, 2*time.Millisecond))
+		require.NoError(t, err)
+
+		_, err = r.Last()
+
+		assert.ErrorIs(t, err, dps.ErrUnavailable)
+		assert.Equal(t, 4, index.count("Last"))
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		t.Parallel()
+
+		index := newIndex(t)
+		index.reader.SealFunc = func(flow.Identifier) (*flow.Seal, error) {
+			return nil, fmt.Errorf("could not get seal: %w", dps.ErrNotFound)
+		}
+		r, err := NewReader(index, WithBackoff(time.Millisecond, time.Millisecond))
+		require.NoError(t, err)
+
+		_, err = r.Seal(mocks.GenericSealIDs(1)[0])
+
+		assert.ErrorIs(t, err, dps.ErrNotFound)
+		assert.Equal(t, 1, index.count("Seal"))
+	})
+
+	t.Run("applies timeout to calls", func(t *testing.T) {
+		t.Parallel()
+
+		index := newIndex(t)
+		r, err := NewReader(index, WithTimeout(time.Minute))
+		require.NoError(t, err)
+
+		_, err = r.First()
+
+		require.NoError(t, err)
+		deadline, ok := index.ctx.Deadline()
+		require.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, 10*time.Second)
+	})
+}
+
+func TestReader_Cache(t *testing.T) {
+	txID := mocks.GenericTransactionIDs(1)[0]
+
+	t.Run("caches immutable results", func(t *testing.T) {
+		t.Parallel()
+
+		index := newIndex(t)
+		r, err := NewReader(index)
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			header, err := r.Header(mocks.GenericHeight)
+			require.NoError(t, err)
+			assert.Equal(t, mocks.GenericHeader, header)
+
+			tx, err := r.Transaction(txID)
+			require.NoError(t, err)
+			assert.Equal(t, mocks.GenericTransaction(0), tx)
+
+			r.cache.Wait()
+		}
+
+		assert.Equal(t, 1, index.count("Header"))
+		assert.Equal(t, 1, index.count("Transaction"))
+	})
+
+	t.Run("separates event types", func(t *testing.T) {
+		t.Parallel()
+
+		index := newIndex(t)
+		r, err := NewReader(index)
+		require.NoError(t, err)
+
+		_, err = r.Events(mocks.GenericHeight)
+		require.NoError(t, err)
+		r.cache.Wait()
+		_, err = r.Events(mocks.GenericHeight, mocks.GenericEventType(0))
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, index.count("Events"))
+	})
+
+	t.Run("does not cache mutable results", func(t *testing.T) {
+		t.Parallel()
+
+		index := newIndex(t)
+		r, err := NewReader(index)
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			_, err = r.Last()
+			require.NoError(t, err)
+			_, err = r.Values(mocks.GenericHeight, mocks.GenericLedgerPaths(1))
+			require.NoError(t, err)
+			r.cache.Wait()
+		}
+
+		assert.Equal(t, 2, index.count("Last"))
+		assert.Equal(t, 2, index.count("Values"))
+	})
+
+	t.Run("does not cache failures", func(t *testing.T) {
+		t.Parallel()
+
+		index := newIndex(t)
+		index.reader.ResultFunc = func(flow.Identifier) (*flow.TransactionResult, error) {
+			return nil, mocks.GenericError
+		}
+		r, err := NewReader(index)
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			_, err = r.Result(txID)
+			require.Error(t, err)
+			r.cache.Wait()
+		}
+
+		assert.Equal(t, 2, index.count("Result"))
+	})
+
+	t.Run("works without cache", func(t *testing.T) {
+		t.Parallel()
+
+		index := newIndex(t)
+		r, err := NewReader(index, WithCacheSize(0))
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			_, err = r.Header(mocks.GenericHeight)
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, 2, index.count("Header"))
+	})
+}
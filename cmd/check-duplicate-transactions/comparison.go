@@ -24,21 +24,29 @@ import (
 	"github.com/onflow/flow-go/storage/badger/operation"
 
 	"github.com/optakt/flow-dps/codec/zbor"
-	"github.com/optakt/flow-dps/models/dps"
 	"github.com/optakt/flow-dps/service/storage"
+	"github.com/optakt/flow-dps/service/tuning"
 )
 
-func compareDuplicates(log zerolog.Logger, dataDir string, indexDir string, duplicates map[uint64][]flow.Identifier) error {
+func compareDuplicates(log zerolog.Logger, tune *tuning.Config, dataDir string, indexDir string, duplicates map[uint64][]flow.Identifier) error {
 
 	log.Info().Msg("comparing duplicates between databases")
 
 	// Initialize the databases.
-	protocol, err := badger.Open(dps.DefaultOptions(dataDir).WithReadOnly(true))
+	protocolOpts, err := tune.BadgerOptions(dataDir)
+	if err != nil {
+		return fmt.Errorf("could not get Badger options for protocol state (dir: %s): %w", dataDir, err)
+	}
+	protocol, err := badger.Open(protocolOpts.WithReadOnly(true))
 	if err != nil {
 		return fmt.Errorf("could not open protocol state (dir: %s): %w", dataDir, err)
 	}
 	defer protocol.Close()
-	index, err := badger.Open(dps.DefaultOptions(indexDir).WithReadOnly(true))
+	indexOpts, err := tune.BadgerOptions(indexDir)
+	if err != nil {
+		return fmt.Errorf("could not get Badger options for state index (dir: %s): %w", indexDir, err)
+	}
+	index, err := badger.Open(indexOpts.WithReadOnly(true))
 	if err != nil {
 		return fmt.Errorf("could not open state index (dir: %s): %w", indexDir, err)
 	}
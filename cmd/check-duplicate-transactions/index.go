@@ -24,11 +24,11 @@ import (
 	"github.com/onflow/flow-go/model/flow"
 
 	"github.com/optakt/flow-dps/codec/zbor"
-	"github.com/optakt/flow-dps/models/dps"
 	"github.com/optakt/flow-dps/service/storage"
+	"github.com/optakt/flow-dps/service/tuning"
 )
 
-func indexCheck(log zerolog.Logger, dir string) (map[uint64][]flow.Identifier, error) {
+func indexCheck(log zerolog.Logger, tune *tuning.Config, dir string) (map[uint64][]flow.Identifier, error) {
 
 	// We keep track of the duplicate transactions per height.
 	duplicates := make(map[uint64][]flow.Identifier)
@@ -36,7 +36,11 @@ func indexCheck(log zerolog.Logger, dir string) (map[uint64][]flow.Identifier, e
 	log.Info().Str("index", dir).Msg("starting index state duplicate check")
 
 	// Open the index database.
-	index, err := badger.Open(dps.DefaultOptions(dir).WithReadOnly(true))
+	indexOpts, err := tune.BadgerOptions(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not get Badger options for state index (dir: %s): %w", dir, err)
+	}
+	index, err := badger.Open(indexOpts.WithReadOnly(true))
 	if err != nil {
 		return nil, fmt.Errorf("could not open state index (dir: %s): %w", dir, err)
 	}
@@ -22,6 +22,8 @@ import (
 	"github.com/spf13/pflag"
 
 	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/service/tuning"
 )
 
 const (
@@ -37,14 +39,20 @@ func run() int {
 
 	// Parse the command line arguments.
 	var (
-		flagData  string
-		flagIndex string
-		flagLevel string
+		flagBadgerConfig  string
+		flagBadgerOptions map[string]string
+		flagBadgerProfile string
+		flagData          string
+		flagIndex         string
+		flagLevel         string
 	)
 
 	pflag.StringVarP(&flagData, "data", "d", "", "database directory for protocol state")
 	pflag.StringVarP(&flagIndex, "index", "i", "", "database directory for state index")
 	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagBadgerConfig, "badger-config", "", "path to JSON or YAML file with Badger profile and option overrides")
+	pflag.StringToStringVar(&flagBadgerOptions, "badger-option", nil, "comma-separated list of Badger option overrides (name=value)")
+	pflag.StringVar(&flagBadgerProfile, "badger-profile", "", "Badger tuning profile, one of bulk-ingest, live, read-only-serving or low-memory (read-only-serving when left empty)")
 
 	pflag.Parse()
 
@@ -58,6 +66,14 @@ func run() int {
 	}
 	log = log.Level(level)
 
+	// Load the Badger configuration, which selects the profile of options used
+	// to open the index and protocol databases.
+	tune, err := tuning.Load(tuning.ProfileServing, flagBadgerConfig, flagBadgerProfile, flagBadgerOptions)
+	if err != nil {
+		log.Error().Err(err).Msg("could not load Badger configuration")
+		return failure
+	}
+
 	// We should have at least one of data or index directories.
 	if flagData == "" && flagIndex == "" {
 		log.Error().Msg("need at least one of data or index directories")
@@ -66,7 +82,7 @@ func run() int {
 
 	// Only check the protocol state if a directory for it is given.
 	if flagData != "" {
-		err = protocolCheck(log, flagData)
+		err = protocolCheck(log, tune, flagData)
 		if err != nil {
 			log.Error().Err(err).Msg("could not execute protocol state duplicate check")
 			return failure
@@ -79,7 +95,7 @@ func run() int {
 	// Only check the state index if a directory for it is given.
 	var duplicates map[uint64][]flow.Identifier
 	if flagIndex != "" {
-		duplicates, err = indexCheck(log, flagIndex)
+		duplicates, err = indexCheck(log, tune, flagIndex)
 		if err != nil {
 			log.Error().Err(err).Msg("could not execute state index duplicate check")
 			return failure
@@ -89,7 +105,7 @@ func run() int {
 	// If we have both a protocol state and a state index database, we can check
 	// duplicates from the state index against the protocol state.
 	if flagData != "" && flagIndex != "" && len(duplicates) > 0 {
-		err := compareDuplicates(log, flagData, flagIndex, duplicates)
+		err := compareDuplicates(log, tune, flagData, flagIndex, duplicates)
 		if err != nil {
 			log.Error().Err(err).Msg("could not compare duplicates")
 			return failure
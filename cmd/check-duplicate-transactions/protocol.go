@@ -25,15 +25,19 @@ import (
 	"github.com/onflow/flow-go/storage"
 	"github.com/onflow/flow-go/storage/badger/operation"
 
-	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/tuning"
 )
 
-func protocolCheck(log zerolog.Logger, dir string) error {
+func protocolCheck(log zerolog.Logger, tune *tuning.Config, dir string) error {
 
 	log.Info().Str("data", dir).Msg("starting protocol state duplicate check")
 
 	// Open the protocol state database.
-	protocol, err := badger.Open(dps.DefaultOptions(dir).WithReadOnly(true))
+	protocolOpts, err := tune.BadgerOptions(dir)
+	if err != nil {
+		return fmt.Errorf("could not get Badger options for protocol state (dir: %s): %w", dir, err)
+	}
+	protocol, err := badger.Open(protocolOpts.WithReadOnly(true))
 	if err != nil {
 		return fmt.Errorf("could not open protocol state (dir: %s): %w", dir, err)
 	}
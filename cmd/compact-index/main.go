@@ -0,0 +1,152 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+
+	"github.com/optakt/flow-dps/service/maintenance"
+	"github.com/optakt/flow-dps/service/tuning"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Parse the command line arguments.
+	var (
+		flagBadgerConfig  string
+		flagBadgerOptions map[string]string
+		flagBadgerProfile string
+		flagIndex         string
+		flagLevel         string
+		flagRatio         float64
+		flagSkipFlatten   bool
+		flagSkipGC        bool
+		flagWorkers       int
+	)
+
+	pflag.StringVarP(&flagIndex, "index", "i", "index", "path to database directory for state index")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.Float64VarP(&flagRatio, "ratio", "r", maintenance.DefaultConfig.DiscardRatio, "minimum ratio of stale data for a value log file to be rewritten")
+	pflag.IntVarP(&flagWorkers, "workers", "w", maintenance.DefaultConfig.FlattenWorkers, "number of concurrent compaction workers used to flatten the LSM tree")
+	pflag.BoolVar(&flagSkipFlatten, "skip-flatten", false, "skip flattening of the LSM tree")
+	pflag.BoolVar(&flagSkipGC, "skip-gc", false, "skip value log garbage collection")
+	pflag.StringVar(&flagBadgerConfig, "badger-config", "", "path to JSON or YAML file with Badger profile and option overrides")
+	pflag.StringToStringVar(&flagBadgerOptions, "badger-option", nil, "comma-separated list of Badger option overrides (name=value)")
+	pflag.StringVar(&flagBadgerProfile, "badger-profile", "", "Badger tuning profile, one of bulk-ingest, live, read-only-serving or low-memory (bulk-ingest when left empty)")
+
+	pflag.Parse()
+
+	// Initialize the logger.
+	zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
+	log := zerolog.New(os.Stderr).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+	level, err := zerolog.ParseLevel(flagLevel)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Err(err).Msg("could not parse log level")
+		return failure
+	}
+	log = log.Level(level)
+
+	// Load the Badger configuration, which selects the profile of options used
+	// to open the index database.
+	tune, err := tuning.Load(tuning.ProfileBulkIngest, flagBadgerConfig, flagBadgerProfile, flagBadgerOptions)
+	if err != nil {
+		log.Error().Err(err).Msg("could not load Badger configuration")
+		return failure
+	}
+
+	// Open the index database; it can not be opened in read-only mode, as both
+	// compaction and garbage collection rewrite files.
+	indexOpts, err := tune.BadgerOptions(flagIndex)
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not get Badger options")
+		return failure
+	}
+	db, err := badger.Open(indexOpts)
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not open index DB")
+		return failure
+	}
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("could not close index database")
+		}
+	}()
+
+	maintain := maintenance.NewMaintainer(db, flagIndex)
+	before, err := maintain.Size()
+	if err != nil {
+		log.Error().Err(err).Msg("could not get index size")
+		return failure
+	}
+
+	// We flatten the LSM tree first, as compactions are what allows Badger to
+	// know how much stale data each value log file contains.
+	if !flagSkipFlatten {
+		log.Info().Int("workers", flagWorkers).Msg("flattening LSM tree")
+		result, err := maintain.Flatten(flagWorkers)
+		if err != nil {
+			log.Error().Err(err).Msg("could not flatten LSM tree")
+			return failure
+		}
+		log.Info().
+			Uint64("reclaimed", result.Reclaimed()).
+			Dur("duration", result.Duration).
+			Msg("LSM tree flattened")
+	}
+
+	if !flagSkipGC {
+		log.Info().Float64("ratio", flagRatio).Msg("collecting value log garbage")
+		result, err := maintain.CollectGarbage(flagRatio)
+		if err != nil {
+			log.Error().Err(err).Msg("could not collect value log garbage")
+			return failure
+		}
+		log.Info().
+			Uint("rewrites", result.Rewrites).
+			Uint64("reclaimed", result.Reclaimed()).
+			Dur("duration", result.Duration).
+			Msg("value log garbage collected")
+	}
+
+	after, err := maintain.Size()
+	if err != nil {
+		log.Error().Err(err).Msg("could not get index size")
+		return failure
+	}
+	total := maintenance.Result{Before: before, After: after}
+
+	log.Info().
+		Int64("before", before).
+		Int64("after", after).
+		Uint64("reclaimed", total.Reclaimed()).
+		Msg("index compaction complete")
+
+	return success
+}
@@ -15,19 +15,24 @@
 package main
 
 import (
-	"compress/gzip"
-	"encoding/base64"
+	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"io"
+	"math"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/dgraph-io/badger/v2"
-	"github.com/klauspost/compress/zstd"
 	"github.com/rs/zerolog"
 	"github.com/spf13/pflag"
 
-	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/service/index"
+	"github.com/optakt/flow-dps/service/snapshot"
+	"github.com/optakt/flow-dps/service/storage"
+	"github.com/optakt/flow-dps/service/tuning"
 )
 
 const (
@@ -55,14 +60,34 @@ func run() int {
 
 	// Parse the command line arguments.
 	var (
-		flagCompression string
-		flagEncoding    string
-		flagIndex       string
+		flagBadgerConfig  string
+		flagBadgerOptions map[string]string
+		flagBadgerProfile string
+		flagBase          string
+		flagCategories    []string
+		flagChunkSize     uint64
+		flagCompression   string
+		flagEncoding      string
+		flagFrom          uint64
+		flagIndex         string
+		flagManifest      string
+		flagOutput        string
+		flagTo            uint64
 	)
 
+	pflag.StringVarP(&flagBase, "base", "b", "", "manifest of the base snapshot for an incremental snapshot (full snapshot when left empty)")
+	pflag.StringSliceVarP(&flagCategories, "categories", "t", nil, "comma-separated list of data categories to include in a partial snapshot (all categories when left empty)")
+	pflag.Uint64VarP(&flagChunkSize, "chunk-size", "s", 0, "size of uncompressed snapshot data per chunk file, for snapshots that can be restored in parallel (single file when zero)")
 	pflag.StringVarP(&flagCompression, "compression", "c", compressionZstd, "compression algorithm (\"none\", \"zstd\" or \"gzip\")")
 	pflag.StringVarP(&flagEncoding, "encoding", "e", encodingNone, "output encoding (\"none\", \"hex\" or \"base64\")")
+	pflag.Uint64VarP(&flagFrom, "from", "f", 0, "first height to include in a partial snapshot")
 	pflag.StringVarP(&flagIndex, "index", "i", "index", "database directory for state index")
+	pflag.StringVarP(&flagManifest, "manifest", "m", "", "path to write the snapshot manifest to (no manifest is written when left empty)")
+	pflag.StringVarP(&flagOutput, "output", "o", "", "path to write the snapshot to (standard output when left empty)")
+	pflag.Uint64VarP(&flagTo, "to", "u", math.MaxUint64, "last height to include in a partial snapshot")
+	pflag.StringVar(&flagBadgerConfig, "badger-config", "", "path to JSON or YAML file with Badger profile and option overrides")
+	pflag.StringToStringVar(&flagBadgerOptions, "badger-option", nil, "comma-separated list of Badger option overrides (name=value)")
+	pflag.StringVar(&flagBadgerProfile, "badger-profile", "", "Badger tuning profile, one of bulk-ingest, live, read-only-serving or low-memory (read-only-serving when left empty)")
 
 	pflag.Parse()
 
@@ -70,58 +95,251 @@ func run() int {
 	zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
 	log := zerolog.New(os.Stderr).With().Timestamp().Logger().Level(zerolog.DebugLevel)
 
+	// Load the Badger configuration, which selects the profile of options used
+	// to open the index database.
+	tune, err := tuning.Load(tuning.ProfileServing, flagBadgerConfig, flagBadgerProfile, flagBadgerOptions)
+	if err != nil {
+		log.Error().Err(err).Msg("could not load Badger configuration")
+		return failure
+	}
+
+	// A partial snapshot includes only some categories of data, or only some
+	// heights; if only heights are given, we include all categories.
+	partial := len(flagCategories) > 0 || flagFrom > 0 || flagTo < math.MaxUint64
+	if partial && flagBase != "" {
+		log.Error().Msg("partial snapshots can not be incremental")
+		return failure
+	}
+	if partial && len(flagCategories) == 0 {
+		for category := range storage.Categories {
+			flagCategories = append(flagCategories, category)
+		}
+	}
+
+	// Chunk files are named after the output file.
+	if flagChunkSize > 0 && flagOutput == "" {
+		log.Error().Msg("chunked snapshots need an output file")
+		return failure
+	}
+
+	// An incremental snapshot needs the manifest of its base snapshot.
+	var base *snapshot.Manifest
+	if flagBase != "" {
+		manifest, err := snapshot.ReadManifest(flagBase)
+		if err != nil {
+			log.Error().Str("base", flagBase).Err(err).Msg("could not read base snapshot manifest")
+			return failure
+		}
+		base = manifest
+		log.Info().Str("base", base.Checksum).Uint64("since", base.Next()).Msg("creating incremental snapshot")
+	}
+
 	// Open the index database.
-	db, err := badger.Open(dps.DefaultOptions(flagIndex).WithReadOnly(true))
+	indexOpts, err := tune.BadgerOptions(flagIndex)
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not get Badger options")
+		return failure
+	}
+	db, err := badger.Open(indexOpts.WithReadOnly(true))
 	if err != nil {
 		log.Error().Str("index", flagIndex).Err(err).Msg("could not open badger db")
 		return failure
 	}
 	defer db.Close()
 
-	// We want to pipe everything to stdout in the end; if the user wants to
-	// create a file, he can redirect the output.
-	var writer io.Writer
-	writer = os.Stdout
-	defer os.Stdout.Close()
-
-	// Wrap the output writer in a compressing writer of the given algorithm.
-	switch flagCompression {
-	case compressionNone:
-		// nothing to do
-	case compressionZstd:
-		compressor, _ := zstd.NewWriter(writer, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
-		defer compressor.Close()
-		writer = compressor
-	case compressionGzip:
-		compressor, _ := gzip.NewWriterLevel(writer, gzip.BestCompression)
-		defer compressor.Close()
-		writer = compressor
-	default:
-		log.Error().Str("compression", flagCompression).Msg("invalid compression algorithm specified")
-	}
-
-	// Create the writer(s) for the output format.
-	switch flagEncoding {
-	case encodingNone:
-		// nothing to do
-	case encodingHex:
-		writer = hex.NewEncoder(writer)
-	case encodingBase64:
-		encoder := base64.NewEncoder(base64.StdEncoding, writer)
-		defer encoder.Close()
-		writer = encoder
-	default:
-		log.Error().Str("encoding", flagEncoding).Msg("invalid encoding format specified")
+	// Read the range of heights and the last state commitment of the index
+	// before creating the snapshot, so they can be verified on restore. For a
+	// partial snapshot, the range of heights is restricted by the filter, and
+	// the commitment is only available if commits are included.
+	codec := zbor.NewCodec()
+	reader := index.NewReader(db, storage.New(codec))
+	var filter *snapshot.Filter
+	if partial {
+		filter, err = snapshot.NewFilter(reader, codec, flagFrom, flagTo, flagCategories...)
+		if err != nil {
+			log.Error().Err(err).Msg("could not initialize partial snapshot filter")
+			return failure
+		}
+		log.Info().Uint64("first", filter.First()).Uint64("last", filter.Last()).Strs("categories", filter.Categories()).Msg("creating partial snapshot")
+	}
+	first, err := reader.First()
+	if err != nil {
+		log.Error().Err(err).Msg("could not get first height from index")
+		return failure
+	}
+	last, err := reader.Last()
+	if err != nil {
+		log.Error().Err(err).Msg("could not get last height from index")
+		return failure
+	}
+	if filter != nil {
+		first = filter.First()
+		last = filter.Last()
+	}
+	var commit string
+	if filter == nil || contains(filter.Categories(), storage.CategoryCommits) {
+		lastCommit, err := reader.Commit(last)
+		if err != nil {
+			log.Error().Uint64("last", last).Err(err).Msg("could not get last commit from index")
+			return failure
+		}
+		commit = hex.EncodeToString(lastCommit[:])
+	}
+
+	// We want to pipe everything to stdout in the end, unless an output file
+	// is given; if the user wants to create a file, he can also redirect the
+	// output. When a chunk size is given, the snapshot is split into chunk
+	// files named after the output file instead. All written data is also
+	// hashed to compute the checksum.
+	total := sha256.New()
+	var writer io.WriteCloser
+	var outputs []*output
+	var chunks []string
+	if flagChunkSize > 0 {
+		writer = snapshot.NewSplitter(flagChunkSize, func(index int) (io.WriteCloser, error) {
+			path := fmt.Sprintf("%s.%04d", flagOutput, index)
+			file, err := os.Create(path)
+			if err != nil {
+				return nil, fmt.Errorf("could not create chunk file: %w", err)
+			}
+			out, err := newOutput(file, flagCompression, flagEncoding, total)
+			if err != nil {
+				_ = file.Close()
+				return nil, err
+			}
+			outputs = append(outputs, out)
+			chunks = append(chunks, path)
+			return out, nil
+		})
+	} else {
+		var file io.WriteCloser
+		file = os.Stdout
+		if flagOutput != "" {
+			file, err = os.Create(flagOutput)
+			if err != nil {
+				log.Error().Str("output", flagOutput).Err(err).Msg("could not create output file")
+				return failure
+			}
+		}
+		out, err := newOutput(file, flagCompression, flagEncoding, total)
+		if err != nil {
+			_ = file.Close()
+			log.Error().Err(err).Msg("could not initialize output")
+			return failure
+		}
+		outputs = append(outputs, out)
+		writer = out
 	}
 
 	// Run the DB backup mechanism on top of the writer to create the snapshot.
-	_, err = db.Backup(writer, 0)
+	// For incremental snapshots, only versions after the base are included,
+	// while for partial snapshots, only the entries chosen by the filter are.
+	since := uint64(0)
+	if base != nil {
+		since = base.Next()
+	}
+	var version uint64
+	if filter != nil {
+		version, err = filter.Backup(db, writer)
+	} else {
+		version, err = db.Backup(writer, since)
+	}
 	if err != nil {
 		log.Error().Err(err).Msg("snapshot generation failed")
 		return failure
 	}
+	err = writer.Close()
+	if err != nil {
+		log.Error().Err(err).Msg("could not flush snapshot")
+		return failure
+	}
+
+	// If nothing changed since the base snapshot, the incremental snapshot is
+	// empty and covers the same versions as its base.
+	if version < since {
+		version = since - 1
+	}
+
+	checksum := hex.EncodeToString(total.Sum(nil))
+	log.Info().Uint64("first", first).Uint64("last", last).Str("commit", commit).Str("checksum", checksum).Int("files", len(outputs)).Msg("snapshot created")
+
+	if flagManifest == "" {
+		log.Info().Msg("snapshot generation complete")
+		return success
+	}
+
+	// Write the manifest, which allows verifying and restoring chains of
+	// snapshots. The path of the snapshot file is stored relative to the
+	// manifest; it is left empty if the snapshot was written to stdout or split
+	// into chunks.
+	var file string
+	if flagOutput != "" && flagChunkSize == 0 {
+		file, err = relativePath(flagManifest, flagOutput)
+		if err != nil {
+			log.Error().Err(err).Msg("could not determine snapshot file path")
+			return failure
+		}
+	}
+	manifest := snapshot.Manifest{
+		File:        file,
+		Compression: flagCompression,
+		Encoding:    flagEncoding,
+		Since:       since,
+		Version:     version,
+		First:       first,
+		Last:        last,
+		Commit:      commit,
+		Codec:       zbor.Version,
+		Checksum:    checksum,
+	}
+	if base != nil {
+		manifest.Base = base.Checksum
+	}
+	for i, path := range chunks {
+		file, err := relativePath(flagManifest, path)
+		if err != nil {
+			log.Error().Err(err).Msg("could not determine chunk file path")
+			return failure
+		}
+		chunk := snapshot.Chunk{
+			File:     file,
+			Checksum: outputs[i].Checksum(),
+		}
+		manifest.Chunks = append(manifest.Chunks, chunk)
+	}
+	if filter != nil {
+		manifest.Categories = filter.Categories()
+	}
+	err = snapshot.WriteManifest(flagManifest, &manifest)
+	if err != nil {
+		log.Error().Str("manifest", flagManifest).Err(err).Msg("could not write snapshot manifest")
+		return failure
+	}
 
 	log.Info().Msg("snapshot generation complete")
 
 	return success
 }
+
+func relativePath(manifest string, output string) (string, error) {
+
+	manifest, err := filepath.Abs(manifest)
+	if err != nil {
+		return "", fmt.Errorf("could not get absolute manifest path: %w", err)
+	}
+	output, err = filepath.Abs(output)
+	if err != nil {
+		return "", fmt.Errorf("could not get absolute output path: %w", err)
+	}
+
+	return filepath.Rel(filepath.Dir(manifest), output)
+}
+
+func contains(categories []string, category string) bool {
+	for _, c := range categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
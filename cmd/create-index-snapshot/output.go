@@ -0,0 +1,106 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// output is a single snapshot output, which compresses and encodes the data
+// written to it, and hashes it as it is written to the underlying file.
+type output struct {
+	file    io.WriteCloser
+	hash    hash.Hash
+	writer  io.Writer
+	closers []io.Closer
+}
+
+// newOutput wraps the given file with the given compression and encoding. The
+// data written to the file is also written to the given total writer, so that
+// the checksum of multiple chunks can be computed.
+func newOutput(file io.WriteCloser, compression string, encoding string, total io.Writer) (*output, error) {
+
+	o := output{
+		file: file,
+		hash: sha256.New(),
+	}
+	o.writer = io.MultiWriter(file, o.hash, total)
+
+	// Wrap the output writer in a compressing writer of the given algorithm.
+	switch compression {
+	case compressionNone:
+		// nothing to do
+	case compressionZstd:
+		compressor, _ := zstd.NewWriter(o.writer, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+		o.closers = append(o.closers, compressor)
+		o.writer = compressor
+	case compressionGzip:
+		compressor, _ := gzip.NewWriterLevel(o.writer, gzip.BestCompression)
+		o.closers = append(o.closers, compressor)
+		o.writer = compressor
+	default:
+		return nil, fmt.Errorf("invalid compression algorithm specified (%s)", compression)
+	}
+
+	// Create the writer(s) for the output format.
+	switch encoding {
+	case encodingNone:
+		// nothing to do
+	case encodingHex:
+		o.writer = hex.NewEncoder(o.writer)
+	case encodingBase64:
+		encoder := base64.NewEncoder(base64.StdEncoding, o.writer)
+		o.closers = append(o.closers, encoder)
+		o.writer = encoder
+	default:
+		return nil, fmt.Errorf("invalid encoding format specified (%s)", encoding)
+	}
+
+	return &o, nil
+}
+
+// Write compresses, encodes and writes the given data.
+func (o *output) Write(p []byte) (int, error) {
+	return o.writer.Write(p)
+}
+
+// Close flushes the compressing and encoding writers, which needs to happen
+// before computing the checksum, and closes the file.
+func (o *output) Close() error {
+	for i := len(o.closers) - 1; i >= 0; i-- {
+		err := o.closers[i].Close()
+		if err != nil {
+			return fmt.Errorf("could not flush output: %w", err)
+		}
+	}
+	err := o.file.Close()
+	if err != nil {
+		return fmt.Errorf("could not close output: %w", err)
+	}
+	return nil
+}
+
+// Checksum returns the hex-encoded SHA-256 checksum of the written file.
+func (o *output) Checksum() string {
+	return hex.EncodeToString(o.hash.Sum(nil))
+}
@@ -15,6 +15,7 @@
 package main
 
 import (
+	"encoding/json"
 	"os"
 	"os/signal"
 	"runtime"
@@ -26,7 +27,7 @@ import (
 
 	"github.com/optakt/flow-dps/codec/generator"
 	"github.com/optakt/flow-dps/codec/zbor"
-	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/tuning"
 )
 
 const (
@@ -45,20 +46,37 @@ func run() int {
 
 	// Command line parameter initialization.
 	var (
+		flagBadgerConfig   string
+		flagBadgerOptions  map[string]string
+		flagBadgerProfile  string
 		flagDictionaryPath string
 		flagIndex          string
+		flagKinds          []string
 		flagLevel          string
+		flagReport         string
+		flagRetrain        bool
 		flagSamplePath     string
 		flagStartSize      int
 		flagTolerance      float64
 	)
 
+	kinds := make([]string, 0, len(generator.Kinds))
+	for _, kind := range generator.Kinds {
+		kinds = append(kinds, kind.String())
+	}
+
 	pflag.StringVar(&flagDictionaryPath, "dictionary-path", "./codec/zbor", "path to the package in which to write dictionaries")
 	pflag.StringVarP(&flagIndex, "index", "i", "index", "path to database directory for state index")
+	pflag.StringSliceVarP(&flagKinds, "kinds", "k", kinds, "comma-separated list of dictionary kinds to generate")
 	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagReport, "report", "", "path to write the retraining report to (standard output when left empty)")
+	pflag.BoolVar(&flagRetrain, "retrain", false, "compare new dictionaries against the current ones and only emit those that improve compression by the tolerance")
 	pflag.StringVar(&flagSamplePath, "sample-path", "", "path to the directory in which to store samples for dictionary training (temporary folder when left empty)")
 	pflag.IntVar(&flagStartSize, "start-size", 512, "minimum dictionary size in bytes to generate (will be doubled on each iteration)")
 	pflag.Float64Var(&flagTolerance, "tolerance", 0.1, "compression ratio increase tolerance, between 0 and 1")
+	pflag.StringVar(&flagBadgerConfig, "badger-config", "", "path to JSON or YAML file with Badger profile and option overrides")
+	pflag.StringToStringVar(&flagBadgerOptions, "badger-option", nil, "comma-separated list of Badger option overrides (name=value)")
+	pflag.StringVar(&flagBadgerProfile, "badger-profile", "", "Badger tuning profile, one of bulk-ingest, live, read-only-serving or low-memory (read-only-serving when left empty)")
 
 	pflag.Parse()
 
@@ -76,8 +94,21 @@ func run() int {
 	}
 	log = log.Level(level)
 
+	// Load the Badger configuration, which selects the profile of options used
+	// to open the index database.
+	tune, err := tuning.Load(tuning.ProfileServing, flagBadgerConfig, flagBadgerProfile, flagBadgerOptions)
+	if err != nil {
+		log.Error().Err(err).Msg("could not load Badger configuration")
+		return failure
+	}
+
 	// Initialize the index core state and open database in read-only mode.
-	db, err := badger.Open(dps.DefaultOptions(flagIndex).WithReadOnly(true))
+	indexOpts, err := tune.BadgerOptions(flagIndex)
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not get Badger options")
+		return failure
+	}
+	db, err := badger.Open(indexOpts.WithReadOnly(true))
 	if err != nil {
 		log.Error().Str("index", flagIndex).Err(err).Msg("could not open index DB")
 		return failure
@@ -103,22 +134,45 @@ func run() int {
 		generator.WithStartSize(flagStartSize),
 	)
 
-	err = generate.Dictionary(generator.KindPayloads)
-	if err != nil {
-		log.Error().Err(err).Msg("could not generate payload dictionary")
-		return failure
-	}
+	// In retraining mode, each new dictionary is compared against the current
+	// one, and we write a report of the comparisons.
+	if flagRetrain {
+		reports := make([]*generator.Report, 0, len(flagKinds))
+		for _, kind := range flagKinds {
+			report, err := generate.Retrain(generator.DictionaryKind(kind))
+			if err != nil {
+				log.Error().Str("kind", kind).Err(err).Msg("could not retrain dictionary")
+				return failure
+			}
+			reports = append(reports, report)
+		}
 
-	err = generate.Dictionary(generator.KindTransactions)
-	if err != nil {
-		log.Error().Err(err).Msg("could not generate transactions dictionary")
-		return failure
+		output := os.Stdout
+		if flagReport != "" {
+			output, err = os.Create(flagReport)
+			if err != nil {
+				log.Error().Str("report", flagReport).Err(err).Msg("could not create report file")
+				return failure
+			}
+			defer output.Close()
+		}
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(reports)
+		if err != nil {
+			log.Error().Err(err).Msg("could not write retraining report")
+			return failure
+		}
+
+		return success
 	}
 
-	err = generate.Dictionary(generator.KindEvents)
-	if err != nil {
-		log.Error().Err(err).Msg("could not generate events dictionary")
-		return failure
+	for _, kind := range flagKinds {
+		err = generate.Dictionary(generator.DictionaryKind(kind))
+		if err != nil {
+			log.Error().Str("kind", kind).Err(err).Msg("could not generate dictionary")
+			return failure
+		}
 	}
 
 	return success
@@ -0,0 +1,180 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/service/storage"
+	"github.com/optakt/flow-dps/service/tuning"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+// usage is the number and size of the values of one prefix that were encoded
+// with the same codec version and dictionary.
+type usage struct {
+	Prefix     string `json:"prefix"`
+	Version    uint8  `json:"version"`
+	Dictionary string `json:"dictionary"`
+	Values     uint64 `json:"values"`
+	Bytes      uint64 `json:"bytes"`
+}
+
+type usageKey struct {
+	prefix     uint8
+	version    uint8
+	dictionary zbor.Dictionary
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Parse the command line arguments.
+	var (
+		flagBadgerConfig  string
+		flagBadgerOptions map[string]string
+		flagBadgerProfile string
+		flagIndex         string
+		flagLevel         string
+	)
+
+	pflag.StringVarP(&flagIndex, "index", "i", "index", "path to database directory for state index")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagBadgerConfig, "badger-config", "", "path to JSON or YAML file with Badger profile and option overrides")
+	pflag.StringToStringVar(&flagBadgerOptions, "badger-option", nil, "comma-separated list of Badger option overrides (name=value)")
+	pflag.StringVar(&flagBadgerProfile, "badger-profile", "", "Badger tuning profile, one of bulk-ingest, live, read-only-serving or low-memory (read-only-serving when left empty)")
+
+	pflag.Parse()
+
+	// Initialize the logger.
+	zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
+	log := zerolog.New(os.Stderr).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+	level, err := zerolog.ParseLevel(flagLevel)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Err(err).Msg("could not parse log level")
+		return failure
+	}
+	log = log.Level(level)
+
+	// Load the Badger configuration, which selects the profile of options used
+	// to open the index database.
+	tune, err := tuning.Load(tuning.ProfileServing, flagBadgerConfig, flagBadgerProfile, flagBadgerOptions)
+	if err != nil {
+		log.Error().Err(err).Msg("could not load Badger configuration")
+		return failure
+	}
+
+	// Open the index database in read-only mode.
+	indexOpts, err := tune.BadgerOptions(flagIndex)
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not get Badger options")
+		return failure
+	}
+	db, err := badger.Open(indexOpts.WithReadOnly(true))
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not open index DB")
+		return failure
+	}
+	defer db.Close()
+
+	// Go through all values of the index and count them by prefix, codec
+	// version and dictionary. Values that can not be inspected are counted
+	// separately, so that they do not abort the report.
+	counts := make(map[usageKey]*usage)
+	invalid := uint64(0)
+	err = db.View(func(tx *badger.Txn) error {
+		it := tx.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			prefix := item.Key()[0]
+			err := item.Value(func(val []byte) error {
+				version, dictionary, err := zbor.Inspect(val)
+				if err != nil {
+					return err
+				}
+				key := usageKey{prefix: prefix, version: version, dictionary: dictionary}
+				count, ok := counts[key]
+				if !ok {
+					count = &usage{
+						Prefix:     storage.PrefixName(prefix),
+						Version:    version,
+						Dictionary: dictionary.String(),
+					}
+					counts[key] = count
+				}
+				count.Values++
+				count.Bytes += uint64(len(val))
+				return nil
+			})
+			if err != nil {
+				log.Debug().Hex("key", item.KeyCopy(nil)).Err(err).Msg("could not inspect value")
+				invalid++
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("could not read index database")
+		return failure
+	}
+
+	keys := make([]usageKey, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i int, j int) bool {
+		if keys[i].prefix != keys[j].prefix {
+			return keys[i].prefix < keys[j].prefix
+		}
+		if keys[i].version != keys[j].version {
+			return keys[i].version < keys[j].version
+		}
+		return keys[i].dictionary < keys[j].dictionary
+	})
+	report := make([]*usage, 0, len(keys))
+	for _, key := range keys {
+		report = append(report, counts[key])
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(report)
+	if err != nil {
+		log.Error().Err(err).Msg("could not write report")
+		return failure
+	}
+
+	log.Info().Int("entries", len(report)).Uint64("invalid", invalid).Msg("dictionary report complete")
+
+	return success
+}
@@ -24,14 +24,19 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/spf13/pflag"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/onflow/cadence"
 	"github.com/onflow/cadence/encoding/json"
 
-	"github.com/optakt/flow-dps/api/dps"
+	"github.com/optakt/flow-dps/api/auth"
+	api "github.com/optakt/flow-dps/api/dps"
+	"github.com/optakt/flow-dps/api/remote"
 	"github.com/optakt/flow-dps/codec/zbor"
 	"github.com/optakt/flow-dps/models/convert"
+	"github.com/optakt/flow-dps/models/dps"
 	"github.com/optakt/flow-dps/service/invoker"
+	"github.com/optakt/flow-dps/service/spork"
 )
 
 const (
@@ -51,20 +56,41 @@ func run() int {
 
 	// Command line parameter initialization.
 	var (
-		flagAPI    string
-		flagCache  uint64
-		flagHeight uint64
-		flagLevel  string
-		flagParams string
-		flagScript string
+		flagAPI      string
+		flagCache    uint64
+		flagDiscover bool
+		flagHeight   uint64
+		flagLevel    string
+		flagParams   string
+		flagRetries  uint
+		flagScript   string
+		flagSporks   string
+		flagTimeout  time.Duration
+
+		flagTLS           bool
+		flagTLSCA         string
+		flagTLSCert       string
+		flagTLSKey        string
+		flagTLSServerName string
+		flagToken         string
 	)
 
 	pflag.StringVarP(&flagAPI, "api", "a", "", "host for GRPC API server")
 	pflag.Uint64VarP(&flagCache, "cache", "e", 1_000_000_000, "maximum cache size for register reads in bytes")
+	pflag.BoolVarP(&flagDiscover, "discover", "d", false, "refresh spork boundaries from their DPS APIs before executing the script")
 	pflag.Uint64VarP(&flagHeight, "height", "h", 0, "block height to execute the script at")
 	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
 	pflag.StringVarP(&flagParams, "params", "p", "", "comma-separated list of Cadence parameters")
+	pflag.UintVar(&flagRetries, "retries", 5, "number of times a call to the DPS API is retried while it is unavailable")
 	pflag.StringVarP(&flagScript, "script", "s", "script.cdc", "path to file with Cadence script")
+	pflag.StringVar(&flagSporks, "sporks", "", "path to JSON or YAML spork manifest (built-in public sporks when left empty)")
+	pflag.DurationVar(&flagTimeout, "timeout", 10*time.Second, "timeout for each call to the DPS API (0s for none)")
+	pflag.BoolVar(&flagTLS, "tls", false, "connect to the DPS API over TLS, verifying the server with the certificate authorities of the system")
+	pflag.StringVar(&flagTLSCA, "tls-ca", "", "path to PEM file with the certificate authorities of the server certificate (enables TLS)")
+	pflag.StringVar(&flagTLSCert, "tls-cert", "", "path to PEM client certificate file for mutual TLS (enables TLS)")
+	pflag.StringVar(&flagTLSKey, "tls-key", "", "path to PEM client private key file for mutual TLS (enables TLS)")
+	pflag.StringVar(&flagTLSServerName, "tls-server-name", "", "name used to verify the server certificate (host of the API address when left empty)")
+	pflag.StringVar(&flagToken, "token", "", "API key presented as bearer token to the DPS API")
 
 	pflag.Parse()
 
@@ -78,28 +104,107 @@ func run() int {
 	}
 	log = log.Level(level)
 
-	// If no API server is given, choose based on height.
-	if flagAPI == "" {
-		for _, spork := range DefaultSporks {
-			if flagHeight >= spork.First && flagHeight <= spork.Last {
-				log.Info().Uint64("height", flagHeight).Str("spork", spork.Name).Str("api", spork.API).Msg("spork and API chosen based on height")
-				flagAPI = spork.API
-				break
-			}
+	// Initialize codec.
+	codec := zbor.NewCodec()
+
+	// The connection to the DPS API is secured with TLS when any of the TLS
+	// flags are given, and presents an API key on each call if there is one.
+	dialOpts := []grpc.DialOption{grpc.WithInsecure()}
+	secure := flagTLS || flagTLSCA != "" || flagTLSCert != "" || flagTLSKey != ""
+	if secure {
+		tlsConfig, err := auth.ClientTLS(flagTLSCA, flagTLSCert, flagTLSKey, flagTLSServerName)
+		if err != nil {
+			log.Error().Err(err).Msg("could not load TLS configuration")
+			return failure
 		}
+		dialOpts = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))}
 	}
-	if flagAPI == "" {
-		log.Error().Uint64("height", flagHeight).Msg("could not find spork and API for height")
-		return failure
+	if flagToken != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(auth.NewToken(flagToken, secure)))
 	}
 
-	// Initialize the API client.
-	conn, err := grpc.Dial(flagAPI, grpc.WithInsecure())
-	if err != nil {
-		log.Error().Str("api", flagAPI).Err(err).Msg("could not dial API host")
-		return failure
+	// If an API server is given, we read from its index directly. Otherwise, we
+	// federate the APIs of all sporks from the registry, so that each read is
+	// routed to the spork that covers it.
+	var conns []*grpc.ClientConn
+	defer func() {
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	}()
+	clients := make(map[string]api.APIClient)
+	dial := func(address string) (api.APIClient, error) {
+		client, ok := clients[address]
+		if ok {
+			return client, nil
+		}
+		conn, err := grpc.Dial(address, dialOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("could not dial API host: %w", err)
+		}
+		conns = append(conns, conn)
+		client = api.NewAPIClient(conn)
+		clients[address] = client
+		return client, nil
+	}
+
+	// Each index reader for a DPS API bounds its calls with a timeout, retries
+	// them while the API is unavailable, and caches the results which never
+	// change once indexed.
+	connect := func(address string) (dps.Reader, error) {
+		client, err := dial(address)
+		if err != nil {
+			return nil, err
+		}
+		return remote.NewReader(api.IndexFromAPI(client, codec),
+			remote.WithTimeout(flagTimeout),
+			remote.WithRetries(flagRetries),
+		)
+	}
+
+	var index dps.Reader
+	if flagAPI != "" {
+		index, err = connect(flagAPI)
+		if err != nil {
+			log.Error().Str("api", flagAPI).Err(err).Msg("could not initialize API client")
+			return failure
+		}
+	} else {
+		sporks := spork.Default()
+		if flagSporks != "" {
+			sporks, err = spork.FromFile(flagSporks)
+			if err != nil {
+				log.Error().Str("sporks", flagSporks).Err(err).Msg("could not load spork manifest")
+				return failure
+			}
+		}
+		if flagDiscover {
+			sporks, err = sporks.Refresh(func(target spork.Spork) (spork.Index, error) {
+				client, err := dial(target.API)
+				if err != nil {
+					return nil, err
+				}
+				return api.IndexFromAPI(client, codec), nil
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("could not discover spork boundaries")
+				return failure
+			}
+		}
+		current, err := sporks.Spork(flagHeight)
+		if err != nil {
+			log.Error().Uint64("height", flagHeight).Err(err).Msg("could not find spork for height")
+			return failure
+		}
+		log.Info().Uint64("height", flagHeight).Str("spork", current.Name).Str("api", current.API).Msg("spork chosen based on height")
+		index, err = api.NewFederation(sporks, func(target spork.Spork) (dps.Reader, error) {
+			return connect(target.API)
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("could not initialize federated index")
+			return failure
+		}
 	}
-	defer conn.Close()
 
 	// Read the script.
 	script, err := os.ReadFile(flagScript)
@@ -122,12 +227,8 @@ func run() int {
 		}
 	}
 
-	// Initialize codec.
-	codec := zbor.NewCodec()
-
 	// Execute the script using remote lookup and read.
-	client := dps.NewAPIClient(conn)
-	invoke, err := invoker.New(dps.IndexFromAPI(client, codec), invoker.WithCacheSize(flagCache))
+	invoke, err := invoker.New(index, invoker.WithCacheSize(flagCache))
 	if err != nil {
 		log.Error().Err(err).Msg("could not initialize invoker")
 		return failure
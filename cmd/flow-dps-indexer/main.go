@@ -27,7 +27,6 @@ import (
 	"github.com/spf13/pflag"
 
 	"github.com/optakt/flow-dps/codec/zbor"
-	"github.com/optakt/flow-dps/models/dps"
 	"github.com/optakt/flow-dps/service/chain"
 	"github.com/optakt/flow-dps/service/feeder"
 	"github.com/optakt/flow-dps/service/forest"
@@ -35,6 +34,7 @@ import (
 	"github.com/optakt/flow-dps/service/loader"
 	"github.com/optakt/flow-dps/service/mapper"
 	"github.com/optakt/flow-dps/service/storage"
+	"github.com/optakt/flow-dps/service/tuning"
 )
 
 const (
@@ -54,12 +54,15 @@ func run() int {
 
 	// Command line parameter initialization.
 	var (
-		flagCheckpoint string
-		flagData       string
-		flagIndex      string
-		flagLevel      string
-		flagTrie       string
-		flagSkip       bool
+		flagBadgerConfig  string
+		flagBadgerOptions map[string]string
+		flagBadgerProfile string
+		flagCheckpoint    string
+		flagData          string
+		flagIndex         string
+		flagLevel         string
+		flagTrie          string
+		flagSkip          bool
 	)
 
 	pflag.StringVarP(&flagCheckpoint, "checkpoint", "c", "", "path to root checkpoint file for execution state trie")
@@ -68,6 +71,9 @@ func run() int {
 	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
 	pflag.StringVarP(&flagTrie, "trie", "t", "", "path to data directory for execution state ledger")
 	pflag.BoolVarP(&flagSkip, "skip", "s", false, "skip indexing of execution state ledger registers")
+	pflag.StringVar(&flagBadgerConfig, "badger-config", "", "path to JSON or YAML file with Badger profile and option overrides")
+	pflag.StringToStringVar(&flagBadgerOptions, "badger-option", nil, "comma-separated list of Badger option overrides (name=value)")
+	pflag.StringVar(&flagBadgerProfile, "badger-profile", "", "Badger tuning profile, one of bulk-ingest, live, read-only-serving or low-memory (bulk-ingest when left empty)")
 
 	pflag.Parse()
 
@@ -85,8 +91,21 @@ func run() int {
 	}
 	log = log.Level(level)
 
+	// Load the Badger configuration, which selects the profile of options used
+	// to open the index and protocol databases.
+	tune, err := tuning.Load(tuning.ProfileBulkIngest, flagBadgerConfig, flagBadgerProfile, flagBadgerOptions)
+	if err != nil {
+		log.Error().Err(err).Msg("could not load Badger configuration")
+		return failure
+	}
+
 	// Open the needed databases.
-	indexDB, err := badger.Open(dps.DefaultOptions(flagIndex))
+	indexOpts, err := tune.BadgerOptions(flagIndex)
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not get Badger options")
+		return failure
+	}
+	indexDB, err := badger.Open(indexOpts)
 	if err != nil {
 		log.Error().Str("index", flagIndex).Err(err).Msg("could not open index database")
 		return failure
@@ -97,7 +116,12 @@ func run() int {
 			log.Error().Err(err).Msg("could not close index database")
 		}
 	}()
-	protocolDB, err := badger.Open(dps.DefaultOptions(flagData))
+	protocolOpts, err := tune.BadgerOptions(flagData)
+	if err != nil {
+		log.Error().Str("protocol", flagData).Err(err).Msg("could not get Badger options")
+		return failure
+	}
+	protocolDB, err := badger.Open(protocolOpts)
 	if err != nil {
 		log.Error().Err(err).Msg("could not open protocol state database")
 		return failure
@@ -0,0 +1,345 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/service/stats"
+	"github.com/optakt/flow-dps/service/storage"
+	"github.com/optakt/flow-dps/service/tuning"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+// entry is a single decoded entry of the index.
+type entry struct {
+	Prefix     string                 `json:"prefix"`
+	Key        map[string]interface{} `json:"key"`
+	Version    uint8                  `json:"version"`
+	Dictionary string                 `json:"dictionary"`
+	Size       int                    `json:"size"`
+	Value      interface{}            `json:"value,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Parse the command line arguments.
+	var (
+		flagBadgerConfig  string
+		flagBadgerOptions map[string]string
+		flagBadgerProfile string
+		flagEnd           string
+		flagIndex         string
+		flagLevel         string
+		flagLimit         uint
+		flagPrefix        string
+		flagStart         string
+		flagStats         bool
+	)
+
+	pflag.StringVarP(&flagEnd, "end", "e", "", "hex-encoded key at which to stop scanning (exclusive)")
+	pflag.StringVarP(&flagIndex, "index", "i", "index", "path to database directory for state index")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.UintVarP(&flagLimit, "limit", "n", 0, "maximum number of entries to scan (no limit when zero)")
+	pflag.StringVarP(&flagPrefix, "prefix", "p", "", "name or number of the key prefix to scan (all prefixes when empty)")
+	pflag.StringVarP(&flagStart, "start", "s", "", "hex-encoded key at which to start scanning (inclusive)")
+	pflag.BoolVar(&flagStats, "stats", false, "only print statistics, without printing the decoded entries")
+	pflag.StringVar(&flagBadgerConfig, "badger-config", "", "path to JSON or YAML file with Badger profile and option overrides")
+	pflag.StringToStringVar(&flagBadgerOptions, "badger-option", nil, "comma-separated list of Badger option overrides (name=value)")
+	pflag.StringVar(&flagBadgerProfile, "badger-profile", "", "Badger tuning profile, one of bulk-ingest, live, read-only-serving or low-memory (read-only-serving when left empty)")
+
+	pflag.Parse()
+
+	// Initialize the logger.
+	zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
+	log := zerolog.New(os.Stderr).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+	level, err := zerolog.ParseLevel(flagLevel)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Err(err).Msg("could not parse log level")
+		return failure
+	}
+	log = log.Level(level)
+
+	// Load the Badger configuration, which selects the profile of options used
+	// to open the index database.
+	tune, err := tuning.Load(tuning.ProfileServing, flagBadgerConfig, flagBadgerProfile, flagBadgerOptions)
+	if err != nil {
+		log.Error().Err(err).Msg("could not load Badger configuration")
+		return failure
+	}
+
+	// Determine the range of keys to scan. A prefix restricts the scan to the
+	// keys with that prefix, while the start and end keys restrict it further.
+	var prefix []byte
+	if flagPrefix != "" {
+		p, err := parsePrefix(flagPrefix)
+		if err != nil {
+			log.Error().Str("prefix", flagPrefix).Err(err).Msg("could not parse prefix")
+			return failure
+		}
+		prefix = []byte{p}
+	}
+	start := prefix
+	if flagStart != "" {
+		start, err = hex.DecodeString(flagStart)
+		if err != nil {
+			log.Error().Str("start", flagStart).Err(err).Msg("could not decode start key")
+			return failure
+		}
+	}
+	var end []byte
+	if flagEnd != "" {
+		end, err = hex.DecodeString(flagEnd)
+		if err != nil {
+			log.Error().Str("end", flagEnd).Err(err).Msg("could not decode end key")
+			return failure
+		}
+	}
+	err = checkRange(prefix, start, end)
+	if err != nil {
+		log.Error().Str("prefix", flagPrefix).Str("start", flagStart).Str("end", flagEnd).Err(err).Msg("invalid key range")
+		return failure
+	}
+
+	// Open the index database in read-only mode.
+	indexOpts, err := tune.BadgerOptions(flagIndex)
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not get Badger options")
+		return failure
+	}
+	db, err := badger.Open(indexOpts.WithReadOnly(true))
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not open index DB")
+		return failure
+	}
+	defer db.Close()
+
+	codec := zbor.NewCodec()
+	encoder := json.NewEncoder(os.Stdout)
+	counter := stats.NewCounter(codec)
+	scanned := uint(0)
+	err = db.View(func(tx *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+		it := tx.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(start); it.ValidForPrefix(prefix); it.Next() {
+			if flagLimit != 0 && scanned >= flagLimit {
+				break
+			}
+
+			item := it.Item()
+			key := item.Key()
+			if end != nil && bytes.Compare(key, end) >= 0 {
+				break
+			}
+			scanned++
+
+			err := item.Value(func(val []byte) error {
+				counter.Add(key, val)
+				if flagStats {
+					return nil
+				}
+				return encoder.Encode(decode(codec, key, val))
+			})
+			if err != nil {
+				return fmt.Errorf("could not inspect entry (key: %x): %w", key, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("could not scan index database")
+		return failure
+	}
+
+	report := counter.Report()
+	err = encoder.Encode(map[string]interface{}{"stats": report.Prefixes})
+	if err != nil {
+		log.Error().Err(err).Msg("could not write statistics")
+		return failure
+	}
+
+	log.Info().Uint("entries", scanned).Msg("index inspection complete")
+
+	return success
+}
+
+// decode decodes the given key and value into an entry. Errors are recorded
+// on the entry, so that a single invalid entry does not abort the scan.
+func decode(codec *zbor.Codec, key []byte, val []byte) entry {
+
+	e := entry{
+		Prefix: storage.PrefixName(key[0]),
+		Size:   len(val),
+	}
+
+	prefix, segments, err := storage.DecodeKey(key)
+	if err != nil {
+		e.Key = map[string]interface{}{"raw": hex.EncodeToString(key)}
+		e.Error = fmt.Sprintf("could not decode key: %s", err)
+		return e
+	}
+	e.Key = make(map[string]interface{}, len(segments))
+	names := segmentNames[prefix]
+	for i, segment := range segments {
+		switch s := segment.(type) {
+		case flow.Identifier:
+			e.Key[names[i]] = s.String()
+		case ledger.Path:
+			e.Key[names[i]] = hex.EncodeToString(s[:])
+		case uint64:
+			if prefix == storage.PrefixEvents && names[i] == "type_hash" {
+				e.Key[names[i]] = strconv.FormatUint(s, 16)
+				continue
+			}
+			e.Key[names[i]] = s
+		}
+	}
+
+	version, dictionary, err := zbor.Inspect(val)
+	if err != nil {
+		e.Error = fmt.Sprintf("could not inspect value: %s", err)
+		return e
+	}
+	e.Version = version
+	e.Dictionary = dictionary.String()
+
+	value := newValue(prefix)
+	err = codec.Unmarshal(val, value)
+	if err != nil {
+		e.Error = fmt.Sprintf("could not decode value: %s", err)
+		return e
+	}
+	e.Value = value
+
+	// State commitments have no JSON encoding of their own, so we print them
+	// as hexadecimal strings, like the other hashes.
+	commit, ok := value.(*flow.StateCommitment)
+	if ok {
+		e.Value = hex.EncodeToString(commit[:])
+	}
+
+	return e
+}
+
+// segmentNames maps each prefix to the names of the segments of its keys.
+var segmentNames = map[uint8][]string{
+	storage.PrefixHeightForBlock:            {"block_id"},
+	storage.PrefixHeightForTransaction:      {"transaction_id"},
+	storage.PrefixHeightForCollection:       {"collection_id"},
+	storage.PrefixHeightForSeal:             {"seal_id"},
+	storage.PrefixCommit:                    {"height"},
+	storage.PrefixHeader:                    {"height"},
+	storage.PrefixEvents:                    {"height", "type_hash"},
+	storage.PrefixPayload:                   {"path", "height"},
+	storage.PrefixTransaction:               {"transaction_id"},
+	storage.PrefixCollection:                {"collection_id"},
+	storage.PrefixGuarantee:                 {"collection_id"},
+	storage.PrefixTransactionsForHeight:     {"height"},
+	storage.PrefixTransactionsForCollection: {"collection_id"},
+	storage.PrefixCollectionsForHeight:      {"height"},
+	storage.PrefixResults:                   {"transaction_id"},
+	storage.PrefixSeal:                      {"seal_id"},
+	storage.PrefixSealsForHeight:            {"height"},
+}
+
+// newValue returns a pointer to a value of the type stored under the given
+// prefix, into which the value can be decoded.
+func newValue(prefix uint8) interface{} {
+	switch prefix {
+	case storage.PrefixFirst, storage.PrefixLast, storage.PrefixHeightForBlock, storage.PrefixHeightForTransaction,
+		storage.PrefixHeightForCollection, storage.PrefixHeightForSeal:
+		return new(uint64)
+	case storage.PrefixContents:
+		return new([]string)
+	case storage.PrefixCommit:
+		return new(flow.StateCommitment)
+	case storage.PrefixHeader:
+		return new(flow.Header)
+	case storage.PrefixEvents:
+		return new([]flow.Event)
+	case storage.PrefixPayload:
+		return new(ledger.Payload)
+	case storage.PrefixTransaction:
+		return new(flow.TransactionBody)
+	case storage.PrefixCollection:
+		return new(flow.LightCollection)
+	case storage.PrefixGuarantee:
+		return new(flow.CollectionGuarantee)
+	case storage.PrefixResults:
+		return new(flow.TransactionResult)
+	case storage.PrefixSeal:
+		return new(flow.Seal)
+	default:
+		return new([]flow.Identifier)
+	}
+}
+
+// checkRange checks that the given start and end keys are within the given
+// prefix, and that the start key comes before the end key, as the scan would
+// otherwise silently find no entries.
+func checkRange(prefix []byte, start []byte, end []byte) error {
+	if !bytes.HasPrefix(start, prefix) {
+		return fmt.Errorf("start key %x is outside of prefix %x", start, prefix)
+	}
+	if end != nil && !bytes.HasPrefix(end, prefix) {
+		return fmt.Errorf("end key %x is outside of prefix %x", end, prefix)
+	}
+	if end != nil && bytes.Compare(start, end) >= 0 {
+		return fmt.Errorf("start key %x is not before end key %x", start, end)
+	}
+	return nil
+}
+
+// parsePrefix parses the given prefix, which is either the name of a prefix
+// or its number.
+func parsePrefix(name string) (uint8, error) {
+	for prefix, prefixName := range storage.PrefixNames {
+		if prefixName == name {
+			return prefix, nil
+		}
+	}
+	prefix, err := strconv.ParseUint(name, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("unknown prefix name or invalid prefix number: %w", err)
+	}
+	return uint8(prefix), nil
+}
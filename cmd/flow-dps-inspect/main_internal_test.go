@@ -0,0 +1,84 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRange(t *testing.T) {
+	prefix := []byte{0x03}
+
+	tests := []struct {
+		name   string
+		prefix []byte
+		start  []byte
+		end    []byte
+
+		checkErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:     "nominal case with prefix",
+			prefix:   prefix,
+			start:    []byte{0x03, 0x01},
+			end:      []byte{0x03, 0x0a},
+			checkErr: assert.NoError,
+		},
+		{
+			name:     "nominal case with only prefix",
+			prefix:   prefix,
+			start:    prefix,
+			checkErr: assert.NoError,
+		},
+		{
+			name:     "nominal case without prefix",
+			start:    []byte{0x03, 0x01},
+			end:      []byte{0x05},
+			checkErr: assert.NoError,
+		},
+		{
+			name:     "handles start key outside of prefix",
+			prefix:   prefix,
+			start:    []byte{0x05, 0x01},
+			checkErr: assert.Error,
+		},
+		{
+			name:     "handles end key outside of prefix",
+			prefix:   prefix,
+			start:    prefix,
+			end:      []byte{0x02, 0x01},
+			checkErr: assert.Error,
+		},
+		{
+			name:     "handles start key after end key",
+			prefix:   prefix,
+			start:    []byte{0x03, 0x0a},
+			end:      []byte{0x03, 0x01},
+			checkErr: assert.Error,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkRange(test.prefix, test.start, test.end)
+			test.checkErr(t, err)
+		})
+	}
+}
@@ -17,7 +17,9 @@ package main
 import (
 	"context"
 	"crypto/rand"
+	"crypto/tls"
 	"errors"
+	"math"
 	"net"
 	"net/http"
 	"os"
@@ -36,6 +38,10 @@ import (
 	"github.com/spf13/pflag"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	grpchealth "google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 
 	sdk "github.com/onflow/flow-go-sdk/crypto"
 	"github.com/onflow/flow-go/cmd/bootstrap/utils"
@@ -43,7 +49,12 @@ import (
 	unstaked "github.com/onflow/flow-go/follower"
 	"github.com/onflow/flow-go/model/bootstrap"
 
+	"github.com/optakt/flow-dps/api/auth"
+	"github.com/optakt/flow-dps/api/cache"
 	api "github.com/optakt/flow-dps/api/dps"
+	"github.com/optakt/flow-dps/api/gateway"
+	"github.com/optakt/flow-dps/api/health"
+	"github.com/optakt/flow-dps/api/limit"
 	"github.com/optakt/flow-dps/codec/zbor"
 	"github.com/optakt/flow-dps/models/dps"
 	"github.com/optakt/flow-dps/service/cloud"
@@ -51,10 +62,13 @@ import (
 	"github.com/optakt/flow-dps/service/index"
 	"github.com/optakt/flow-dps/service/initializer"
 	"github.com/optakt/flow-dps/service/loader"
+	"github.com/optakt/flow-dps/service/maintenance"
 	"github.com/optakt/flow-dps/service/mapper"
 	"github.com/optakt/flow-dps/service/metrics"
+	"github.com/optakt/flow-dps/service/stats"
 	"github.com/optakt/flow-dps/service/storage"
 	"github.com/optakt/flow-dps/service/tracker"
+	"github.com/optakt/flow-dps/service/tuning"
 )
 
 const (
@@ -74,34 +88,68 @@ func run() int {
 
 	// Command line parameter initialization.
 	var (
-		flagAddress    string
-		flagBootstrap  string
-		flagBucket     string
-		flagCheckpoint string
-		flagData       string
-		flagIndex      string
-		flagLevel      string
-		flagMetrics    string
-		flagSkip       bool
-
-		flagFlushInterval time.Duration
-		flagSeedAddress   string
-		flagSeedKey       string
+		flagAddress       string
+		flagAuthKeys      string
+		flagBadgerConfig  string
+		flagBadgerOptions map[string]string
+		flagBadgerProfile string
+		flagHTTP          string
+		flagBootstrap     string
+		flagBucket        string
+		flagCheckpoint    string
+		flagData          string
+		flagIndex         string
+		flagLevel         string
+		flagMetrics       string
+		flagSkip          bool
+		flagStats         bool
+		flagTLSCert       string
+		flagTLSClientCA   string
+		flagTLSKey        string
+
+		flagCacheSize       uint64
+		flagFlushInterval   time.Duration
+		flagFlattenInterval time.Duration
+		flagGCInterval      time.Duration
+		flagGCRatio         float64
+		flagMaxLag          uint64
+		flagRateBurst       float64
+		flagRateLookups     float64
+		flagRateRegisters   float64
+		flagSeedAddress     string
+		flagSeedKey         string
 	)
 
 	pflag.StringVarP(&flagAddress, "address", "a", "127.0.0.1:5005", "bind address for serving DPS API")
+	pflag.StringVar(&flagAuthKeys, "auth-keys", "", "path to JSON or YAML keyring with the API keys allowed to call the DPS API (no authentication when left empty)")
 	pflag.StringVarP(&flagBootstrap, "bootstrap", "b", "bootstrap", "path to directory with bootstrap information for spork")
 	pflag.StringVarP(&flagBucket, "bucket", "u", "", "Google Cloude Storage bucket with block data records")
 	pflag.StringVarP(&flagCheckpoint, "checkpoint", "c", "", "path to root checkpoint file for execution state trie")
 	pflag.StringVarP(&flagData, "data", "d", "data", "path to database directory for protocol data")
+	pflag.StringVar(&flagHTTP, "http", "", "bind address for serving the HTTP/JSON gateway of the DPS API (no gateway is served when left empty)")
 	pflag.StringVarP(&flagIndex, "index", "i", "index", "path to database directory for state index")
 	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
 	pflag.StringVarP(&flagMetrics, "metrics", "m", "", "address on which to expose metrics (no metrics are exposed when left empty)")
 	pflag.BoolVarP(&flagSkip, "skip", "s", false, "skip indexing of execution state ledger registers")
+	pflag.BoolVar(&flagStats, "stats", false, "enable serving index statistics, which requires walking through the whole index for each request")
 
+	pflag.Uint64Var(&flagCacheSize, "cache-size", 0, "maximum size in bytes of the cache for immutable API responses (0 for disabled)")
 	pflag.DurationVar(&flagFlushInterval, "flush-interval", 1*time.Second, "interval for flushing badger transactions (0s for disabled)")
+	pflag.DurationVar(&flagFlattenInterval, "flatten-interval", maintenance.DefaultConfig.FlattenInterval, "interval without index writes after which the LSM tree is flattened (0s for disabled)")
+	pflag.DurationVar(&flagGCInterval, "gc-interval", maintenance.DefaultConfig.GCInterval, "interval for running value log garbage collection on the index (0s for disabled)")
+	pflag.Float64Var(&flagGCRatio, "gc-ratio", maintenance.DefaultConfig.DiscardRatio, "minimum ratio of stale data for a value log file to be rewritten")
 	pflag.StringVar(&flagSeedAddress, "seed-address", "", "host address of seed node to follow consensus")
 	pflag.StringVar(&flagSeedKey, "seed-key", "", "hex-encoded public network key of seed node to follow consensus")
+	pflag.Uint64Var(&flagMaxLag, "max-lag", 0, "maximum number of blocks the index can lag behind the finalized height before it is reported as not serving (0 for unlimited)")
+	pflag.Float64Var(&flagRateBurst, "rate-burst", 2, "number of seconds worth of calls at the rate limit that a client can make at once")
+	pflag.Float64Var(&flagRateLookups, "rate-lookups", 0, "rate limit for lookup calls per second and client (0 for unlimited)")
+	pflag.Float64Var(&flagRateRegisters, "rate-registers", 0, "rate limit for register reads per second and client (0 for unlimited)")
+	pflag.StringVar(&flagTLSCert, "tls-cert", "", "path to PEM certificate file for serving the DPS API over TLS")
+	pflag.StringVar(&flagTLSClientCA, "tls-client-ca", "", "path to PEM file with the certificate authorities of client certificates, which enables mutual TLS")
+	pflag.StringVar(&flagTLSKey, "tls-key", "", "path to PEM private key file for serving the DPS API over TLS")
+	pflag.StringVar(&flagBadgerConfig, "badger-config", "", "path to JSON or YAML file with Badger profile and option overrides")
+	pflag.StringToStringVar(&flagBadgerOptions, "badger-option", nil, "comma-separated list of Badger option overrides (name=value)")
+	pflag.StringVar(&flagBadgerProfile, "badger-profile", "", "Badger tuning profile, one of bulk-ingest, live, read-only-serving or low-memory (live when left empty)")
 
 	pflag.Parse()
 
@@ -119,11 +167,24 @@ func run() int {
 	}
 	log = log.Level(level)
 
+	// Load the Badger configuration, which selects the profile of options used
+	// to open the index and protocol databases.
+	tune, err := tuning.Load(tuning.ProfileLive, flagBadgerConfig, flagBadgerProfile, flagBadgerOptions)
+	if err != nil {
+		log.Error().Err(err).Msg("could not load Badger configuration")
+		return failure
+	}
+
 	// As a first step, we will open the protocol state and the index database.
 	// The protocol state database is what the consensus follower will write to
 	// and the mapper will read from. The index database is what the mapper will
 	// write to and the DPS API will read from.
-	indexDB, err := badger.Open(dps.DefaultOptions(flagIndex))
+	indexOpts, err := tune.BadgerOptions(flagIndex)
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not get Badger options")
+		return failure
+	}
+	indexDB, err := badger.Open(indexOpts)
 	if err != nil {
 		log.Error().Str("index", flagIndex).Err(err).Msg("could not open index database")
 		return failure
@@ -134,7 +195,12 @@ func run() int {
 			log.Error().Err(err).Msg("could not close index database")
 		}
 	}()
-	protocolDB, err := badger.Open(dps.DefaultOptions(flagData))
+	protocolOpts, err := tune.BadgerOptions(flagData)
+	if err != nil {
+		log.Error().Str("protocol", flagData).Err(err).Msg("could not get Badger options")
+		return failure
+	}
+	protocolDB, err := badger.Open(protocolOpts)
 	if err != nil {
 		log.Error().Err(err).Msg("could not open protocol state database")
 		return failure
@@ -373,17 +439,135 @@ func run() int {
 		logging.WithLevels(logging.DefaultServerCodeToLevel),
 	}
 	interceptor := grpczerolog.InterceptorLogger(log.With().Str("component", "grpc_server").Logger())
-	gsvr := grpc.NewServer(
-		grpc.ChainUnaryInterceptor(
-			tags.UnaryServerInterceptor(),
-			logging.UnaryServerInterceptor(interceptor, logOpts...),
-		),
-		grpc.ChainStreamInterceptor(
-			tags.StreamServerInterceptor(),
-			logging.StreamServerInterceptor(interceptor, logOpts...),
-		),
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		tags.UnaryServerInterceptor(),
+		logging.UnaryServerInterceptor(interceptor, logOpts...),
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		tags.StreamServerInterceptor(),
+		logging.StreamServerInterceptor(interceptor, logOpts...),
+	}
+
+	// When a certificate is given, the DPS API and its gateway are served over
+	// TLS; when a client CA is given as well, clients also need to present a
+	// certificate signed by it.
+	var serverOpts []grpc.ServerOption
+	var tlsConfig *tls.Config
+	if flagTLSCert != "" || flagTLSKey != "" {
+		tlsConfig, err = auth.ServerTLS(flagTLSCert, flagTLSKey, flagTLSClientCA)
+		if err != nil {
+			log.Error().Str("cert", flagTLSCert).Str("key", flagTLSKey).Err(err).Msg("could not load TLS configuration")
+			return failure
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	if tlsConfig == nil && flagTLSClientCA != "" {
+		log.Error().Str("client_ca", flagTLSClientCA).Msg("mutual TLS requires a server certificate and key")
+		return failure
+	}
+
+	// When a keyring is given, each call needs to present one of its API keys,
+	// which has to allow the called method.
+	var gatewayOpts []gateway.Option
+	if flagAuthKeys != "" {
+		keys, err := auth.ReadKeys(flagAuthKeys)
+		if err != nil {
+			log.Error().Str("auth_keys", flagAuthKeys).Err(err).Msg("could not read API keys")
+			return failure
+		}
+		authenticate, err := auth.NewAuthenticator(keys...)
+		if err != nil {
+			log.Error().Str("auth_keys", flagAuthKeys).Err(err).Msg("could not initialize authenticator")
+			return failure
+		}
+		if tlsConfig == nil {
+			log.Warn().Msg("API keys are sent in clear text without TLS")
+		}
+		unaryInterceptors = append(unaryInterceptors, health.BypassUnary(authenticate.UnaryServerInterceptor()))
+		streamInterceptors = append(streamInterceptors, health.BypassStream(authenticate.StreamServerInterceptor()))
+		gatewayOpts = append(gatewayOpts, gateway.WithAuthorizer(authenticate))
+	}
+
+	// When rate limits are given, each client, identified by its API key or its
+	// address, is limited separately for lookups and for register reads.
+	if flagRateLookups > 0 || flagRateRegisters > 0 {
+		limiter := limit.NewLimiter(
+			limit.WithLimit(limit.ClassLookups, flagRateLookups, int(math.Ceil(flagRateLookups*flagRateBurst))),
+			limit.WithLimit(limit.ClassRegisters, flagRateRegisters, int(math.Ceil(flagRateRegisters*flagRateBurst))),
+		)
+		var throttle limit.Throttler
+		throttle = limiter
+		if metricsEnabled {
+			throttle = limit.NewMetricsLimiter(limiter)
+		}
+		unaryInterceptors = append(unaryInterceptors, health.BypassUnary(limit.UnaryServerInterceptor(throttle)))
+		streamInterceptors = append(streamInterceptors, health.BypassStream(limit.StreamServerInterceptor(throttle)))
+		gatewayOpts = append(gatewayOpts, gateway.WithThrottler(throttle))
+	}
+
+	serverOpts = append(serverOpts,
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	)
+	gsvr := grpc.NewServer(serverOpts...)
+	// Index statistics are only served on demand, and are also exposed as
+	// metrics when metrics are enabled.
+	var options []api.Option
+	if flagStats {
+		collect := stats.NewCollector(indexDB, codec)
+		option := api.WithStats(collect)
+		if metricsEnabled {
+			option = api.WithStats(stats.NewMetricsCollector(collect))
+		}
+		options = append(options, option)
+	}
+
+	// Headers, transactions and other entities never change once indexed, so
+	// their encoded responses can be cached to serve popular ones from memory.
+	if flagCacheSize > 0 {
+		responses, err := cache.NewCache(cache.WithSize(flagCacheSize))
+		if err != nil {
+			log.Error().Err(err).Msg("could not initialize response cache")
+			return failure
+		}
+		option := api.WithCache(responses)
+		if metricsEnabled {
+			option = api.WithCache(cache.NewMetricsCache(responses))
+		}
+		options = append(options, option)
+	}
+	server := api.NewServer(read, codec, options...)
+
+	// The health service reports the server as not serving until the index
+	// has a valid range of heights and, if configured, whenever the index lags
+	// too far behind the finalized height of the consensus follower.
+	hlth := grpchealth.NewServer()
+	monitor := health.NewMonitor(log, read, hlth,
+		health.WithServices(api.API_ServiceDesc.ServiceName),
+		health.WithMaxLag(consensus, flagMaxLag),
+	)
+
+	// The HTTP gateway exposes the same DPS API as REST endpoints with plain
+	// JSON responses, for clients that can not use GRPC and the DPS codec.
+	hsvr := &http.Server{
+		Addr:      flagHTTP,
+		Handler:   gateway.NewGateway(server, codec, gatewayOpts...),
+		TLSConfig: tlsConfig,
+	}
+
+	// The maintenance controller reclaims the disk space taken up by stale
+	// data in the index, which accumulates as the index is rewritten.
+	var task maintenance.Task
+	maintain := maintenance.NewMaintainer(indexDB, flagIndex)
+	task = maintain
+	if metricsEnabled {
+		task = maintenance.NewMetricsMaintainer(maintain)
+	}
+	ctrl := maintenance.NewController(log, task,
+		maintenance.WithGCInterval(flagGCInterval),
+		maintenance.WithDiscardRatio(flagGCRatio),
+		maintenance.WithFlattenInterval(flagFlattenInterval),
 	)
-	server := api.NewServer(read, codec)
 
 	// This section launches the main executing components in their own
 	// goroutine, so they can run concurrently. Afterwards, we wait for an
@@ -416,12 +600,41 @@ func run() int {
 	go func() {
 		log.Info().Msg("Flow DPS Live Server starting")
 		api.RegisterAPIServer(gsvr, server)
+		grpc_health_v1.RegisterHealthServer(gsvr, hlth)
+		reflection.Register(gsvr)
 		err = gsvr.Serve(listener)
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Warn().Err(err).Msg("Flow DPS Server failed")
 		}
 		log.Info().Msg("Flow DPS Live Server stopped")
 	}()
+	go func() {
+		log.Info().Msg("health monitor starting")
+		monitor.Run()
+		log.Info().Msg("health monitor stopped")
+	}()
+	go func() {
+		log.Info().Msg("maintenance controller starting")
+		ctrl.Run()
+		log.Info().Msg("maintenance controller stopped")
+	}()
+	go func() {
+		if flagHTTP == "" {
+			return
+		}
+
+		log.Info().Str("address", flagHTTP).Msg("HTTP gateway starting")
+		var err error
+		if tlsConfig != nil {
+			err = hsvr.ListenAndServeTLS("", "")
+		} else {
+			err = hsvr.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Warn().Err(err).Msg("HTTP gateway failed")
+		}
+		log.Info().Msg("HTTP gateway stopped")
+	}()
 	go func() {
 		if !metricsEnabled {
 			return
@@ -454,9 +667,15 @@ func run() int {
 		os.Exit(1)
 	}()
 
-	// We first stop serving the DPS API by shutting down the GRPC server. Next,
-	// we shut down the consensus follower, so that there is no indexing to be
-	// done anymore. Lastly, we stop the mapper logic itself.
+	// We first report the DPS API as not serving and stop serving it by shutting
+	// down the GRPC server. Next, we shut down the consensus follower, so that
+	// there is no indexing to be done anymore. Lastly, we stop the mapper logic
+	// itself, and the index maintenance once nothing writes to the index anymore.
+	err = hsvr.Shutdown(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("could not shut down HTTP gateway")
+	}
+	hlth.Shutdown()
 	gsvr.GracefulStop()
 	cancel()
 	<-follow.NodeBuilder.Done()
@@ -465,6 +684,16 @@ func run() int {
 		log.Error().Err(err).Msg("could not stop indexer")
 		return failure
 	}
+	err = ctrl.Stop()
+	if err != nil {
+		log.Error().Err(err).Msg("could not stop maintenance controller")
+		return failure
+	}
+	err = monitor.Stop()
+	if err != nil {
+		log.Error().Err(err).Msg("could not stop health monitor")
+		return failure
+	}
 
 	return success
 }
@@ -15,7 +15,10 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
+	"math"
 	"net"
 	"net/http"
 	"os"
@@ -26,16 +29,29 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/spf13/pflag"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	grpchealth "google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 
 	grpczerolog "github.com/grpc-ecosystem/go-grpc-middleware/providers/zerolog/v2"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/tags"
 
+	"github.com/optakt/flow-dps/api/auth"
+	"github.com/optakt/flow-dps/api/cache"
 	api "github.com/optakt/flow-dps/api/dps"
+	"github.com/optakt/flow-dps/api/gateway"
+	"github.com/optakt/flow-dps/api/health"
+	"github.com/optakt/flow-dps/api/limit"
 	"github.com/optakt/flow-dps/codec/zbor"
 	"github.com/optakt/flow-dps/models/dps"
 	"github.com/optakt/flow-dps/service/index"
+	"github.com/optakt/flow-dps/service/maintenance"
+	"github.com/optakt/flow-dps/service/metrics"
+	"github.com/optakt/flow-dps/service/stats"
 	"github.com/optakt/flow-dps/service/storage"
+	"github.com/optakt/flow-dps/service/tuning"
 )
 
 const (
@@ -55,14 +71,49 @@ func run() int {
 
 	// Command line parameter initialization.
 	var (
-		flagAddress string
-		flagLevel   string
-		flagIndex   string
+		flagAddress       string
+		flagAuthKeys      string
+		flagBadgerConfig  string
+		flagBadgerOptions map[string]string
+		flagBadgerProfile string
+		flagHTTP          string
+		flagLevel         string
+		flagIndex         string
+		flagMetrics       string
+		flagStats         bool
+		flagTLSCert       string
+		flagTLSClientCA   string
+		flagTLSKey        string
+
+		flagCacheSize       uint64
+		flagFlattenInterval time.Duration
+		flagGCInterval      time.Duration
+		flagGCRatio         float64
+		flagRateBurst       float64
+		flagRateLookups     float64
+		flagRateRegisters   float64
 	)
 
 	pflag.StringVarP(&flagAddress, "address", "a", "127.0.0.1:5005", "bind address for serving DPS API")
+	pflag.StringVar(&flagAuthKeys, "auth-keys", "", "path to JSON or YAML keyring with the API keys allowed to call the DPS API (no authentication when left empty)")
+	pflag.StringVar(&flagHTTP, "http", "", "bind address for serving the HTTP/JSON gateway of the DPS API (no gateway is served when left empty)")
 	pflag.StringVarP(&flagIndex, "index", "i", "index", "path to database directory for state index")
 	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVarP(&flagMetrics, "metrics", "m", "", "address on which to expose metrics (no metrics are exposed when left empty)")
+	pflag.BoolVar(&flagStats, "stats", false, "enable serving index statistics, which requires walking through the whole index for each request")
+	pflag.Uint64Var(&flagCacheSize, "cache-size", 0, "maximum size in bytes of the cache for immutable API responses (0 for disabled)")
+	pflag.DurationVar(&flagFlattenInterval, "flatten-interval", 0, "interval without index writes after which the LSM tree is flattened (0s for disabled)")
+	pflag.DurationVar(&flagGCInterval, "gc-interval", 0, "interval for running value log garbage collection on the index (0s for disabled)")
+	pflag.Float64Var(&flagGCRatio, "gc-ratio", maintenance.DefaultConfig.DiscardRatio, "minimum ratio of stale data for a value log file to be rewritten")
+	pflag.Float64Var(&flagRateBurst, "rate-burst", 2, "number of seconds worth of calls at the rate limit that a client can make at once")
+	pflag.Float64Var(&flagRateLookups, "rate-lookups", 0, "rate limit for lookup calls per second and client (0 for unlimited)")
+	pflag.Float64Var(&flagRateRegisters, "rate-registers", 0, "rate limit for register reads per second and client (0 for unlimited)")
+	pflag.StringVar(&flagTLSCert, "tls-cert", "", "path to PEM certificate file for serving the DPS API over TLS")
+	pflag.StringVar(&flagTLSClientCA, "tls-client-ca", "", "path to PEM file with the certificate authorities of client certificates, which enables mutual TLS")
+	pflag.StringVar(&flagTLSKey, "tls-key", "", "path to PEM private key file for serving the DPS API over TLS")
+	pflag.StringVar(&flagBadgerConfig, "badger-config", "", "path to JSON or YAML file with Badger profile and option overrides")
+	pflag.StringToStringVar(&flagBadgerOptions, "badger-option", nil, "comma-separated list of Badger option overrides (name=value)")
+	pflag.StringVar(&flagBadgerProfile, "badger-profile", "", "Badger tuning profile, one of bulk-ingest, live, read-only-serving or low-memory (read-only-serving when left empty)")
 
 	pflag.Parse()
 
@@ -76,8 +127,23 @@ func run() int {
 	}
 	log = log.Level(level)
 
-	// Initialize the index core state and open database in read-only mode.
-	db, err := badger.Open(dps.DefaultOptions(flagIndex).WithReadOnly(true))
+	// Load the Badger configuration, which selects the profile of options used
+	// to open the index database.
+	tune, err := tuning.Load(tuning.ProfileServing, flagBadgerConfig, flagBadgerProfile, flagBadgerOptions)
+	if err != nil {
+		log.Error().Err(err).Msg("could not load Badger configuration")
+		return failure
+	}
+
+	// Initialize the index core state and open database in read-only mode,
+	// unless index maintenance is enabled, as it needs to rewrite files.
+	indexOpts, err := tune.BadgerOptions(flagIndex)
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not get Badger options")
+		return failure
+	}
+	maintenanceEnabled := flagGCInterval > 0 || flagFlattenInterval > 0
+	db, err := badger.Open(indexOpts.WithReadOnly(!maintenanceEnabled))
 	if err != nil {
 		log.Error().Str("index", flagIndex).Err(err).Msg("could not open index DB")
 		return failure
@@ -89,21 +155,155 @@ func run() int {
 	storage := storage.New(codec)
 
 	// GRPC API initialization.
+	metricsEnabled := flagMetrics != ""
 	opts := []logging.Option{
 		logging.WithLevels(logging.DefaultServerCodeToLevel),
 	}
-	gsvr := grpc.NewServer(
-		grpc.ChainUnaryInterceptor(
-			tags.UnaryServerInterceptor(),
-			logging.UnaryServerInterceptor(grpczerolog.InterceptorLogger(log), opts...),
-		),
-		grpc.ChainStreamInterceptor(
-			tags.StreamServerInterceptor(),
-			logging.StreamServerInterceptor(grpczerolog.InterceptorLogger(log), opts...),
-		),
+	interceptor := grpczerolog.InterceptorLogger(log)
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		tags.UnaryServerInterceptor(),
+		logging.UnaryServerInterceptor(interceptor, opts...),
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		tags.StreamServerInterceptor(),
+		logging.StreamServerInterceptor(interceptor, opts...),
+	}
+
+	// When a certificate is given, the DPS API and its gateway are served over
+	// TLS; when a client CA is given as well, clients also need to present a
+	// certificate signed by it.
+	var serverOpts []grpc.ServerOption
+	var tlsConfig *tls.Config
+	if flagTLSCert != "" || flagTLSKey != "" {
+		tlsConfig, err = auth.ServerTLS(flagTLSCert, flagTLSKey, flagTLSClientCA)
+		if err != nil {
+			log.Error().Str("cert", flagTLSCert).Str("key", flagTLSKey).Err(err).Msg("could not load TLS configuration")
+			return failure
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	if tlsConfig == nil && flagTLSClientCA != "" {
+		log.Error().Str("client_ca", flagTLSClientCA).Msg("mutual TLS requires a server certificate and key")
+		return failure
+	}
+
+	// When a keyring is given, each call needs to present one of its API keys,
+	// which has to allow the called method.
+	var gatewayOpts []gateway.Option
+	if flagAuthKeys != "" {
+		keys, err := auth.ReadKeys(flagAuthKeys)
+		if err != nil {
+			log.Error().Str("auth_keys", flagAuthKeys).Err(err).Msg("could not read API keys")
+			return failure
+		}
+		authenticate, err := auth.NewAuthenticator(keys...)
+		if err != nil {
+			log.Error().Str("auth_keys", flagAuthKeys).Err(err).Msg("could not initialize authenticator")
+			return failure
+		}
+		if tlsConfig == nil {
+			log.Warn().Msg("API keys are sent in clear text without TLS")
+		}
+		unaryInterceptors = append(unaryInterceptors, health.BypassUnary(authenticate.UnaryServerInterceptor()))
+		streamInterceptors = append(streamInterceptors, health.BypassStream(authenticate.StreamServerInterceptor()))
+		gatewayOpts = append(gatewayOpts, gateway.WithAuthorizer(authenticate))
+	}
+
+	// When rate limits are given, each client, identified by its API key or its
+	// address, is limited separately for lookups and for register reads.
+	if flagRateLookups > 0 || flagRateRegisters > 0 {
+		limiter := limit.NewLimiter(
+			limit.WithLimit(limit.ClassLookups, flagRateLookups, int(math.Ceil(flagRateLookups*flagRateBurst))),
+			limit.WithLimit(limit.ClassRegisters, flagRateRegisters, int(math.Ceil(flagRateRegisters*flagRateBurst))),
+		)
+		var throttle limit.Throttler
+		throttle = limiter
+		if metricsEnabled {
+			throttle = limit.NewMetricsLimiter(limiter)
+		}
+		unaryInterceptors = append(unaryInterceptors, health.BypassUnary(limit.UnaryServerInterceptor(throttle)))
+		streamInterceptors = append(streamInterceptors, health.BypassStream(limit.StreamServerInterceptor(throttle)))
+		gatewayOpts = append(gatewayOpts, gateway.WithThrottler(throttle))
+	}
+
+	serverOpts = append(serverOpts,
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	)
+	gsvr := grpc.NewServer(serverOpts...)
+
+	// A partial index only includes some categories of data, which it lists in
+	// its contents; we then make sure to reject calls for other categories.
+	var reader dps.Reader
+	reader = index.NewReader(db, storage)
+	var categories []string
+	err = db.View(storage.RetrieveContents(&categories))
+	if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+		log.Error().Err(err).Msg("could not retrieve index contents")
+		return failure
+	}
+	if err == nil {
+		log.Info().Strs("categories", categories).Msg("serving partial index")
+		reader = index.NewPartial(reader, categories...)
+	}
+
+	// Index statistics are only served on demand, and are also exposed as
+	// metrics when metrics are enabled.
+	var options []api.Option
+	if flagStats {
+		collect := stats.NewCollector(db, codec)
+		option := api.WithStats(collect)
+		if metricsEnabled {
+			option = api.WithStats(stats.NewMetricsCollector(collect))
+		}
+		options = append(options, option)
+	}
+
+	// Headers, transactions and other entities never change once indexed, so
+	// their encoded responses can be cached to serve popular ones from memory.
+	if flagCacheSize > 0 {
+		responses, err := cache.NewCache(cache.WithSize(flagCacheSize))
+		if err != nil {
+			log.Error().Err(err).Msg("could not initialize response cache")
+			return failure
+		}
+		option := api.WithCache(responses)
+		if metricsEnabled {
+			option = api.WithCache(cache.NewMetricsCache(responses))
+		}
+		options = append(options, option)
+	}
+	server := api.NewServer(reader, codec, options...)
+
+	// The health service reports the server as not serving until the index
+	// has a valid range of heights, so that load balancers and orchestrators
+	// only route traffic to it once it can answer requests.
+	hlth := grpchealth.NewServer()
+	monitor := health.NewMonitor(log, reader, hlth,
+		health.WithServices(api.API_ServiceDesc.ServiceName),
+	)
+
+	// The HTTP gateway exposes the same DPS API as REST endpoints with plain
+	// JSON responses, for clients that can not use GRPC and the DPS codec.
+	hsvr := &http.Server{
+		Addr:      flagHTTP,
+		Handler:   gateway.NewGateway(server, codec, gatewayOpts...),
+		TLSConfig: tlsConfig,
+	}
+
+	// The maintenance controller reclaims the disk space taken up by stale
+	// data in the index, for example after it was pruned.
+	var task maintenance.Task
+	maintain := maintenance.NewMaintainer(db, flagIndex)
+	task = maintain
+	if metricsEnabled {
+		task = maintenance.NewMetricsMaintainer(maintain)
+	}
+	ctrl := maintenance.NewController(log, task,
+		maintenance.WithGCInterval(flagGCInterval),
+		maintenance.WithDiscardRatio(flagGCRatio),
+		maintenance.WithFlattenInterval(flagFlattenInterval),
 	)
-	index := index.NewReader(db, storage)
-	server := api.NewServer(index, codec)
 
 	// This section launches the main executing components in their own
 	// goroutine, so they can run concurrently. Afterwards, we wait for an
@@ -118,6 +318,8 @@ func run() int {
 	go func() {
 		log.Info().Msg("Flow DPS Server starting")
 		api.RegisterAPIServer(gsvr, server)
+		grpc_health_v1.RegisterHealthServer(gsvr, hlth)
+		reflection.Register(gsvr)
 		err = gsvr.Serve(listener)
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Warn().Err(err).Msg("Flow DPS Server failed")
@@ -127,6 +329,50 @@ func run() int {
 		}
 		log.Info().Msg("Flow DPS Server stopped")
 	}()
+	go func() {
+		log.Info().Msg("health monitor starting")
+		monitor.Run()
+		log.Info().Msg("health monitor stopped")
+	}()
+	go func() {
+		if !maintenanceEnabled {
+			return
+		}
+
+		log.Info().Msg("maintenance controller starting")
+		ctrl.Run()
+		log.Info().Msg("maintenance controller stopped")
+	}()
+	go func() {
+		if flagHTTP == "" {
+			return
+		}
+
+		log.Info().Str("address", flagHTTP).Msg("HTTP gateway starting")
+		var err error
+		if tlsConfig != nil {
+			err = hsvr.ListenAndServeTLS("", "")
+		} else {
+			err = hsvr.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Warn().Err(err).Msg("HTTP gateway failed")
+		}
+		log.Info().Msg("HTTP gateway stopped")
+	}()
+	go func() {
+		if !metricsEnabled {
+			return
+		}
+
+		log.Info().Msg("metrics server starting")
+		server := metrics.NewServer(log, flagMetrics)
+		err := server.Start()
+		if err != nil {
+			log.Warn().Err(err).Msg("metrics server failed")
+		}
+		log.Info().Msg("metrics server stopped")
+	}()
 
 	select {
 	case <-sig:
@@ -143,7 +389,24 @@ func run() int {
 		os.Exit(1)
 	}()
 
+	err = hsvr.Shutdown(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("could not shut down HTTP gateway")
+	}
+	hlth.Shutdown()
 	gsvr.GracefulStop()
+	err = monitor.Stop()
+	if err != nil {
+		log.Error().Err(err).Msg("could not stop health monitor")
+		return failure
+	}
+	if maintenanceEnabled {
+		err = ctrl.Stop()
+		if err != nil {
+			log.Error().Err(err).Msg("could not stop maintenance controller")
+			return failure
+		}
+	}
 
 	return success
 }
@@ -16,22 +16,28 @@ package main
 
 import (
 	"compress/gzip"
+	"context"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"fmt"
 	"io"
 	"os"
-	"runtime"
+	"path/filepath"
 	"time"
 
 	"github.com/dgraph-io/badger/v2"
 	"github.com/klauspost/compress/zstd"
 	"github.com/rs/zerolog"
 	"github.com/spf13/pflag"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
 
 	"github.com/optakt/flow-dps/codec/zbor"
-	"github.com/optakt/flow-dps/models/dps"
 	"github.com/optakt/flow-dps/service/index"
+	"github.com/optakt/flow-dps/service/snapshot"
 	"github.com/optakt/flow-dps/service/storage"
+	"github.com/optakt/flow-dps/service/tuning"
 )
 
 const (
@@ -45,6 +51,10 @@ const (
 	encodingBase64 = "base64"
 )
 
+// keyStdin is the checkpoint key for snapshots restored from standard input
+// without a manifest, as their checksum is unknown.
+const keyStdin = "stdin"
+
 const (
 	compressionNone = "none"
 	compressionZstd = "zstd"
@@ -59,14 +69,26 @@ func run() int {
 
 	// Parse the command line arguments.
 	var (
-		flagCompression string
-		flagEncoding    string
-		flagIndex       string
+		flagBadgerConfig  string
+		flagBadgerOptions map[string]string
+		flagBadgerProfile string
+		flagCheckpoint    string
+		flagCompression   string
+		flagEncoding      string
+		flagIndex         string
+		flagManifests     []string
+		flagWriters       int
 	)
 
+	pflag.StringVarP(&flagCheckpoint, "checkpoint", "k", "", "path to the checkpoint file used to resume an interrupted restoration (next to the index directory when left empty)")
 	pflag.StringVarP(&flagCompression, "compression", "c", compressionZstd, "compression algorithm (\"none\", \"zstd\" or \"gzip\")")
 	pflag.StringVarP(&flagEncoding, "encoding", "e", encodingNone, "output encoding (\"none\", \"hex\" or \"base64\")")
 	pflag.StringVarP(&flagIndex, "index", "i", "index", "database directory for state index")
+	pflag.StringSliceVarP(&flagManifests, "manifests", "m", nil, "comma-separated list of snapshot manifests to restore in order (standard input when left empty)")
+	pflag.IntVarP(&flagWriters, "writers", "w", 4, "number of snapshot chunk files to restore in parallel")
+	pflag.StringVar(&flagBadgerConfig, "badger-config", "", "path to JSON or YAML file with Badger profile and option overrides")
+	pflag.StringToStringVar(&flagBadgerOptions, "badger-option", nil, "comma-separated list of Badger option overrides (name=value)")
+	pflag.StringVar(&flagBadgerProfile, "badger-profile", "", "Badger tuning profile, one of bulk-ingest, live, read-only-serving or low-memory (bulk-ingest when left empty)")
 
 	pflag.Parse()
 
@@ -74,46 +96,295 @@ func run() int {
 	zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
 	log := zerolog.New(os.Stderr).With().Timestamp().Logger().Level(zerolog.DebugLevel)
 
+	// Load the Badger configuration, which selects the profile of options used
+	// to open the index database.
+	tune, err := tuning.Load(tuning.ProfileBulkIngest, flagBadgerConfig, flagBadgerProfile, flagBadgerOptions)
+	if err != nil {
+		log.Error().Err(err).Msg("could not load Badger configuration")
+		return failure
+	}
+
+	if flagWriters < 1 {
+		log.Error().Int("writers", flagWriters).Msg("at least one writer is needed")
+		return failure
+	}
+
+	// Open the checkpoint, which records the progress of a previous
+	// restoration that was interrupted, if there was one.
+	if flagCheckpoint == "" {
+		flagCheckpoint = filepath.Clean(flagIndex) + ".checkpoint"
+	}
+	checkpoint, err := snapshot.OpenCheckpoint(flagCheckpoint)
+	if err != nil {
+		log.Error().Str("checkpoint", flagCheckpoint).Err(err).Msg("could not open restoration checkpoint")
+		return failure
+	}
+
 	// Open the index database.
-	db, err := badger.Open(dps.DefaultOptions(flagIndex))
+	indexOpts, err := tune.BadgerOptions(flagIndex)
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not get Badger options")
+		return failure
+	}
+	db, err := badger.Open(indexOpts)
 	if err != nil {
 		log.Error().Str("index", flagIndex).Err(err).Msg("could not open badger db")
 		return failure
 	}
 	defer db.Close()
 
-	// Check if the database is empty.
+	// Check if the database is empty, unless we are resuming a restoration,
+	// in which case it already contains part of the snapshots.
 	index := index.NewReader(db, storage.New(zbor.NewCodec()))
 	_, err = index.First()
-	if err == nil {
+	if err == nil && !checkpoint.Resumed() {
 		log.Error().Msg("database directory already contains index database")
 		return failure
 	}
+	if checkpoint.Resumed() {
+		log.Info().Str("checkpoint", flagCheckpoint).Msg("resuming snapshot restoration")
+	}
 
-	// We will consume from stdin; if the user wants to load from a file, he can
-	// pipe it into the command.
-	var reader io.Reader
-	reader = os.Stdin
-	defer os.Stdin.Close()
+	loader := snapshot.NewLoader(db)
+
+	// If no manifests are given, we will consume from stdin; if the user wants
+	// to load from a file, he can pipe it into the command.
+	if len(flagManifests) == 0 {
+		defer os.Stdin.Close()
+		progress, err := restore(loader, checkpoint, keyStdin, os.Stdin, flagCompression, flagEncoding)
+		if err != nil {
+			log.Error().Err(err).Msg("snapshot restoration failed")
+			return failure
+		}
+		err = checkpoint.Update(keyStdin, progress)
+		if err != nil {
+			log.Error().Err(err).Msg("could not checkpoint snapshot restoration")
+			return failure
+		}
+		err = complete(loader, checkpoint)
+		if err != nil {
+			log.Error().Err(err).Msg("could not complete snapshot restoration")
+			return failure
+		}
+		log.Info().Msg("snapshot restoration complete")
+		return success
+	}
+
+	// Otherwise, we restore the chain of snapshots described by the manifests,
+	// starting with the full snapshot and applying each incremental snapshot in
+	// order.
+	manifests := make([]*snapshot.Manifest, 0, len(flagManifests))
+	for _, path := range flagManifests {
+		manifest, err := snapshot.ReadManifest(path)
+		if err != nil {
+			log.Error().Str("manifest", path).Err(err).Msg("could not read snapshot manifest")
+			return failure
+		}
+		manifests = append(manifests, manifest)
+	}
+	err = snapshot.Chain(manifests...)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid snapshot chain")
+		return failure
+	}
+	latest := manifests[len(manifests)-1]
+	if latest.Codec > zbor.Version {
+		log.Error().Uint("have", zbor.Version).Uint("want", latest.Codec).Msg("snapshot codec version not supported")
+		return failure
+	}
+
+	// Snapshots without a file were written to stdout on creation, so we read
+	// them from stdin; this only works for a single snapshot.
+	stdin := 0
+	for _, manifest := range manifests {
+		for _, chunk := range manifest.Files() {
+			if chunk.File == "" {
+				stdin++
+			}
+		}
+	}
+	if stdin > 1 {
+		log.Error().Int("snapshots", stdin).Msg("only one snapshot can be read from standard input")
+		return failure
+	}
+
+	// The chunks of each snapshot are restored in parallel, with up to the
+	// given number of writers, as they never contain the same keys.
+	for i, manifest := range manifests {
+		group, ctx := errgroup.WithContext(context.Background())
+		sema := semaphore.NewWeighted(int64(flagWriters))
+		for _, chunk := range manifest.Files() {
+			chunk := chunk
+			path := filepath.Join(filepath.Dir(flagManifests[i]), chunk.File)
+			if chunk.File == "" {
+				path = os.Stdin.Name()
+			}
+			err = sema.Acquire(ctx, 1)
+			if err != nil {
+				break
+			}
+			group.Go(func() error {
+				defer sema.Release(1)
+				err := restoreFile(loader, checkpoint, path, chunk.Checksum, manifest)
+				if err != nil {
+					return fmt.Errorf("could not restore snapshot file (%s): %w", path, err)
+				}
+				log.Debug().Str("file", path).Msg("snapshot file restored")
+				return nil
+			})
+		}
+		err = group.Wait()
+		if err != nil {
+			log.Error().Err(err).Msg("snapshot restoration failed")
+			return failure
+		}
+		log.Info().Str("manifest", flagManifests[i]).Uint64("last", manifest.Last).Msg("snapshot restored")
+	}
+
+	// Finally, we make sure that the restored index matches the heights and the
+	// state commitment recorded in the manifest of the latest snapshot.
+	err = complete(loader, checkpoint)
+	if err != nil {
+		log.Error().Err(err).Msg("could not complete snapshot restoration")
+		return failure
+	}
+	err = latest.Verify(index)
+	if err != nil {
+		log.Error().Err(err).Msg("restored index does not match snapshot manifest")
+		return failure
+	}
+
+	log.Info().Uint64("first", latest.First).Uint64("last", latest.Last).Str("commit", latest.Commit).Msg("snapshot restoration complete")
+
+	return success
+}
+
+// complete concludes the restoration once all snapshot files were loaded, and
+// removes the checkpoint, as there is nothing left to resume.
+func complete(loader *snapshot.Loader, checkpoint *snapshot.Checkpoint) error {
+
+	err := loader.Finish()
+	if err != nil {
+		return fmt.Errorf("could not finish loading: %w", err)
+	}
+	err = checkpoint.Remove()
+	if err != nil {
+		return fmt.Errorf("could not remove checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// restoreFile restores the snapshot file at the given path, while verifying
+// that its checksum matches the given one. Files that were already restored
+// according to the checkpoint are skipped, so a file is only recorded as done
+// once its checksum was verified. Files on disk are verified in a first pass,
+// before any of their entries are loaded, so that a corrupted file is never
+// written into the index; standard input can only be read once, so it is only
+// verified while loading.
+func restoreFile(loader *snapshot.Loader, checkpoint *snapshot.Checkpoint, path string, checksum string, manifest *snapshot.Manifest) error {
+
+	progress := checkpoint.Progress(checksum)
+	if progress.Done {
+		loader.Skip(progress)
+		return nil
+	}
+
+	if path != os.Stdin.Name() {
+		err := verifyFile(path, checksum)
+		if err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open snapshot file: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	reader := io.TeeReader(file, hash)
+	progress, err = restore(loader, checkpoint, checksum, reader, manifest.Compression, manifest.Encoding)
+	if err != nil {
+		return err
+	}
+
+	// Make sure the whole file was hashed, even if the decompression did not
+	// need to read it until the end. If the checksum no longer matches at this
+	// point, the file changed after it was verified, or it was read from
+	// standard input; either way, its entries are already in the index.
+	_, err = io.Copy(io.Discard, reader)
+	if err != nil {
+		return fmt.Errorf("could not read snapshot file: %w", err)
+	}
+	have := hex.EncodeToString(hash.Sum(nil))
+	if have != checksum {
+		return fmt.Errorf("snapshot checksum mismatch after loading (have: %s, want: %s), the index database contains invalid entries and must be discarded", have, checksum)
+	}
+
+	err = checkpoint.Update(checksum, progress)
+	if err != nil {
+		return fmt.Errorf("could not checkpoint snapshot progress: %w", err)
+	}
+
+	return nil
+}
+
+// verifyFile checks that the checksum of the snapshot file at the given path
+// matches the given one.
+func verifyFile(path string, checksum string) error {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open snapshot file: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return fmt.Errorf("could not hash snapshot file: %w", err)
+	}
+	have := hex.EncodeToString(hash.Sum(nil))
+	if have != checksum {
+		return fmt.Errorf("snapshot checksum mismatch (have: %s, want: %s)", have, checksum)
+	}
+
+	return nil
+}
+
+// restore decompresses and decodes the snapshot from the given reader, and
+// loads it into the database, resuming from and recording its progress in the
+// checkpoint under the given key. The final progress, which marks the snapshot
+// as done, is returned instead of being recorded, so that the caller can first
+// verify the snapshot.
+func restore(loader *snapshot.Loader, checkpoint *snapshot.Checkpoint, key string, reader io.Reader, compression string, encoding string) (snapshot.Progress, error) {
 
 	// When reading, we first need to decompress, so we start with that
-	switch flagCompression {
+	switch compression {
 	case compressionNone:
 		// nothing to do
 	case compressionZstd:
-		decompressor, _ := zstd.NewReader(reader)
+		decompressor, err := zstd.NewReader(reader)
+		if err != nil {
+			return snapshot.Progress{}, fmt.Errorf("could not initialize zstd decompression: %w", err)
+		}
 		defer decompressor.Close()
 		reader = decompressor
 	case compressionGzip:
-		decompressor, _ := gzip.NewReader(reader)
+		decompressor, err := gzip.NewReader(reader)
+		if err != nil {
+			return snapshot.Progress{}, fmt.Errorf("could not initialize gzip decompression: %w", err)
+		}
 		defer decompressor.Close()
 		reader = decompressor
 	default:
-		log.Error().Str("compression", flagCompression).Msg("invalid compression algorithm specified")
+		return snapshot.Progress{}, fmt.Errorf("invalid compression algorithm specified (%s)", compression)
 	}
 
 	// After decompression, we can decode the encoding.
-	switch flagEncoding {
+	switch encoding {
 	case encodingNone:
 		// nothing to do
 	case encodingHex:
@@ -121,17 +392,21 @@ func run() int {
 	case encodingBase64:
 		reader = base64.NewDecoder(base64.StdEncoding, reader)
 	default:
-		log.Error().Str("encoding", flagEncoding).Msg("invalid encoding format specified")
+		return snapshot.Progress{}, fmt.Errorf("invalid encoding format specified (%s)", encoding)
 	}
 
-	// Restore the database
-	err = db.Load(reader, runtime.GOMAXPROCS(0))
+	// Restore the database, skipping what was already loaded.
+	progress := checkpoint.Progress(key)
+	err := loader.Load(reader, progress, func(update snapshot.Progress) error {
+		if update.Done {
+			progress = update
+			return nil
+		}
+		return checkpoint.Update(key, update)
+	})
 	if err != nil {
-		log.Error().Err(err).Msg("snapshot restoration failed")
-		return failure
+		return snapshot.Progress{}, fmt.Errorf("could not load snapshot: %w", err)
 	}
 
-	log.Info().Msg("snapshot restoration complete")
-
-	return success
+	return progress, nil
 }
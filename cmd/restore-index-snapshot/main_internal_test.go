@@ -0,0 +1,136 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/service/snapshot"
+	"github.com/optakt/flow-dps/testing/helpers"
+)
+
+func TestRestoreFile(t *testing.T) {
+
+	dir := t.TempDir()
+	manifest := snapshot.Manifest{
+		Compression: compressionNone,
+		Encoding:    encodingNone,
+	}
+
+	// The snapshot file on disk is corrupted, so its checksum no longer matches
+	// the one recorded in the manifest.
+	data := testStream(t, 3, 2)
+	hash := sha256.Sum256(data)
+	checksum := hex.EncodeToString(hash[:])
+	path := filepath.Join(dir, "snapshot")
+	corrupted := bytes.Replace(data, []byte("value-2-1"), []byte("value-2-x"), 1)
+	require.NoError(t, os.WriteFile(path, corrupted, 0644))
+
+	db := helpers.InMemoryDB(t)
+	defer db.Close()
+
+	checkpointPath := filepath.Join(dir, "checkpoint")
+	checkpoint, err := snapshot.OpenCheckpoint(checkpointPath)
+	require.NoError(t, err)
+
+	loader := snapshot.NewLoader(db)
+	err = restoreFile(loader, checkpoint, path, checksum, &manifest)
+	require.Error(t, err)
+	assert.Equal(t, snapshot.Progress{}, checkpoint.Progress(checksum))
+
+	// None of the entries of the corrupted file should have been loaded, so
+	// that the index remains usable.
+	require.NoError(t, loader.Finish())
+	assertKeys(t, db, 0)
+
+	// When resuming, the file should not be skipped, but verified again and
+	// rejected once more, as long as it is corrupted.
+	checkpoint, err = snapshot.OpenCheckpoint(checkpointPath)
+	require.NoError(t, err)
+
+	err = restoreFile(snapshot.NewLoader(db), checkpoint, path, checksum, &manifest)
+	require.Error(t, err)
+	assert.False(t, checkpoint.Progress(checksum).Done)
+	assertKeys(t, db, 0)
+
+	// Once the file is repaired, resuming restores it and marks it as done.
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	checkpoint, err = snapshot.OpenCheckpoint(checkpointPath)
+	require.NoError(t, err)
+
+	loader = snapshot.NewLoader(db)
+	err = restoreFile(loader, checkpoint, path, checksum, &manifest)
+	require.NoError(t, err)
+	progress := checkpoint.Progress(checksum)
+	assert.True(t, progress.Done)
+	assert.Equal(t, uint64(3), progress.Lists)
+
+	require.NoError(t, loader.Finish())
+	assertKeys(t, db, 6)
+}
+
+func testStream(t *testing.T, lists int, entries int) []byte {
+	t.Helper()
+
+	var stream bytes.Buffer
+	version := uint64(1)
+	for i := 0; i < lists; i++ {
+		var list pb.KVList
+		for j := 0; j < entries; j++ {
+			kv := pb.KV{
+				Key:     []byte(fmt.Sprintf("key-%d-%d", i, j)),
+				Value:   []byte(fmt.Sprintf("value-%d-%d", i, j)),
+				Version: version,
+			}
+			list.Kv = append(list.Kv, &kv)
+			version++
+		}
+		data, err := list.Marshal()
+		require.NoError(t, err)
+		require.NoError(t, binary.Write(&stream, binary.LittleEndian, uint64(len(data))))
+		_, err = stream.Write(data)
+		require.NoError(t, err)
+	}
+
+	return stream.Bytes()
+}
+
+func assertKeys(t *testing.T, db *badger.DB, want int) {
+	t.Helper()
+
+	count := 0
+	err := db.View(func(tx *badger.Txn) error {
+		it := tx.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			count++
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, want, count)
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	url2 "net/url"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/rs/zerolog"
+)
+
+// MetaSpork is the key of the proxy target metadata that holds the name of the
+// spork a target serves. It is required when adding targets to the balancer.
+const MetaSpork = "spork"
+
+// TargetState is the current state of a single proxy target, as reported by
+// the admin endpoint.
+type TargetState struct {
+	Name    string    `json:"name"`
+	URL     string    `json:"url"`
+	Spork   string    `json:"spork"`
+	Healthy bool      `json:"healthy"`
+	Height  uint64    `json:"height,omitempty"`
+	Checked time.Time `json:"checked,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+type target struct {
+	proxy *middleware.ProxyTarget
+	state TargetState
+}
+
+// All requests which  have BlockID or TransactionID have them in the same field
+type BlockAwareRequest struct {
+	BlockID       BlockIdentifier       `json:"block_identifier,omitempty"`
+	TransactionID TransactionIdentifier `json:"transaction_identifier,omitempty"`
+}
+
+// FlowHeightAwareBalancer is a proxy balancer which routes each Rosetta request
+// to one of the healthy targets of the spork containing the requested height,
+// in a round-robin fashion. Requests which only identify a block or transaction
+// by hash are routed to the spork which the hash was resolved to. The health of
+// each target is checked periodically using the `/network/status` endpoint of
+// its Rosetta API.
+type FlowHeightAwareBalancer struct {
+	log     zerolog.Logger
+	client  *http.Client
+	network string
+	hashes  *ristretto.Cache // spork name by block or transaction hash
+
+	mutex   sync.RWMutex
+	sporks  SporkList
+	targets [][]*target // targets by spork index
+	counts  []uint64    // round-robin counters by spork index
+}
+
+// NewFlowHeightAwareBalancer creates a new balancer for the targets of the
+// given spork list. All targets are considered healthy until they are checked.
+// The cache size is the maximum number of resolved hashes to remember.
+func NewFlowHeightAwareBalancer(log zerolog.Logger, sporkList SporkList, network string, timeout time.Duration, cacheSize uint64) (*FlowHeightAwareBalancer, error) {
+
+	hashes, err := ristretto.NewCache(&ristretto.Config{
+		NumCounters: int64(cacheSize) * 10,
+		MaxCost:     int64(cacheSize),
+		BufferItems: 64,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize hash cache: %w", err)
+	}
+
+	f := FlowHeightAwareBalancer{
+		log:     log.With().Str("component", "balancer").Logger(),
+		client:  &http.Client{Timeout: timeout},
+		network: network,
+		hashes:  hashes,
+		sporks:  sporkList,
+		targets: make([][]*target, len(sporkList)),
+		counts:  make([]uint64, len(sporkList)),
+	}
+
+	for i, spork := range sporkList {
+		for _, proxy := range spork.Targets {
+			f.targets[i] = append(f.targets[i], newTarget(proxy, spork.Name))
+		}
+	}
+
+	return &f, nil
+}
+
+// Update atomically replaces the spork list of the balancer. Targets which are
+// part of the same spork in both lists keep their current health state.
+func (f *FlowHeightAwareBalancer) Update(sporkList SporkList) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	existing := make(map[string]*target)
+	for _, targets := range f.targets {
+		for _, t := range targets {
+			existing[t.proxy.Name] = t
+		}
+	}
+
+	targets := make([][]*target, len(sporkList))
+	for i, spork := range sporkList {
+		for _, proxy := range spork.Targets {
+			t, ok := existing[proxy.Name]
+			if !ok || t.state.Spork != spork.Name {
+				t = newTarget(proxy, spork.Name)
+			}
+			targets[i] = append(targets[i], t)
+		}
+	}
+
+	f.sporks = sporkList
+	f.targets = targets
+	f.counts = make([]uint64, len(sporkList))
+}
+
+// AddTarget adds the given target to the spork named in its metadata. It
+// returns false if the spork is unknown or if a target with the same name
+// already exists.
+func (f *FlowHeightAwareBalancer) AddTarget(proxy *middleware.ProxyTarget) bool {
+	name, _ := proxy.Meta[MetaSpork].(string)
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for _, targets := range f.targets {
+		for _, t := range targets {
+			if t.proxy.Name == proxy.Name {
+				return false
+			}
+		}
+	}
+
+	for i, spork := range f.sporks {
+		if spork.Name == name {
+			f.targets[i] = append(f.targets[i], newTarget(proxy, name))
+			return true
+		}
+	}
+
+	return false
+}
+
+// RemoveTarget removes the target with the given name from the balancer. It
+// returns false if no such target exists.
+func (f *FlowHeightAwareBalancer) RemoveTarget(name string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for i, targets := range f.targets {
+		for j, t := range targets {
+			if t.proxy.Name == name {
+				f.targets[i] = append(targets[:j:j], targets[j+1:]...)
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Middleware selects the target to forward each request to, before the proxy
+// middleware forwards it. When no target can be selected, it fails the request
+// with the error of `NextTarget`, so that the proxy is only used when a target
+// was found.
+func (f *FlowHeightAwareBalancer) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(e echo.Context) error {
+		proxy, err := f.NextTarget(e)
+		if err != nil {
+			f.log.Error().Err(err).Msg("could not select proxy target")
+			return err
+		}
+		e.Set(middleware.DefaultProxyConfig.ContextKey, proxy)
+		return next(e)
+	}
+}
+
+// Next returns the target that was selected for the request by the balancer
+// middleware, which must run before the proxy middleware, as the proxy can not
+// handle requests without a target.
+func (f *FlowHeightAwareBalancer) Next(e echo.Context) *middleware.ProxyTarget {
+	return e.Get(middleware.DefaultProxyConfig.ContextKey).(*middleware.ProxyTarget)
+}
+
+// NextTarget returns the target to forward the request to, or an error if the
+// spork containing the requested height has no targets.
+func (f *FlowHeightAwareBalancer) NextTarget(e echo.Context) (*middleware.ProxyTarget, error) {
+	blockAware := BlockAwareRequest{}
+
+	var reqBody []byte
+	if e.Request().Body != nil { // Read
+		reqBody, _ = ioutil.ReadAll(e.Request().Body)
+	}
+	e.Request().Body = ioutil.NopCloser(bytes.NewBuffer(reqBody)) // Reset
+
+	err := json.Unmarshal(reqBody, &blockAware)
+	if err != nil {
+		e.Error(err)
+	}
+
+	// The height is the cheapest way to find the spork, so we use it if it is
+	// given. Otherwise, we resolve the block hash or transaction hash to the
+	// spork holding it. If nothing matches, just use latest spork.
+	f.mutex.RLock()
+	sporks := f.sporks
+	f.mutex.RUnlock()
+
+	var name string
+	blockID := blockAware.BlockID
+	txID := blockAware.TransactionID
+	switch {
+	case blockID.Index != nil && *blockID.Index != 0:
+		index, err := sporks.SporkForHeight(*blockID.Index)
+		if err == nil {
+			name = sporks[index].Name
+		}
+	case blockID.Hash != "":
+		name, _ = f.locateBlock(e.Request().Context(), blockID.Hash)
+	case txID.Hash != "":
+		name, _ = f.locateTransaction(e.Request().Context(), txID.Hash)
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	// The spork list might have been updated since we resolved the spork, so
+	// we look it up by name, rather than by index.
+	index := len(f.sporks) - 1
+	for i, spork := range f.sporks {
+		if spork.Name == name {
+			index = i
+			break
+		}
+	}
+
+	targets := f.targets[index]
+	if len(targets) == 0 {
+		return nil, echo.NewHTTPError(http.StatusServiceUnavailable, fmt.Sprintf("no targets for spork %s", f.sporks[index].Name))
+	}
+
+	// We round-robin between the healthy targets of the spork. If none of them
+	// are healthy, we round-robin between all of them, as a failing health
+	// check is still better than not forwarding the request at all.
+	healthy := make([]*target, 0, len(targets))
+	for _, t := range targets {
+		if t.state.Healthy {
+			healthy = append(healthy, t)
+		}
+	}
+	if len(healthy) == 0 {
+		healthy = targets
+	}
+
+	next := healthy[f.counts[index]%uint64(len(healthy))]
+	f.counts[index]++
+
+	return next.proxy, nil
+}
+
+// Monitor periodically checks the health of all targets, until the given
+// context is canceled.
+func (f *FlowHeightAwareBalancer) Monitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		f.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check checks the health of all targets concurrently and updates their state.
+func (f *FlowHeightAwareBalancer) Check(ctx context.Context) {
+	f.mutex.RLock()
+	var targets []*target
+	for _, sporkTargets := range f.targets {
+		targets = append(targets, sporkTargets...)
+	}
+	f.mutex.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		go func(t *target) {
+			defer wg.Done()
+
+			height, err := f.status(ctx, t.proxy)
+
+			f.mutex.Lock()
+			defer f.mutex.Unlock()
+
+			if err != nil && t.state.Healthy {
+				f.log.Warn().Str("target", t.proxy.Name).Err(err).Msg("target became unhealthy")
+			}
+			if err == nil && !t.state.Healthy {
+				f.log.Info().Str("target", t.proxy.Name).Msg("target became healthy")
+			}
+
+			t.state.Checked = time.Now().UTC()
+			t.state.Healthy = err == nil
+			t.state.Error = ""
+			if err != nil {
+				t.state.Error = err.Error()
+				return
+			}
+			t.state.Height = height
+		}(t)
+	}
+	wg.Wait()
+}
+
+// Targets returns the current state of all targets, ordered by spork.
+func (f *FlowHeightAwareBalancer) Targets() []TargetState {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	var states []TargetState
+	for _, targets := range f.targets {
+		for _, t := range targets {
+			states = append(states, t.state)
+		}
+	}
+
+	return states
+}
+
+// Handler returns the admin endpoint handler, which lists the state of all
+// targets.
+func (f *FlowHeightAwareBalancer) Handler(e echo.Context) error {
+	return e.JSON(http.StatusOK, f.Targets())
+}
+
+// status requests the network status from the given target and returns the
+// height of its current block.
+func (f *FlowHeightAwareBalancer) status(ctx context.Context, proxy *middleware.ProxyTarget) (uint64, error) {
+
+	request := struct {
+		NetworkID NetworkIdentifier `json:"network_identifier"`
+	}{
+		NetworkID: f.networkID(),
+	}
+	var response struct {
+		BlockID BlockIdentifier `json:"current_block_identifier"`
+	}
+	err := f.post(ctx, proxy, "/network/status", request, &response)
+	if err != nil {
+		return 0, fmt.Errorf("could not get network status: %w", err)
+	}
+	if response.BlockID.Index == nil {
+		return 0, fmt.Errorf("status response is missing current block index")
+	}
+
+	return *response.BlockID.Index, nil
+}
+
+// post sends the given request to the given endpoint of the target's Rosetta
+// API, and decodes the response into the given value.
+func (f *FlowHeightAwareBalancer) post(ctx context.Context, proxy *middleware.ProxyTarget, path string, request interface{}, response interface{}) error {
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("could not encode request: %w", err)
+	}
+
+	url := proxy.URL.ResolveReference(&url2.URL{Path: path})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url.String(), bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("could not create request: %w", err)
+	}
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not execute request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code (%d)", res.StatusCode)
+	}
+
+	err = json.NewDecoder(res.Body).Decode(response)
+	if err != nil {
+		return fmt.Errorf("could not decode response: %w", err)
+	}
+
+	return nil
+}
+
+func (f *FlowHeightAwareBalancer) networkID() NetworkIdentifier {
+	return NetworkIdentifier{
+		Blockchain: "flow",
+		Network:    f.network,
+	}
+}
+
+func newTarget(proxy *middleware.ProxyTarget, spork string) *target {
+	t := target{
+		proxy: proxy,
+		state: TargetState{
+			Name:    proxy.Name,
+			URL:     proxy.URL.String(),
+			Spork:   spork,
+			Healthy: true,
+		},
+	}
+	return &t
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	url2 "net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowHeightAwareBalancer_NextTarget(t *testing.T) {
+
+	t.Run("routes by height", func(t *testing.T) {
+		t.Parallel()
+
+		sporks := testSporks(t, []string{"http://spork1-a", "http://spork1-b"}, []string{"http://spork2-a"})
+		balancer := testBalancer(t, sporks)
+
+		proxy, err := balancer.NextTarget(testContext(`{"block_identifier":{"index":50}}`))
+		require.NoError(t, err)
+		assert.Equal(t, "http://spork1-a", proxy.Name)
+
+		proxy, err = balancer.NextTarget(testContext(`{"block_identifier":{"index":150}}`))
+		require.NoError(t, err)
+		assert.Equal(t, "http://spork2-a", proxy.Name)
+	})
+
+	t.Run("round-robins between targets", func(t *testing.T) {
+		t.Parallel()
+
+		sporks := testSporks(t, []string{"http://spork1-a", "http://spork1-b"}, []string{"http://spork2-a"})
+		balancer := testBalancer(t, sporks)
+
+		var names []string
+		for i := 0; i < 4; i++ {
+			proxy, err := balancer.NextTarget(testContext(`{"block_identifier":{"index":50}}`))
+			require.NoError(t, err)
+			names = append(names, proxy.Name)
+		}
+		assert.Equal(t, []string{"http://spork1-a", "http://spork1-b", "http://spork1-a", "http://spork1-b"}, names)
+	})
+
+	t.Run("skips unhealthy targets", func(t *testing.T) {
+		t.Parallel()
+
+		sporks := testSporks(t, []string{"http://spork1-a", "http://spork1-b"}, []string{"http://spork2-a"})
+		balancer := testBalancer(t, sporks)
+		balancer.targets[0][0].state.Healthy = false
+
+		for i := 0; i < 3; i++ {
+			proxy, err := balancer.NextTarget(testContext(`{"block_identifier":{"index":50}}`))
+			require.NoError(t, err)
+			assert.Equal(t, "http://spork1-b", proxy.Name)
+		}
+	})
+
+	t.Run("uses all targets when none are healthy", func(t *testing.T) {
+		t.Parallel()
+
+		sporks := testSporks(t, []string{"http://spork1-a", "http://spork1-b"}, []string{"http://spork2-a"})
+		balancer := testBalancer(t, sporks)
+		balancer.targets[0][0].state.Healthy = false
+		balancer.targets[0][1].state.Healthy = false
+
+		proxy, err := balancer.NextTarget(testContext(`{"block_identifier":{"index":50}}`))
+		require.NoError(t, err)
+		assert.Equal(t, "http://spork1-a", proxy.Name)
+	})
+
+	t.Run("uses latest spork without height", func(t *testing.T) {
+		t.Parallel()
+
+		sporks := testSporks(t, []string{"http://spork1-a"}, []string{"http://spork2-a"})
+		balancer := testBalancer(t, sporks)
+
+		proxy, err := balancer.NextTarget(testContext(`{"network_identifier":{"blockchain":"flow"}}`))
+		require.NoError(t, err)
+		assert.Equal(t, "http://spork2-a", proxy.Name)
+
+		proxy, err = balancer.NextTarget(testContext(`{"block_identifier":{"index":1000}}`))
+		require.NoError(t, err)
+		assert.Equal(t, "http://spork2-a", proxy.Name)
+	})
+
+	t.Run("keeps request body", func(t *testing.T) {
+		t.Parallel()
+
+		sporks := testSporks(t, []string{"http://spork1-a"}, []string{"http://spork2-a"})
+		balancer := testBalancer(t, sporks)
+
+		body := `{"block_identifier":{"index":50}}`
+		ctx := testContext(body)
+		_, err := balancer.NextTarget(ctx)
+		require.NoError(t, err)
+
+		forwarded, err := ioutil.ReadAll(ctx.Request().Body)
+		require.NoError(t, err)
+		assert.Equal(t, body, string(forwarded))
+	})
+
+	t.Run("handles spork without targets", func(t *testing.T) {
+		t.Parallel()
+
+		sporks := testSporks(t, []string{"http://spork1-a"}, []string{"http://spork2-a"})
+		balancer := testBalancer(t, sporks)
+		require.True(t, balancer.RemoveTarget("http://spork1-a"))
+
+		_, err := balancer.NextTarget(testContext(`{"block_identifier":{"index":50}}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestFlowHeightAwareBalancer_Middleware(t *testing.T) {
+
+	past, _ := testRosetta(t, 100, nil, nil)
+	latest, _ := testRosetta(t, 200, nil, nil)
+	balancer := testBalancer(t, testSporks(t, []string{past.URL}, []string{latest.URL}))
+	require.True(t, balancer.RemoveTarget(past.URL))
+
+	server := echo.New()
+	server.Use(balancer.Middleware)
+	server.Use(middleware.Proxy(balancer))
+
+	t.Run("nominal case", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/network/status", strings.NewReader(`{"block_identifier":{"index":150}}`))
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "current_block_identifier")
+	})
+
+	t.Run("handles spork without targets", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/network/status", strings.NewReader(`{"block_identifier":{"index":50}}`))
+		rec := httptest.NewRecorder()
+		assert.NotPanics(t, func() {
+			server.ServeHTTP(rec, req)
+		})
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), "no targets for spork spork1")
+	})
+}
+
+func TestFlowHeightAwareBalancer_Check(t *testing.T) {
+
+	healthy, _ := testRosetta(t, 150, nil, nil)
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	sporks := testSporks(t, []string{failing.URL}, []string{healthy.URL})
+	balancer := testBalancer(t, sporks)
+
+	balancer.Check(context.Background())
+
+	states := balancer.Targets()
+	require.Len(t, states, 2)
+
+	assert.Equal(t, failing.URL, states[0].Name)
+	assert.Equal(t, "spork1", states[0].Spork)
+	assert.False(t, states[0].Healthy)
+	assert.NotEmpty(t, states[0].Error)
+	assert.False(t, states[0].Checked.IsZero())
+
+	assert.Equal(t, healthy.URL, states[1].Name)
+	assert.Equal(t, "spork2", states[1].Spork)
+	assert.True(t, states[1].Healthy)
+	assert.Empty(t, states[1].Error)
+	assert.Equal(t, uint64(150), states[1].Height)
+
+	// Unhealthy targets are skipped when routing requests.
+	proxy, err := balancer.NextTarget(testContext(`{"network_identifier":{"blockchain":"flow"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, healthy.URL, proxy.Name)
+}
+
+func TestFlowHeightAwareBalancer_Update(t *testing.T) {
+
+	sporks := testSporks(t, []string{"http://spork1-a"}, []string{"http://spork2-a"})
+	balancer := testBalancer(t, sporks)
+	balancer.targets[0][0].state.Healthy = false
+	balancer.targets[1][0].state.Healthy = false
+
+	// The first spork keeps its target, while the target of the second spork
+	// is moved to a new third spork, and a new target is added.
+	updated := testSporks(t, []string{"http://spork1-a"}, []string{"http://spork2-b"}, []string{"http://spork2-a"})
+	balancer.Update(updated)
+
+	states := balancer.Targets()
+	require.Len(t, states, 3)
+	assert.Equal(t, TargetState{Name: "http://spork1-a", URL: "http://spork1-a", Spork: "spork1", Healthy: false}, states[0])
+	assert.Equal(t, TargetState{Name: "http://spork2-b", URL: "http://spork2-b", Spork: "spork2", Healthy: true}, states[1])
+	assert.Equal(t, TargetState{Name: "http://spork2-a", URL: "http://spork2-a", Spork: "spork3", Healthy: true}, states[2])
+
+	proxy, err := balancer.NextTarget(testContext(`{"block_identifier":{"index":250}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "http://spork2-a", proxy.Name)
+}
+
+// testSporks returns a spork list with one spork of 100 heights for each of
+// the given lists of target URLs, named `spork1`, `spork2` and so on.
+func testSporks(t *testing.T, targets ...[]string) SporkList {
+	t.Helper()
+
+	sporks := make(SporkList, 0, len(targets))
+	for i, urls := range targets {
+		spork := Spork{
+			Name:  "spork" + string(rune('1'+i)),
+			First: uint64(i*100 + 1),
+			Last:  uint64(i*100 + 100),
+		}
+		for _, address := range urls {
+			url, err := url2.Parse(address)
+			require.NoError(t, err)
+			target := middleware.ProxyTarget{
+				Name: address,
+				URL:  url,
+				Meta: echo.Map{MetaSpork: spork.Name},
+			}
+			spork.Targets = append(spork.Targets, &target)
+		}
+		sporks = append(sporks, spork)
+	}
+
+	return sporks
+}
+
+func testBalancer(t *testing.T, sporks SporkList) *FlowHeightAwareBalancer {
+	t.Helper()
+
+	balancer, err := NewFlowHeightAwareBalancer(zerolog.Nop(), sporks, "flow-testnet", time.Second, 100)
+	require.NoError(t, err)
+
+	return balancer
+}
+
+func testContext(body string) echo.Context {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+// testRosetta starts a fake Rosetta API server at the given height, which
+// knows about the given block and transaction hashes. It returns the server
+// and the number of lookups it received.
+func testRosetta(t *testing.T, height uint64, blocks []string, transactions []string) (*httptest.Server, *int64) {
+	t.Helper()
+
+	var lookups int64
+	mux := http.NewServeMux()
+	mux.HandleFunc("/network/status", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"current_block_identifier": BlockIdentifier{Index: &height, Hash: "current"},
+		})
+	})
+	mux.HandleFunc("/block", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&lookups, 1)
+		var req struct {
+			BlockID BlockIdentifier `json:"block_identifier"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		for _, hash := range blocks {
+			if hash == req.BlockID.Hash {
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"block": map[string]interface{}{}})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/search/transactions", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&lookups, 1)
+		var req struct {
+			TransactionID TransactionIdentifier `json:"transaction_identifier"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		count := 0
+		for _, hash := range transactions {
+			if hash == req.TransactionID.Hash {
+				count++
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"total_count": count})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server, &lookups
+}
@@ -0,0 +1,21 @@
+package main
+
+// The Rosetta identifiers below only include the fields which the dispatcher
+// needs in order to route requests and to query the Rosetta API servers.
+
+// NetworkIdentifier identifies the Flow network of a Rosetta request.
+type NetworkIdentifier struct {
+	Blockchain string `json:"blockchain"`
+	Network    string `json:"network"`
+}
+
+// BlockIdentifier identifies a block by height, by hash, or by both.
+type BlockIdentifier struct {
+	Index *uint64 `json:"index,omitempty"`
+	Hash  string  `json:"hash,omitempty"`
+}
+
+// TransactionIdentifier identifies a transaction by hash.
+type TransactionIdentifier struct {
+	Hash string `json:"hash"`
+}
@@ -1,22 +1,20 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	url2 "net/url"
 	"os"
 	"os/signal"
-	"sort"
+	"syscall"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
-	"github.com/optakt/flow-dps/rosetta/identifier"
+	"github.com/optakt/flow-dps/service/metrics"
+	"github.com/optakt/flow-dps/service/spork"
 	"github.com/rs/zerolog"
 	"github.com/spf13/pflag"
 	"github.com/ziflex/lecho/v2"
@@ -28,51 +26,78 @@ const (
 )
 
 type Spork struct {
-	First      uint64
-	Last       uint64
-	ProxyTaget *middleware.ProxyTarget
+	Name    string
+	First   uint64
+	Last    uint64
+	Targets []*middleware.ProxyTarget
 }
 
 type SporkList []Spork
 
-func NewSporkList(addresses []string, firsts []int64, lasts []int64) (SporkList, error) {
-	// TODO: Add tests for validating sporks configuration
+// NewSporkList creates the list of proxy targets for the sporks of the given
+// registry, which is already sorted and validated.
+func NewSporkList(registry *spork.Registry) (SporkList, error) {
 
-	// Check past sporks configuration
-	if len(addresses) != len(firsts) || len(addresses) != len(lasts) {
-		return nil, fmt.Errorf("data length mismatch")
-	}
-
-	if len(addresses) < 2 {
+	sporks := registry.Sporks()
+	if len(sporks) < 2 {
 		return nil, fmt.Errorf("at least two sporks must be provided")
 	}
 
-	sporksTable := make(SporkList, len(addresses))
-
-	for i, sporkAddress := range addresses {
-		sporksTable[i].First = uint64(firsts[i])
-		sporksTable[i].Last = uint64(lasts[i])
+	sporksTable := make(SporkList, len(sporks))
+	for i, spork := range sporks {
+		sporksTable[i].Name = spork.Name
+		sporksTable[i].First = spork.First
+		sporksTable[i].Last = spork.Last
 
-		url, err := url2.Parse(sporkAddress)
-		if err != nil {
-			return nil, fmt.Errorf("spork address %d is invalid: %w", i, err)
+		endpoints := spork.Endpoints()
+		if len(endpoints) == 0 {
+			return nil, fmt.Errorf("spork %d (%s) has no Rosetta API address", i, spork.Name)
 		}
-		if sporksTable[i].First >= sporksTable[i].Last {
-			return nil, fmt.Errorf("spork %d last height is not greater than first", i)
+		for j, endpoint := range endpoints {
+			url, err := url2.Parse(endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("spork %d address %d is invalid: %w", i, j, err)
+			}
+			target := middleware.ProxyTarget{
+				Name: endpoint,
+				URL:  url,
+				Meta: echo.Map{MetaSpork: spork.Name},
+			}
+			sporksTable[i].Targets = append(sporksTable[i].Targets, &target)
 		}
-
-		sporksTable[i].ProxyTaget = &middleware.ProxyTarget{URL: url}
 	}
 
-	sort.Sort(sporksTable)
+	return sporksTable, nil
+}
+
+// RegistryFromFlags creates a spork registry from the legacy command line
+// flags, which give the addresses and boundaries of each spork separately.
+// Addresses with identical boundaries are replicas of the same spork.
+func RegistryFromFlags(addresses []string, firsts []int64, lasts []int64) (*spork.Registry, error) {
 
-	for i := 0; i < len(sporksTable)-1; i++ {
-		if sporksTable[i].Last != sporksTable[i+1].First-1 {
-			return nil, fmt.Errorf("gap between sporks boundaries: %d and %d", sporksTable[i].Last, sporksTable[i+1].First)
+	if len(addresses) != len(firsts) || len(addresses) != len(lasts) {
+		return nil, fmt.Errorf("data length mismatch")
+	}
+
+	var sporks []spork.Spork
+	lookup := make(map[[2]int64]int)
+	for i, address := range addresses {
+		boundaries := [2]int64{firsts[i], lasts[i]}
+		index, ok := lookup[boundaries]
+		if ok {
+			sporks[index].Replicas = append(sporks[index].Replicas, address)
+			continue
 		}
+		lookup[boundaries] = len(sporks)
+		sporks = append(sporks, spork.Spork{
+			Name:    address,
+			Rosetta: address,
+			First:   uint64(firsts[i]),
+			Last:    uint64(lasts[i]),
+		})
 	}
 
-	return sporksTable, nil
+	return spork.New(sporks...)
 }
 
 func (s SporkList) Len() int {
@@ -87,113 +112,28 @@ func (s SporkList) Swap(i, j int) {
 	s[i], s[j] = s[j], s[i]
 }
 
-func (s SporkList) ServerForHeight(height uint64) (*middleware.ProxyTarget, error) {
+// SporkForHeight returns the index of the spork containing the given height.
+func (s SporkList) SporkForHeight(height uint64) (int, error) {
 	// Assume sorted and more than zero items
 
 	first := s[0].First
 	last := s[len(s)-1].Last
 
 	if height < first {
-		return nil, fmt.Errorf("height %d below supported range %d - %d", height, first, last)
+		return 0, fmt.Errorf("height %d below supported range %d - %d", height, first, last)
 	}
 
 	if height > last {
-		return nil, fmt.Errorf("height %d above supported range %d - %d", height, first, last)
+		return 0, fmt.Errorf("height %d above supported range %d - %d", height, first, last)
 	}
 
-	for _, spork := range s {
+	for i, spork := range s {
 		if height >= spork.First && height <= spork.Last {
-			return spork.ProxyTaget, nil
+			return i, nil
 		}
 	}
 
-	return nil, fmt.Errorf("spork for height %d not found", height)
-}
-
-func NewFlowHeightAwareBalancer(sporkList SporkList) *FlowHeightAwareBalancer {
-
-	//targets := make([]*middleware.ProxyTarget, sporkList.Len())
-	//
-	//for i, spork := range sporkList {
-	//
-	//}
-
-	return &FlowHeightAwareBalancer{
-		//targets:   nil,
-		sporkList: sporkList,
-	}
-}
-
-type FlowHeightAwareBalancer struct {
-	//targets []*middleware.ProxyTarget
-	//mutex   sync.RWMutex
-	sporkList SporkList
-}
-
-func (f *FlowHeightAwareBalancer) AddTarget(target *middleware.ProxyTarget) bool {
-	// boilerplate from labstack/echi
-	//for _, t := range f.targets {
-	//	if t.Name == target.Name {
-	//		return false
-	//	}
-	//}
-	//f.mutex.Lock()
-	//defer f.mutex.Unlock()
-	//f.targets = append(f.targets, target)
-	//return true
-	return false
-}
-
-func (f *FlowHeightAwareBalancer) RemoveTarget(name string) bool {
-	//f.mutex.Lock()
-	//defer f.mutex.Unlock()
-	//for i, t := range f.targets {
-	//	if t.Name == name {
-	//		f.targets = append(f.targets[:i], f.targets[i+1:]...)
-	//		return true
-	//	}
-	//}
-	//return false
-	return false
-}
-
-// All requests which  have BlockID have them in the same field
-type BlockAwareRequest struct {
-	BlockID identifier.Block `json:"block_identifier,omitempty"`
-}
-
-func (f *FlowHeightAwareBalancer) Next(e echo.Context) *middleware.ProxyTarget {
-	blockAware := BlockAwareRequest{}
-
-	var reqBody []byte
-	if e.Request().Body != nil { // Read
-		reqBody, _ = ioutil.ReadAll(e.Request().Body)
-	}
-	e.Request().Body = ioutil.NopCloser(bytes.NewBuffer(reqBody)) // Reset
-
-	err := json.Unmarshal(reqBody, &blockAware)
-	if err != nil {
-		e.Error(err)
-	}
-
-	// no block ID = just use latest spork
-	// TODO - or maybe random?
-	blockHeight := uint64(0)
-	if blockAware.BlockID.Index != nil {
-		blockHeight = *blockAware.BlockID.Index
-	}
-	if blockHeight == 0 {
-		return f.sporkList[len(f.sporkList)-1].ProxyTaget
-	}
-
-	for _, spork := range f.sporkList {
-		if blockHeight >= spork.First && blockHeight <= spork.Last {
-			return spork.ProxyTaget
-		}
-	}
-
-	// return last if nothing matches
-	return f.sporkList[len(f.sporkList)-1].ProxyTaget
+	return 0, fmt.Errorf("spork for height %d not found", height)
 }
 
 func main() {
@@ -206,18 +146,37 @@ func run() int {
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt)
 
+	// Signal catching for spork manifest reloads.
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+
 	// Command line parameter initialization.
 	var (
-		flagLevel string
-		flagPort  uint16
+		flagAdmin    uint16
+		flagCache    uint64
+		flagInterval time.Duration
+		flagLevel    string
+		flagMetrics  string
+		flagNetwork  string
+		flagPort     uint16
+		flagSporks   string
+		flagTimeout  time.Duration
 
 		flagSporkAddresses []string
 		flagSporkFirsts    []int64
 		flagSporkLast      []int64
 	)
 
+	pflag.Uint16VarP(&flagAdmin, "admin-port", "a", 8081, "port to host admin API on")
+	pflag.Uint64VarP(&flagCache, "cache-size", "c", 100_000, "maximum number of resolved block and transaction hashes to cache")
+	pflag.DurationVarP(&flagInterval, "health-interval", "i", 10*time.Second, "interval between health checks of the Rosetta API servers")
 	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVarP(&flagMetrics, "metrics", "m", "", "address on which to expose metrics (no metrics are exposed when left empty)")
+	pflag.StringVarP(&flagNetwork, "network", "n", "flow-mainnet", "Flow network name used for health checks")
 	pflag.Uint16VarP(&flagPort, "port", "p", 8080, "port to host Rosetta API on")
+	pflag.StringVarP(&flagSporks, "sporks", "s", "", "path to JSON or YAML spork manifest (takes precedence over the other spork flags)")
+	pflag.DurationVarP(&flagTimeout, "health-timeout", "t", 5*time.Second, "timeout for health checks of the Rosetta API servers")
 
 	pflag.StringSliceVar(&flagSporkAddresses, "spork-addresses", nil, "comma-separated list of past sporks Rosetta API servers")
 	pflag.Int64SliceVar(&flagSporkFirsts, "spork-firsts", nil, "comma-separated list of past sporks first supported block height, corresponding to spork addresses")
@@ -236,24 +195,110 @@ func run() int {
 	log = log.Level(level)
 	elog := lecho.From(log)
 
-	sporkList, err := NewSporkList(flagSporkAddresses, flagSporkFirsts, flagSporkLast)
+	// The sporks are loaded from the manifest if one is given, and otherwise
+	// from the individual spork command line flags.
+	var registry *spork.Registry
+	if flagSporks != "" {
+		registry, err = spork.FromFile(flagSporks)
+	} else {
+		registry, err = RegistryFromFlags(flagSporkAddresses, flagSporkFirsts, flagSporkLast)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("could not load spork registry")
+		return failure
+	}
+	sporkList, err := NewSporkList(registry)
 	if err != nil {
 		log.Error().Err(err).Msg("spork list configuration error")
 		return failure
 	}
 
+	// The balancer routes requests to the healthy targets of each spork, and the
+	// admin server exposes the current state of those targets.
+	balancer, err := NewFlowHeightAwareBalancer(log, sporkList, flagNetwork, flagTimeout, flagCache)
+	if err != nil {
+		log.Error().Err(err).Msg("could not initialize balancer")
+		return failure
+	}
+
 	server := echo.New()
 	server.HideBanner = true
 	server.HidePort = true
 	server.Logger = elog
 	server.Use(lecho.Middleware(lecho.Config{Logger: elog}))
-	server.Use(middleware.Proxy(NewFlowHeightAwareBalancer(sporkList)))
+	metricsEnabled := flagMetrics != ""
+	if metricsEnabled {
+		server.Use(NewMetrics().Middleware)
+	}
+	server.Use(balancer.Middleware)
+	server.Use(middleware.Proxy(balancer))
+
+	var msvr *metrics.Server
+	if metricsEnabled {
+		msvr = metrics.NewServer(log, flagMetrics)
+	}
+
+	admin := echo.New()
+	admin.HideBanner = true
+	admin.HidePort = true
+	admin.Logger = elog
+	admin.GET("/targets", balancer.Handler)
 
 	// This section launches the main executing components in their own
 	// goroutine, so they can run concurrently. Afterwards, we wait for an
 	// interrupt signal in order to proceed with the next section.
+	monitor, stop := context.WithCancel(context.Background())
+	defer stop()
 	done := make(chan struct{})
 	failed := make(chan struct{})
+	go func() {
+		log.Info().Msg("Rosetta Dispatcher health monitor starting")
+		balancer.Monitor(monitor, flagInterval)
+		log.Info().Msg("Rosetta Dispatcher health monitor stopped")
+	}()
+	if flagSporks == "" {
+		log.Info().Msg("spork manifest reloading on SIGHUP disabled, as sporks were not given in a manifest")
+	}
+	go func() {
+		for range reload {
+			if flagSporks == "" {
+				log.Warn().Msg("no spork manifest to reload")
+				continue
+			}
+			registry, err := spork.FromFile(flagSporks)
+			if err != nil {
+				log.Error().Str("sporks", flagSporks).Err(err).Msg("could not reload spork manifest")
+				continue
+			}
+			sporkList, err := NewSporkList(registry)
+			if err != nil {
+				log.Error().Err(err).Msg("spork list configuration error")
+				continue
+			}
+			balancer.Update(sporkList)
+			log.Info().Int("sporks", len(sporkList)).Msg("spork manifest reloaded")
+		}
+	}()
+	go func() {
+		if !metricsEnabled {
+			return
+		}
+
+		log.Info().Msg("metrics server starting")
+		err := msvr.Start()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Warn().Err(err).Msg("metrics server failed")
+		}
+		log.Info().Msg("metrics server stopped")
+	}()
+	go func() {
+		log.Info().Msg("Rosetta Dispatcher admin server starting")
+		err := admin.Start(fmt.Sprint(":", flagAdmin))
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Warn().Err(err).Msg("Rosetta Dispatcher admin server failed")
+		}
+		log.Info().Msg("Rosetta Dispatcher admin server stopped")
+	}()
 	go func() {
 		log.Info().Msg("Rosetta Dispatcher Server starting")
 		err := server.Start(fmt.Sprint(":", flagPort))
@@ -285,13 +330,26 @@ func run() int {
 	// sure that the main executing components are shutting down within the
 	// allocated shutdown time. Otherwise, we will force the shutdown and log
 	// an error. We then wait for shutdown on each component to complete.
+	stop()
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
+	err = admin.Shutdown(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("could not shut down Rosetta Dispatcher admin server")
+		return failure
+	}
 	err = server.Shutdown(ctx)
 	if err != nil {
 		log.Error().Err(err).Msg("could not shut down Rosetta Dispatcher Server")
 		return failure
 	}
+	if metricsEnabled {
+		err = msvr.Shutdown(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("could not shut down metrics server")
+			return failure
+		}
+	}
 
 	return success
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics records the requests forwarded to each proxy target and exposes them
+// as prometheus metrics.
+type Metrics struct {
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewMetrics creates the prometheus metrics for the proxy targets.
+func NewMetrics() *Metrics {
+	labels := []string{"spork", "target"}
+
+	requestOpts := prometheus.CounterOpts{
+		Name: "dispatcher_requests",
+		Help: "number of requests forwarded to each target",
+	}
+	requests := promauto.NewCounterVec(requestOpts, append(labels, "code"))
+
+	errorOpts := prometheus.CounterOpts{
+		Name: "dispatcher_errors",
+		Help: "number of requests forwarded to each target that failed",
+	}
+	failures := promauto.NewCounterVec(errorOpts, labels)
+
+	durationOpts := prometheus.HistogramOpts{
+		Name:    "dispatcher_request_duration_seconds",
+		Help:    "duration of requests forwarded to each target",
+		Buckets: prometheus.DefBuckets,
+	}
+	duration := promauto.NewHistogramVec(durationOpts, labels)
+
+	m := Metrics{
+		requests: requests,
+		errors:   failures,
+		duration: duration,
+	}
+
+	return &m
+}
+
+// Middleware records the metrics for each request, based on the target that
+// the proxy middleware forwarded it to. It must be registered before the proxy
+// middleware.
+func (m *Metrics) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(e echo.Context) error {
+		start := time.Now()
+		err := next(e)
+
+		proxy, ok := e.Get("target").(*middleware.ProxyTarget)
+		if !ok || proxy == nil {
+			return err
+		}
+		spork, _ := proxy.Meta[MetaSpork].(string)
+
+		// When the proxy fails, the error has not been written to the response
+		// yet, so we need to get the status code from the error itself.
+		code := e.Response().Status
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			code = httpErr.Code
+		} else if err != nil {
+			code = http.StatusInternalServerError
+		}
+
+		m.requests.WithLabelValues(spork, proxy.Name, strconv.Itoa(code)).Inc()
+		m.duration.WithLabelValues(spork, proxy.Name).Observe(time.Since(start).Seconds())
+		if code >= http.StatusInternalServerError {
+			m.errors.WithLabelValues(spork, proxy.Name).Inc()
+		}
+
+		return err
+	}
+}
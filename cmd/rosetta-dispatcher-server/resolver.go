@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// unknownTTL is how long hashes which could not be resolved on any spork are
+// remembered, so that repeated requests for them do not query every spork.
+const unknownTTL = time.Minute
+
+// locateBlock returns the name of the spork holding the block with the given
+// hash. Resolved hashes are cached, so that only the first request for a given
+// block needs to query the backends.
+func (f *FlowHeightAwareBalancer) locateBlock(ctx context.Context, hash string) (string, bool) {
+	return f.locate(ctx, "block:"+hash, func(ctx context.Context, proxy *middleware.ProxyTarget) error {
+
+		request := struct {
+			NetworkID NetworkIdentifier `json:"network_identifier"`
+			BlockID   BlockIdentifier   `json:"block_identifier"`
+		}{
+			NetworkID: f.networkID(),
+			BlockID:   BlockIdentifier{Hash: hash},
+		}
+		var response struct {
+			Block *struct{} `json:"block"`
+		}
+		err := f.post(ctx, proxy, "/block", request, &response)
+		if err != nil {
+			return fmt.Errorf("could not look up block: %w", err)
+		}
+		if response.Block == nil {
+			return fmt.Errorf("block not found")
+		}
+
+		return nil
+	})
+}
+
+// locateTransaction returns the name of the spork holding the transaction
+// with the given hash. Resolved hashes are cached, so that only the first
+// request for a given transaction needs to query the backends.
+func (f *FlowHeightAwareBalancer) locateTransaction(ctx context.Context, hash string) (string, bool) {
+	return f.locate(ctx, "transaction:"+hash, func(ctx context.Context, proxy *middleware.ProxyTarget) error {
+
+		request := struct {
+			NetworkID     NetworkIdentifier     `json:"network_identifier"`
+			TransactionID TransactionIdentifier `json:"transaction_identifier"`
+		}{
+			NetworkID:     f.networkID(),
+			TransactionID: TransactionIdentifier{Hash: hash},
+		}
+		var response struct {
+			TotalCount uint64 `json:"total_count"`
+		}
+		err := f.post(ctx, proxy, "/search/transactions", request, &response)
+		if err != nil {
+			return fmt.Errorf("could not search transaction: %w", err)
+		}
+		if response.TotalCount == 0 {
+			return fmt.Errorf("transaction not found")
+		}
+
+		return nil
+	})
+}
+
+// locate returns the name of the spork for the given cache key. On a cache
+// miss, it executes the given lookup on one target of each spork, starting
+// with the most recent one, and caches the first spork for which it succeeds.
+// Keys which can not be resolved on any spork are cached for a short time.
+func (f *FlowHeightAwareBalancer) locate(ctx context.Context, key string, lookup func(context.Context, *middleware.ProxyTarget) error) (string, bool) {
+
+	cached, ok := f.hashes.Get(key)
+	if ok {
+		name := cached.(string)
+		return name, name != ""
+	}
+
+	for _, resolver := range f.resolvers() {
+		err := lookup(ctx, resolver.proxy)
+		if err != nil {
+			f.log.Debug().Str("key", key).Str("spork", resolver.state.Spork).Err(err).Msg("could not resolve hash on spork")
+			continue
+		}
+		f.hashes.Set(key, resolver.state.Spork, 1)
+		return resolver.state.Spork, true
+	}
+
+	f.log.Warn().Str("key", key).Msg("could not resolve hash on any spork")
+	f.hashes.SetWithTTL(key, "", 1, unknownTTL)
+
+	return "", false
+}
+
+// resolvers returns the targets used to resolve hashes, which are the first
+// healthy target of each spork, starting with the most recent spork.
+func (f *FlowHeightAwareBalancer) resolvers() []target {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	var resolvers []target
+	for index := len(f.targets) - 1; index >= 0; index-- {
+		for _, t := range f.targets[index] {
+			if t.state.Healthy {
+				resolvers = append(resolvers, *t)
+				break
+			}
+		}
+	}
+
+	return resolvers
+}
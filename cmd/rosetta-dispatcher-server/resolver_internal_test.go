@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowHeightAwareBalancer_locateBlock(t *testing.T) {
+
+	t.Run("resolves hash on cache miss", func(t *testing.T) {
+		t.Parallel()
+
+		past, pastLookups := testRosetta(t, 100, []string{"block"}, nil)
+		latest, latestLookups := testRosetta(t, 200, nil, nil)
+		balancer := testBalancer(t, testSporks(t, []string{past.URL}, []string{latest.URL}))
+
+		name, ok := balancer.locateBlock(context.Background(), "block")
+
+		require.True(t, ok)
+		assert.Equal(t, "spork1", name)
+		assert.Equal(t, int64(1), atomic.LoadInt64(latestLookups))
+		assert.Equal(t, int64(1), atomic.LoadInt64(pastLookups))
+	})
+
+	t.Run("uses cache on hit", func(t *testing.T) {
+		t.Parallel()
+
+		past, pastLookups := testRosetta(t, 100, []string{"block"}, nil)
+		latest, latestLookups := testRosetta(t, 200, nil, nil)
+		balancer := testBalancer(t, testSporks(t, []string{past.URL}, []string{latest.URL}))
+
+		_, ok := balancer.locateBlock(context.Background(), "block")
+		require.True(t, ok)
+		balancer.hashes.Wait()
+
+		name, ok := balancer.locateBlock(context.Background(), "block")
+
+		require.True(t, ok)
+		assert.Equal(t, "spork1", name)
+		assert.Equal(t, int64(1), atomic.LoadInt64(latestLookups))
+		assert.Equal(t, int64(1), atomic.LoadInt64(pastLookups))
+
+		// Requests which only identify the block by hash are routed to the
+		// spork holding it.
+		proxy, err := balancer.NextTarget(testContext(`{"block_identifier":{"hash":"block"}}`))
+		require.NoError(t, err)
+		assert.Equal(t, past.URL, proxy.Name)
+		assert.Equal(t, int64(1), atomic.LoadInt64(pastLookups))
+	})
+
+	t.Run("handles unknown hash", func(t *testing.T) {
+		t.Parallel()
+
+		past, pastLookups := testRosetta(t, 100, nil, nil)
+		latest, latestLookups := testRosetta(t, 200, nil, nil)
+		balancer := testBalancer(t, testSporks(t, []string{past.URL}, []string{latest.URL}))
+
+		name, ok := balancer.locateBlock(context.Background(), "unknown")
+
+		assert.False(t, ok)
+		assert.Empty(t, name)
+		assert.Equal(t, int64(1), atomic.LoadInt64(latestLookups))
+		assert.Equal(t, int64(1), atomic.LoadInt64(pastLookups))
+
+		// Unknown hashes are cached as well, and requests for them are routed
+		// to the latest spork.
+		balancer.hashes.Wait()
+		proxy, err := balancer.NextTarget(testContext(`{"block_identifier":{"hash":"unknown"}}`))
+		require.NoError(t, err)
+		assert.Equal(t, latest.URL, proxy.Name)
+		assert.Equal(t, int64(1), atomic.LoadInt64(latestLookups))
+		assert.Equal(t, int64(1), atomic.LoadInt64(pastLookups))
+	})
+
+	t.Run("skips unhealthy targets", func(t *testing.T) {
+		t.Parallel()
+
+		past, pastLookups := testRosetta(t, 100, []string{"block"}, nil)
+		latest, latestLookups := testRosetta(t, 200, []string{"block"}, nil)
+		balancer := testBalancer(t, testSporks(t, []string{past.URL}, []string{latest.URL}))
+		balancer.targets[1][0].state.Healthy = false
+
+		name, ok := balancer.locateBlock(context.Background(), "block")
+
+		require.True(t, ok)
+		assert.Equal(t, "spork1", name)
+		assert.Zero(t, atomic.LoadInt64(latestLookups))
+		assert.Equal(t, int64(1), atomic.LoadInt64(pastLookups))
+	})
+}
+
+func TestFlowHeightAwareBalancer_locateTransaction(t *testing.T) {
+
+	past, pastLookups := testRosetta(t, 100, nil, []string{"transaction"})
+	latest, latestLookups := testRosetta(t, 200, nil, nil)
+	balancer := testBalancer(t, testSporks(t, []string{past.URL}, []string{latest.URL}))
+
+	proxy, err := balancer.NextTarget(testContext(`{"transaction_identifier":{"hash":"transaction"}}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, past.URL, proxy.Name)
+	assert.Equal(t, int64(1), atomic.LoadInt64(latestLookups))
+	assert.Equal(t, int64(1), atomic.LoadInt64(pastLookups))
+
+	// Blocks and transactions are cached separately, even for equal hashes.
+	_, ok := balancer.locateBlock(context.Background(), "transaction")
+	assert.False(t, ok)
+}
@@ -16,18 +16,35 @@ package generator
 
 import (
 	"time"
+
+	"github.com/optakt/flow-dps/service/storage"
 )
 
 // DictionaryKind represents the resources that the dictionary is trained to compress efficiently.
 type DictionaryKind string
 
-// Supported dictionary kinds.
+// Supported dictionary kinds. They match the kinds of values of the codec.
 const (
 	KindPayloads     DictionaryKind = "payloads"
 	KindEvents       DictionaryKind = "events"
 	KindTransactions DictionaryKind = "transactions"
 )
 
+// Kinds are all of the dictionary kinds that can be generated.
+var Kinds = []DictionaryKind{
+	KindPayloads,
+	KindEvents,
+	KindTransactions,
+}
+
+// prefixes maps each dictionary kind to the storage prefix of the values that
+// are sampled to train it.
+var prefixes = map[DictionaryKind]uint8{
+	KindPayloads:     storage.PrefixPayload,
+	KindEvents:       storage.PrefixEvents,
+	KindTransactions: storage.PrefixTransaction,
+}
+
 func (k DictionaryKind) String() string {
 	return string(k)
 }
@@ -23,6 +23,7 @@ import (
 	"github.com/dgraph-io/badger/v2"
 	"github.com/rs/zerolog"
 
+	"github.com/optakt/flow-dps/codec/zbor"
 	"github.com/optakt/flow-dps/models/dps"
 	"github.com/optakt/flow-dps/service/storage"
 )
@@ -34,6 +35,10 @@ type Generator struct {
 	log   zerolog.Logger
 	db    *badger.DB
 	codec dps.Codec
+
+	// allocated is the last dictionary ID given to a compiled dictionary, as
+	// those are not registered with the codec until the next build.
+	allocated zbor.Dictionary
 }
 
 // New returns a new dictionary generator.
@@ -70,6 +75,30 @@ func (g *Generator) Dictionary(kind DictionaryKind) error {
 		Dur("compression_duration", baseline.duration).
 		Msg("benchmarked baseline compression")
 
+	best, err := g.optimize(kind)
+	if err != nil {
+		return fmt.Errorf("could not optimize dictionary: %w", err)
+	}
+
+	// Compile the dictionary into a proper Go file.
+	_, _, err = g.compile(best)
+	if err != nil {
+		return fmt.Errorf("could not compile dictionary into Go file: %w", err)
+	}
+
+	// Remove samples from the filesystem.
+	err = os.RemoveAll(g.cfg.SamplePath)
+	if err != nil {
+		return fmt.Errorf("could not clean up sample folder: %w", err)
+	}
+
+	return nil
+}
+
+// optimize generates increasingly bigger dictionaries of the given kind, and
+// returns the most optimized one.
+func (g *Generator) optimize(kind DictionaryKind) (*dictionary, error) {
+
 	// As long as the increase in compression ratio is considered tolerable, this loop
 	// generates increasingly bigger dictionaries, multiplying their size by a factor of
 	// two at each iteration. In each loop, dictionaries are generated and benchmarked.
@@ -81,21 +110,21 @@ func (g *Generator) Dictionary(kind DictionaryKind) error {
 		}
 
 		// Generate samples equal in size to 100 times the desired dictionary size.
-		err = g.generateSamples(kind, size*100)
+		err := g.generateSamples(kind, size*100)
 		if err != nil {
-			return fmt.Errorf("could not generate samples: %w", err)
+			return nil, fmt.Errorf("could not generate samples: %w", err)
 		}
 
 		// Train a dictionary using those samples.
 		dict, err := g.trainDictionary(kind, size)
 		if err != nil {
-			return fmt.Errorf("could not generate raw dictionary: %w", err)
+			return nil, fmt.Errorf("could not generate raw dictionary: %w", err)
 		}
 
 		// Benchmark the dictionary's compression ratio and duration.
 		err = g.benchmarkDictionary(dict)
 		if err != nil {
-			return fmt.Errorf("could not benchmark dictionary: %w", err)
+			return nil, fmt.Errorf("could not benchmark dictionary: %w", err)
 		}
 
 		current = dict
@@ -106,24 +135,13 @@ func (g *Generator) Dictionary(kind DictionaryKind) error {
 	best := previous
 
 	g.log.Info().
+		Str("kind", string(kind)).
 		Int("best_size", best.size).
 		Float64("best_ratio", best.ratio).
 		Dur("best_duration", best.duration).
 		Msg("found most optimized dictionary")
 
-	// Compile the dictionary into a proper Go file.
-	err = g.compile(best)
-	if err != nil {
-		return fmt.Errorf("could not compile dictionary into Go file: %w", err)
-	}
-
-	// Remove samples from the filesystem.
-	err = os.RemoveAll(g.cfg.SamplePath)
-	if err != nil {
-		return fmt.Errorf("could not clean up sample folder: %w", err)
-	}
-
-	return nil
+	return best, nil
 }
 
 // tolerateImprovement returns true if the improvement between current and previous is at least equal to the
@@ -171,16 +189,12 @@ func (g *Generator) generateSamples(kind DictionaryKind, size int) error {
 func (g *Generator) getSamples(kind DictionaryKind, size int) ([][]byte, error) {
 
 	// Create an iterator prefix based on the kind of sample we want.
-	var prefix []byte
-	switch kind {
-	case KindPayloads:
-		prefix = storage.EncodeKey(storage.PrefixPayload)
-	case KindTransactions:
-		prefix = storage.EncodeKey(storage.PrefixTransaction)
-	case KindEvents:
-		// TODO: Select an event type in the prefix. See https://github.com/optakt/flow-dps/issues/501
-		prefix = storage.EncodeKey(storage.PrefixEvents)
+	// TODO: Select an event type in the prefix for events. See https://github.com/optakt/flow-dps/issues/501
+	kindPrefix, ok := prefixes[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown dictionary kind (%s)", kind)
 	}
+	prefix := storage.EncodeKey(kindPrefix)
 
 	key := generateRandomKey(prefix)
 
@@ -193,11 +207,15 @@ func (g *Generator) getSamples(kind DictionaryKind, size int) ([][]byte, error)
 		defer it.Close()
 
 		it.Seek(key)
+		if !it.ValidForPrefix(prefix) {
+			it.Rewind()
+		}
+		if !it.ValidForPrefix(prefix) {
+			return fmt.Errorf("no values found for dictionary kind (%s)", kind)
+		}
 
 		var totalBytes int
 		for totalBytes <= size {
-			sampleKey := it.Item().Key()
-
 			// If we're out of entries to read from, reset the iterator.
 			// This will result in duplicate entries in the samples, but should not be a big deal.
 			if !it.ValidForPrefix(prefix) {
@@ -207,12 +225,16 @@ func (g *Generator) getSamples(kind DictionaryKind, size int) ([][]byte, error)
 				it.Seek(key)
 			}
 
+			sampleKey := it.Item().Key()
+
 			// Retrieve the value of the sample.
 			val, err := tx.Get(sampleKey)
 			if err != nil {
 				return fmt.Errorf("could not get value from key %x: %w", sampleKey, err)
 			}
 
+			// Values written by older versions of the codec have no header
+			// byte, but are decompressed with the dictionary of their frame.
 			err = val.Value(func(val []byte) error {
 				value, err := g.codec.Decompress(val)
 				if err != nil {
@@ -0,0 +1,70 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package generator
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/ledger"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/service/storage"
+	"github.com/optakt/flow-dps/testing/helpers"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestGenerator_getSamples(t *testing.T) {
+	codec := zbor.NewCodec()
+
+	db := helpers.InMemoryDB(t)
+	defer db.Close()
+
+	// The index contains both values written by the current codec and values
+	// written by older versions, which have no header byte.
+	current, err := codec.Marshal(mocks.GenericLedgerPayload(0))
+	require.NoError(t, err)
+	legacy, err := codec.Encode(mocks.GenericLedgerPayload(1))
+	require.NoError(t, err)
+	_, raw := zbor.Current(zbor.KindPayloads)
+	compressor, err := zstd.NewWriter(nil, zstd.WithEncoderDict(raw))
+	require.NoError(t, err)
+
+	err = db.Update(func(tx *badger.Txn) error {
+		err := tx.Set(storage.EncodeKey(storage.PrefixPayload, mocks.GenericLedgerPath(0), uint64(1)), current)
+		if err != nil {
+			return err
+		}
+		return tx.Set(storage.EncodeKey(storage.PrefixPayload, mocks.GenericLedgerPath(1), uint64(1)), compressor.EncodeAll(legacy, nil))
+	})
+	require.NoError(t, err)
+
+	generator := New(zerolog.Nop(), db, codec)
+	samples, err := generator.getSamples(KindPayloads, 2*len(legacy))
+
+	require.NoError(t, err)
+	require.Len(t, samples, 3)
+	assert.Contains(t, samples, legacy)
+	for _, sample := range samples {
+		var payload ledger.Payload
+		err = codec.Decode(sample, &payload)
+		assert.NoError(t, err)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package generator
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+)
+
+// Report is the outcome of retraining the dictionary of one kind of values.
+type Report struct {
+	Kind           DictionaryKind `json:"kind"`
+	Current        string         `json:"current"`       // name of the current dictionary
+	CurrentRatio   float64        `json:"current_ratio"` // compression ratio of the current dictionary
+	CandidateSize  int            `json:"candidate_size"`
+	CandidateRatio float64        `json:"candidate_ratio"`
+	Improvement    float64        `json:"improvement"` // relative decrease of the compression ratio
+	Tolerance      float64        `json:"tolerance"`
+	Emitted        bool           `json:"emitted"`              // whether the candidate was compiled
+	Dictionary     uint8          `json:"dictionary,omitempty"` // ID of the emitted dictionary
+	File           string         `json:"file,omitempty"`       // path of the emitted Go file
+}
+
+// Retrain generates an optimized dictionary of the given kind from the index,
+// and compares it against the dictionary currently used by the codec for that
+// kind of values. The new dictionary is only compiled into a Go file when its
+// compression ratio improves on the current one by at least the configured
+// tolerance.
+func (g *Generator) Retrain(kind DictionaryKind) (*Report, error) {
+
+	// Benchmark the current dictionary on samples from the index, so that we
+	// can compare it with the newly trained one.
+	id, raw := zbor.Current(zbor.Kind(kind))
+	current := dictionary{
+		kind: kind,
+		raw:  raw,
+		size: len(raw),
+	}
+	err := g.benchmarkDictionary(&current)
+	if err != nil {
+		return nil, fmt.Errorf("could not benchmark current dictionary: %w", err)
+	}
+
+	g.log.Info().
+		Str("kind", string(kind)).
+		Str("current", id.String()).
+		Float64("compression_ratio", current.ratio).
+		Dur("compression_duration", current.duration).
+		Msg("benchmarked current dictionary")
+
+	candidate, err := g.optimize(kind)
+	if err != nil {
+		return nil, fmt.Errorf("could not optimize dictionary: %w", err)
+	}
+
+	report := Report{
+		Kind:           kind,
+		Current:        id.String(),
+		CurrentRatio:   current.ratio,
+		CandidateSize:  candidate.size,
+		CandidateRatio: candidate.ratio,
+		Improvement:    1 - candidate.ratio/current.ratio,
+		Tolerance:      g.cfg.RatioImprovements,
+	}
+
+	if report.Improvement >= g.cfg.RatioImprovements {
+		dictionary, path, err := g.compile(candidate)
+		if err != nil {
+			return nil, fmt.Errorf("could not compile dictionary into Go file: %w", err)
+		}
+		report.Emitted = true
+		report.Dictionary = uint8(dictionary)
+		report.File = path
+	}
+
+	g.log.Info().
+		Str("kind", string(kind)).
+		Float64("improvement", report.Improvement).
+		Bool("emitted", report.Emitted).
+		Msg("retrained dictionary")
+
+	// Remove samples from the filesystem.
+	err = os.RemoveAll(g.cfg.SamplePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not clean up sample folder: %w", err)
+	}
+
+	return &report, nil
+}
@@ -19,6 +19,8 @@ import (
 	"os"
 	"path/filepath"
 	"text/template"
+
+	"github.com/optakt/flow-dps/codec/zbor"
 )
 
 const dictionaryTemplate = `// Copyright 2021 Optakt Labs OÜ
@@ -37,39 +39,66 @@ const dictionaryTemplate = `// Copyright 2021 Optakt Labs OÜ
 
 package zbor
 
-// {{ .Name }}Dictionary is a byte slice that contains the result of running the Zstandard training mode
-// on the {{ .Name }} of the DPS index. This allows zstandard to achieve a better compression ratio, specifically for
+// {{ .Name }} is a byte slice that contains the result of running the Zstandard training mode
+// on the {{ .Kind }} of the DPS index. This allows zstandard to achieve a better compression ratio, specifically for
 // small data.
 // See http://facebook.github.io/zstd/#small-data
 // See https://github.com/facebook/zstd/blob/master/doc/zstd_compression_format.md#dictionary-format
-var {{ .Name }}Dictionary = []byte{
+var {{ .Name }} = []byte{
 	{{ range .Bytes }}{{ . }}, {{ end }}
 }
+
+func init() {
+	register({{ .ID }}, "{{ .Kind }}", {{ .Name }})
+}
 `
 
 type templateData struct {
 	Name  string
+	Kind  string
+	ID    zbor.Dictionary
 	Bytes []byte
 }
 
-// compile takes a raw dictionary and uses a template to compile it into a Go file.
-func (g *Generator) compile(dict *dictionary) error {
+// compile takes a raw dictionary and uses a template to compile it into a Go file. The
+// dictionary is registered with the next free dictionary ID of the codec, so that values
+// compressed with previous dictionaries can still be decoded. It returns the ID and the
+// path of the Go file.
+func (g *Generator) compile(dict *dictionary) (zbor.Dictionary, string, error) {
+
+	id, err := zbor.Next()
+	if err != nil {
+		return 0, "", fmt.Errorf("could not get dictionary ID: %w", err)
+	}
+	if id <= g.allocated {
+		id = g.allocated + 1
+	}
+	if id > zbor.MaxDictionary {
+		return 0, "", fmt.Errorf("no free dictionary ID left (max: %d)", zbor.MaxDictionary)
+	}
+	g.allocated = id
 
 	// Create dictionary file.
-	filename := dict.kind.String() + ".go"
+	filename := fmt.Sprintf("%s_%d.go", dict.kind, id)
 	path := filepath.Join(g.cfg.DictionaryPath, filename)
 	file, err := os.Create(path)
 	if err != nil {
-		return fmt.Errorf("could not open dictionary file: %w", err)
+		return 0, "", fmt.Errorf("could not open dictionary file: %w", err)
 	}
 	defer file.Close()
 
 	// Execute template using the dictionary file as the writer.
+	data := templateData{
+		Name:  fmt.Sprintf("%sDictionary%d", dict.kind, id),
+		Kind:  dict.kind.String(),
+		ID:    id,
+		Bytes: dict.raw,
+	}
 	t := template.Must(template.New("").Parse(dictionaryTemplate))
-	err = t.Execute(file, templateData{Name: dict.kind.String(), Bytes: dict.raw})
+	err = t.Execute(file, data)
 	if err != nil {
-		return fmt.Errorf("could not execute dictionary template: %w", err)
+		return 0, "", fmt.Errorf("could not execute dictionary template: %w", err)
 	}
 
-	return nil
+	return id, path, nil
 }
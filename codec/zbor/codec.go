@@ -24,19 +24,27 @@ import (
 	"github.com/onflow/flow-go/model/flow"
 )
 
+// Version is the version of the format of the values encoded by the codec,
+// which is stored in the header byte of each value. It needs to be incremented
+// whenever a change to the format prevents values from being decoded by
+// previous versions of the codec. Values encoded by previous versions can still
+// be decoded.
+const Version = 2
+
 // Codec encodes and decodes Go values using cbor encoding and zstandard compression.
 type Codec struct {
 	encoder cbor.EncMode
 	decoder cbor.DecMode
 
-	compressor              *zstd.Encoder
-	decompressor            *zstd.Decoder
-	payloadCompressor       *zstd.Encoder
-	payloadDecompressor     *zstd.Decoder
-	eventCompressor         *zstd.Encoder
-	eventDecompressor       *zstd.Decoder
-	transactionCompressor   *zstd.Encoder
-	transactionDecompressor *zstd.Decoder
+	compressors   map[Dictionary]*zstd.Encoder
+	decompressors map[Dictionary]*zstd.Decoder
+
+	// The legacy decompressors decode values without header byte, by trying
+	// all of the dictionaries that could have been used for the type of value.
+	legacyDecompressor            *zstd.Decoder
+	legacyPayloadDecompressor     *zstd.Decoder
+	legacyEventDecompressor       *zstd.Decoder
+	legacyTransactionDecompressor *zstd.Decoder
 }
 
 // NewCodec creates a new Codec.
@@ -59,14 +67,36 @@ func NewCodec() *Codec {
 		panic(err)
 	}
 
-	compressor, err := zstd.NewWriter(nil,
-		zstd.WithEncoderLevel(zstd.SpeedDefault),
-		zstd.WithEncoderDict(genericDictionary),
-	)
-	if err != nil {
-		panic(err)
+	// Each dictionary gets its own decompressor, so that values are always
+	// decompressed with the dictionary identified by their header. Only the
+	// current dictionary of each kind of values is used for compression.
+	compressors := make(map[Dictionary]*zstd.Encoder)
+	for _, dictionary := range current {
+		_, ok := compressors[dictionary]
+		if ok {
+			continue
+		}
+		compressor, err := zstd.NewWriter(nil,
+			zstd.WithEncoderLevel(zstd.SpeedDefault),
+			zstd.WithEncoderDict(dictionaries[dictionary].raw),
+		)
+		if err != nil {
+			panic(err)
+		}
+		compressors[dictionary] = compressor
+	}
+	decompressors := make(map[Dictionary]*zstd.Decoder)
+	for dictionary, entry := range dictionaries {
+		decompressor, err := zstd.NewReader(nil,
+			zstd.WithDecoderDicts(entry.raw),
+		)
+		if err != nil {
+			panic(err)
+		}
+		decompressors[dictionary] = decompressor
 	}
-	decompressor, err := zstd.NewReader(nil,
+
+	legacyDecompressor, err := zstd.NewReader(nil,
 		zstd.WithDecoderDicts(
 			genericDictionary,
 			legacyGenericDictionary,
@@ -76,15 +106,7 @@ func NewCodec() *Codec {
 	if err != nil {
 		panic(err)
 	}
-
-	payloadCompressor, err := zstd.NewWriter(nil,
-		zstd.WithEncoderLevel(zstd.SpeedDefault),
-		zstd.WithEncoderDict(payloadDictionary),
-	)
-	if err != nil {
-		panic(err)
-	}
-	payloadDecompressor, err := zstd.NewReader(nil,
+	legacyPayloadDecompressor, err := zstd.NewReader(nil,
 		zstd.WithDecoderDicts(
 			payloadDictionary,
 			legacyPayloadDictionary,
@@ -93,15 +115,7 @@ func NewCodec() *Codec {
 	if err != nil {
 		panic(err)
 	}
-
-	eventCompressor, err := zstd.NewWriter(nil,
-		zstd.WithEncoderLevel(zstd.SpeedDefault),
-		zstd.WithEncoderDict(eventDictionary),
-	)
-	if err != nil {
-		panic(err)
-	}
-	eventDecompressor, err := zstd.NewReader(nil,
+	legacyEventDecompressor, err := zstd.NewReader(nil,
 		zstd.WithDecoderDicts(
 			eventDictionary,
 			legacyEventDictionary,
@@ -110,15 +124,7 @@ func NewCodec() *Codec {
 	if err != nil {
 		panic(err)
 	}
-
-	transactionCompressor, err := zstd.NewWriter(nil,
-		zstd.WithEncoderLevel(zstd.SpeedDefault),
-		zstd.WithEncoderDict(transactionDictionary),
-	)
-	if err != nil {
-		panic(err)
-	}
-	transactionDecompressor, err := zstd.NewReader(nil,
+	legacyTransactionDecompressor, err := zstd.NewReader(nil,
 		zstd.WithDecoderDicts(transactionDictionary),
 	)
 	if err != nil {
@@ -129,14 +135,13 @@ func NewCodec() *Codec {
 		encoder: encoder,
 		decoder: decoder,
 
-		compressor:              compressor,
-		decompressor:            decompressor,
-		payloadCompressor:       payloadCompressor,
-		payloadDecompressor:     payloadDecompressor,
-		eventCompressor:         eventCompressor,
-		eventDecompressor:       eventDecompressor,
-		transactionCompressor:   transactionCompressor,
-		transactionDecompressor: transactionDecompressor,
+		compressors:   compressors,
+		decompressors: decompressors,
+
+		legacyDecompressor:            legacyDecompressor,
+		legacyPayloadDecompressor:     legacyPayloadDecompressor,
+		legacyEventDecompressor:       legacyEventDecompressor,
+		legacyTransactionDecompressor: legacyTransactionDecompressor,
 	}
 
 	return &c
@@ -147,10 +152,10 @@ func (c *Codec) Encode(value interface{}) ([]byte, error) {
 	return c.encoder.Marshal(value)
 }
 
-// Compress encodes the given bytes into a compressed format using zstandard.
+// Compress encodes the given bytes into a compressed format using zstandard
+// and the generic dictionary, prefixed with the header byte.
 func (c *Codec) Compress(data []byte) ([]byte, error) {
-	compressed := c.compressor.EncodeAll(data, nil)
-	return compressed, nil
+	return c.compress(DictionaryGeneric, data), nil
 }
 
 // Marshal encodes the given value and then compresses it, and returns the resulting slice of bytes.
@@ -160,17 +165,7 @@ func (c *Codec) Marshal(value interface{}) ([]byte, error) {
 		return nil, fmt.Errorf("could not encode value: %w", err)
 	}
 
-	var compressed []byte
-	switch value.(type) {
-	case *ledger.Payload:
-		compressed = c.payloadCompressor.EncodeAll(data, nil)
-	case []flow.Event:
-		compressed = c.eventCompressor.EncodeAll(data, nil)
-	case *flow.TransactionBody:
-		compressed = c.transactionCompressor.EncodeAll(data, nil)
-	default:
-		compressed = c.compressor.EncodeAll(data, nil)
-	}
+	compressed := c.compress(current[kindOf(value)], data)
 
 	return compressed, nil
 }
@@ -181,10 +176,13 @@ func (c *Codec) Decode(data []byte, value interface{}) error {
 }
 
 // Decompress reads compressed data that uses the zstandard format and returns the original
-// uncompressed byte slice.
+// uncompressed byte slice. The dictionary is identified by the header byte; for values without
+// header, it is identified by the dictionary ID of their Zstandard frame instead.
 func (c *Codec) Decompress(compressed []byte) ([]byte, error) {
-	data, err := c.decompressor.DecodeAll(compressed, nil)
-	return data, err
+	if legacy(compressed) {
+		return c.decompressLegacy(compressed, nil)
+	}
+	return c.decompress(compressed)
 }
 
 // Unmarshal decompresses the given bytes and decodes the resulting CBOR-encoded data into
@@ -193,15 +191,10 @@ func (c *Codec) Unmarshal(compressed []byte, value interface{}) error {
 
 	var data []byte
 	var err error
-	switch value.(type) {
-	case *ledger.Payload:
-		data, err = c.payloadDecompressor.DecodeAll(compressed, nil)
-	case *[]flow.Event:
-		data, err = c.eventDecompressor.DecodeAll(compressed, nil)
-	case *flow.TransactionBody:
-		data, err = c.transactionDecompressor.DecodeAll(compressed, nil)
-	default:
-		data, err = c.decompressor.DecodeAll(compressed, nil)
+	if legacy(compressed) {
+		data, err = c.decompressLegacy(compressed, value)
+	} else {
+		data, err = c.decompress(compressed)
 	}
 	if err != nil {
 		return fmt.Errorf("could not decompress value: %w", err)
@@ -213,3 +206,57 @@ func (c *Codec) Unmarshal(compressed []byte, value interface{}) error {
 	}
 	return nil
 }
+
+// kindOf returns the kind of the given value, which determines the dictionary
+// used to compress it.
+func kindOf(value interface{}) Kind {
+	switch value.(type) {
+	case *ledger.Payload:
+		return KindPayloads
+	case []flow.Event:
+		return KindEvents
+	case *flow.TransactionBody:
+		return KindTransactions
+	default:
+		return KindGeneric
+	}
+}
+
+func (c *Codec) compress(dictionary Dictionary, data []byte) []byte {
+	compressed := make([]byte, 1, len(data)+1)
+	compressed[0] = header(dictionary)
+	return c.compressors[dictionary].EncodeAll(data, compressed)
+}
+
+func (c *Codec) decompress(compressed []byte) ([]byte, error) {
+	_, dictionary, frame, err := parseHeader(compressed, Version)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse header: %w", err)
+	}
+	return c.decompressors[dictionary].DecodeAll(frame, nil)
+}
+
+// decompressLegacy decompresses a value without header byte. The dictionary is
+// resolved from the Zstandard frame header, like `Inspect` does; only frames
+// which do not identify a known dictionary fall back to trying all of the
+// dictionaries that could have been used for the type of the value.
+func (c *Codec) decompressLegacy(compressed []byte, value interface{}) ([]byte, error) {
+	var frame zstd.Header
+	err := frame.Decode(compressed)
+	if err == nil {
+		dictionary, ok := frameDictionary(frame.DictionaryID)
+		if ok {
+			return c.decompressors[dictionary].DecodeAll(compressed, nil)
+		}
+	}
+	switch value.(type) {
+	case *ledger.Payload:
+		return c.legacyPayloadDecompressor.DecodeAll(compressed, nil)
+	case *[]flow.Event:
+		return c.legacyEventDecompressor.DecodeAll(compressed, nil)
+	case *flow.TransactionBody:
+		return c.legacyTransactionDecompressor.DecodeAll(compressed, nil)
+	default:
+		return c.legacyDecompressor.DecodeAll(compressed, nil)
+	}
+}
@@ -0,0 +1,342 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package zbor
+
+import (
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestCodec_Marshal(t *testing.T) {
+	codec := NewCodec()
+
+	tests := []struct {
+		name string
+
+		value  interface{}
+		target interface{}
+
+		wantDictionary Dictionary
+	}{
+		{
+			name:           "generic value",
+			value:          mocks.GenericHeader,
+			target:         &flow.Header{},
+			wantDictionary: DictionaryGeneric,
+		},
+		{
+			name:           "payload",
+			value:          mocks.GenericLedgerPayload(0),
+			target:         &ledger.Payload{},
+			wantDictionary: DictionaryPayloads,
+		},
+		{
+			name:           "events",
+			value:          mocks.GenericEvents(2),
+			target:         &[]flow.Event{},
+			wantDictionary: DictionaryEvents,
+		},
+		{
+			name:           "transaction",
+			value:          mocks.GenericTransaction(0),
+			target:         &flow.TransactionBody{},
+			wantDictionary: DictionaryTransactions,
+		},
+		{
+			name:           "collection",
+			value:          mocks.GenericCollection(0),
+			target:         &flow.LightCollection{},
+			wantDictionary: DictionaryGeneric,
+		},
+		{
+			name:           "result",
+			value:          mocks.GenericResult(0),
+			target:         &flow.TransactionResult{},
+			wantDictionary: DictionaryGeneric,
+		},
+		{
+			name:           "seal",
+			value:          mocks.GenericSeal(0),
+			target:         &flow.Seal{},
+			wantDictionary: DictionaryGeneric,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := codec.Marshal(test.value)
+			require.NoError(t, err)
+
+			version, dictionary, err := Inspect(data)
+			require.NoError(t, err)
+			assert.Equal(t, uint8(Version), version)
+			assert.Equal(t, test.wantDictionary, dictionary)
+
+			err = codec.Unmarshal(data, test.target)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCodec_Unmarshal(t *testing.T) {
+	codec := NewCodec()
+
+	t.Run("legacy value", func(t *testing.T) {
+		t.Parallel()
+
+		// Legacy values are compressed without header byte.
+		data, err := codec.Encode(mocks.GenericHeader)
+		require.NoError(t, err)
+		compressor, err := zstd.NewWriter(nil, zstd.WithEncoderDict(legacyHeaderDictionary))
+		require.NoError(t, err)
+		compressed := compressor.EncodeAll(data, nil)
+
+		version, dictionary, err := Inspect(compressed)
+		require.NoError(t, err)
+		assert.Equal(t, uint8(LegacyVersion), version)
+		assert.Equal(t, DictionaryLegacyHeaders, dictionary)
+
+		var header flow.Header
+		err = codec.Unmarshal(compressed, &header)
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeader.ID(), header.ID())
+	})
+
+	t.Run("handles unsupported version", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := codec.Marshal(mocks.GenericHeader)
+		require.NoError(t, err)
+		data[0] = (Version+1)<<versionShift | byte(DictionaryGeneric)
+
+		var header flow.Header
+		err = codec.Unmarshal(data, &header)
+		assert.Error(t, err)
+	})
+
+	t.Run("handles unknown dictionary", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := codec.Marshal(mocks.GenericHeader)
+		require.NoError(t, err)
+		data[0] = header(dictionaryMask)
+
+		var header flow.Header
+		err = codec.Unmarshal(data, &header)
+		assert.Error(t, err)
+	})
+
+	t.Run("handles empty value", func(t *testing.T) {
+		t.Parallel()
+
+		var header flow.Header
+		err := codec.Unmarshal(nil, &header)
+		assert.Error(t, err)
+	})
+}
+
+func TestCodec_Decompress(t *testing.T) {
+	codec := NewCodec()
+
+	// Values compressed with any dictionary can be decompressed without
+	// knowing their type.
+	data, err := codec.Encode(mocks.GenericLedgerPayload(0))
+	require.NoError(t, err)
+	compressed, err := codec.Marshal(mocks.GenericLedgerPayload(0))
+	require.NoError(t, err)
+
+	decompressed, err := codec.Decompress(compressed)
+
+	require.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+}
+
+func TestCodec_Legacy(t *testing.T) {
+	codec := NewCodec()
+
+	// Legacy values are compressed without header byte, with whichever
+	// dictionary was used for their type, so each of them has to be resolved
+	// from the dictionary ID of the Zstandard frame.
+	tests := []struct {
+		name       string
+		raw        []byte
+		value      interface{}
+		target     func() interface{}
+		dictionary Dictionary
+	}{
+		{
+			name:       "generic legacy dictionary",
+			raw:        legacyGenericDictionary,
+			value:      mocks.GenericHeader,
+			target:     func() interface{} { return &flow.Header{} },
+			dictionary: DictionaryLegacyGeneric,
+		},
+		{
+			name:       "header legacy dictionary",
+			raw:        legacyHeaderDictionary,
+			value:      mocks.GenericHeader,
+			target:     func() interface{} { return &flow.Header{} },
+			dictionary: DictionaryLegacyHeaders,
+		},
+		{
+			name:       "payload legacy dictionary",
+			raw:        legacyPayloadDictionary,
+			value:      mocks.GenericLedgerPayload(0),
+			target:     func() interface{} { return &ledger.Payload{} },
+			dictionary: DictionaryLegacyPayloads,
+		},
+		{
+			name:       "payload dictionary",
+			raw:        payloadDictionary,
+			value:      mocks.GenericLedgerPayload(0),
+			target:     func() interface{} { return &ledger.Payload{} },
+			dictionary: DictionaryPayloads,
+		},
+		{
+			name:       "event legacy dictionary",
+			raw:        legacyEventDictionary,
+			value:      mocks.GenericEvents(4),
+			target:     func() interface{} { return &[]flow.Event{} },
+			dictionary: DictionaryLegacyEvents,
+		},
+		{
+			name:       "event dictionary",
+			raw:        eventDictionary,
+			value:      mocks.GenericEvents(4),
+			target:     func() interface{} { return &[]flow.Event{} },
+			dictionary: DictionaryEvents,
+		},
+		{
+			name:       "transaction dictionary",
+			raw:        transactionDictionary,
+			value:      mocks.GenericTransaction(0),
+			target:     func() interface{} { return &flow.TransactionBody{} },
+			dictionary: DictionaryTransactions,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := codec.Encode(test.value)
+			require.NoError(t, err)
+			compressor, err := zstd.NewWriter(nil, zstd.WithEncoderDict(test.raw))
+			require.NoError(t, err)
+			compressed := compressor.EncodeAll(data, nil)
+
+			version, dictionary, err := Inspect(compressed)
+			require.NoError(t, err)
+			assert.Equal(t, uint8(LegacyVersion), version)
+			assert.Equal(t, test.dictionary, dictionary)
+
+			decompressed, err := codec.Decompress(compressed)
+			require.NoError(t, err)
+			assert.Equal(t, data, decompressed)
+
+			target := test.target()
+			err = codec.Unmarshal(compressed, target)
+			require.NoError(t, err)
+			reencoded, err := codec.Encode(target)
+			require.NoError(t, err)
+			assert.Equal(t, data, reencoded)
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	codec := NewCodec()
+
+	data, err := codec.Marshal(mocks.GenericHeader)
+	require.NoError(t, err)
+
+	version, dictionary, err := Inspect(data)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(Version), version)
+	assert.Equal(t, DictionaryGeneric, dictionary)
+
+	// Once the version is increased, values with a header of the previous
+	// version keep reporting the version they were encoded with.
+	version, dictionary, err = inspect(data, Version+1)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(Version), version)
+	assert.Equal(t, DictionaryGeneric, dictionary)
+
+	newer := append([]byte{(Version+1)<<versionShift | byte(DictionaryPayloads)}, data[1:]...)
+	version, dictionary, err = inspect(newer, Version+1)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(Version+1), version)
+	assert.Equal(t, DictionaryPayloads, dictionary)
+
+	_, _, err = Inspect(newer)
+	assert.Error(t, err)
+}
+
+func TestReadable(t *testing.T) {
+	// Values written by an older version of the codec with a header byte
+	// remain readable once the version is increased.
+	assert.True(t, readable(Version, Version))
+	assert.True(t, readable(Version, Version+1))
+	assert.False(t, readable(LegacyVersion, Version))
+	assert.False(t, readable(Version+1, Version))
+}
+
+func TestRegister(t *testing.T) {
+	// This test modifies the global dictionaries, so it restores them once
+	// done and must not run in parallel with other tests.
+	savedDictionaries := make(map[Dictionary]entry, len(dictionaries))
+	for dictionary, entry := range dictionaries {
+		savedDictionaries[dictionary] = entry
+	}
+	savedCurrent := make(map[Kind]Dictionary, len(current))
+	for kind, dictionary := range current {
+		savedCurrent[kind] = dictionary
+	}
+	defer func() {
+		dictionaries = savedDictionaries
+		current = savedCurrent
+	}()
+
+	next, err := Next()
+	require.NoError(t, err)
+	assert.Equal(t, DictionaryLegacyEvents+1, next)
+
+	raw := []byte("events dictionary")
+	register(next, KindEvents, raw)
+
+	dictionary, got := Current(KindEvents)
+	assert.Equal(t, next, dictionary)
+	assert.Equal(t, raw, got)
+	assert.Equal(t, "events_9", dictionary.String())
+
+	following, err := Next()
+	require.NoError(t, err)
+	assert.Equal(t, next+1, following)
+
+	assert.Panics(t, func() { register(next, KindEvents, raw) })
+	assert.Panics(t, func() { register(MaxDictionary+1, KindEvents, raw) })
+}
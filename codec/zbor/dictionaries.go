@@ -0,0 +1,154 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package zbor
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Dictionary identifies the Zstandard dictionary that was used to compress a
+// value. Once a dictionary has been used to encode values, its ID must never
+// be reused: a retrained dictionary is given a new ID, while the previous one
+// is kept so that existing values can still be decoded.
+type Dictionary uint8
+
+// Dictionaries that are part of the codec. The legacy dictionaries are no
+// longer used to encode values, but are still needed to decode values from
+// older indexes. Dictionaries generated by the dictionary generator register
+// themselves with the next free ID.
+const (
+	DictionaryGeneric        Dictionary = 1
+	DictionaryPayloads       Dictionary = 2
+	DictionaryEvents         Dictionary = 3
+	DictionaryTransactions   Dictionary = 4
+	DictionaryLegacyGeneric  Dictionary = 5
+	DictionaryLegacyHeaders  Dictionary = 6
+	DictionaryLegacyPayloads Dictionary = 7
+	DictionaryLegacyEvents   Dictionary = 8
+)
+
+// MaxDictionary is the highest dictionary ID that fits into the header byte.
+const MaxDictionary = dictionaryMask
+
+// Kind is the kind of values that a dictionary is trained to compress. The
+// generic kind is used for all values without a kind of their own.
+type Kind string
+
+// Supported kinds of values.
+const (
+	KindGeneric      Kind = "generic"
+	KindPayloads     Kind = "payloads"
+	KindEvents       Kind = "events"
+	KindTransactions Kind = "transactions"
+)
+
+type entry struct {
+	name string
+	kind Kind
+	raw  []byte
+}
+
+// dictionaries maps each dictionary ID to the raw dictionary.
+var dictionaries = map[Dictionary]entry{
+	DictionaryGeneric:        {name: "generic", kind: KindGeneric, raw: genericDictionary},
+	DictionaryPayloads:       {name: "payloads", kind: KindPayloads, raw: payloadDictionary},
+	DictionaryEvents:         {name: "events", kind: KindEvents, raw: eventDictionary},
+	DictionaryTransactions:   {name: "transactions", kind: KindTransactions, raw: transactionDictionary},
+	DictionaryLegacyGeneric:  {name: "legacy_generic", kind: KindGeneric, raw: legacyGenericDictionary},
+	DictionaryLegacyHeaders:  {name: "legacy_headers", kind: KindGeneric, raw: legacyHeaderDictionary},
+	DictionaryLegacyPayloads: {name: "legacy_payloads", kind: KindPayloads, raw: legacyPayloadDictionary},
+	DictionaryLegacyEvents:   {name: "legacy_events", kind: KindEvents, raw: legacyEventDictionary},
+}
+
+// current maps each kind of values to the dictionary used to compress them.
+var current = map[Kind]Dictionary{
+	KindGeneric:      DictionaryGeneric,
+	KindPayloads:     DictionaryPayloads,
+	KindEvents:       DictionaryEvents,
+	KindTransactions: DictionaryTransactions,
+}
+
+// register adds a generated dictionary for the given kind of values. The
+// dictionary with the highest ID for a kind is used to compress its values.
+// It is called on initialization by generated dictionary files, so it panics
+// on invalid IDs, like the codec does for invalid options.
+func register(dictionary Dictionary, kind Kind, raw []byte) {
+
+	if dictionary == 0 || dictionary > MaxDictionary {
+		panic(fmt.Sprintf("invalid dictionary ID (%d)", dictionary))
+	}
+	_, ok := dictionaries[dictionary]
+	if ok {
+		panic(fmt.Sprintf("duplicate dictionary ID (%d)", dictionary))
+	}
+
+	dictionaries[dictionary] = entry{
+		name: fmt.Sprintf("%s_%d", kind, dictionary),
+		kind: kind,
+		raw:  raw,
+	}
+
+	previous := current[kind]
+	if dictionaries[previous].kind != kind || dictionary > previous {
+		current[kind] = dictionary
+	}
+}
+
+// Current returns the dictionary currently used to compress values of the
+// given kind, along with its raw bytes.
+func Current(kind Kind) (Dictionary, []byte) {
+	dictionary, ok := current[kind]
+	if !ok {
+		dictionary = DictionaryGeneric
+	}
+	return dictionary, dictionaries[dictionary].raw
+}
+
+// Next returns the ID to use for the next generated dictionary.
+func Next() (Dictionary, error) {
+	next := Dictionary(0)
+	for dictionary := range dictionaries {
+		if dictionary > next {
+			next = dictionary
+		}
+	}
+	next++
+	if next > MaxDictionary {
+		return 0, fmt.Errorf("no free dictionary ID left (max: %d)", MaxDictionary)
+	}
+	return next, nil
+}
+
+// String returns the name of the dictionary.
+func (d Dictionary) String() string {
+	entry, ok := dictionaries[d]
+	if !ok {
+		return "unknown"
+	}
+	return entry.name
+}
+
+// frameDictionary returns the dictionary whose Zstandard ID matches the given
+// one, as found in the frame header of a compressed value.
+func frameDictionary(id uint32) (Dictionary, bool) {
+	for dictionary, entry := range dictionaries {
+		// The Zstandard ID of a dictionary directly follows its magic number.
+		if len(entry.raw) >= 8 && binary.LittleEndian.Uint32(entry.raw[4:8]) == id {
+			return dictionary, true
+		}
+	}
+	return 0, false
+}
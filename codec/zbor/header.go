@@ -0,0 +1,114 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package zbor
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Each value encoded by the codec starts with a header byte, which holds the
+// version of the codec in its three most significant bits, and the ID of the
+// dictionary used for compression in its five least significant bits. This
+// makes decoding deterministic, instead of relying on the type of the value
+// to pick the right dictionaries.
+const (
+	versionShift   = 5
+	dictionaryMask = 0x1f
+)
+
+// LegacyVersion is the codec version of values that were encoded before the
+// header was introduced. Those values start directly with the Zstandard magic
+// number instead of a header byte.
+const LegacyVersion = 1
+
+// zstdMagic is the magic number at the start of each Zstandard frame.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// header returns the header byte for a value compressed with the given
+// dictionary by the current version of the codec.
+func header(dictionary Dictionary) byte {
+	return Version<<versionShift | byte(dictionary)
+}
+
+// legacy returns whether the given value was encoded before the header byte
+// was introduced.
+func legacy(value []byte) bool {
+	return bytes.HasPrefix(value, zstdMagic)
+}
+
+// readable returns whether values with a header of the given version can be
+// read by the given version of the codec. All versions with a header byte up
+// to the current one are readable, as they keep the same header layout, while
+// values encoded by newer versions of the codec are not.
+func readable(version uint8, current uint8) bool {
+	return version > LegacyVersion && version <= current
+}
+
+// parseHeader returns the version and the dictionary from the header of the
+// given value, and the compressed data that follows it. Values of all versions
+// that are readable by the given current version are accepted.
+func parseHeader(value []byte, current uint8) (uint8, Dictionary, []byte, error) {
+
+	if len(value) == 0 {
+		return 0, 0, nil, fmt.Errorf("missing header byte")
+	}
+
+	version := value[0] >> versionShift
+	if !readable(version, current) {
+		return 0, 0, nil, fmt.Errorf("unsupported codec version (%d)", version)
+	}
+
+	dictionary := Dictionary(value[0] & dictionaryMask)
+	_, ok := dictionaries[dictionary]
+	if !ok {
+		return 0, 0, nil, fmt.Errorf("unknown dictionary (%d)", dictionary)
+	}
+
+	return version, dictionary, value[1:], nil
+}
+
+// Inspect returns the codec version of the given encoded value, as well as the
+// dictionary that was used to compress it. For legacy values, the dictionary
+// is identified by the Zstandard frame header instead. It can be used to report
+// on the usage of dictionaries across an index.
+func Inspect(value []byte) (uint8, Dictionary, error) {
+	return inspect(value, Version)
+}
+
+func inspect(value []byte, current uint8) (uint8, Dictionary, error) {
+
+	if !legacy(value) {
+		version, dictionary, _, err := parseHeader(value, current)
+		if err != nil {
+			return 0, 0, fmt.Errorf("could not parse header: %w", err)
+		}
+		return version, dictionary, nil
+	}
+
+	var frame zstd.Header
+	err := frame.Decode(value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not decode frame header: %w", err)
+	}
+	dictionary, ok := frameDictionary(frame.DictionaryID)
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown frame dictionary (%d)", frame.DictionaryID)
+	}
+
+	return LegacyVersion, dictionary, nil
+}
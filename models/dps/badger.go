@@ -20,6 +20,7 @@ import (
 )
 
 // DefaultOptions returns the default Badger options preferred by the DPS for its index database.
+// They correspond to the bulk-ingest tuning profile, which favors write throughput while indexing.
 func DefaultOptions(dir string) badger.Options {
 	return badger.DefaultOptions(dir).
 		WithMaxTableSize(256 << 20).
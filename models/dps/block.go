@@ -0,0 +1,55 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+import (
+	"github.com/onflow/flow-go/model/flow"
+)
+
+// Block is the aggregate of the data indexed for a finalized block. Only the
+// fields selected by the mask it was read with are set.
+type Block struct {
+	Height       uint64
+	BlockID      flow.Identifier
+	Header       *flow.Header
+	Collections  []*flow.LightCollection
+	Guarantees   []*flow.CollectionGuarantee
+	Transactions []*flow.TransactionBody
+	Results      []*flow.TransactionResult
+	Events       []flow.Event
+	Seals        []*flow.Seal
+}
+
+// BlockMask selects which fields of a block are read.
+type BlockMask struct {
+	Header       bool
+	Collections  bool
+	Guarantees   bool
+	Transactions bool
+	Results      bool
+	Events       bool
+	Seals        bool
+}
+
+// FullBlock is the block mask that selects all the fields of a block.
+var FullBlock = BlockMask{
+	Header:       true,
+	Collections:  true,
+	Guarantees:   true,
+	Transactions: true,
+	Results:      true,
+	Events:       true,
+	Seals:        true,
+}
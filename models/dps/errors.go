@@ -20,6 +20,10 @@ import (
 
 // Sentinel errors.
 var (
-	ErrFinished    = errors.New("finished")
-	ErrUnavailable = errors.New("unavailable")
+	ErrFinished        = errors.New("finished")
+	ErrUnavailable     = errors.New("unavailable")
+	ErrNotIncluded     = errors.New("not included in this index")
+	ErrNotFound        = errors.New("not found")
+	ErrInvalidArgument = errors.New("invalid argument")
+	ErrOutOfRange      = errors.New("out of range")
 )
@@ -26,6 +26,8 @@ type Reader interface {
 
 	HeightForBlock(blockID flow.Identifier) (uint64, error)
 	HeightForTransaction(txID flow.Identifier) (uint64, error)
+	HeightForCollection(collID flow.Identifier) (uint64, error)
+	HeightForSeal(sealID flow.Identifier) (uint64, error)
 
 	Commit(height uint64) (flow.StateCommitment, error)
 	Header(height uint64) (*flow.Header, error)
@@ -41,4 +43,12 @@ type Reader interface {
 	CollectionsByHeight(height uint64) ([]flow.Identifier, error)
 	TransactionsByHeight(height uint64) ([]flow.Identifier, error)
 	SealsByHeight(height uint64) ([]flow.Identifier, error)
+	ResultsByHeight(height uint64) ([]*flow.TransactionResult, error)
+}
+
+// BlockReader represents something that can read all the data of a block from
+// a DPS index at once.
+type BlockReader interface {
+	Block(height uint64, mask BlockMask) (*Block, error)
+	BlockByID(blockID flow.Identifier, mask BlockMask) (*Block, error)
 }
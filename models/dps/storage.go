@@ -36,6 +36,8 @@ type ReadLibrary interface {
 
 	LookupHeightForBlock(blockID flow.Identifier, height *uint64) func(*badger.Txn) error
 	LookupHeightForTransaction(txID flow.Identifier, height *uint64) func(*badger.Txn) error
+	LookupHeightForCollection(collID flow.Identifier, height *uint64) func(*badger.Txn) error
+	LookupHeightForSeal(sealID flow.Identifier, height *uint64) func(*badger.Txn) error
 
 	RetrieveCommit(height uint64, commit *flow.StateCommitment) func(*badger.Txn) error
 	RetrieveHeader(height uint64, header *flow.Header) func(*badger.Txn) error
@@ -64,6 +66,8 @@ type WriteLibrary interface {
 
 	IndexHeightForBlock(blockID flow.Identifier, height uint64) func(*badger.Txn) error
 	IndexHeightForTransaction(txID flow.Identifier, height uint64) func(*badger.Txn) error
+	IndexHeightForCollection(collID flow.Identifier, height uint64) func(*badger.Txn) error
+	IndexHeightForSeal(sealID flow.Identifier, height uint64) func(*badger.Txn) error
 
 	SaveCommit(height uint64, commit flow.StateCommitment) func(*badger.Txn) error
 	SaveHeader(height uint64, header *flow.Header) func(*badger.Txn) error
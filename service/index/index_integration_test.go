@@ -27,6 +27,7 @@ import (
 	"github.com/onflow/flow-go/model/flow"
 
 	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/models/dps"
 	"github.com/optakt/flow-dps/service/index"
 	"github.com/optakt/flow-dps/service/storage"
 	"github.com/optakt/flow-dps/testing/helpers"
@@ -165,6 +166,13 @@ func TestIndex(t *testing.T) {
 			assert.ElementsMatch(t, mocks.GenericCollectionIDs(4), got)
 		})
 
+		t.Run("retrieve height for collection", func(t *testing.T) {
+			got, err := reader.HeightForCollection(collections[0].ID())
+
+			require.NoError(t, err)
+			assert.Equal(t, mocks.GenericHeight, got)
+		})
+
 		t.Run("retrieve transactions from collection", func(t *testing.T) {
 			// For now this index is not used.
 		})
@@ -316,6 +324,88 @@ func TestIndex(t *testing.T) {
 			require.NoError(t, err)
 			assert.ElementsMatch(t, got, mocks.GenericSealIDs(4))
 		})
+
+		t.Run("retrieve height for seal", func(t *testing.T) {
+			got, err := reader.HeightForSeal(seals[0].ID())
+
+			require.NoError(t, err)
+			assert.Equal(t, mocks.GenericHeight, got)
+		})
+	})
+
+	t.Run("blocks", func(t *testing.T) {
+		t.Parallel()
+
+		reader, writer, db := setupIndex(t)
+		defer db.Close()
+
+		header := mocks.GenericHeader
+		collections := mocks.GenericCollections(4)
+		// The last collection stands in for the system collection, which has
+		// no guarantee.
+		guarantees := make([]*flow.CollectionGuarantee, 0, len(collections)-1)
+		for _, collection := range collections[:len(collections)-1] {
+			guarantees = append(guarantees, &flow.CollectionGuarantee{CollectionID: collection.ID()})
+		}
+		transactions := mocks.GenericTransactions(4)
+		results := make([]*flow.TransactionResult, 0, len(transactions))
+		for _, transaction := range transactions {
+			results = append(results, &flow.TransactionResult{TransactionID: transaction.ID()})
+		}
+		events := mocks.GenericEvents(4)
+		seals := mocks.GenericSeals(4)
+
+		assert.NoError(t, writer.Header(mocks.GenericHeight, header))
+		assert.NoError(t, writer.Height(header.ID(), mocks.GenericHeight))
+		assert.NoError(t, writer.Collections(mocks.GenericHeight, collections))
+		assert.NoError(t, writer.Guarantees(mocks.GenericHeight, guarantees))
+		assert.NoError(t, writer.Transactions(mocks.GenericHeight, transactions))
+		assert.NoError(t, writer.Results(results))
+		assert.NoError(t, writer.Events(mocks.GenericHeight, events))
+		assert.NoError(t, writer.Seals(mocks.GenericHeight, seals))
+		// Close the writer to make it commit its transactions.
+		require.NoError(t, writer.Close())
+
+		// NOTE: The following subtests should NOT be run in parallel, because of the deferral
+		// to close the database above.
+		t.Run("retrieve full block by height", func(t *testing.T) {
+			got, err := reader.Block(mocks.GenericHeight, dps.FullBlock)
+
+			require.NoError(t, err)
+			assert.Equal(t, mocks.GenericHeight, got.Height)
+			assert.Equal(t, header.ID(), got.BlockID)
+			assert.Equal(t, header, got.Header)
+			assert.ElementsMatch(t, collections, got.Collections)
+			assert.ElementsMatch(t, guarantees, got.Guarantees)
+			assert.ElementsMatch(t, transactions, got.Transactions)
+			assert.ElementsMatch(t, results, got.Results)
+			assert.ElementsMatch(t, events, got.Events)
+			assert.ElementsMatch(t, seals, got.Seals)
+		})
+
+		t.Run("retrieve results by height", func(t *testing.T) {
+			got, err := reader.ResultsByHeight(mocks.GenericHeight)
+
+			require.NoError(t, err)
+			assert.Equal(t, results, got)
+		})
+
+		t.Run("retrieve masked block by ID", func(t *testing.T) {
+			got, err := reader.BlockByID(header.ID(), dps.BlockMask{Seals: true})
+
+			require.NoError(t, err)
+			assert.Equal(t, mocks.GenericHeight, got.Height)
+			assert.Nil(t, got.Header)
+			assert.Empty(t, got.Collections)
+			assert.Empty(t, got.Events)
+			assert.ElementsMatch(t, seals, got.Seals)
+		})
+
+		t.Run("handles missing block", func(t *testing.T) {
+			_, err := reader.Block(mocks.GenericHeight+1, dps.FullBlock)
+
+			assert.ErrorIs(t, err, badger.ErrKeyNotFound)
+		})
 	})
 }
 
@@ -0,0 +1,235 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package index
+
+import (
+	"fmt"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/storage"
+)
+
+// Partial wraps an index reader for a partial index, which only includes some
+// categories of data. Calls which need data from a category that was not
+// included fail with `dps.ErrNotIncluded`, instead of a missing key error.
+type Partial struct {
+	read     dps.Reader
+	included map[string]struct{}
+}
+
+// NewPartial creates a new partial index reader, which only serves the given
+// categories of data from the given reader.
+func NewPartial(read dps.Reader, categories ...string) *Partial {
+
+	included := make(map[string]struct{}, len(categories))
+	for _, category := range categories {
+		included[category] = struct{}{}
+	}
+
+	p := Partial{
+		read:     read,
+		included: included,
+	}
+
+	return &p
+}
+
+// First returns the height of the first finalized block that was indexed.
+func (p *Partial) First() (uint64, error) {
+	return p.read.First()
+}
+
+// Last returns the height of the last finalized block that was indexed.
+func (p *Partial) Last() (uint64, error) {
+	return p.read.Last()
+}
+
+// HeightForBlock returns the height for the given block identifier.
+func (p *Partial) HeightForBlock(blockID flow.Identifier) (uint64, error) {
+	err := p.check(storage.CategoryHeaders)
+	if err != nil {
+		return 0, err
+	}
+	return p.read.HeightForBlock(blockID)
+}
+
+// Commit returns the commitment of the execution state as it was after the
+// execution of the finalized block at the given height.
+func (p *Partial) Commit(height uint64) (flow.StateCommitment, error) {
+	err := p.check(storage.CategoryCommits)
+	if err != nil {
+		return flow.DummyStateCommitment, err
+	}
+	return p.read.Commit(height)
+}
+
+// Header returns the header for the finalized block at the given height.
+func (p *Partial) Header(height uint64) (*flow.Header, error) {
+	err := p.check(storage.CategoryHeaders)
+	if err != nil {
+		return nil, err
+	}
+	return p.read.Header(height)
+}
+
+// Events returns the events of all transactions that were part of the
+// finalized block at the given height. It can optionally filter them by event
+// type; if no event types are given, all events are returned.
+func (p *Partial) Events(height uint64, types ...flow.EventType) ([]flow.Event, error) {
+	err := p.check(storage.CategoryEvents)
+	if err != nil {
+		return nil, err
+	}
+	return p.read.Events(height, types...)
+}
+
+// Values returns the Ledger values of the execution state at the given paths
+// as they were after the execution of the finalized block at the given height.
+func (p *Partial) Values(height uint64, paths []ledger.Path) ([]ledger.Value, error) {
+	err := p.check(storage.CategoryRegisters)
+	if err != nil {
+		return nil, err
+	}
+	return p.read.Values(height, paths)
+}
+
+// Collection returns the collection with the given ID.
+func (p *Partial) Collection(collID flow.Identifier) (*flow.LightCollection, error) {
+	err := p.check(storage.CategoryCollections)
+	if err != nil {
+		return nil, err
+	}
+	return p.read.Collection(collID)
+}
+
+// CollectionsByHeight returns the collection IDs at the given height.
+func (p *Partial) CollectionsByHeight(height uint64) ([]flow.Identifier, error) {
+	err := p.check(storage.CategoryCollections)
+	if err != nil {
+		return nil, err
+	}
+	return p.read.CollectionsByHeight(height)
+}
+
+// Guarantee returns the guarantee with the given collection ID.
+func (p *Partial) Guarantee(collID flow.Identifier) (*flow.CollectionGuarantee, error) {
+	err := p.check(storage.CategoryCollections)
+	if err != nil {
+		return nil, err
+	}
+	return p.read.Guarantee(collID)
+}
+
+// Transaction returns the transaction with the given ID.
+func (p *Partial) Transaction(txID flow.Identifier) (*flow.TransactionBody, error) {
+	err := p.check(storage.CategoryTransactions)
+	if err != nil {
+		return nil, err
+	}
+	return p.read.Transaction(txID)
+}
+
+// HeightForTransaction returns the height of the block within which the given
+// transaction identifier is.
+func (p *Partial) HeightForTransaction(txID flow.Identifier) (uint64, error) {
+	err := p.check(storage.CategoryTransactions)
+	if err != nil {
+		return 0, err
+	}
+	return p.read.HeightForTransaction(txID)
+}
+
+// HeightForCollection returns the height of the block within which the given
+// collection identifier is.
+func (p *Partial) HeightForCollection(collID flow.Identifier) (uint64, error) {
+	err := p.check(storage.CategoryCollections)
+	if err != nil {
+		return 0, err
+	}
+	return p.read.HeightForCollection(collID)
+}
+
+// HeightForSeal returns the height of the block within which the given seal
+// identifier is.
+func (p *Partial) HeightForSeal(sealID flow.Identifier) (uint64, error) {
+	err := p.check(storage.CategorySeals)
+	if err != nil {
+		return 0, err
+	}
+	return p.read.HeightForSeal(sealID)
+}
+
+// TransactionsByHeight returns the transaction IDs within the given height.
+func (p *Partial) TransactionsByHeight(height uint64) ([]flow.Identifier, error) {
+	err := p.check(storage.CategoryTransactions)
+	if err != nil {
+		return nil, err
+	}
+	return p.read.TransactionsByHeight(height)
+}
+
+// Result returns the transaction result for the given transaction ID.
+func (p *Partial) Result(txID flow.Identifier) (*flow.TransactionResult, error) {
+	err := p.check(storage.CategoryResults)
+	if err != nil {
+		return nil, err
+	}
+	return p.read.Result(txID)
+}
+
+// Seal returns the seal with the given ID.
+func (p *Partial) Seal(sealID flow.Identifier) (*flow.Seal, error) {
+	err := p.check(storage.CategorySeals)
+	if err != nil {
+		return nil, err
+	}
+	return p.read.Seal(sealID)
+}
+
+// SealsByHeight returns all of the seals that were part of the finalized block
+// at the given height.
+func (p *Partial) SealsByHeight(height uint64) ([]flow.Identifier, error) {
+	err := p.check(storage.CategorySeals)
+	if err != nil {
+		return nil, err
+	}
+	return p.read.SealsByHeight(height)
+}
+
+// ResultsByHeight returns the results of all of the transactions that were part
+// of the finalized block at the given height. It needs both the transactions
+// and their results to be included.
+func (p *Partial) ResultsByHeight(height uint64) ([]*flow.TransactionResult, error) {
+	err := p.check(storage.CategoryTransactions)
+	if err != nil {
+		return nil, err
+	}
+	err = p.check(storage.CategoryResults)
+	if err != nil {
+		return nil, err
+	}
+	return p.read.ResultsByHeight(height)
+}
+
+func (p *Partial) check(category string) error {
+	_, ok := p.included[category]
+	if !ok {
+		return fmt.Errorf("%s are %w", category, dps.ErrNotIncluded)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package index_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/ledger"
+
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/index"
+	"github.com/optakt/flow-dps/service/storage"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestPartial(t *testing.T) {
+	partial := index.NewPartial(mocks.BaselineReader(t), storage.CategoryHeaders, storage.CategoryEvents)
+
+	t.Run("boundaries are always included", func(t *testing.T) {
+		t.Parallel()
+
+		first, err := partial.First()
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, first)
+
+		last, err := partial.Last()
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, last)
+	})
+
+	t.Run("included categories", func(t *testing.T) {
+		t.Parallel()
+
+		header, err := partial.Header(mocks.GenericHeight)
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeader, header)
+
+		_, err = partial.HeightForBlock(mocks.GenericHeader.ID())
+		assert.NoError(t, err)
+
+		_, err = partial.Events(mocks.GenericHeight)
+		assert.NoError(t, err)
+	})
+
+	t.Run("excluded categories", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := partial.Commit(mocks.GenericHeight)
+		assert.ErrorIs(t, err, dps.ErrNotIncluded)
+
+		_, err = partial.Values(mocks.GenericHeight, []ledger.Path{mocks.GenericLedgerPath(0)})
+		assert.ErrorIs(t, err, dps.ErrNotIncluded)
+
+		_, err = partial.Transaction(mocks.GenericTransaction(0).ID())
+		assert.ErrorIs(t, err, dps.ErrNotIncluded)
+
+		_, err = partial.CollectionsByHeight(mocks.GenericHeight)
+		assert.ErrorIs(t, err, dps.ErrNotIncluded)
+
+		_, err = partial.Result(mocks.GenericTransaction(0).ID())
+		assert.ErrorIs(t, err, dps.ErrNotIncluded)
+
+		_, err = partial.SealsByHeight(mocks.GenericHeight)
+		assert.ErrorIs(t, err, dps.ErrNotIncluded)
+
+		_, err = partial.HeightForCollection(mocks.GenericCollectionIDs(1)[0])
+		assert.ErrorIs(t, err, dps.ErrNotIncluded)
+
+		_, err = partial.HeightForSeal(mocks.GenericSealIDs(1)[0])
+		assert.ErrorIs(t, err, dps.ErrNotIncluded)
+	})
+
+	t.Run("results by height need transactions and results", func(t *testing.T) {
+		t.Parallel()
+
+		results := index.NewPartial(mocks.BaselineReader(t), storage.CategoryResults)
+		_, err := results.ResultsByHeight(mocks.GenericHeight)
+		assert.ErrorIs(t, err, dps.ErrNotIncluded)
+
+		both := index.NewPartial(mocks.BaselineReader(t), storage.CategoryTransactions, storage.CategoryResults)
+		got, err := both.ResultsByHeight(mocks.GenericHeight)
+		require.NoError(t, err)
+		assert.Len(t, got, 4)
+	})
+}
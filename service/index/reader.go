@@ -96,7 +96,7 @@ func (r *Reader) Values(height uint64, paths []ledger.Path) ([]ledger.Value, err
 		return nil, fmt.Errorf("could not check last height: %w", err)
 	}
 	if height < first || height > last {
-		return nil, fmt.Errorf("invalid height (given: %d, first: %d, last: %d)", height, first, last)
+		return nil, fmt.Errorf("invalid height: %w (given: %d, first: %d, last: %d)", dps.ErrOutOfRange, height, first, last)
 	}
 	values := make([]ledger.Value, 0, len(paths))
 	err = r.db.View(func(tx *badger.Txn) error {
@@ -153,6 +153,22 @@ func (r *Reader) HeightForTransaction(txID flow.Identifier) (uint64, error) {
 	return height, err
 }
 
+// HeightForCollection returns the height of the block within which the given
+// collection identifier is.
+func (r *Reader) HeightForCollection(collID flow.Identifier) (uint64, error) {
+	var height uint64
+	err := r.db.View(r.lib.LookupHeightForCollection(collID, &height))
+	return height, err
+}
+
+// HeightForSeal returns the height of the block within which the given seal
+// identifier is.
+func (r *Reader) HeightForSeal(sealID flow.Identifier) (uint64, error) {
+	var height uint64
+	err := r.db.View(r.lib.LookupHeightForSeal(sealID, &height))
+	return height, err
+}
+
 // TransactionsByHeight returns the transaction IDs within the block with the given ID.
 func (r *Reader) TransactionsByHeight(height uint64) ([]flow.Identifier, error) {
 	var txIDs []flow.Identifier
@@ -180,7 +196,7 @@ func (r *Reader) Events(height uint64, types ...flow.EventType) ([]flow.Event, e
 		return nil, fmt.Errorf("could not check last height: %w", err)
 	}
 	if height < first || height > last {
-		return nil, fmt.Errorf("invalid height (given: %d, first: %d, last: %d)", height, first, last)
+		return nil, fmt.Errorf("invalid height: %w (given: %d, first: %d, last: %d)", dps.ErrOutOfRange, height, first, last)
 	}
 
 	var events []flow.Event
@@ -205,3 +221,158 @@ func (r *Reader) SealsByHeight(height uint64) ([]flow.Identifier, error) {
 	err := r.db.View(r.lib.LookupSealsForHeight(height, &sealIDs))
 	return sealIDs, err
 }
+
+// ResultsByHeight returns the results of all of the transactions that were part
+// of the finalized block at the given height, in the order of the transactions.
+func (r *Reader) ResultsByHeight(height uint64) ([]*flow.TransactionResult, error) {
+	var results []*flow.TransactionResult
+	err := r.db.View(func(tx *badger.Txn) error {
+		var txIDs []flow.Identifier
+		err := r.lib.LookupTransactionsForHeight(height, &txIDs)(tx)
+		if err != nil {
+			return fmt.Errorf("could not look up transactions: %w", err)
+		}
+		results = make([]*flow.TransactionResult, 0, len(txIDs))
+		for _, txID := range txIDs {
+			var result flow.TransactionResult
+			err = r.lib.RetrieveResult(txID, &result)(tx)
+			if err != nil {
+				return fmt.Errorf("could not retrieve transaction result (id: %x): %w", txID, err)
+			}
+			results = append(results, &result)
+		}
+		return nil
+	})
+	return results, err
+}
+
+// Block returns the data selected by the given mask for the finalized block at
+// the given height. All of it is read within a single database transaction, so
+// that it is consistent.
+func (r *Reader) Block(height uint64, mask dps.BlockMask) (*dps.Block, error) {
+	var block *dps.Block
+	err := r.db.View(func(tx *badger.Txn) error {
+		var err error
+		block, err = r.block(tx, height, mask)
+		return err
+	})
+	return block, err
+}
+
+// BlockByID returns the data selected by the given mask for the finalized
+// block with the given ID. All of it is read within a single database
+// transaction, so that it is consistent.
+func (r *Reader) BlockByID(blockID flow.Identifier, mask dps.BlockMask) (*dps.Block, error) {
+	var block *dps.Block
+	err := r.db.View(func(tx *badger.Txn) error {
+		var height uint64
+		err := r.lib.LookupHeightForBlock(blockID, &height)(tx)
+		if err != nil {
+			return fmt.Errorf("could not look up height for block: %w", err)
+		}
+		block, err = r.block(tx, height, mask)
+		return err
+	})
+	return block, err
+}
+
+func (r *Reader) block(tx *badger.Txn, height uint64, mask dps.BlockMask) (*dps.Block, error) {
+
+	// The header is always needed to know the ID of the block, and allows us
+	// to check that the block was indexed before reading anything else.
+	var header flow.Header
+	err := r.lib.RetrieveHeader(height, &header)(tx)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve header: %w", err)
+	}
+
+	block := dps.Block{
+		Height:  height,
+		BlockID: header.ID(),
+	}
+	if mask.Header {
+		block.Header = &header
+	}
+
+	if mask.Collections || mask.Guarantees {
+		var collIDs []flow.Identifier
+		err = r.lib.LookupCollectionsForHeight(height, &collIDs)(tx)
+		if err != nil {
+			return nil, fmt.Errorf("could not look up collections: %w", err)
+		}
+		for _, collID := range collIDs {
+			if mask.Collections {
+				var collection flow.LightCollection
+				err = r.lib.RetrieveCollection(collID, &collection)(tx)
+				if err != nil {
+					return nil, fmt.Errorf("could not retrieve collection (id: %x): %w", collID, err)
+				}
+				block.Collections = append(block.Collections, &collection)
+			}
+			// Only the collections of the block payload have a guarantee, which
+			// is not the case for the system collection of the block.
+			if mask.Guarantees {
+				var guarantee flow.CollectionGuarantee
+				err = r.lib.RetrieveGuarantee(collID, &guarantee)(tx)
+				if errors.Is(err, badger.ErrKeyNotFound) {
+					continue
+				}
+				if err != nil {
+					return nil, fmt.Errorf("could not retrieve guarantee (id: %x): %w", collID, err)
+				}
+				block.Guarantees = append(block.Guarantees, &guarantee)
+			}
+		}
+	}
+
+	if mask.Transactions || mask.Results {
+		var txIDs []flow.Identifier
+		err = r.lib.LookupTransactionsForHeight(height, &txIDs)(tx)
+		if err != nil {
+			return nil, fmt.Errorf("could not look up transactions: %w", err)
+		}
+		for _, txID := range txIDs {
+			if mask.Transactions {
+				var transaction flow.TransactionBody
+				err = r.lib.RetrieveTransaction(txID, &transaction)(tx)
+				if err != nil {
+					return nil, fmt.Errorf("could not retrieve transaction (id: %x): %w", txID, err)
+				}
+				block.Transactions = append(block.Transactions, &transaction)
+			}
+			if mask.Results {
+				var result flow.TransactionResult
+				err = r.lib.RetrieveResult(txID, &result)(tx)
+				if err != nil {
+					return nil, fmt.Errorf("could not retrieve transaction result (id: %x): %w", txID, err)
+				}
+				block.Results = append(block.Results, &result)
+			}
+		}
+	}
+
+	if mask.Events {
+		err = r.lib.RetrieveEvents(height, nil, &block.Events)(tx)
+		if err != nil {
+			return nil, fmt.Errorf("could not retrieve events: %w", err)
+		}
+	}
+
+	if mask.Seals {
+		var sealIDs []flow.Identifier
+		err = r.lib.LookupSealsForHeight(height, &sealIDs)(tx)
+		if err != nil {
+			return nil, fmt.Errorf("could not look up seals: %w", err)
+		}
+		for _, sealID := range sealIDs {
+			var seal flow.Seal
+			err = r.lib.RetrieveSeal(sealID, &seal)(tx)
+			if err != nil {
+				return nil, fmt.Errorf("could not retrieve seal (id: %x): %w", sealID, err)
+			}
+			block.Seals = append(block.Seals, &seal)
+		}
+	}
+
+	return &block, nil
+}
@@ -129,7 +129,7 @@ func (w *Writer) Payloads(height uint64, paths []ledger.Path, payloads []*ledger
 // Collections indexes the collections at the given height.
 func (w *Writer) Collections(height uint64, collections []*flow.LightCollection) error {
 
-	ops := make([]func(*badger.Txn) error, 0, 2*len(collections)+1)
+	ops := make([]func(*badger.Txn) error, 0, 3*len(collections)+1)
 
 	collIDs := make([]flow.Identifier, 0, len(collections))
 	for _, collection := range collections {
@@ -137,6 +137,7 @@ func (w *Writer) Collections(height uint64, collections []*flow.LightCollection)
 		collIDs = append(collIDs, collID)
 		ops = append(ops, w.lib.SaveCollection(collection))
 		ops = append(ops, w.lib.IndexTransactionsForCollection(collID, collection.Transactions))
+		ops = append(ops, w.lib.IndexHeightForCollection(collID, height))
 	}
 
 	ops = append(ops, w.lib.IndexCollectionsForHeight(height, collIDs))
@@ -207,13 +208,14 @@ func (w *Writer) Events(height uint64, events []flow.Event) error {
 // block at the given height.
 func (w *Writer) Seals(height uint64, seals []*flow.Seal) error {
 
-	ops := make([]func(*badger.Txn) error, 0, len(seals)+1)
+	ops := make([]func(*badger.Txn) error, 0, 2*len(seals)+1)
 
 	sealIDs := make([]flow.Identifier, 0, len(seals))
 	for _, seal := range seals {
 		sealID := seal.ID()
 		sealIDs = append(sealIDs, sealID)
 		ops = append(ops, w.lib.SaveSeal(seal))
+		ops = append(ops, w.lib.IndexHeightForSeal(sealID, height))
 	}
 
 	ops = append(ops, w.lib.IndexSealsForHeight(height, sealIDs))
@@ -0,0 +1,75 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package maintenance
+
+import (
+	"time"
+)
+
+// DefaultConfig is the default configuration for the maintenance controller.
+// It is used when no options are specified.
+var DefaultConfig = Config{
+	GCInterval:      10 * time.Minute,
+	DiscardRatio:    0.5,
+	FlattenInterval: time.Hour,
+	FlattenWorkers:  1,
+}
+
+// Config contains the configuration options for the maintenance controller.
+type Config struct {
+	GCInterval      time.Duration
+	DiscardRatio    float64
+	FlattenInterval time.Duration
+	FlattenWorkers  int
+}
+
+// Option is a configuration option for the maintenance controller. It can be
+// passed to the controller's construction function to set optional parameters.
+type Option func(*Config)
+
+// WithGCInterval sets the interval at which value log garbage collection is
+// run. A zero interval disables value log garbage collection.
+func WithGCInterval(interval time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.GCInterval = interval
+	}
+}
+
+// WithDiscardRatio sets the minimum ratio of stale data a value log file needs
+// to contain in order to be rewritten during garbage collection. It has to be
+// strictly between zero and one.
+func WithDiscardRatio(ratio float64) Option {
+	return func(cfg *Config) {
+		cfg.DiscardRatio = ratio
+	}
+}
+
+// WithFlattenInterval sets the interval at which the controller checks whether
+// the database was idle, in which case it flattens the LSM tree. A database is
+// considered idle when no writes happened during the whole interval. A zero
+// interval disables flattening.
+func WithFlattenInterval(interval time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.FlattenInterval = interval
+	}
+}
+
+// WithFlattenWorkers sets the number of concurrent compaction workers used to
+// flatten the LSM tree.
+func WithFlattenWorkers(workers int) Option {
+	return func(cfg *Config) {
+		cfg.FlattenWorkers = workers
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package maintenance
+
+import (
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Task represents the maintenance operations the controller schedules.
+type Task interface {
+	CollectGarbage(ratio float64) (*Result, error)
+	Flatten(workers int) (*Result, error)
+	Activity() int64
+}
+
+// Controller runs value log garbage collection on a schedule, and flattens the
+// LSM tree when the database has been idle for a whole flatten interval.
+type Controller struct {
+	log  zerolog.Logger
+	task Task
+	cfg  Config
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewController creates a new maintenance controller for the given task.
+func NewController(log zerolog.Logger, task Task, options ...Option) *Controller {
+
+	cfg := DefaultConfig
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	c := Controller{
+		log:  log.With().Str("component", "maintenance_controller").Logger(),
+		task: task,
+		cfg:  cfg,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	return &c
+}
+
+// Run runs the maintenance operations on their schedule until the controller
+// is stopped. Failed operations are logged and retried at the next interval,
+// as they should not interrupt the components that use the database.
+func (c *Controller) Run() {
+	defer close(c.done)
+
+	// A nil channel blocks forever, which allows us to disable operations by
+	// simply not creating their ticker.
+	var gc, flatten <-chan time.Time
+	if c.cfg.GCInterval > 0 {
+		ticker := time.NewTicker(c.cfg.GCInterval)
+		defer ticker.Stop()
+		gc = ticker.C
+	}
+	if c.cfg.FlattenInterval > 0 {
+		ticker := time.NewTicker(c.cfg.FlattenInterval)
+		defer ticker.Stop()
+		flatten = ticker.C
+	}
+
+	// We keep track of the activity at the last check, to know whether there
+	// were any writes during the last interval, and of the activity at the
+	// last flatten, so we don't flatten an unchanged tree over and over.
+	last := c.task.Activity()
+	flattened := int64(-1)
+
+	for {
+		select {
+		case <-c.stop:
+			return
+
+		case <-gc:
+			c.collectGarbage()
+
+		case <-flatten:
+			activity := c.task.Activity()
+			idle := activity == last
+			last = activity
+			if !idle {
+				c.log.Debug().Msg("skipping flatten, database is not idle")
+				continue
+			}
+			if activity == flattened {
+				c.log.Debug().Msg("skipping flatten, database unchanged since last flatten")
+				continue
+			}
+			ok := c.flatten()
+			if ok {
+				flattened = activity
+			}
+		}
+	}
+}
+
+// Stop stops the controller and waits for a running operation to finish. As it
+// waits for the run loop to return, it should only be used once Run was called.
+func (c *Controller) Stop() error {
+	close(c.stop)
+	<-c.done
+	return nil
+}
+
+func (c *Controller) collectGarbage() {
+	result, err := c.task.CollectGarbage(c.cfg.DiscardRatio)
+	if err != nil {
+		c.log.Warn().Err(err).Msg("could not collect value log garbage")
+		return
+	}
+	c.log.Info().
+		Uint("rewrites", result.Rewrites).
+		Uint64("reclaimed", result.Reclaimed()).
+		Dur("duration", result.Duration).
+		Msg("value log garbage collected")
+}
+
+func (c *Controller) flatten() bool {
+	result, err := c.task.Flatten(c.cfg.FlattenWorkers)
+	if err != nil {
+		c.log.Warn().Err(err).Msg("could not flatten LSM tree")
+		return false
+	}
+	c.log.Info().
+		Uint64("reclaimed", result.Reclaimed()).
+		Dur("duration", result.Duration).
+		Msg("LSM tree flattened")
+	return true
+}
@@ -0,0 +1,150 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package maintenance_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/optakt/flow-dps/service/maintenance"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestController(t *testing.T) {
+	log := zerolog.Nop()
+
+	t.Run("collects garbage on schedule", func(t *testing.T) {
+		t.Parallel()
+
+		var calls int64
+		var ratio float64
+		task := mocks.BaselineTask(t)
+		task.CollectGarbageFunc = func(r float64) (*maintenance.Result, error) {
+			ratio = r
+			atomic.AddInt64(&calls, 1)
+			return &maintenance.Result{}, nil
+		}
+		task.FlattenFunc = func(int) (*maintenance.Result, error) {
+			t.Error("flatten should not be called")
+			return nil, nil
+		}
+
+		ctrl := maintenance.NewController(log, task,
+			maintenance.WithGCInterval(10*time.Millisecond),
+			maintenance.WithDiscardRatio(0.25),
+			maintenance.WithFlattenInterval(0),
+		)
+		runFor(ctrl, 55*time.Millisecond)
+
+		assert.GreaterOrEqual(t, atomic.LoadInt64(&calls), int64(3))
+		assert.Equal(t, 0.25, ratio)
+	})
+
+	t.Run("keeps running after failures", func(t *testing.T) {
+		t.Parallel()
+
+		var calls int64
+		task := mocks.BaselineTask(t)
+		task.CollectGarbageFunc = func(float64) (*maintenance.Result, error) {
+			atomic.AddInt64(&calls, 1)
+			return nil, mocks.GenericError
+		}
+
+		ctrl := maintenance.NewController(log, task,
+			maintenance.WithGCInterval(10*time.Millisecond),
+			maintenance.WithFlattenInterval(0),
+		)
+		runFor(ctrl, 55*time.Millisecond)
+
+		assert.GreaterOrEqual(t, atomic.LoadInt64(&calls), int64(3))
+	})
+
+	t.Run("flattens only when idle", func(t *testing.T) {
+		t.Parallel()
+
+		// Activity keeps increasing, so the database is never idle.
+		var activity int64
+		var calls int64
+		task := mocks.BaselineTask(t)
+		task.ActivityFunc = func() int64 {
+			return atomic.AddInt64(&activity, 1)
+		}
+		task.FlattenFunc = func(int) (*maintenance.Result, error) {
+			atomic.AddInt64(&calls, 1)
+			return &maintenance.Result{}, nil
+		}
+
+		ctrl := maintenance.NewController(log, task,
+			maintenance.WithGCInterval(0),
+			maintenance.WithFlattenInterval(10*time.Millisecond),
+		)
+		runFor(ctrl, 55*time.Millisecond)
+
+		assert.Zero(t, atomic.LoadInt64(&calls))
+	})
+
+	t.Run("flattens once while unchanged", func(t *testing.T) {
+		t.Parallel()
+
+		var calls int64
+		var workers int
+		task := mocks.BaselineTask(t)
+		task.FlattenFunc = func(w int) (*maintenance.Result, error) {
+			workers = w
+			atomic.AddInt64(&calls, 1)
+			return &maintenance.Result{}, nil
+		}
+
+		ctrl := maintenance.NewController(log, task,
+			maintenance.WithGCInterval(0),
+			maintenance.WithFlattenInterval(10*time.Millisecond),
+			maintenance.WithFlattenWorkers(3),
+		)
+		runFor(ctrl, 55*time.Millisecond)
+
+		assert.Equal(t, int64(1), atomic.LoadInt64(&calls))
+		assert.Equal(t, 3, workers)
+	})
+
+	t.Run("retries failed flatten", func(t *testing.T) {
+		t.Parallel()
+
+		var calls int64
+		task := mocks.BaselineTask(t)
+		task.FlattenFunc = func(int) (*maintenance.Result, error) {
+			atomic.AddInt64(&calls, 1)
+			return nil, mocks.GenericError
+		}
+
+		ctrl := maintenance.NewController(log, task,
+			maintenance.WithGCInterval(0),
+			maintenance.WithFlattenInterval(10*time.Millisecond),
+		)
+		runFor(ctrl, 55*time.Millisecond)
+
+		assert.GreaterOrEqual(t, atomic.LoadInt64(&calls), int64(2))
+	})
+}
+
+// runFor runs the controller for the given duration before stopping it.
+func runFor(ctrl *maintenance.Controller, duration time.Duration) {
+	go ctrl.Run()
+	time.Sleep(duration)
+	_ = ctrl.Stop()
+}
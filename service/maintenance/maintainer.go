@@ -0,0 +1,147 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/y"
+)
+
+// Maintainer runs maintenance operations on a Badger database, in order to
+// reclaim the disk space taken up by stale data.
+type Maintainer struct {
+	db  *badger.DB
+	dir string
+}
+
+// NewMaintainer creates a new maintainer for the given Badger database. The
+// directory should be the one the database was opened with, and is used to
+// measure how much disk space the operations reclaim.
+func NewMaintainer(db *badger.DB, dir string) *Maintainer {
+
+	m := Maintainer{
+		db:  db,
+		dir: dir,
+	}
+
+	return &m
+}
+
+// CollectGarbage runs value log garbage collection until no more value log
+// files can be rewritten. A value log file is only rewritten if at least the
+// given ratio of its data is stale.
+func (m *Maintainer) CollectGarbage(ratio float64) (*Result, error) {
+
+	start := time.Now()
+	before, err := m.Size()
+	if err != nil {
+		return nil, fmt.Errorf("could not get size before garbage collection: %w", err)
+	}
+
+	var rewrites uint
+	for {
+		err := m.db.RunValueLogGC(ratio)
+		if errors.Is(err, badger.ErrNoRewrite) || errors.Is(err, badger.ErrRejected) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not run value log garbage collection: %w", err)
+		}
+		rewrites++
+	}
+
+	after, err := m.Size()
+	if err != nil {
+		return nil, fmt.Errorf("could not get size after garbage collection: %w", err)
+	}
+
+	result := Result{
+		Rewrites: rewrites,
+		Before:   before,
+		After:    after,
+		Duration: time.Since(start),
+	}
+
+	return &result, nil
+}
+
+// Flatten forces compactions on the LSM tree until all tables are on the same
+// level, using the given number of concurrent workers. It should preferably be
+// run while no writes are going on, as it stops regular compactions.
+func (m *Maintainer) Flatten(workers int) (*Result, error) {
+
+	start := time.Now()
+	before, err := m.Size()
+	if err != nil {
+		return nil, fmt.Errorf("could not get size before flattening: %w", err)
+	}
+
+	err = m.db.Flatten(workers)
+	if err != nil {
+		return nil, fmt.Errorf("could not flatten LSM tree: %w", err)
+	}
+
+	after, err := m.Size()
+	if err != nil {
+		return nil, fmt.Errorf("could not get size after flattening: %w", err)
+	}
+
+	result := Result{
+		Before:   before,
+		After:    after,
+		Duration: time.Since(start),
+	}
+
+	return &result, nil
+}
+
+// Size returns the total size of the table and value log files of the
+// database. Unlike the sizes reported by Badger itself, which are only
+// refreshed once per minute, it is computed from the files on disk.
+func (m *Maintainer) Size() (int64, error) {
+	var size int64
+	err := filepath.Walk(m.dir, func(path string, info os.FileInfo, err error) error {
+		// Files can be deleted by compactions or garbage collection while we
+		// walk the directory, in which case they no longer take up space.
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch filepath.Ext(path) {
+		case ".sst", ".vlog":
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("could not walk database directory: %w", err)
+	}
+	return size, nil
+}
+
+// Activity returns the cumulative number of writes done on the Badger
+// databases of the current process. Badger does not track writes per database,
+// so writes to other databases also count as activity.
+func (m *Maintainer) Activity() int64 {
+	return y.NumPuts.Value()
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package maintenance_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/service/maintenance"
+)
+
+func TestMaintainer(t *testing.T) {
+	dir := t.TempDir()
+	opts := badger.DefaultOptions(dir).
+		WithLogger(nil).
+		WithValueThreshold(32).
+		WithValueLogFileSize(1 << 20).
+		WithMaxTableSize(1 << 20)
+	db, err := badger.Open(opts)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// We write the same keys several times, so that most of the values in the
+	// value log become stale.
+	value := bytes.Repeat([]byte{0xff}, 1024)
+	for round := 0; round < 4; round++ {
+		batch := db.NewWriteBatch()
+		for i := 0; i < 1024; i++ {
+			err := batch.Set([]byte(fmt.Sprintf("key-%04d", i)), value)
+			require.NoError(t, err)
+		}
+		require.NoError(t, batch.Flush())
+	}
+
+	maintain := maintenance.NewMaintainer(db, dir)
+
+	t.Run("size", func(t *testing.T) {
+		size, err := maintain.Size()
+
+		require.NoError(t, err)
+		assert.Greater(t, size, int64(4*1024*1024))
+	})
+
+	t.Run("flatten", func(t *testing.T) {
+		result, err := maintain.Flatten(1)
+
+		require.NoError(t, err)
+		assert.Zero(t, result.Rewrites)
+		assert.Greater(t, result.Before, int64(0))
+	})
+
+	t.Run("collect garbage", func(t *testing.T) {
+		result, err := maintain.CollectGarbage(0.5)
+
+		require.NoError(t, err)
+		assert.Greater(t, result.Before, int64(0))
+		assert.Greater(t, result.Duration, time.Duration(0))
+	})
+
+	t.Run("invalid ratio", func(t *testing.T) {
+		_, err := maintain.CollectGarbage(1.5)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestResult_Reclaimed(t *testing.T) {
+	result := maintenance.Result{Before: 100, After: 40}
+	assert.Equal(t, uint64(60), result.Reclaimed())
+
+	result = maintenance.Result{Before: 40, After: 100}
+	assert.Zero(t, result.Reclaimed())
+}
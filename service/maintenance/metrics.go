@@ -0,0 +1,130 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package maintenance
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	operationGC      = "value_log_gc"
+	operationFlatten = "flatten"
+)
+
+// MetricsMaintainer wraps the maintainer and records metrics for the
+// maintenance operations it runs.
+type MetricsMaintainer struct {
+	maintain *Maintainer
+
+	runs      *prometheus.CounterVec
+	failures  *prometheus.CounterVec
+	reclaimed *prometheus.CounterVec
+	duration  *prometheus.CounterVec
+	rewrites  prometheus.Counter
+	size      prometheus.Gauge
+}
+
+// NewMetricsMaintainer creates a maintainer that records the number of runs,
+// the reclaimed disk space and the database size as Prometheus metrics.
+func NewMetricsMaintainer(maintain *Maintainer) *MetricsMaintainer {
+
+	runsOpts := prometheus.CounterOpts{
+		Name: "maintenance_runs",
+		Help: "number of maintenance operations run per operation",
+	}
+	runs := promauto.NewCounterVec(runsOpts, []string{"operation"})
+
+	failuresOpts := prometheus.CounterOpts{
+		Name: "maintenance_failures",
+		Help: "number of failed maintenance operations per operation",
+	}
+	failures := promauto.NewCounterVec(failuresOpts, []string{"operation"})
+
+	reclaimedOpts := prometheus.CounterOpts{
+		Name: "maintenance_reclaimed_bytes",
+		Help: "disk space reclaimed by maintenance operations per operation",
+	}
+	reclaimed := promauto.NewCounterVec(reclaimedOpts, []string{"operation"})
+
+	durationOpts := prometheus.CounterOpts{
+		Name: "maintenance_duration_seconds",
+		Help: "time spent running maintenance operations per operation",
+	}
+	duration := promauto.NewCounterVec(durationOpts, []string{"operation"})
+
+	rewritesOpts := prometheus.CounterOpts{
+		Name: "maintenance_value_log_rewrites",
+		Help: "number of value log files rewritten by garbage collection",
+	}
+	rewrites := promauto.NewCounter(rewritesOpts)
+
+	sizeOpts := prometheus.GaugeOpts{
+		Name: "maintenance_database_size_bytes",
+		Help: "size of the database files after the last maintenance operation",
+	}
+	size := promauto.NewGauge(sizeOpts)
+
+	m := MetricsMaintainer{
+		maintain: maintain,
+
+		runs:      runs,
+		failures:  failures,
+		reclaimed: reclaimed,
+		duration:  duration,
+		rewrites:  rewrites,
+		size:      size,
+	}
+
+	return &m
+}
+
+// CollectGarbage runs value log garbage collection and records its metrics.
+func (m *MetricsMaintainer) CollectGarbage(ratio float64) (*Result, error) {
+	result, err := m.maintain.CollectGarbage(ratio)
+	m.record(operationGC, result, err)
+	if err != nil {
+		return nil, err
+	}
+	m.rewrites.Add(float64(result.Rewrites))
+	return result, nil
+}
+
+// Flatten flattens the LSM tree and records its metrics.
+func (m *MetricsMaintainer) Flatten(workers int) (*Result, error) {
+	result, err := m.maintain.Flatten(workers)
+	m.record(operationFlatten, result, err)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Activity returns the cumulative number of writes done on the Badger
+// databases of the current process.
+func (m *MetricsMaintainer) Activity() int64 {
+	return m.maintain.Activity()
+}
+
+func (m *MetricsMaintainer) record(operation string, result *Result, err error) {
+	m.runs.WithLabelValues(operation).Inc()
+	if err != nil {
+		m.failures.WithLabelValues(operation).Inc()
+		return
+	}
+	m.reclaimed.WithLabelValues(operation).Add(float64(result.Reclaimed()))
+	m.duration.WithLabelValues(operation).Add(result.Duration.Seconds())
+	m.size.Set(float64(result.After))
+}
@@ -0,0 +1,37 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package maintenance
+
+import (
+	"time"
+)
+
+// Result describes the outcome of a maintenance operation on the database.
+type Result struct {
+	Rewrites uint          // number of value log files rewritten
+	Before   int64         // size of the database files before the operation
+	After    int64         // size of the database files after the operation
+	Duration time.Duration // time it took to run the operation
+}
+
+// Reclaimed returns the number of bytes of disk space reclaimed by the
+// operation. If the database grew during the operation, for example because
+// of concurrent writes, no space is considered to have been reclaimed.
+func (r Result) Reclaimed() uint64 {
+	if r.After >= r.Before {
+		return 0
+	}
+	return uint64(r.Before - r.After)
+}
@@ -15,6 +15,7 @@
 package metrics
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -59,3 +60,15 @@ func (s *Server) Start() error {
 
 	return nil
 }
+
+// Shutdown gracefully stops the server, waiting for active requests to finish
+// until the given context expires. Once it was called, `Start` returns an
+// error that wraps `http.ErrServerClosed`.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		return fmt.Errorf("could not shut down server: %w", err)
+	}
+
+	return nil
+}
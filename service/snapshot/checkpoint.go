@@ -0,0 +1,130 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+
+	"github.com/dgraph-io/badger/v2/pb"
+)
+
+// Progress is the restoration progress of a single snapshot file.
+type Progress struct {
+	Lists uint64 `json:"lists"` // number of lists of entries loaded
+	Keys  uint64 `json:"keys"`  // number of keys loaded
+	Bytes uint64 `json:"bytes"` // number of decoded snapshot bytes loaded
+	Done  bool   `json:"done"`  // whether the whole file was loaded
+
+	// Latest is the loaded entry with the highest version, which is needed to
+	// finish the restoration even if the file is skipped when resuming.
+	Latest *pb.KV `json:"latest,omitempty"`
+}
+
+// Checkpoint records the restoration progress of snapshot files, keyed by
+// their checksum, so that an interrupted restoration can be resumed. It is
+// safe for concurrent use.
+type Checkpoint struct {
+	path     string
+	mutex    sync.Mutex
+	progress map[string]Progress
+}
+
+// OpenCheckpoint opens the checkpoint at the given path. If no checkpoint file
+// exists yet, it returns an empty checkpoint, which creates the file on its
+// first update.
+func OpenCheckpoint(path string) (*Checkpoint, error) {
+
+	c := Checkpoint{
+		path:     path,
+		progress: make(map[string]Progress),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read checkpoint file: %w", err)
+	}
+
+	err = json.Unmarshal(data, &c.progress)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode checkpoint: %w", err)
+	}
+
+	return &c, nil
+}
+
+// Resumed returns whether the checkpoint contains any progress.
+func (c *Checkpoint) Resumed() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return len(c.progress) > 0
+}
+
+// Progress returns the restoration progress of the snapshot file with the
+// given key.
+func (c *Checkpoint) Progress(key string) Progress {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.progress[key]
+}
+
+// Update records the restoration progress of the snapshot file with the given
+// key. The checkpoint file is replaced atomically, so that it remains valid
+// if the restoration is interrupted while writing it.
+func (c *Checkpoint) Update(key string, progress Progress) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.progress[key] = progress
+
+	data, err := json.MarshalIndent(c.progress, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode checkpoint: %w", err)
+	}
+
+	temp := c.path + ".tmp"
+	err = os.WriteFile(temp, data, 0644)
+	if err != nil {
+		return fmt.Errorf("could not write checkpoint file: %w", err)
+	}
+	err = os.Rename(temp, c.path)
+	if err != nil {
+		return fmt.Errorf("could not replace checkpoint file: %w", err)
+	}
+
+	return nil
+}
+
+// Remove removes the checkpoint file, once the restoration is complete.
+func (c *Checkpoint) Remove() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := os.Remove(c.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("could not remove checkpoint file: %w", err)
+	}
+
+	return nil
+}
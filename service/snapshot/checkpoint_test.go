@@ -0,0 +1,47 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package snapshot_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/service/snapshot"
+)
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "restore.checkpoint")
+
+	checkpoint, err := snapshot.OpenCheckpoint(path)
+	require.NoError(t, err)
+	assert.False(t, checkpoint.Resumed())
+	assert.Equal(t, snapshot.Progress{}, checkpoint.Progress("chunk"))
+
+	progress := snapshot.Progress{Lists: 3, Keys: 42, Bytes: 1337}
+	err = checkpoint.Update("chunk", progress)
+	require.NoError(t, err)
+
+	reopened, err := snapshot.OpenCheckpoint(path)
+	require.NoError(t, err)
+	assert.True(t, reopened.Resumed())
+	assert.Equal(t, progress, reopened.Progress("chunk"))
+
+	err = reopened.Remove()
+	require.NoError(t, err)
+	assert.NoFileExists(t, path)
+}
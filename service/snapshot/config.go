@@ -0,0 +1,51 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package snapshot
+
+import (
+	"runtime"
+)
+
+// DefaultConfig is the default configuration for the Loader.
+var DefaultConfig = Config{
+	SegmentSize:   64 * 1024 * 1024,
+	PendingWrites: runtime.GOMAXPROCS(0),
+}
+
+// Config contains optional parameters for the Loader.
+type Config struct {
+	SegmentSize   uint64
+	PendingWrites int
+}
+
+// Option is an option that can be given to the loader to configure optional
+// parameters on initialization.
+type Option func(*Config)
+
+// WithSegmentSize sets the number of decoded snapshot bytes that are loaded
+// between two checkpoints of the restoration progress.
+func WithSegmentSize(size uint64) Option {
+	return func(cfg *Config) {
+		cfg.SegmentSize = size
+	}
+}
+
+// WithPendingWrites sets the maximum number of pending writes to the database
+// for each snapshot file that is being loaded.
+func WithPendingWrites(writes int) Option {
+	return func(cfg *Config) {
+		cfg.PendingWrites = writes
+	}
+}
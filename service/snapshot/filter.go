@@ -0,0 +1,245 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package snapshot
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/dgraph-io/badger/v2"
+
+	"github.com/onflow/flow-go/ledger/common/pathfinder"
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/storage"
+)
+
+// Filter selects the entries of an index database that are included in a
+// partial snapshot, based on their category and height.
+//
+// Entries keyed by height are included if their height is within the range.
+// Entries keyed by identifier are included if they are referenced at a height
+// within the range. Registers are included up to the last height of the range,
+// so that their values can be read at every height within the range.
+type Filter struct {
+	codec      dps.Codec
+	categories []string
+	prefixes   map[uint8]struct{}
+	first      uint64
+	last       uint64
+	ranged     bool
+	ids        map[flow.Identifier]struct{}
+}
+
+// NewFilter creates a new filter for the given index, which includes the given
+// categories of data between the given heights. The height range is clamped
+// to the range of heights available in the index.
+func NewFilter(index dps.Reader, codec dps.Codec, from uint64, to uint64, categories ...string) (*Filter, error) {
+
+	prefixes := make(map[uint8]struct{})
+	for _, category := range categories {
+		categoryPrefixes, ok := storage.Categories[category]
+		if !ok {
+			return nil, fmt.Errorf("unknown data category (%s)", category)
+		}
+		for _, prefix := range categoryPrefixes {
+			prefixes[prefix] = struct{}{}
+		}
+	}
+
+	first, err := index.First()
+	if err != nil {
+		return nil, fmt.Errorf("could not get first height: %w", err)
+	}
+	last, err := index.Last()
+	if err != nil {
+		return nil, fmt.Errorf("could not get last height: %w", err)
+	}
+	ranged := from > first || to < last
+	if from > first {
+		first = from
+	}
+	if to < last {
+		last = to
+	}
+	if first > last {
+		return nil, fmt.Errorf("height range %d - %d does not overlap with index", from, to)
+	}
+
+	sorted := make([]string, len(categories))
+	copy(sorted, categories)
+	sort.Strings(sorted)
+
+	f := Filter{
+		codec:      codec,
+		categories: sorted,
+		prefixes:   prefixes,
+		first:      first,
+		last:       last,
+		ranged:     ranged,
+		ids:        make(map[flow.Identifier]struct{}),
+	}
+
+	// When only a range of heights is included, we need to know which of the
+	// entries keyed by identifier are referenced within that range.
+	if !ranged {
+		return &f, nil
+	}
+	for height := first; height <= last; height++ {
+		err = f.reference(index, height)
+		if err != nil {
+			return nil, fmt.Errorf("could not get identifiers for height %d: %w", height, err)
+		}
+	}
+
+	return &f, nil
+}
+
+// First returns the first height included by the filter.
+func (f *Filter) First() uint64 {
+	return f.first
+}
+
+// Last returns the last height included by the filter.
+func (f *Filter) Last() uint64 {
+	return f.last
+}
+
+// Categories returns the sorted categories of data included by the filter.
+func (f *Filter) Categories() []string {
+	return f.categories
+}
+
+// Choose returns whether the given entry is included by the filter. It can be
+// used as the `ChooseKey` function of a Badger stream.
+func (f *Filter) Choose(item *badger.Item) bool {
+
+	key := item.Key()
+	if len(key) == 0 {
+		return false
+	}
+
+	// The boundaries and contents are written separately, as they differ from
+	// those of the original index.
+	prefix := key[0]
+	_, ok := f.prefixes[prefix]
+	if !ok {
+		return false
+	}
+	if !f.ranged {
+		return true
+	}
+
+	switch prefix {
+
+	case storage.PrefixCommit,
+		storage.PrefixHeader,
+		storage.PrefixEvents,
+		storage.PrefixTransactionsForHeight,
+		storage.PrefixCollectionsForHeight,
+		storage.PrefixSealsForHeight:
+		height := binary.BigEndian.Uint64(key[1:9])
+		return height >= f.first && height <= f.last
+
+	case storage.PrefixPayload:
+		height := binary.BigEndian.Uint64(key[1+pathfinder.PathByteSize:])
+		return height <= f.last
+
+	case storage.PrefixHeightForBlock,
+		storage.PrefixHeightForTransaction,
+		storage.PrefixHeightForCollection,
+		storage.PrefixHeightForSeal:
+		var height uint64
+		err := item.Value(func(val []byte) error {
+			return f.codec.Unmarshal(val, &height)
+		})
+		return err == nil && height >= f.first && height <= f.last
+
+	default:
+		var id flow.Identifier
+		copy(id[:], key[1:])
+		_, ok := f.ids[id]
+		return ok
+	}
+}
+
+// Backup writes the entries included by the filter to the given writer, using
+// the Badger backup format, followed by the boundaries and the contents of the
+// partial index. It returns the last Badger version included.
+func (f *Filter) Backup(db *badger.DB, writer io.Writer) (uint64, error) {
+
+	stream := db.NewStream()
+	stream.LogPrefix = "DB.Backup"
+	stream.ChooseKey = f.Choose
+	version, err := stream.Backup(writer, 0)
+	if err != nil {
+		return 0, fmt.Errorf("could not back up filtered entries: %w", err)
+	}
+
+	// We use a temporary in-memory database to encode the boundaries and the
+	// contents of the partial index, and then append its backup to the
+	// filtered entries.
+	opts := badger.DefaultOptions("").WithInMemory(true).WithLogger(nil)
+	mem, err := badger.Open(opts)
+	if err != nil {
+		return 0, fmt.Errorf("could not open in-memory database: %w", err)
+	}
+	defer mem.Close()
+
+	lib := storage.New(f.codec)
+	err = mem.Update(storage.Combine(
+		lib.SaveFirst(f.first),
+		lib.SaveLast(f.last),
+		lib.SaveContents(f.categories),
+	))
+	if err != nil {
+		return 0, fmt.Errorf("could not encode partial index metadata: %w", err)
+	}
+	_, err = mem.Backup(writer, 0)
+	if err != nil {
+		return 0, fmt.Errorf("could not back up partial index metadata: %w", err)
+	}
+
+	return version, nil
+}
+
+func (f *Filter) reference(index dps.Reader, height uint64) error {
+
+	// Blocks can be missing any of these, so we simply skip identifiers for
+	// which no index entry exists.
+	lookups := []func(uint64) ([]flow.Identifier, error){
+		index.TransactionsByHeight,
+		index.CollectionsByHeight,
+		index.SealsByHeight,
+	}
+	for _, lookup := range lookups {
+		ids, err := lookup(height)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			f.ids[id] = struct{}{}
+		}
+	}
+
+	return nil
+}
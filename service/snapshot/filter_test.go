@@ -0,0 +1,109 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package snapshot_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/service/index"
+	"github.com/optakt/flow-dps/service/snapshot"
+	"github.com/optakt/flow-dps/service/storage"
+	"github.com/optakt/flow-dps/testing/helpers"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestFilter_Backup(t *testing.T) {
+	codec := zbor.NewCodec()
+	lib := storage.New(codec)
+
+	db := helpers.InMemoryDB(t)
+	defer db.Close()
+
+	// Index three heights, each with its own header, commit and transaction.
+	var headers []*flow.Header
+	transactions := mocks.GenericTransactions(3)
+	for i, height := range []uint64{1, 2, 3} {
+		header := *mocks.GenericHeader
+		header.Height = height
+		headers = append(headers, &header)
+		tx := transactions[i]
+		err := db.Update(storage.Combine(
+			lib.SaveHeader(height, &header),
+			lib.IndexHeightForBlock(header.ID(), height),
+			lib.SaveCommit(height, mocks.GenericCommit(i)),
+			lib.SaveTransaction(tx),
+			lib.IndexHeightForTransaction(tx.ID(), height),
+			lib.IndexTransactionsForHeight(height, []flow.Identifier{tx.ID()}),
+		))
+		require.NoError(t, err)
+	}
+	err := db.Update(storage.Combine(lib.SaveFirst(1), lib.SaveLast(3)))
+	require.NoError(t, err)
+
+	filter, err := snapshot.NewFilter(index.NewReader(db, lib), codec, 2, 5, storage.CategoryTransactions, storage.CategoryHeaders)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), filter.First())
+	assert.Equal(t, uint64(3), filter.Last())
+	assert.Equal(t, []string{storage.CategoryHeaders, storage.CategoryTransactions}, filter.Categories())
+
+	var buf bytes.Buffer
+	_, err = filter.Backup(db, &buf)
+	require.NoError(t, err)
+
+	restored := helpers.InMemoryDB(t)
+	defer restored.Close()
+	err = restored.Load(&buf, 10)
+	require.NoError(t, err)
+
+	reader := index.NewReader(restored, lib)
+
+	first, err := reader.First()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), first)
+
+	last, err := reader.Last()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), last)
+
+	var categories []string
+	err = restored.View(lib.RetrieveContents(&categories))
+	require.NoError(t, err)
+	assert.Equal(t, filter.Categories(), categories)
+
+	_, err = reader.Header(1)
+	assert.ErrorIs(t, err, badger.ErrKeyNotFound)
+	_, err = reader.HeightForBlock(headers[0].ID())
+	assert.ErrorIs(t, err, badger.ErrKeyNotFound)
+	_, err = reader.Transaction(transactions[0].ID())
+	assert.ErrorIs(t, err, badger.ErrKeyNotFound)
+
+	header, err := reader.Header(2)
+	require.NoError(t, err)
+	assert.Equal(t, headers[1], header)
+	height, err := reader.HeightForTransaction(transactions[2].ID())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), height)
+
+	_, err = reader.Commit(2)
+	assert.ErrorIs(t, err, badger.ErrKeyNotFound)
+}
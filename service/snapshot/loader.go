@@ -0,0 +1,213 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/pb"
+)
+
+// Loader loads Badger backup streams into a database. Unlike `db.Load`, it
+// loads streams in segments, and reports its progress after each segment has
+// been synced to disk, so that the restoration of a stream can be resumed
+// after an interruption. Multiple streams can be loaded concurrently, as long
+// as they do not contain the same keys, which is the case for the chunks of a
+// single snapshot.
+type Loader struct {
+	db     *badger.DB
+	cfg    Config
+	mutex  sync.Mutex
+	latest *pb.KV
+}
+
+// NewLoader creates a new loader for the given database.
+func NewLoader(db *badger.DB, options ...Option) *Loader {
+
+	cfg := DefaultConfig
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	l := Loader{
+		db:  db,
+		cfg: cfg,
+	}
+
+	return &l
+}
+
+// Load loads the backup stream from the given reader, starting from the given
+// progress. The lists of entries that were already loaded are skipped. After
+// each segment, and once the whole stream is loaded, the updated progress is
+// passed to the given checkpoint function.
+func (l *Loader) Load(reader io.Reader, progress Progress, checkpoint func(Progress) error) error {
+
+	if progress.Done {
+		l.Skip(progress)
+		return nil
+	}
+
+	buffered := bufio.NewReaderSize(reader, 16<<10)
+	buf := make([]byte, 1<<10)
+	loader := l.db.NewKVLoader(l.cfg.PendingWrites)
+	var latest *pb.KV
+	lists := uint64(0)
+	pending := uint64(0)
+	for {
+
+		var size uint64
+		err := binary.Read(buffered, binary.LittleEndian, &size)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("could not read list size: %w", err)
+		}
+		if uint64(cap(buf)) < size {
+			buf = make([]byte, size)
+		}
+		_, err = io.ReadFull(buffered, buf[:size])
+		if err != nil {
+			return fmt.Errorf("could not read list: %w", err)
+		}
+
+		// We still decode the lists that were already loaded, so that we know
+		// the highest version of the stream when resuming.
+		var list pb.KVList
+		err = list.Unmarshal(buf[:size])
+		if err != nil {
+			return fmt.Errorf("could not decode list: %w", err)
+		}
+		for _, kv := range list.Kv {
+			if latest == nil || kv.Version > latest.Version {
+				latest = kv
+			}
+		}
+		lists++
+		if lists <= progress.Lists {
+			continue
+		}
+
+		for _, kv := range list.Kv {
+			err = loader.Set(kv)
+			if err != nil {
+				return fmt.Errorf("could not load entry: %w", err)
+			}
+		}
+		progress.Lists++
+		progress.Keys += uint64(len(list.Kv))
+		progress.Bytes += 8 + size
+		pending += 8 + size
+
+		if pending < l.cfg.SegmentSize {
+			continue
+		}
+
+		err = l.flush(loader)
+		if err != nil {
+			return err
+		}
+		progress.Latest = latest
+		err = checkpoint(progress)
+		if err != nil {
+			return fmt.Errorf("could not checkpoint progress: %w", err)
+		}
+		loader = l.db.NewKVLoader(l.cfg.PendingWrites)
+		pending = 0
+	}
+
+	err := l.flush(loader)
+	if err != nil {
+		return err
+	}
+	l.track(latest)
+	progress.Latest = latest
+	progress.Done = true
+	err = checkpoint(progress)
+	if err != nil {
+		return fmt.Errorf("could not checkpoint progress: %w", err)
+	}
+
+	return nil
+}
+
+// Skip accounts for a stream that was already loaded completely by a previous
+// restoration, without reading it again.
+func (l *Loader) Skip(progress Progress) {
+	l.track(progress.Latest)
+}
+
+// Finish concludes the restoration once all streams were loaded. The loaded
+// entries keep the versions of the backup, but Badger only advances its own
+// timestamp when loading with `db.Load`. In order to make sure that later
+// writes are not shadowed by restored entries, we reload the entry with the
+// highest version that way.
+func (l *Loader) Finish() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.latest == nil {
+		return nil
+	}
+
+	list := pb.KVList{Kv: []*pb.KV{l.latest}}
+	data, err := list.Marshal()
+	if err != nil {
+		return fmt.Errorf("could not encode latest entry: %w", err)
+	}
+	var stream bytes.Buffer
+	_ = binary.Write(&stream, binary.LittleEndian, uint64(len(data)))
+	_, _ = stream.Write(data)
+	err = l.db.Load(&stream, 1)
+	if err != nil {
+		return fmt.Errorf("could not reload latest entry: %w", err)
+	}
+
+	return nil
+}
+
+func (l *Loader) flush(loader *badger.KVLoader) error {
+
+	err := loader.Finish()
+	if err != nil {
+		return fmt.Errorf("could not finish loading segment: %w", err)
+	}
+	err = l.db.Sync()
+	if err != nil {
+		return fmt.Errorf("could not sync segment to disk: %w", err)
+	}
+
+	return nil
+}
+
+func (l *Loader) track(kv *pb.KV) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if kv == nil {
+		return
+	}
+	if l.latest == nil || kv.Version > l.latest.Version {
+		l.latest = kv
+	}
+}
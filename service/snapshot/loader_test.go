@@ -0,0 +1,203 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package snapshot_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/service/snapshot"
+	"github.com/optakt/flow-dps/testing/helpers"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestLoader_Load(t *testing.T) {
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		db := helpers.InMemoryDB(t)
+		defer db.Close()
+
+		var checkpoints []snapshot.Progress
+		loader := snapshot.NewLoader(db, snapshot.WithSegmentSize(1))
+		err := loader.Load(testStream(t, 3, 2), snapshot.Progress{}, func(progress snapshot.Progress) error {
+			checkpoints = append(checkpoints, progress)
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.Len(t, checkpoints, 4)
+		last := checkpoints[len(checkpoints)-1]
+		assert.True(t, last.Done)
+		assert.Equal(t, uint64(3), last.Lists)
+		assert.Equal(t, uint64(6), last.Keys)
+		assert.Equal(t, uint64(6), last.Latest.Version)
+
+		require.NoError(t, loader.Finish())
+		assertKeys(t, db, 6)
+	})
+
+	t.Run("resumes after interruption", func(t *testing.T) {
+		t.Parallel()
+
+		db := helpers.InMemoryDB(t)
+		defer db.Close()
+
+		// Interrupt the restoration after the first segment.
+		var saved snapshot.Progress
+		loader := snapshot.NewLoader(db, snapshot.WithSegmentSize(1))
+		err := loader.Load(testStream(t, 3, 2), snapshot.Progress{}, func(progress snapshot.Progress) error {
+			saved = progress
+			return mocks.GenericError
+		})
+		require.Error(t, err)
+		assert.Equal(t, uint64(1), saved.Lists)
+
+		var checkpoints []snapshot.Progress
+		loader = snapshot.NewLoader(db, snapshot.WithSegmentSize(1))
+		err = loader.Load(testStream(t, 3, 2), saved, func(progress snapshot.Progress) error {
+			checkpoints = append(checkpoints, progress)
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.Len(t, checkpoints, 3)
+		assert.Equal(t, uint64(2), checkpoints[0].Lists)
+		last := checkpoints[len(checkpoints)-1]
+		assert.True(t, last.Done)
+		assert.Equal(t, uint64(6), last.Keys)
+
+		require.NoError(t, loader.Finish())
+		assertKeys(t, db, 6)
+	})
+
+	t.Run("skips finished streams", func(t *testing.T) {
+		t.Parallel()
+
+		db := helpers.InMemoryDB(t)
+		defer db.Close()
+
+		loader := snapshot.NewLoader(db)
+		err := loader.Load(testStream(t, 3, 2), snapshot.Progress{Done: true}, func(snapshot.Progress) error {
+			return mocks.GenericError
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, loader.Finish())
+		assertKeys(t, db, 0)
+	})
+
+	t.Run("handles truncated stream", func(t *testing.T) {
+		t.Parallel()
+
+		db := helpers.InMemoryDB(t)
+		defer db.Close()
+
+		data, err := io.ReadAll(testStream(t, 1, 2))
+		require.NoError(t, err)
+
+		loader := snapshot.NewLoader(db)
+		err = loader.Load(bytes.NewReader(data[:len(data)-1]), snapshot.Progress{}, func(snapshot.Progress) error {
+			return nil
+		})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestLoader_Finish(t *testing.T) {
+	db := helpers.InMemoryDB(t)
+	defer db.Close()
+
+	loader := snapshot.NewLoader(db)
+	err := loader.Load(testStream(t, 2, 2), snapshot.Progress{}, func(snapshot.Progress) error {
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = loader.Finish()
+	require.NoError(t, err)
+
+	// New writes should have a higher version than all restored entries.
+	err = db.Update(func(tx *badger.Txn) error {
+		return tx.Set([]byte("key-0-0"), []byte("updated"))
+	})
+	require.NoError(t, err)
+	err = db.View(func(tx *badger.Txn) error {
+		item, err := tx.Get([]byte("key-0-0"))
+		if err != nil {
+			return err
+		}
+		assert.Greater(t, item.Version(), uint64(4))
+		return item.Value(func(val []byte) error {
+			assert.Equal(t, []byte("updated"), val)
+			return nil
+		})
+	})
+	require.NoError(t, err)
+}
+
+// testStream creates a backup stream with the given number of lists, each
+// containing the given number of entries with increasing versions.
+func testStream(t *testing.T, lists int, entries int) io.Reader {
+	t.Helper()
+
+	var stream bytes.Buffer
+	version := uint64(1)
+	for i := 0; i < lists; i++ {
+		var list pb.KVList
+		for j := 0; j < entries; j++ {
+			kv := pb.KV{
+				Key:     []byte(fmt.Sprintf("key-%d-%d", i, j)),
+				Value:   []byte(fmt.Sprintf("value-%d-%d", i, j)),
+				Version: version,
+			}
+			list.Kv = append(list.Kv, &kv)
+			version++
+		}
+		data, err := list.Marshal()
+		require.NoError(t, err)
+		require.NoError(t, binary.Write(&stream, binary.LittleEndian, uint64(len(data))))
+		_, err = stream.Write(data)
+		require.NoError(t, err)
+	}
+
+	return &stream
+}
+
+func assertKeys(t *testing.T, db *badger.DB, want int) {
+	t.Helper()
+
+	count := 0
+	err := db.View(func(tx *badger.Txn) error {
+		it := tx.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			count++
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, want, count)
+}
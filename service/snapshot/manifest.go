@@ -0,0 +1,177 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package snapshot
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// Manifest describes a single index snapshot. A snapshot is either a full dump
+// of the index database, or an incremental dump which only contains the
+// changes since its base snapshot.
+type Manifest struct {
+	File        string `json:"file"`
+	Compression string `json:"compression"`
+	Encoding    string `json:"encoding"`
+	Base        string `json:"base,omitempty"` // checksum of the base snapshot
+	Since       uint64 `json:"since"`          // first Badger version included
+	Version     uint64 `json:"version"`        // last Badger version included
+	First       uint64 `json:"first"`          // first indexed height after applying
+	Last        uint64 `json:"last"`           // last indexed height after applying
+	Commit      string `json:"commit"`         // state commitment at last height
+	Codec       uint   `json:"codec"`          // version of the codec for values
+	Checksum    string `json:"checksum"`       // SHA-256 of the snapshot file(s), in order
+
+	// Chunks are the files of a snapshot that was split into chunks, which
+	// can be restored in parallel; they are empty for a single-file snapshot.
+	Chunks []Chunk `json:"chunks,omitempty"`
+
+	// Categories are the categories of data included in a partial snapshot;
+	// they are empty for a snapshot of the full index.
+	Categories []string `json:"categories,omitempty"`
+}
+
+// Chunk is a single file of a snapshot that was split into chunks.
+type Chunk struct {
+	File     string `json:"file"`
+	Checksum string `json:"checksum"` // SHA-256 of the chunk file
+}
+
+// Files returns the files of the snapshot, which is a single file unless the
+// snapshot was split into chunks.
+func (m Manifest) Files() []Chunk {
+	if len(m.Chunks) > 0 {
+		return m.Chunks
+	}
+	return []Chunk{{File: m.File, Checksum: m.Checksum}}
+}
+
+// Incremental returns whether the snapshot only contains the changes since its
+// base snapshot.
+func (m Manifest) Incremental() bool {
+	return m.Base != ""
+}
+
+// Next returns the Badger version from which an incremental snapshot based on
+// this snapshot should start.
+func (m Manifest) Next() uint64 {
+	return m.Version + 1
+}
+
+// Verify checks that the given index matches the heights and the state
+// commitment of the manifest, which should be the case after restoring the
+// snapshot and all of the snapshots it is based on. The state commitment is
+// not verified for partial snapshots that do not include commits.
+func (m Manifest) Verify(index dps.Reader) error {
+
+	first, err := index.First()
+	if err != nil {
+		return fmt.Errorf("could not get first height: %w", err)
+	}
+	if first != m.First {
+		return fmt.Errorf("first height mismatch (have: %d, want: %d)", first, m.First)
+	}
+
+	last, err := index.Last()
+	if err != nil {
+		return fmt.Errorf("could not get last height: %w", err)
+	}
+	if last != m.Last {
+		return fmt.Errorf("last height mismatch (have: %d, want: %d)", last, m.Last)
+	}
+
+	if m.Commit == "" {
+		return nil
+	}
+
+	commit, err := index.Commit(last)
+	if err != nil {
+		return fmt.Errorf("could not get commit for last height: %w", err)
+	}
+	if hex.EncodeToString(commit[:]) != m.Commit {
+		return fmt.Errorf("commit mismatch at last height (have: %x, want: %s)", commit, m.Commit)
+	}
+
+	return nil
+}
+
+// ReadManifest reads the snapshot manifest at the given path.
+func ReadManifest(path string) (*Manifest, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read manifest file: %w", err)
+	}
+
+	var manifest Manifest
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode manifest: %w", err)
+	}
+
+	return &manifest, nil
+}
+
+// WriteManifest writes the given snapshot manifest to the given path.
+func WriteManifest(path string, manifest *Manifest) error {
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode manifest: %w", err)
+	}
+
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return fmt.Errorf("could not write manifest file: %w", err)
+	}
+
+	return nil
+}
+
+// Chain validates that the given manifests form a chain of snapshots which can
+// be restored in order: a full snapshot, followed by incremental snapshots that
+// are each based on the previous one. As the codec can decode values encoded by
+// its previous versions, the codec version can only increase along the chain.
+func Chain(manifests ...*Manifest) error {
+
+	if len(manifests) == 0 {
+		return fmt.Errorf("at least one manifest must be provided")
+	}
+
+	if manifests[0].Incremental() {
+		return fmt.Errorf("first snapshot must be a full snapshot (base: %s)", manifests[0].Base)
+	}
+
+	for i := 1; i < len(manifests); i++ {
+		previous := manifests[i-1]
+		current := manifests[i]
+		if current.Codec < previous.Codec {
+			return fmt.Errorf("snapshot %d codec version is older than snapshot %d (codec: %d, previous: %d)", i, i-1, current.Codec, previous.Codec)
+		}
+		if current.Base != previous.Checksum {
+			return fmt.Errorf("snapshot %d is not based on snapshot %d (base: %s, previous: %s)", i, i-1, current.Base, previous.Checksum)
+		}
+		if current.Since != previous.Next() {
+			return fmt.Errorf("snapshot %d does not continue snapshot %d (since: %d, previous: %d)", i, i-1, current.Since, previous.Version)
+		}
+	}
+
+	return nil
+}
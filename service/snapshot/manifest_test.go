@@ -0,0 +1,178 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package snapshot_test
+
+import (
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/service/snapshot"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestManifest_ReadWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	manifest := snapshot.Manifest{
+		File:        "snapshot.zst",
+		Compression: "zstd",
+		Encoding:    "none",
+		Base:        "base",
+		Since:       11,
+		Version:     20,
+		First:       1,
+		Last:        42,
+		Commit:      "commit",
+		Codec:       1,
+		Checksum:    "checksum",
+	}
+
+	err := snapshot.WriteManifest(path, &manifest)
+	require.NoError(t, err)
+
+	got, err := snapshot.ReadManifest(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, &manifest, got)
+	assert.True(t, got.Incremental())
+	assert.Equal(t, uint64(21), got.Next())
+}
+
+func TestChain(t *testing.T) {
+	full := &snapshot.Manifest{Since: 0, Version: 10, Checksum: "full"}
+	first := &snapshot.Manifest{Base: "full", Since: 11, Version: 20, Checksum: "first"}
+	second := &snapshot.Manifest{Base: "first", Since: 21, Version: 30, Checksum: "second"}
+
+	tests := []struct {
+		name string
+
+		manifests []*snapshot.Manifest
+
+		checkErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:      "nominal case",
+			manifests: []*snapshot.Manifest{full, first, second},
+			checkErr:  assert.NoError,
+		},
+		{
+			name:      "full snapshot only",
+			manifests: []*snapshot.Manifest{full},
+			checkErr:  assert.NoError,
+		},
+		{
+			name:      "no manifests",
+			manifests: []*snapshot.Manifest{},
+			checkErr:  assert.Error,
+		},
+		{
+			name:      "missing full snapshot",
+			manifests: []*snapshot.Manifest{first, second},
+			checkErr:  assert.Error,
+		},
+		{
+			name:      "missing incremental snapshot",
+			manifests: []*snapshot.Manifest{full, second},
+			checkErr:  assert.Error,
+		},
+		{
+			name:      "codec version upgrade",
+			manifests: []*snapshot.Manifest{full, {Base: "full", Since: 11, Version: 20, Codec: 2}},
+			checkErr:  assert.NoError,
+		},
+		{
+			name:      "codec version downgrade",
+			manifests: []*snapshot.Manifest{{Since: 0, Version: 10, Codec: 2, Checksum: "full"}, {Base: "full", Since: 11, Version: 20, Codec: 1}},
+			checkErr:  assert.Error,
+		},
+		{
+			name:      "version gap",
+			manifests: []*snapshot.Manifest{full, {Base: "full", Since: 12, Version: 20}},
+			checkErr:  assert.Error,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := snapshot.Chain(test.manifests...)
+
+			test.checkErr(t, err)
+		})
+	}
+}
+
+func TestManifest_Verify(t *testing.T) {
+	commit := mocks.GenericCommit(0)
+	manifest := snapshot.Manifest{
+		First:  mocks.GenericHeight,
+		Last:   mocks.GenericHeight,
+		Commit: hex.EncodeToString(commit[:]),
+	}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		err := manifest.Verify(mocks.BaselineReader(t))
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("handles last height mismatch", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return mocks.GenericHeight + 1, nil
+		}
+
+		err := manifest.Verify(index)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles commit mismatch", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.CommitFunc = func(uint64) (flow.StateCommitment, error) {
+			return mocks.GenericCommit(1), nil
+		}
+
+		err := manifest.Verify(index)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles index failure", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.FirstFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		err := manifest.Verify(index)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,141 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package snapshot
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Splitter is a writer which splits a Badger backup stream into chunks of
+// roughly the given size. It only splits the stream between lists of entries,
+// so that each chunk is a valid backup stream of its own, and chunks can be
+// restored independently and in parallel.
+type Splitter struct {
+	size    uint64
+	open    func(index int) (io.WriteCloser, error)
+	current io.WriteCloser
+	chunks  int
+	written uint64
+	buffer  []byte
+}
+
+// NewSplitter creates a new splitter which uses the given function to open
+// the writer for each chunk, and starts a new chunk once the current one
+// reaches the given size.
+func NewSplitter(size uint64, open func(index int) (io.WriteCloser, error)) *Splitter {
+
+	s := Splitter{
+		size: size,
+		open: open,
+	}
+
+	return &s
+}
+
+// Write buffers the given data until it contains complete lists of entries,
+// which are then written to the current chunk.
+func (s *Splitter) Write(p []byte) (int, error) {
+
+	s.buffer = append(s.buffer, p...)
+
+	// Each list of entries is prefixed with its size, as a little-endian
+	// unsigned 64-bit integer.
+	offset := 0
+	for len(s.buffer)-offset >= 8 {
+		size := binary.LittleEndian.Uint64(s.buffer[offset : offset+8])
+		if uint64(len(s.buffer)-offset-8) < size {
+			break
+		}
+		end := offset + 8 + int(size)
+		err := s.write(s.buffer[offset:end])
+		if err != nil {
+			return 0, err
+		}
+		offset = end
+	}
+
+	// Keep only the incomplete list at the end of the buffer.
+	s.buffer = append(s.buffer[:0], s.buffer[offset:]...)
+
+	return len(p), nil
+}
+
+// Close closes the current chunk. If no data was written at all, it creates
+// a single empty chunk, so that there is always at least one chunk.
+func (s *Splitter) Close() error {
+
+	if len(s.buffer) > 0 {
+		return fmt.Errorf("incomplete list of entries at end of stream (%d bytes)", len(s.buffer))
+	}
+
+	if s.current == nil {
+		err := s.next()
+		if err != nil {
+			return err
+		}
+	}
+
+	err := s.current.Close()
+	if err != nil {
+		return fmt.Errorf("could not close chunk %d: %w", s.chunks-1, err)
+	}
+
+	return nil
+}
+
+// Chunks returns the number of chunks that were opened.
+func (s *Splitter) Chunks() int {
+	return s.chunks
+}
+
+func (s *Splitter) write(list []byte) error {
+
+	if s.current == nil || (s.written > 0 && s.written+uint64(len(list)) > s.size) {
+		err := s.next()
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := s.current.Write(list)
+	if err != nil {
+		return fmt.Errorf("could not write to chunk %d: %w", s.chunks-1, err)
+	}
+	s.written += uint64(len(list))
+
+	return nil
+}
+
+func (s *Splitter) next() error {
+
+	if s.current != nil {
+		err := s.current.Close()
+		if err != nil {
+			return fmt.Errorf("could not close chunk %d: %w", s.chunks-1, err)
+		}
+	}
+
+	current, err := s.open(s.chunks)
+	if err != nil {
+		return fmt.Errorf("could not open chunk %d: %w", s.chunks, err)
+	}
+	s.current = current
+	s.chunks++
+	s.written = 0
+
+	return nil
+}
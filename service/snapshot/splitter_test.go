@@ -0,0 +1,69 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package snapshot_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/service/snapshot"
+	"github.com/optakt/flow-dps/testing/helpers"
+)
+
+func TestSplitter(t *testing.T) {
+	data, err := io.ReadAll(testStream(t, 3, 2))
+	require.NoError(t, err)
+
+	var chunks []*bytes.Buffer
+	splitter := snapshot.NewSplitter(1, func(index int) (io.WriteCloser, error) {
+		chunk := &bytes.Buffer{}
+		chunks = append(chunks, chunk)
+		return nopCloser{chunk}, nil
+	})
+
+	// Write the stream in small pieces, so that lists are split across writes.
+	for len(data) > 0 {
+		size := 7
+		if len(data) < size {
+			size = len(data)
+		}
+		_, err = splitter.Write(data[:size])
+		require.NoError(t, err)
+		data = data[size:]
+	}
+	err = splitter.Close()
+	require.NoError(t, err)
+
+	// Each chunk should be a valid backup stream of its own.
+	require.Equal(t, 3, splitter.Chunks())
+	for _, chunk := range chunks {
+		db := helpers.InMemoryDB(t)
+		err = db.Load(chunk, 1)
+		require.NoError(t, err)
+		assertKeys(t, db, 2)
+		require.NoError(t, db.Close())
+	}
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package spork
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// defaultManifest is the manifest of the sporks served by the public DPS
+// instances, which is used when no other manifest is provided.
+//
+//go:embed sporks.yaml
+var defaultManifest []byte
+
+// Manifest is the on-disk representation of a spork registry.
+type Manifest struct {
+	Sporks []Spork `json:"sporks" yaml:"sporks"`
+}
+
+// Default returns the registry for the sporks served by the public DPS
+// instances.
+func Default() *Registry {
+
+	// The default manifest is embedded at compile time, so we should never
+	// fail here; use panic to keep the function signature clean.
+	registry, err := FromYAML(defaultManifest)
+	if err != nil {
+		panic(err)
+	}
+
+	return registry
+}
+
+// FromFile reads the spork manifest at the given path and returns the related
+// registry. The format of the manifest is chosen based on the file extension,
+// which should be one of `.json`, `.yaml` or `.yml`.
+func FromFile(path string) (*Registry, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read manifest file: %w", err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".json":
+		return FromJSON(data)
+	case ".yaml", ".yml":
+		return FromYAML(data)
+	default:
+		return nil, fmt.Errorf("unsupported manifest extension (%s)", ext)
+	}
+}
+
+// FromJSON decodes the given JSON spork manifest and returns the related
+// registry.
+func FromJSON(data []byte) (*Registry, error) {
+
+	var manifest Manifest
+	err := json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode JSON manifest: %w", err)
+	}
+
+	return New(manifest.Sporks...)
+}
+
+// FromYAML decodes the given YAML spork manifest and returns the related
+// registry.
+func FromYAML(data []byte) (*Registry, error) {
+
+	var manifest Manifest
+	err := yaml.UnmarshalStrict(data, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode YAML manifest: %w", err)
+	}
+
+	return New(manifest.Sporks...)
+}
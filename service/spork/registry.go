@@ -0,0 +1,166 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package spork
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ErrNotFound is returned when no spork of the registry covers a given height.
+var ErrNotFound = errors.New("spork not found")
+
+// Index represents something that can report the range of heights available
+// on a DPS index, such as the GRPC-based `dps.Index`.
+type Index interface {
+	First() (uint64, error)
+	Last() (uint64, error)
+}
+
+// Registry is a validated list of sporks, sorted by height. It guarantees that
+// the sporks form a contiguous range of heights, without gaps or overlaps.
+// A registry is immutable once created, so it can safely be shared between
+// goroutines.
+type Registry struct {
+	sporks []Spork
+}
+
+// New creates a new registry from the given sporks. The sporks can be given in
+// any order; they are sorted by first height before being validated. A last
+// height of zero on the most recent spork means that it is still ongoing.
+func New(sporks ...Spork) (*Registry, error) {
+
+	if len(sporks) == 0 {
+		return nil, fmt.Errorf("at least one spork must be provided")
+	}
+
+	sorted := make([]Spork, len(sporks))
+	copy(sorted, sporks)
+	sort.Slice(sorted, func(i int, j int) bool {
+		return sorted[i].First < sorted[j].First
+	})
+
+	// The most recent spork is allowed to omit its last height, in which case
+	// it is considered to be ongoing and covers all heights above its first.
+	latest := &sorted[len(sorted)-1]
+	if latest.Last == 0 {
+		latest.Last = math.MaxUint64
+	}
+
+	for i, spork := range sorted {
+		if spork.API == "" && spork.Rosetta == "" {
+			return nil, fmt.Errorf("spork %d (%s) has no API address", i, spork.Name)
+		}
+		if spork.First >= spork.Last {
+			return nil, fmt.Errorf("spork %d (%s) last height is not greater than first", i, spork.Name)
+		}
+	}
+
+	for i := 0; i < len(sorted)-1; i++ {
+		current := sorted[i]
+		next := sorted[i+1]
+		if current.Last >= next.First {
+			return nil, fmt.Errorf("overlap between sporks boundaries: %d and %d", current.Last, next.First)
+		}
+		if current.Last != next.First-1 {
+			return nil, fmt.Errorf("gap between sporks boundaries: %d and %d", current.Last, next.First)
+		}
+	}
+
+	r := Registry{
+		sporks: sorted,
+	}
+
+	return &r, nil
+}
+
+// Sporks returns a copy of the sporks of the registry, sorted by height.
+func (r *Registry) Sporks() []Spork {
+	sporks := make([]Spork, len(r.sporks))
+	copy(sporks, r.sporks)
+	return sporks
+}
+
+// First returns the first height covered by the registry.
+func (r *Registry) First() uint64 {
+	return r.sporks[0].First
+}
+
+// Last returns the last height covered by the registry.
+func (r *Registry) Last() uint64 {
+	return r.sporks[len(r.sporks)-1].Last
+}
+
+// Latest returns the most recent spork of the registry.
+func (r *Registry) Latest() Spork {
+	return r.sporks[len(r.sporks)-1]
+}
+
+// Spork returns the spork that covers the given height.
+func (r *Registry) Spork(height uint64) (Spork, error) {
+
+	first := r.First()
+	last := r.Last()
+	if height < first || height > last {
+		return Spork{}, fmt.Errorf("height %d outside of supported range %d - %d: %w", height, first, last, ErrNotFound)
+	}
+
+	// As sporks are sorted and contiguous, we can find the first spork whose
+	// last height is at or above the given height using a binary search.
+	index := sort.Search(len(r.sporks), func(i int) bool {
+		return r.sporks[i].Last >= height
+	})
+	if index == len(r.sporks) {
+		return Spork{}, fmt.Errorf("no spork for height %d: %w", height, ErrNotFound)
+	}
+
+	return r.sporks[index], nil
+}
+
+// Refresh creates a new registry with the boundaries of each spork updated to
+// the range of heights reported by its DPS API. The connect function is used
+// to get access to the index of each spork. Ongoing sporks keep their open
+// last height, so that heights indexed after the refresh remain covered.
+func (r *Registry) Refresh(connect func(spork Spork) (Index, error)) (*Registry, error) {
+
+	sporks := r.Sporks()
+	for i, spork := range sporks {
+
+		index, err := connect(spork)
+		if err != nil {
+			return nil, fmt.Errorf("could not connect to spork %s API: %w", spork.Name, err)
+		}
+
+		first, err := index.First()
+		if err != nil {
+			return nil, fmt.Errorf("could not get first height for spork %s: %w", spork.Name, err)
+		}
+		sporks[i].First = first
+
+		if spork.Open() {
+			continue
+		}
+
+		last, err := index.Last()
+		if err != nil {
+			return nil, fmt.Errorf("could not get last height for spork %s: %w", spork.Name, err)
+		}
+		sporks[i].Last = last
+	}
+
+	return New(sporks...)
+}
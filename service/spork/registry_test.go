@@ -0,0 +1,275 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package spork_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/service/spork"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+
+		sporks []spork.Spork
+
+		wantSporks []spork.Spork
+		checkErr   require.ErrorAssertionFunc
+	}{
+		{
+			name: "nominal case with unsorted sporks",
+			sporks: []spork.Spork{
+				{Name: "second", API: "second:5005", First: 101, Last: 200},
+				{Name: "first", API: "first:5005", First: 1, Last: 100},
+			},
+			wantSporks: []spork.Spork{
+				{Name: "first", API: "first:5005", First: 1, Last: 100},
+				{Name: "second", API: "second:5005", First: 101, Last: 200},
+			},
+			checkErr: require.NoError,
+		},
+		{
+			name: "open latest spork",
+			sporks: []spork.Spork{
+				{Name: "first", API: "first:5005", First: 1, Last: 100},
+				{Name: "second", API: "second:5005", First: 101},
+			},
+			wantSporks: []spork.Spork{
+				{Name: "first", API: "first:5005", First: 1, Last: 100},
+				{Name: "second", API: "second:5005", First: 101, Last: math.MaxUint64},
+			},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "no sporks",
+			sporks:   []spork.Spork{},
+			checkErr: require.Error,
+		},
+		{
+			name: "missing address",
+			sporks: []spork.Spork{
+				{Name: "first", First: 1, Last: 100},
+			},
+			checkErr: require.Error,
+		},
+		{
+			name: "inverted boundaries",
+			sporks: []spork.Spork{
+				{Name: "first", API: "first:5005", First: 100, Last: 1},
+			},
+			checkErr: require.Error,
+		},
+		{
+			name: "gap between sporks",
+			sporks: []spork.Spork{
+				{Name: "first", API: "first:5005", First: 1, Last: 100},
+				{Name: "second", API: "second:5005", First: 102, Last: 200},
+			},
+			checkErr: require.Error,
+		},
+		{
+			name: "overlap between sporks",
+			sporks: []spork.Spork{
+				{Name: "first", API: "first:5005", First: 1, Last: 100},
+				{Name: "second", API: "second:5005", First: 100, Last: 200},
+			},
+			checkErr: require.Error,
+		},
+		{
+			name: "open spork that is not the latest",
+			sporks: []spork.Spork{
+				{Name: "first", API: "first:5005", First: 1, Last: math.MaxUint64},
+				{Name: "second", API: "second:5005", First: 101, Last: 200},
+			},
+			checkErr: require.Error,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := spork.New(test.sporks...)
+
+			test.checkErr(t, err)
+			if err == nil {
+				assert.Equal(t, test.wantSporks, got.Sporks())
+			}
+		})
+	}
+}
+
+func TestRegistry_Spork(t *testing.T) {
+	registry, err := spork.New(
+		spork.Spork{Name: "first", API: "first:5005", First: 10, Last: 100},
+		spork.Spork{Name: "second", API: "second:5005", First: 101, Last: 200},
+		spork.Spork{Name: "third", API: "third:5005", First: 201},
+	)
+	require.NoError(t, err)
+
+	t.Run("nominal case", func(t *testing.T) {
+		got, err := registry.Spork(150)
+
+		require.NoError(t, err)
+		assert.Equal(t, "second", got.Name)
+	})
+
+	t.Run("boundaries", func(t *testing.T) {
+		got, err := registry.Spork(100)
+
+		require.NoError(t, err)
+		assert.Equal(t, "first", got.Name)
+
+		got, err = registry.Spork(101)
+
+		require.NoError(t, err)
+		assert.Equal(t, "second", got.Name)
+	})
+
+	t.Run("open spork", func(t *testing.T) {
+		got, err := registry.Spork(math.MaxUint64 - 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, "third", got.Name)
+	})
+
+	t.Run("height below first spork", func(t *testing.T) {
+		_, err := registry.Spork(9)
+
+		assert.ErrorIs(t, err, spork.ErrNotFound)
+	})
+}
+
+func TestRegistry_Refresh(t *testing.T) {
+	registry, err := spork.New(
+		spork.Spork{Name: "first", API: "first:5005", First: 1, Last: 100},
+		spork.Spork{Name: "second", API: "second:5005", First: 101},
+	)
+	require.NoError(t, err)
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		boundaries := map[string][2]uint64{
+			"first:5005":  {5, 110},
+			"second:5005": {111, 300},
+		}
+
+		got, err := registry.Refresh(func(s spork.Spork) (spork.Index, error) {
+			index := mocks.BaselineReader(t)
+			index.FirstFunc = func() (uint64, error) {
+				return boundaries[s.API][0], nil
+			}
+			index.LastFunc = func() (uint64, error) {
+				return boundaries[s.API][1], nil
+			}
+			return index, nil
+		})
+
+		require.NoError(t, err)
+		want := []spork.Spork{
+			{Name: "first", API: "first:5005", First: 5, Last: 110},
+			{Name: "second", API: "second:5005", First: 111, Last: math.MaxUint64},
+		}
+		assert.Equal(t, want, got.Sporks())
+	})
+
+	t.Run("handles connection failure", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := registry.Refresh(func(spork.Spork) (spork.Index, error) {
+			return nil, mocks.GenericError
+		})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles index failure", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := registry.Refresh(func(spork.Spork) (spork.Index, error) {
+			index := mocks.BaselineReader(t)
+			index.LastFunc = func() (uint64, error) {
+				return 0, mocks.GenericError
+			}
+			return index, nil
+		})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles invalid refreshed boundaries", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := registry.Refresh(func(spork.Spork) (spork.Index, error) {
+			return mocks.BaselineReader(t), nil
+		})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestFromYAML(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		manifest := []byte(`
+sporks:
+  - name: first
+    api: first:5005
+    rosetta: http://first:8080
+    replicas:
+      - http://first-replica:8080
+    first: 1
+    last: 100
+  - name: second
+    api: second:5005
+    first: 101
+`)
+
+		got, err := spork.FromYAML(manifest)
+
+		require.NoError(t, err)
+		assert.Len(t, got.Sporks(), 2)
+		assert.Equal(t, []string{"http://first:8080", "http://first-replica:8080"}, got.Sporks()[0].Endpoints())
+		assert.True(t, got.Latest().Open())
+	})
+
+	t.Run("handles unknown fields", func(t *testing.T) {
+		_, err := spork.FromYAML([]byte(`sporks: [{name: first, api: first, first: 1, unknown: 2}]`))
+
+		assert.Error(t, err)
+	})
+
+	t.Run("default manifest", func(t *testing.T) {
+		assert.NotPanics(t, func() {
+			spork.Default()
+		})
+	})
+}
+
+func TestFromJSON(t *testing.T) {
+	manifest := []byte(`{"sporks": [{"name": "first", "api": "first:5005", "first": 1}]}`)
+
+	got, err := spork.FromJSON(manifest)
+
+	require.NoError(t, err)
+	assert.Equal(t, "first", got.Latest().Name)
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package spork
+
+import (
+	"math"
+)
+
+// Spork describes a single spork of the Flow network, along with the
+// endpoints serving its history and the range of heights it covers. Replicas
+// are optional additional Rosetta API servers serving the same spork.
+type Spork struct {
+	Name     string   `json:"name" yaml:"name"`
+	API      string   `json:"api" yaml:"api"`
+	Rosetta  string   `json:"rosetta,omitempty" yaml:"rosetta,omitempty"`
+	Replicas []string `json:"replicas,omitempty" yaml:"replicas,omitempty"`
+	First    uint64   `json:"first" yaml:"first"`
+	Last     uint64   `json:"last,omitempty" yaml:"last,omitempty"`
+}
+
+// Endpoints returns the addresses of all Rosetta API servers for the spork,
+// starting with the main one.
+func (s Spork) Endpoints() []string {
+	var endpoints []string
+	if s.Rosetta != "" {
+		endpoints = append(endpoints, s.Rosetta)
+	}
+	endpoints = append(endpoints, s.Replicas...)
+	return endpoints
+}
+
+// Contains returns whether the given height is part of the spork.
+func (s Spork) Contains(height uint64) bool {
+	return height >= s.First && height <= s.Last
+}
+
+// Open returns whether the spork is still ongoing, which means that it has no
+// known last height yet.
+func (s Spork) Open() bool {
+	return s.Last == math.MaxUint64
+}
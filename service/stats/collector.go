@@ -0,0 +1,71 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package stats
+
+import (
+	"fmt"
+
+	"github.com/dgraph-io/badger/v2"
+
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// Collector collects the statistics of an index database, by walking through
+// the key ranges of each prefix. As it reads every value of the index, it is
+// meant to be used on demand rather than continuously.
+type Collector struct {
+	db    *badger.DB
+	codec dps.Codec
+}
+
+// NewCollector creates a new collector for the given index database, which
+// uses the given codec to decompress values.
+func NewCollector(db *badger.DB, codec dps.Codec) *Collector {
+
+	c := Collector{
+		db:    db,
+		codec: codec,
+	}
+
+	return &c
+}
+
+// Collect walks through the index database and returns its statistics.
+func (c *Collector) Collect() (*Report, error) {
+
+	counter := NewCounter(c.codec)
+	err := c.db.View(func(tx *badger.Txn) error {
+		it := tx.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			err := item.Value(func(val []byte) error {
+				counter.Add(item.Key(), val)
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("could not read value (key: %x): %w", item.Key(), err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not walk index database: %w", err)
+	}
+
+	return counter.Report(), nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package stats_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/service/stats"
+	"github.com/optakt/flow-dps/service/storage"
+	"github.com/optakt/flow-dps/testing/helpers"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestCollector_Collect(t *testing.T) {
+	codec := zbor.NewCodec()
+	lib := storage.New(codec)
+
+	db := helpers.InMemoryDB(t)
+	defer db.Close()
+
+	err := db.Update(storage.Combine(
+		lib.SaveFirst(1),
+		lib.SaveLast(2),
+		lib.SaveHeader(1, mocks.GenericHeader),
+		lib.SaveHeader(2, mocks.GenericHeader),
+		lib.SaveEvents(2, mocks.GenericEventType(0), mocks.GenericEvents(2)),
+	))
+	require.NoError(t, err)
+
+	collector := stats.NewCollector(db, codec)
+	report, err := collector.Collect()
+
+	require.NoError(t, err)
+	require.Len(t, report.Prefixes, 4)
+
+	names := make([]string, 0, len(report.Prefixes))
+	for _, prefix := range report.Prefixes {
+		names = append(names, prefix.Name)
+		assert.Zero(t, prefix.Invalid)
+		assert.Greater(t, prefix.UncompressedBytes, uint64(0))
+	}
+	assert.Equal(t, []string{"first", "last", "header", "events"}, names)
+
+	headers := report.Prefixes[2]
+	assert.Equal(t, uint64(2), headers.Keys)
+	assert.Equal(t, &stats.Coverage{First: 1, Last: 2}, headers.Coverage)
+
+	events := report.Prefixes[3]
+	assert.Equal(t, &stats.Coverage{First: 2, Last: 2}, events.Coverage)
+	require.Len(t, events.Dictionaries, 1)
+	assert.Equal(t, zbor.DictionaryEvents.String(), events.Dictionaries[0].Dictionary)
+
+	assert.Nil(t, report.Prefixes[0].Coverage)
+}
@@ -0,0 +1,171 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package stats
+
+import (
+	"sort"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/storage"
+)
+
+// Counter accumulates the statistics of the index entries that are added to
+// it. It is not safe for concurrent use.
+type Counter struct {
+	codec        dps.Codec
+	prefixes     map[uint8]*Prefix
+	dictionaries map[dictionaryKey]*Dictionary
+}
+
+type dictionaryKey struct {
+	prefix     uint8
+	version    uint8
+	dictionary zbor.Dictionary
+}
+
+// NewCounter creates a new counter, which uses the given codec to decompress
+// values.
+func NewCounter(codec dps.Codec) *Counter {
+
+	c := Counter{
+		codec:        codec,
+		prefixes:     make(map[uint8]*Prefix),
+		dictionaries: make(map[dictionaryKey]*Dictionary),
+	}
+
+	return &c
+}
+
+// Add adds the given index entry to the statistics. Values which can not be
+// decompressed are counted as invalid, so that they do not abort the count.
+func (c *Counter) Add(key []byte, value []byte) {
+
+	if len(key) == 0 {
+		return
+	}
+
+	prefix := key[0]
+	p, ok := c.prefixes[prefix]
+	if !ok {
+		p = &Prefix{
+			Prefix:       prefix,
+			Name:         storage.PrefixName(prefix),
+			Dictionaries: []*Dictionary{},
+		}
+		c.prefixes[prefix] = p
+	}
+	p.Keys++
+	p.KeyBytes += uint64(len(key))
+	p.CompressedBytes += uint64(len(value))
+
+	// The height of a key, if it has one, is always its first integer segment;
+	// the second integer segment of event keys is the hash of the event type.
+	_, segments, err := storage.DecodeKey(key)
+	if err == nil {
+		for _, segment := range segments {
+			height, ok := segment.(uint64)
+			if !ok {
+				continue
+			}
+			if p.Coverage == nil {
+				p.Coverage = &Coverage{First: height, Last: height}
+			}
+			if height < p.Coverage.First {
+				p.Coverage.First = height
+			}
+			if height > p.Coverage.Last {
+				p.Coverage.Last = height
+			}
+			break
+		}
+	}
+
+	version, dictionary, err := zbor.Inspect(value)
+	if err != nil {
+		p.Invalid++
+		return
+	}
+	data, err := c.codec.Decompress(value)
+	if err != nil {
+		p.Invalid++
+		return
+	}
+	p.UncompressedBytes += uint64(len(data))
+
+	dk := dictionaryKey{prefix: prefix, version: version, dictionary: dictionary}
+	d, ok := c.dictionaries[dk]
+	if !ok {
+		d = &Dictionary{
+			Version:    version,
+			Dictionary: dictionary.String(),
+		}
+		c.dictionaries[dk] = d
+	}
+	d.Values++
+	d.CompressedBytes += uint64(len(value))
+	d.UncompressedBytes += uint64(len(data))
+}
+
+// Report returns the statistics of all entries added so far.
+func (c *Counter) Report() *Report {
+
+	keys := make([]dictionaryKey, 0, len(c.dictionaries))
+	for key := range c.dictionaries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i int, j int) bool {
+		if keys[i].prefix != keys[j].prefix {
+			return keys[i].prefix < keys[j].prefix
+		}
+		if keys[i].version != keys[j].version {
+			return keys[i].version < keys[j].version
+		}
+		return keys[i].dictionary < keys[j].dictionary
+	})
+
+	// We copy the statistics, so that the report is not modified by entries
+	// that are added later on.
+	prefixes := make(map[uint8]*Prefix, len(c.prefixes))
+	for prefix, p := range c.prefixes {
+		copied := *p
+		copied.Dictionaries = []*Dictionary{}
+		if p.Coverage != nil {
+			coverage := *p.Coverage
+			copied.Coverage = &coverage
+		}
+		prefixes[prefix] = &copied
+	}
+	for _, key := range keys {
+		d := *c.dictionaries[key]
+		if d.CompressedBytes > 0 {
+			d.Ratio = float64(d.UncompressedBytes) / float64(d.CompressedBytes)
+		}
+		p := prefixes[key.prefix]
+		p.Dictionaries = append(p.Dictionaries, &d)
+	}
+
+	report := Report{
+		Prefixes: make([]*Prefix, 0, len(prefixes)),
+	}
+	for _, p := range prefixes {
+		report.Prefixes = append(report.Prefixes, p)
+	}
+	sort.Slice(report.Prefixes, func(i int, j int) bool {
+		return report.Prefixes[i].Prefix < report.Prefixes[j].Prefix
+	})
+
+	return &report
+}
@@ -0,0 +1,122 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package stats_test
+
+import (
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/service/stats"
+	"github.com/optakt/flow-dps/service/storage"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestCounter(t *testing.T) {
+	codec := zbor.NewCodec()
+
+	header, err := codec.Marshal(mocks.GenericHeader)
+	require.NoError(t, err)
+	payload, err := codec.Marshal(mocks.GenericLedgerPayload(0))
+	require.NoError(t, err)
+
+	counter := stats.NewCounter(codec)
+	counter.Add(storage.EncodeKey(storage.PrefixHeader, uint64(3)), header)
+	counter.Add(storage.EncodeKey(storage.PrefixHeader, uint64(1)), header)
+	counter.Add(storage.EncodeKey(storage.PrefixHeader, uint64(2)), []byte("invalid"))
+	counter.Add(storage.EncodeKey(storage.PrefixPayload, mocks.GenericLedgerPath(0), uint64(5)), payload)
+
+	report := counter.Report()
+
+	require.Len(t, report.Prefixes, 2)
+
+	headers := report.Prefixes[0]
+	assert.Equal(t, uint8(storage.PrefixHeader), headers.Prefix)
+	assert.Equal(t, "header", headers.Name)
+	assert.Equal(t, uint64(3), headers.Keys)
+	assert.Equal(t, uint64(27), headers.KeyBytes)
+	assert.Equal(t, uint64(2*len(header)+len("invalid")), headers.CompressedBytes)
+	assert.Equal(t, uint64(1), headers.Invalid)
+	assert.Equal(t, &stats.Coverage{First: 1, Last: 3}, headers.Coverage)
+	require.Len(t, headers.Dictionaries, 1)
+	assert.Equal(t, uint8(zbor.Version), headers.Dictionaries[0].Version)
+	assert.Equal(t, zbor.DictionaryGeneric.String(), headers.Dictionaries[0].Dictionary)
+	assert.Equal(t, uint64(2), headers.Dictionaries[0].Values)
+	assert.Equal(t, uint64(2*len(header)), headers.Dictionaries[0].CompressedBytes)
+	assert.Equal(t, headers.UncompressedBytes, headers.Dictionaries[0].UncompressedBytes)
+	assert.InDelta(t, float64(headers.UncompressedBytes)/float64(2*len(header)), headers.Dictionaries[0].Ratio, 0.0001)
+
+	payloads := report.Prefixes[1]
+	assert.Equal(t, uint8(storage.PrefixPayload), payloads.Prefix)
+	assert.Equal(t, &stats.Coverage{First: 5, Last: 5}, payloads.Coverage)
+	require.Len(t, payloads.Dictionaries, 1)
+	assert.Equal(t, zbor.DictionaryPayloads.String(), payloads.Dictionaries[0].Dictionary)
+
+	// Entries added after the report was created should not modify it.
+	counter.Add(storage.EncodeKey(storage.PrefixHeader, uint64(4)), header)
+	assert.Equal(t, uint64(3), headers.Keys)
+	assert.Equal(t, uint64(3), headers.Coverage.Last)
+}
+
+func TestCounter_Legacy(t *testing.T) {
+	codec := zbor.NewCodec()
+
+	// Values written by older versions of the codec have no header byte, and
+	// are only identified by the dictionary ID of their Zstandard frame.
+	legacy := func(kind zbor.Kind, value interface{}) ([]byte, []byte) {
+		data, err := codec.Encode(value)
+		require.NoError(t, err)
+		_, raw := zbor.Current(kind)
+		compressor, err := zstd.NewWriter(nil, zstd.WithEncoderDict(raw))
+		require.NoError(t, err)
+		return data, compressor.EncodeAll(data, nil)
+	}
+
+	payloadData, payload := legacy(zbor.KindPayloads, mocks.GenericLedgerPayload(0))
+	eventsData, events := legacy(zbor.KindEvents, mocks.GenericEvents(4))
+	transactionData, transaction := legacy(zbor.KindTransactions, mocks.GenericTransaction(0))
+
+	counter := stats.NewCounter(codec)
+	counter.Add(storage.EncodeKey(storage.PrefixPayload, mocks.GenericLedgerPath(0), uint64(5)), payload)
+	counter.Add(storage.EncodeKey(storage.PrefixEvents, uint64(5), uint64(0)), events)
+	counter.Add(storage.EncodeKey(storage.PrefixTransaction, mocks.GenericTransaction(0).ID()), transaction)
+
+	report := counter.Report()
+
+	require.Len(t, report.Prefixes, 3)
+
+	tests := []struct {
+		dictionary zbor.Dictionary
+		data       []byte
+		compressed []byte
+	}{
+		{dictionary: zbor.DictionaryEvents, data: eventsData, compressed: events},
+		{dictionary: zbor.DictionaryPayloads, data: payloadData, compressed: payload},
+		{dictionary: zbor.DictionaryTransactions, data: transactionData, compressed: transaction},
+	}
+	for i, test := range tests {
+		prefix := report.Prefixes[i]
+		assert.Zero(t, prefix.Invalid)
+		assert.Equal(t, uint64(len(test.data)), prefix.UncompressedBytes)
+		require.Len(t, prefix.Dictionaries, 1)
+		assert.Equal(t, uint8(zbor.LegacyVersion), prefix.Dictionaries[0].Version)
+		assert.Equal(t, test.dictionary.String(), prefix.Dictionaries[0].Dictionary)
+		assert.Equal(t, uint64(len(test.compressed)), prefix.Dictionaries[0].CompressedBytes)
+		assert.Equal(t, uint64(len(test.data)), prefix.Dictionaries[0].UncompressedBytes)
+	}
+}
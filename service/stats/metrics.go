@@ -0,0 +1,134 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package stats
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// MetricsCollector wraps the collector and exposes the statistics it collects
+// as Prometheus gauges. The gauges are only updated when statistics are
+// collected, so they reflect the index as it was at the last collection.
+type MetricsCollector struct {
+	collect *Collector
+
+	keys         *prometheus.GaugeVec
+	keyBytes     *prometheus.GaugeVec
+	compressed   *prometheus.GaugeVec
+	uncompressed *prometheus.GaugeVec
+	ratio        *prometheus.GaugeVec
+	first        *prometheus.GaugeVec
+	last         *prometheus.GaugeVec
+}
+
+// NewMetricsCollector creates a collector that records the statistics it
+// collects as Prometheus gauges.
+func NewMetricsCollector(collect *Collector) *MetricsCollector {
+
+	keysOpts := prometheus.GaugeOpts{
+		Name: "index_keys",
+		Help: "number of keys in the index per prefix",
+	}
+	keys := promauto.NewGaugeVec(keysOpts, []string{"prefix"})
+
+	keyBytesOpts := prometheus.GaugeOpts{
+		Name: "index_key_bytes",
+		Help: "total size of the keys in the index per prefix",
+	}
+	keyBytes := promauto.NewGaugeVec(keyBytesOpts, []string{"prefix"})
+
+	compressedOpts := prometheus.GaugeOpts{
+		Name: "index_compressed_bytes",
+		Help: "total size of the stored values in the index per prefix and dictionary",
+	}
+	compressed := promauto.NewGaugeVec(compressedOpts, []string{"prefix", "version", "dictionary"})
+
+	uncompressedOpts := prometheus.GaugeOpts{
+		Name: "index_uncompressed_bytes",
+		Help: "total size of the decompressed values in the index per prefix and dictionary",
+	}
+	uncompressed := promauto.NewGaugeVec(uncompressedOpts, []string{"prefix", "version", "dictionary"})
+
+	ratioOpts := prometheus.GaugeOpts{
+		Name: "index_compression_ratio",
+		Help: "average compression ratio of the values in the index per prefix and dictionary",
+	}
+	ratio := promauto.NewGaugeVec(ratioOpts, []string{"prefix", "version", "dictionary"})
+
+	firstOpts := prometheus.GaugeOpts{
+		Name: "index_first_height",
+		Help: "lowest height found in the keys of the index per prefix",
+	}
+	first := promauto.NewGaugeVec(firstOpts, []string{"prefix"})
+
+	lastOpts := prometheus.GaugeOpts{
+		Name: "index_last_height",
+		Help: "highest height found in the keys of the index per prefix",
+	}
+	last := promauto.NewGaugeVec(lastOpts, []string{"prefix"})
+
+	m := MetricsCollector{
+		collect: collect,
+
+		keys:         keys,
+		keyBytes:     keyBytes,
+		compressed:   compressed,
+		uncompressed: uncompressed,
+		ratio:        ratio,
+		first:        first,
+		last:         last,
+	}
+
+	return &m
+}
+
+// Collect collects the statistics of the index and updates the gauges.
+func (m *MetricsCollector) Collect() (*Report, error) {
+
+	report, err := m.collect.Collect()
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries that are no longer present in the index should not be exposed
+	// with their previous values.
+	m.keys.Reset()
+	m.keyBytes.Reset()
+	m.compressed.Reset()
+	m.uncompressed.Reset()
+	m.ratio.Reset()
+	m.first.Reset()
+	m.last.Reset()
+
+	for _, prefix := range report.Prefixes {
+		m.keys.WithLabelValues(prefix.Name).Set(float64(prefix.Keys))
+		m.keyBytes.WithLabelValues(prefix.Name).Set(float64(prefix.KeyBytes))
+		if prefix.Coverage != nil {
+			m.first.WithLabelValues(prefix.Name).Set(float64(prefix.Coverage.First))
+			m.last.WithLabelValues(prefix.Name).Set(float64(prefix.Coverage.Last))
+		}
+		for _, dictionary := range prefix.Dictionaries {
+			version := strconv.FormatUint(uint64(dictionary.Version), 10)
+			m.compressed.WithLabelValues(prefix.Name, version, dictionary.Dictionary).Set(float64(dictionary.CompressedBytes))
+			m.uncompressed.WithLabelValues(prefix.Name, version, dictionary.Dictionary).Set(float64(dictionary.UncompressedBytes))
+			m.ratio.WithLabelValues(prefix.Name, version, dictionary.Dictionary).Set(dictionary.Ratio)
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package stats
+
+// Report holds the statistics of an index database, for each prefix of its
+// keys, sorted by prefix.
+type Report struct {
+	Prefixes []*Prefix `json:"prefixes"`
+}
+
+// Prefix holds the statistics of the entries with a given key prefix. The
+// compressed size is the size of the values as they are stored, while the
+// uncompressed size is their size once decompressed by the codec.
+type Prefix struct {
+	Prefix            uint8         `json:"prefix"`
+	Name              string        `json:"name"`
+	Keys              uint64        `json:"keys"`
+	KeyBytes          uint64        `json:"key_bytes"`
+	CompressedBytes   uint64        `json:"compressed_bytes"`
+	UncompressedBytes uint64        `json:"uncompressed_bytes"`
+	Invalid           uint64        `json:"invalid"` // values that could not be decompressed
+	Coverage          *Coverage     `json:"coverage,omitempty"`
+	Dictionaries      []*Dictionary `json:"dictionaries"`
+}
+
+// Coverage is the range of heights found in the keys of a prefix. It is only
+// available for prefixes whose keys include a height.
+type Coverage struct {
+	First uint64 `json:"first"`
+	Last  uint64 `json:"last"`
+}
+
+// Dictionary holds the statistics of the values of a prefix which were encoded
+// with a given codec version and dictionary.
+type Dictionary struct {
+	Version           uint8   `json:"version"`
+	Dictionary        string  `json:"dictionary"`
+	Values            uint64  `json:"values"`
+	CompressedBytes   uint64  `json:"compressed_bytes"`
+	UncompressedBytes uint64  `json:"uncompressed_bytes"`
+	Ratio             float64 `json:"ratio"` // uncompressed size over compressed size
+}
@@ -37,7 +37,7 @@ func TestLibrary_Retrieve(t *testing.T) {
 	testKey := []byte{42}
 
 	t.Run("nominal case", func(t *testing.T) {
-		wantEncodedValue := []byte{0x28, 0xb5, 0x2f, 0xfd, 0x7, 0x0, 0x7, 0x81, 0x4a, 0x29, 0x11, 0x0, 0x0, 0x18, 0x2a, 0xc5, 0xb, 0xd5, 0x9d}
+		wantEncodedValue := []byte{0x41, 0x28, 0xb5, 0x2f, 0xfd, 0x7, 0x0, 0x7, 0x81, 0x4a, 0x29, 0x11, 0x0, 0x0, 0x18, 0x2a, 0xc5, 0xb, 0xd5, 0x9d}
 
 		err := insertKeyValue(t, db, testKey, testValue)
 		require.NoError(t, err)
@@ -47,3 +47,53 @@ func EncodeKey(prefix uint8, segments ...interface{}) []byte {
 
 	return key
 }
+
+// DecodeKey decodes the given key into its prefix and its segments, according
+// to the layout of the keys for that prefix. It is the inverse of `EncodeKey`,
+// except that hashes of event types are returned as `uint64`.
+func DecodeKey(key []byte) (uint8, []interface{}, error) {
+
+	if len(key) == 0 {
+		return 0, nil, fmt.Errorf("empty key")
+	}
+
+	prefix := key[0]
+	layout, ok := layouts[prefix]
+	if !ok {
+		return 0, nil, fmt.Errorf("unknown key prefix (%d)", prefix)
+	}
+
+	segments := make([]interface{}, 0, len(layout))
+	data := key[1:]
+	for _, segment := range layout {
+		switch segment.(type) {
+		case uint64:
+			if len(data) < 8 {
+				return 0, nil, fmt.Errorf("key too short for height segment (prefix: %d, remaining: %d)", prefix, len(data))
+			}
+			segments = append(segments, binary.BigEndian.Uint64(data[:8]))
+			data = data[8:]
+		case flow.Identifier:
+			if len(data) < 32 {
+				return 0, nil, fmt.Errorf("key too short for identifier segment (prefix: %d, remaining: %d)", prefix, len(data))
+			}
+			var id flow.Identifier
+			copy(id[:], data[:32])
+			segments = append(segments, id)
+			data = data[32:]
+		case ledger.Path:
+			if len(data) < 32 {
+				return 0, nil, fmt.Errorf("key too short for path segment (prefix: %d, remaining: %d)", prefix, len(data))
+			}
+			var path ledger.Path
+			copy(path[:], data[:32])
+			segments = append(segments, path)
+			data = data[32:]
+		}
+	}
+	if len(data) != 0 {
+		return 0, nil, fmt.Errorf("unexpected trailing bytes in key (prefix: %d, remaining: %d)", prefix, len(data))
+	}
+
+	return prefix, segments, nil
+}
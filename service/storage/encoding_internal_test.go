@@ -96,3 +96,89 @@ func TestEncodeKey(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeKey(t *testing.T) {
+	id := mocks.GenericHeader.ID()
+	path := mocks.GenericLedgerPath(0)
+
+	tests := []struct {
+		name string
+
+		key []byte
+
+		wantPrefix   uint8
+		wantSegments []interface{}
+		checkErr     assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "key without segments",
+			key:          EncodeKey(PrefixLast),
+			wantPrefix:   PrefixLast,
+			wantSegments: []interface{}{},
+			checkErr:     assert.NoError,
+		},
+		{
+			name:         "key with height",
+			key:          EncodeKey(PrefixHeader, uint64(42)),
+			wantPrefix:   PrefixHeader,
+			wantSegments: []interface{}{uint64(42)},
+			checkErr:     assert.NoError,
+		},
+		{
+			name:         "key with identifier",
+			key:          EncodeKey(PrefixHeightForBlock, id),
+			wantPrefix:   PrefixHeightForBlock,
+			wantSegments: []interface{}{id},
+			checkErr:     assert.NoError,
+		},
+		{
+			name:         "key with path and height",
+			key:          EncodeKey(PrefixPayload, path, uint64(42)),
+			wantPrefix:   PrefixPayload,
+			wantSegments: []interface{}{path, uint64(42)},
+			checkErr:     assert.NoError,
+		},
+		{
+			name:         "key with height and event type hash",
+			key:          EncodeKey(PrefixEvents, uint64(42), uint64(1337)),
+			wantPrefix:   PrefixEvents,
+			wantSegments: []interface{}{uint64(42), uint64(1337)},
+			checkErr:     assert.NoError,
+		},
+		{
+			name:     "empty key",
+			key:      []byte{},
+			checkErr: assert.Error,
+		},
+		{
+			name:     "unknown prefix",
+			key:      []byte{0xff},
+			checkErr: assert.Error,
+		},
+		{
+			name:     "truncated key",
+			key:      EncodeKey(PrefixHeader, uint64(42))[:5],
+			checkErr: assert.Error,
+		},
+		{
+			name:     "trailing bytes",
+			key:      append(EncodeKey(PrefixHeader, uint64(42)), 0x1),
+			checkErr: assert.Error,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			prefix, segments, err := DecodeKey(test.key)
+
+			test.checkErr(t, err)
+			if err == nil {
+				assert.Equal(t, test.wantPrefix, prefix)
+				assert.Equal(t, test.wantSegments, segments)
+			}
+		})
+	}
+}
@@ -37,6 +37,12 @@ func (l *Library) SaveLast(height uint64) func(*badger.Txn) error {
 	return l.save(EncodeKey(PrefixLast), height)
 }
 
+// SaveContents is an operation that writes the categories of data included in
+// a partial index.
+func (l *Library) SaveContents(categories []string) func(*badger.Txn) error {
+	return l.save(EncodeKey(PrefixContents), categories)
+}
+
 // IndexHeightForBlock is an operation that indexes the given height for its block identifier.
 func (l *Library) IndexHeightForBlock(blockID flow.Identifier, height uint64) func(*badger.Txn) error {
 	return l.save(EncodeKey(PrefixHeightForBlock, blockID), height)
@@ -73,6 +79,16 @@ func (l *Library) IndexHeightForTransaction(txID flow.Identifier, height uint64)
 	return l.save(EncodeKey(PrefixHeightForTransaction, txID), height)
 }
 
+// IndexHeightForCollection is an operation that writes the height of a collection identifier.
+func (l *Library) IndexHeightForCollection(collID flow.Identifier, height uint64) func(*badger.Txn) error {
+	return l.save(EncodeKey(PrefixHeightForCollection, collID), height)
+}
+
+// IndexHeightForSeal is an operation that writes the height of a seal identifier.
+func (l *Library) IndexHeightForSeal(sealID flow.Identifier, height uint64) func(*badger.Txn) error {
+	return l.save(EncodeKey(PrefixHeightForSeal, sealID), height)
+}
+
 // SaveCollection is an operation that writes the given collection.
 func (l *Library) SaveCollection(collection *flow.LightCollection) func(*badger.Txn) error {
 	return l.save(EncodeKey(PrefixCollection, collection.ID()), collection)
@@ -124,6 +140,12 @@ func (l *Library) RetrieveLast(height *uint64) func(*badger.Txn) error {
 	return l.retrieve(EncodeKey(PrefixLast), height)
 }
 
+// RetrieveContents retrieves the categories of data included in a partial
+// index. Full indexes have no contents, so it returns `badger.ErrKeyNotFound`.
+func (l *Library) RetrieveContents(categories *[]string) func(*badger.Txn) error {
+	return l.retrieve(EncodeKey(PrefixContents), categories)
+}
+
 // LookupHeightForBlock retrieves the height of the given block identifier.
 func (l *Library) LookupHeightForBlock(blockID flow.Identifier, height *uint64) func(*badger.Txn) error {
 	return l.retrieve(EncodeKey(PrefixHeightForBlock, blockID), height)
@@ -231,6 +253,16 @@ func (l *Library) LookupHeightForTransaction(txID flow.Identifier, height *uint6
 	return l.retrieve(EncodeKey(PrefixHeightForTransaction, txID), height)
 }
 
+// LookupHeightForCollection retrieves the height of the collection with the given identifier.
+func (l *Library) LookupHeightForCollection(collID flow.Identifier, height *uint64) func(*badger.Txn) error {
+	return l.retrieve(EncodeKey(PrefixHeightForCollection, collID), height)
+}
+
+// LookupHeightForSeal retrieves the height of the seal with the given identifier.
+func (l *Library) LookupHeightForSeal(sealID flow.Identifier, height *uint64) func(*badger.Txn) error {
+	return l.retrieve(EncodeKey(PrefixHeightForSeal, sealID), height)
+}
+
 // RetrieveSeal retrieves the seal with the given identifier.
 func (l *Library) RetrieveSeal(sealID flow.Identifier, seal *flow.Seal) func(*badger.Txn) error {
 	return l.retrieve(EncodeKey(PrefixSeal, sealID), seal)
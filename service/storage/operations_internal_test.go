@@ -1087,6 +1087,64 @@ func TestIndexAndLookup_CollectionsForHeight(t *testing.T) {
 	})
 }
 
+func TestIndexAndLookup_HeightForCollection(t *testing.T) {
+	collID := mocks.GenericCollectionIDs(1)[0]
+	testKey := EncodeKey(PrefixHeightForCollection, collID)
+
+	t.Run("save height of collection", func(t *testing.T) {
+		t.Parallel()
+
+		db := helpers.InMemoryDB(t)
+		defer db.Close()
+
+		codec := mocks.BaselineCodec(t)
+		codec.MarshalFunc = func(v interface{}) ([]byte, error) {
+			assert.IsType(t, uint64(0), v)
+			return mocks.GenericBytes, nil
+		}
+
+		l := &Library{
+			codec: codec,
+		}
+
+		err := db.Update(l.IndexHeightForCollection(collID, mocks.GenericHeight))
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("retrieve height of collection", func(t *testing.T) {
+		t.Parallel()
+
+		db := helpers.InMemoryDB(t)
+		defer db.Close()
+
+		err := db.Update(func(tx *badger.Txn) error {
+			return tx.Set(testKey, mocks.GenericBytes)
+		})
+		require.NoError(t, err)
+
+		decodeCallCount := 0
+		codec := mocks.BaselineCodec(t)
+		codec.UnmarshalFunc = func(b []byte, v interface{}) error {
+			assert.Equal(t, mocks.GenericBytes, b)
+			assert.IsType(t, &mocks.GenericHeight, v)
+			decodeCallCount++
+
+			return nil
+		}
+
+		l := &Library{
+			codec: codec,
+		}
+
+		var got uint64
+		err = db.View(l.LookupHeightForCollection(collID, &got))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, decodeCallCount)
+	})
+}
+
 func TestSaveAndRetrieve_TransactionResults(t *testing.T) {
 	testKey := EncodeKey(PrefixResults, mocks.GenericResult(0).TransactionID)
 
@@ -1258,6 +1316,64 @@ func TestIndexAndLookup_Seals(t *testing.T) {
 	})
 }
 
+func TestIndexAndLookup_HeightForSeal(t *testing.T) {
+	sealID := mocks.GenericSealIDs(1)[0]
+	testKey := EncodeKey(PrefixHeightForSeal, sealID)
+
+	t.Run("save height of seal", func(t *testing.T) {
+		t.Parallel()
+
+		db := helpers.InMemoryDB(t)
+		defer db.Close()
+
+		codec := mocks.BaselineCodec(t)
+		codec.MarshalFunc = func(v interface{}) ([]byte, error) {
+			assert.IsType(t, uint64(0), v)
+			return mocks.GenericBytes, nil
+		}
+
+		l := &Library{
+			codec: codec,
+		}
+
+		err := db.Update(l.IndexHeightForSeal(sealID, mocks.GenericHeight))
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("retrieve height of seal", func(t *testing.T) {
+		t.Parallel()
+
+		db := helpers.InMemoryDB(t)
+		defer db.Close()
+
+		err := db.Update(func(tx *badger.Txn) error {
+			return tx.Set(testKey, mocks.GenericBytes)
+		})
+		require.NoError(t, err)
+
+		decodeCallCount := 0
+		codec := mocks.BaselineCodec(t)
+		codec.UnmarshalFunc = func(b []byte, v interface{}) error {
+			assert.Equal(t, mocks.GenericBytes, b)
+			assert.IsType(t, &mocks.GenericHeight, v)
+			decodeCallCount++
+
+			return nil
+		}
+
+		l := &Library{
+			codec: codec,
+		}
+
+		var got uint64
+		err = db.View(l.LookupHeightForSeal(sealID, &got))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, decodeCallCount)
+	})
+}
+
 func TestLibrary_IterateLedger(t *testing.T) {
 	entries := 5
 	paths := mocks.GenericLedgerPaths(entries)
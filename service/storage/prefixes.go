@@ -14,12 +14,21 @@
 
 package storage
 
+import (
+	"fmt"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+)
+
 const (
 	PrefixFirst = 1
 	PrefixLast  = 2
 
 	PrefixHeightForBlock       = 7
 	PrefixHeightForTransaction = 16
+	PrefixHeightForCollection  = 19
+	PrefixHeightForSeal        = 20
 
 	PrefixCommit  = 4
 	PrefixHeader  = 3
@@ -37,4 +46,94 @@ const (
 
 	PrefixSeal           = 14
 	PrefixSealsForHeight = 15
+
+	PrefixContents = 18
 )
+
+// Categories of data, which group the prefixes of all keys needed to serve one
+// type of data from the index.
+const (
+	CategoryCommits      = "commits"
+	CategoryHeaders      = "headers"
+	CategoryEvents       = "events"
+	CategoryRegisters    = "registers"
+	CategoryTransactions = "transactions"
+	CategoryCollections  = "collections"
+	CategoryResults      = "results"
+	CategorySeals        = "seals"
+)
+
+// Categories maps each category of data to the prefixes of its keys. The
+// boundaries and contents of the index do not belong to any category, as they
+// are needed for all of them.
+var Categories = map[string][]uint8{
+	CategoryCommits:      {PrefixCommit},
+	CategoryHeaders:      {PrefixHeader, PrefixHeightForBlock},
+	CategoryEvents:       {PrefixEvents},
+	CategoryRegisters:    {PrefixPayload},
+	CategoryTransactions: {PrefixTransaction, PrefixTransactionsForHeight, PrefixHeightForTransaction},
+	CategoryCollections:  {PrefixCollection, PrefixGuarantee, PrefixCollectionsForHeight, PrefixTransactionsForCollection, PrefixHeightForCollection},
+	CategoryResults:      {PrefixResults},
+	CategorySeals:        {PrefixSeal, PrefixSealsForHeight, PrefixHeightForSeal},
+}
+
+// PrefixNames maps each prefix to a human-readable name, for tools that need
+// to report on the contents of the index.
+var PrefixNames = map[uint8]string{
+	PrefixFirst:                     "first",
+	PrefixLast:                      "last",
+	PrefixHeightForBlock:            "height_for_block",
+	PrefixHeightForTransaction:      "height_for_transaction",
+	PrefixHeightForCollection:       "height_for_collection",
+	PrefixHeightForSeal:             "height_for_seal",
+	PrefixCommit:                    "commit",
+	PrefixHeader:                    "header",
+	PrefixEvents:                    "events",
+	PrefixPayload:                   "payload",
+	PrefixTransaction:               "transaction",
+	PrefixCollection:                "collection",
+	PrefixGuarantee:                 "guarantee",
+	PrefixTransactionsForHeight:     "transactions_for_height",
+	PrefixTransactionsForCollection: "transactions_for_collection",
+	PrefixCollectionsForHeight:      "collections_for_height",
+	PrefixResults:                   "results",
+	PrefixSeal:                      "seal",
+	PrefixSealsForHeight:            "seals_for_height",
+	PrefixContents:                  "contents",
+}
+
+// PrefixName returns the human-readable name of the given prefix, or a name
+// derived from its number if the prefix is unknown.
+func PrefixName(prefix uint8) string {
+	name, ok := PrefixNames[prefix]
+	if !ok {
+		return fmt.Sprintf("unknown_%d", prefix)
+	}
+	return name
+}
+
+// layouts maps each prefix to the types of the segments of its keys, in the
+// order in which they are encoded. The second segment of event keys is the
+// hash of the event type.
+var layouts = map[uint8][]interface{}{
+	PrefixFirst:                     {},
+	PrefixLast:                      {},
+	PrefixContents:                  {},
+	PrefixHeightForBlock:            {flow.Identifier{}},
+	PrefixHeightForTransaction:      {flow.Identifier{}},
+	PrefixHeightForCollection:       {flow.Identifier{}},
+	PrefixHeightForSeal:             {flow.Identifier{}},
+	PrefixCommit:                    {uint64(0)},
+	PrefixHeader:                    {uint64(0)},
+	PrefixEvents:                    {uint64(0), uint64(0)},
+	PrefixPayload:                   {ledger.Path{}, uint64(0)},
+	PrefixTransaction:               {flow.Identifier{}},
+	PrefixCollection:                {flow.Identifier{}},
+	PrefixGuarantee:                 {flow.Identifier{}},
+	PrefixTransactionsForHeight:     {uint64(0)},
+	PrefixTransactionsForCollection: {flow.Identifier{}},
+	PrefixCollectionsForHeight:      {uint64(0)},
+	PrefixResults:                   {flow.Identifier{}},
+	PrefixSeal:                      {flow.Identifier{}},
+	PrefixSealsForHeight:            {uint64(0)},
+}
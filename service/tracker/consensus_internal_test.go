@@ -81,6 +81,7 @@ func TestConsensus_OnBlockFinalized(t *testing.T) {
 		cons.OnBlockFinalized(header.ID())
 
 		assert.Equal(t, cons.last, header.Height)
+		assert.Equal(t, header.Height, cons.Finalized())
 	})
 
 	t.Run("handles missing header in DB", func(t *testing.T) {
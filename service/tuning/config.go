@@ -0,0 +1,128 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package tuning
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/badger/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// Config selects the profile of Badger options to use for a database, along
+// with the individual options that override those of the profile.
+type Config struct {
+	Profile Profile           `json:"profile" yaml:"profile"`
+	Options map[string]string `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// ReadConfig reads the Badger configuration file at the given path. The format
+// of the file is chosen based on its extension, which should be one of `.json`,
+// `.yaml` or `.yml`.
+func ReadConfig(path string) (*Config, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read configuration file: %w", err)
+	}
+
+	var cfg Config
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".json":
+		err = json.Unmarshal(data, &cfg)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, &cfg)
+	default:
+		return nil, fmt.Errorf("unsupported configuration extension (%s)", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not decode configuration: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// Load builds the Badger configuration of a command from its command line
+// flags. The profile and options given on the command line take precedence
+// over those of the configuration file at the given path, if any; when neither
+// selects a profile, the given fallback profile is used.
+func Load(fallback Profile, path string, profile string, options map[string]string) (*Config, error) {
+
+	cfg := Config{
+		Profile: fallback,
+		Options: make(map[string]string),
+	}
+
+	if path != "" {
+		file, err := ReadConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		if file.Profile != "" {
+			cfg.Profile = file.Profile
+		}
+		for name, value := range file.Options {
+			cfg.Options[name] = value
+		}
+	}
+
+	if profile != "" {
+		cfg.Profile = Profile(profile)
+	}
+	for name, value := range options {
+		cfg.Options[name] = value
+	}
+
+	// We make sure the configuration is valid right away, so that commands
+	// fail before doing any work.
+	_, err := cfg.BadgerOptions("")
+	if err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// BadgerOptions returns the Badger options of the configured profile for a
+// database in the given directory, with the configured options overridden.
+func (c Config) BadgerOptions(dir string) (badger.Options, error) {
+
+	opts, err := Options(dir, c.Profile)
+	if err != nil {
+		return badger.Options{}, err
+	}
+
+	// Options are applied in a deterministic order, even though they are
+	// independent from each other.
+	names := make([]string, 0, len(c.Options))
+	for name := range c.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		opts, err = Override(opts, name, c.Options[name])
+		if err != nil {
+			return badger.Options{}, err
+		}
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package tuning_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/service/tuning"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "badger.yaml")
+	err := os.WriteFile(yamlPath, []byte("profile: low-memory\noptions:\n  num_memtables: \"2\"\n  index_cache_size: \"1024\"\n"), 0644)
+	require.NoError(t, err)
+	jsonPath := filepath.Join(dir, "badger.json")
+	err = os.WriteFile(jsonPath, []byte(`{"options": {"sync_writes": "true"}}`), 0644)
+	require.NoError(t, err)
+	invalidPath := filepath.Join(dir, "badger.yaml.bak")
+	err = os.WriteFile(invalidPath, []byte("profile: live\n"), 0644)
+	require.NoError(t, err)
+
+	t.Run("fallback profile", func(t *testing.T) {
+		t.Parallel()
+
+		cfg, err := tuning.Load(tuning.ProfileServing, "", "", nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, tuning.ProfileServing, cfg.Profile)
+		assert.Empty(t, cfg.Options)
+	})
+
+	t.Run("configuration file overrides fallback profile", func(t *testing.T) {
+		t.Parallel()
+
+		cfg, err := tuning.Load(tuning.ProfileServing, yamlPath, "", nil)
+		require.NoError(t, err)
+		assert.Equal(t, tuning.ProfileLowMemory, cfg.Profile)
+
+		opts, err := cfg.BadgerOptions("index")
+		require.NoError(t, err)
+		assert.Equal(t, 2, opts.NumMemtables)
+		assert.Equal(t, int64(1024), opts.IndexCacheSize)
+	})
+
+	t.Run("configuration file without profile", func(t *testing.T) {
+		t.Parallel()
+
+		cfg, err := tuning.Load(tuning.ProfileLive, jsonPath, "", nil)
+		require.NoError(t, err)
+		assert.Equal(t, tuning.ProfileLive, cfg.Profile)
+
+		opts, err := cfg.BadgerOptions("index")
+		require.NoError(t, err)
+		assert.True(t, opts.SyncWrites)
+	})
+
+	t.Run("flags override configuration file", func(t *testing.T) {
+		t.Parallel()
+
+		cfg, err := tuning.Load(tuning.ProfileServing, yamlPath, string(tuning.ProfileBulkIngest), map[string]string{"num_memtables": "4"})
+		require.NoError(t, err)
+		assert.Equal(t, tuning.ProfileBulkIngest, cfg.Profile)
+
+		opts, err := cfg.BadgerOptions("index")
+		require.NoError(t, err)
+		assert.Equal(t, 4, opts.NumMemtables)
+		assert.Equal(t, int64(1024), opts.IndexCacheSize)
+	})
+
+	t.Run("handles unknown profile", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tuning.Load(tuning.ProfileServing, "", "unknown", nil)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles invalid option", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tuning.Load(tuning.ProfileServing, "", "", map[string]string{"num_memtables": "many"})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles missing file", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tuning.Load(tuning.ProfileServing, filepath.Join(dir, "missing.yaml"), "", nil)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles unsupported extension", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tuning.Load(tuning.ProfileServing, invalidPath, "", nil)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,133 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package tuning
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/options"
+)
+
+// overrides maps the name of each Badger option that can be overridden to the
+// function that sets it from its string value.
+var overrides = map[string]func(badger.Options, string) (badger.Options, error){
+	"max_table_size":              int64Option(badger.Options.WithMaxTableSize),
+	"level_one_size":              int64Option(badger.Options.WithLevelOneSize),
+	"max_levels":                  intOption(badger.Options.WithMaxLevels),
+	"value_log_file_size":         int64Option(badger.Options.WithValueLogFileSize),
+	"value_log_max_entries":       uint32Option(badger.Options.WithValueLogMaxEntries),
+	"value_threshold":             intOption(badger.Options.WithValueThreshold),
+	"num_memtables":               intOption(badger.Options.WithNumMemtables),
+	"num_level_zero_tables":       intOption(badger.Options.WithNumLevelZeroTables),
+	"num_level_zero_tables_stall": intOption(badger.Options.WithNumLevelZeroTablesStall),
+	"num_compactors":              intOption(badger.Options.WithNumCompactors),
+	"index_cache_size":            int64Option(badger.Options.WithIndexCacheSize),
+	"block_cache_size":            int64Option(badger.Options.WithBlockCacheSize),
+	"keep_l0_in_memory":           boolOption(badger.Options.WithKeepL0InMemory),
+	"compact_l0_on_close":         boolOption(badger.Options.WithCompactL0OnClose),
+	"load_blooms_on_open":         boolOption(badger.Options.WithLoadBloomsOnOpen),
+	"sync_writes":                 boolOption(badger.Options.WithSyncWrites),
+	"truncate":                    boolOption(badger.Options.WithTruncate),
+	"table_loading_mode":          modeOption(badger.Options.WithTableLoadingMode),
+	"value_log_loading_mode":      modeOption(badger.Options.WithValueLogLoadingMode),
+}
+
+// OverrideNames returns the sorted names of the Badger options that can be
+// overridden.
+func OverrideNames() []string {
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Override sets the Badger option with the given name to the given value.
+// Sizes are given in bytes, and loading modes are one of `fileio`, `mmap` or
+// `memory`.
+func Override(opts badger.Options, name string, value string) (badger.Options, error) {
+
+	override, ok := overrides[name]
+	if !ok {
+		return badger.Options{}, fmt.Errorf("unknown Badger option (%s)", name)
+	}
+
+	opts, err := override(opts, value)
+	if err != nil {
+		return badger.Options{}, fmt.Errorf("invalid value for Badger option %s (%s): %w", name, value, err)
+	}
+
+	return opts, nil
+}
+
+func intOption(set func(badger.Options, int) badger.Options) func(badger.Options, string) (badger.Options, error) {
+	return func(opts badger.Options, value string) (badger.Options, error) {
+		number, err := strconv.ParseUint(value, 10, 31)
+		if err != nil {
+			return badger.Options{}, err
+		}
+		return set(opts, int(number)), nil
+	}
+}
+
+func int64Option(set func(badger.Options, int64) badger.Options) func(badger.Options, string) (badger.Options, error) {
+	return func(opts badger.Options, value string) (badger.Options, error) {
+		number, err := strconv.ParseUint(value, 10, 63)
+		if err != nil {
+			return badger.Options{}, err
+		}
+		return set(opts, int64(number)), nil
+	}
+}
+
+func uint32Option(set func(badger.Options, uint32) badger.Options) func(badger.Options, string) (badger.Options, error) {
+	return func(opts badger.Options, value string) (badger.Options, error) {
+		number, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return badger.Options{}, err
+		}
+		return set(opts, uint32(number)), nil
+	}
+}
+
+func boolOption(set func(badger.Options, bool) badger.Options) func(badger.Options, string) (badger.Options, error) {
+	return func(opts badger.Options, value string) (badger.Options, error) {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return badger.Options{}, err
+		}
+		return set(opts, enabled), nil
+	}
+}
+
+func modeOption(set func(badger.Options, options.FileLoadingMode) badger.Options) func(badger.Options, string) (badger.Options, error) {
+	return func(opts badger.Options, value string) (badger.Options, error) {
+		switch strings.ToLower(value) {
+		case "fileio":
+			return set(opts, options.FileIO), nil
+		case "mmap":
+			return set(opts, options.MemoryMap), nil
+		case "memory":
+			return set(opts, options.LoadToRAM), nil
+		default:
+			return badger.Options{}, fmt.Errorf("unknown loading mode (must be fileio, mmap or memory)")
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package tuning_test
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/options"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/optakt/flow-dps/service/tuning"
+)
+
+func TestOverride(t *testing.T) {
+	tests := []struct {
+		name string
+
+		option string
+		value  string
+
+		check    func(t *testing.T, opts badger.Options)
+		checkErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:   "int option",
+			option: "num_memtables",
+			value:  "3",
+			check: func(t *testing.T, opts badger.Options) {
+				assert.Equal(t, 3, opts.NumMemtables)
+			},
+			checkErr: assert.NoError,
+		},
+		{
+			name:   "size option",
+			option: "index_cache_size",
+			value:  "1048576",
+			check: func(t *testing.T, opts badger.Options) {
+				assert.Equal(t, int64(1048576), opts.IndexCacheSize)
+			},
+			checkErr: assert.NoError,
+		},
+		{
+			name:   "entries option",
+			option: "value_log_max_entries",
+			value:  "5000",
+			check: func(t *testing.T, opts badger.Options) {
+				assert.Equal(t, uint32(5000), opts.ValueLogMaxEntries)
+			},
+			checkErr: assert.NoError,
+		},
+		{
+			name:   "bool option",
+			option: "load_blooms_on_open",
+			value:  "true",
+			check: func(t *testing.T, opts badger.Options) {
+				assert.True(t, opts.LoadBloomsOnOpen)
+			},
+			checkErr: assert.NoError,
+		},
+		{
+			name:   "loading mode option",
+			option: "table_loading_mode",
+			value:  "mmap",
+			check: func(t *testing.T, opts badger.Options) {
+				assert.Equal(t, options.MemoryMap, opts.TableLoadingMode)
+			},
+			checkErr: assert.NoError,
+		},
+		{
+			name:     "unknown option",
+			option:   "unknown",
+			value:    "1",
+			checkErr: assert.Error,
+		},
+		{
+			name:     "negative size",
+			option:   "index_cache_size",
+			value:    "-1",
+			checkErr: assert.Error,
+		},
+		{
+			name:     "invalid bool",
+			option:   "sync_writes",
+			value:    "maybe",
+			checkErr: assert.Error,
+		},
+		{
+			name:     "invalid loading mode",
+			option:   "value_log_loading_mode",
+			value:    "disk",
+			checkErr: assert.Error,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts, err := tuning.Override(badger.DefaultOptions("index"), test.option, test.value)
+
+			test.checkErr(t, err)
+			if test.check != nil {
+				test.check(t, opts)
+			}
+		})
+	}
+}
+
+func TestOverrideNames(t *testing.T) {
+	names := tuning.OverrideNames()
+
+	assert.Contains(t, names, "index_cache_size")
+	assert.Contains(t, names, "table_loading_mode")
+	assert.IsIncreasing(t, names)
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package tuning
+
+import (
+	"fmt"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/options"
+
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// Profile is a named set of Badger options, tuned for one way of using a
+// database.
+type Profile string
+
+// Supported profiles.
+const (
+	// ProfileBulkIngest is tuned for writing large amounts of data as fast as
+	// possible, such as when indexing a past spork or restoring a snapshot.
+	// It corresponds to the default options of the DPS.
+	ProfileBulkIngest Profile = "bulk-ingest"
+
+	// ProfileLive is tuned for a database that is written to continuously
+	// while also serving reads, such as the live index.
+	ProfileLive Profile = "live"
+
+	// ProfileServing is tuned for serving random reads from a database that
+	// is opened in read-only mode.
+	ProfileServing Profile = "read-only-serving"
+
+	// ProfileLowMemory keeps the memory usage to a minimum, at the cost of
+	// performance, for small machines and one-off tools.
+	ProfileLowMemory Profile = "low-memory"
+)
+
+// Profiles are all of the supported profiles.
+var Profiles = []Profile{
+	ProfileBulkIngest,
+	ProfileLive,
+	ProfileServing,
+	ProfileLowMemory,
+}
+
+// Options returns the Badger options of the given profile for a database in
+// the given directory.
+func Options(dir string, profile Profile) (badger.Options, error) {
+
+	switch profile {
+
+	case ProfileBulkIngest:
+		return dps.DefaultOptions(dir), nil
+
+	case ProfileLive:
+		// Writes are flushed regularly while the index is being read, so we
+		// allow more level zero tables before stalling writes, and keep
+		// blooms and some blocks in memory for reads.
+		opts := badger.DefaultOptions(dir).
+			WithMaxTableSize(64 << 20).
+			WithValueLogFileSize(256 << 20).
+			WithTableLoadingMode(options.FileIO).
+			WithValueLogLoadingMode(options.FileIO).
+			WithNumMemtables(2).
+			WithKeepL0InMemory(false).
+			WithCompactL0OnClose(true).
+			WithNumLevelZeroTables(4).
+			WithNumLevelZeroTablesStall(8).
+			WithLoadBloomsOnOpen(true).
+			WithIndexCacheSize(512 << 20).
+			WithBlockCacheSize(256 << 20).
+			WithLogger(nil)
+		return opts, nil
+
+	case ProfileServing:
+		// Nothing is written, so memtables and compaction settings do not
+		// matter; we map the files into memory and cache as much as possible
+		// of the indexes and blocks for random reads.
+		opts := badger.DefaultOptions(dir).
+			WithTableLoadingMode(options.MemoryMap).
+			WithValueLogLoadingMode(options.MemoryMap).
+			WithNumMemtables(1).
+			WithKeepL0InMemory(false).
+			WithCompactL0OnClose(false).
+			WithLoadBloomsOnOpen(true).
+			WithIndexCacheSize(1000 << 20).
+			WithBlockCacheSize(500 << 20).
+			WithLogger(nil)
+		return opts, nil
+
+	case ProfileLowMemory:
+		opts := badger.DefaultOptions(dir).
+			WithMaxTableSize(16 << 20).
+			WithValueLogFileSize(16 << 20).
+			WithTableLoadingMode(options.FileIO).
+			WithValueLogLoadingMode(options.FileIO).
+			WithNumMemtables(1).
+			WithKeepL0InMemory(false).
+			WithCompactL0OnClose(false).
+			WithNumLevelZeroTables(1).
+			WithNumLevelZeroTablesStall(2).
+			WithLoadBloomsOnOpen(false).
+			WithIndexCacheSize(64 << 20).
+			WithBlockCacheSize(0).
+			WithLogger(nil)
+		return opts, nil
+
+	default:
+		return badger.Options{}, fmt.Errorf("unknown Badger profile (%s)", profile)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package tuning_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/tuning"
+)
+
+func TestOptions(t *testing.T) {
+	t.Run("all profiles are supported", func(t *testing.T) {
+		t.Parallel()
+
+		for _, profile := range tuning.Profiles {
+			opts, err := tuning.Options("index", profile)
+
+			require.NoError(t, err, profile)
+			assert.Equal(t, "index", opts.Dir, profile)
+			assert.Equal(t, "index", opts.ValueDir, profile)
+		}
+	})
+
+	t.Run("bulk ingest profile uses default options", func(t *testing.T) {
+		t.Parallel()
+
+		opts, err := tuning.Options("index", tuning.ProfileBulkIngest)
+
+		require.NoError(t, err)
+		assert.Equal(t, dps.DefaultOptions("index"), opts)
+	})
+
+	t.Run("serving profile does not use indexer settings", func(t *testing.T) {
+		t.Parallel()
+
+		opts, err := tuning.Options("index", tuning.ProfileServing)
+
+		require.NoError(t, err)
+		assert.NotEqual(t, dps.DefaultOptions("index").IndexCacheSize, opts.IndexCacheSize)
+		assert.True(t, opts.LoadBloomsOnOpen)
+	})
+
+	t.Run("handles unknown profile", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tuning.Options("index", "unknown")
+
+		assert.Error(t, err)
+	})
+}
@@ -34,10 +34,13 @@ type Reader struct {
 	GuaranteeFunc            func(collID flow.Identifier) (*flow.CollectionGuarantee, error)
 	TransactionFunc          func(txID flow.Identifier) (*flow.TransactionBody, error)
 	HeightForTransactionFunc func(txID flow.Identifier) (uint64, error)
+	HeightForCollectionFunc  func(collID flow.Identifier) (uint64, error)
+	HeightForSealFunc        func(sealID flow.Identifier) (uint64, error)
 	TransactionsByHeightFunc func(height uint64) ([]flow.Identifier, error)
 	ResultFunc               func(txID flow.Identifier) (*flow.TransactionResult, error)
 	SealFunc                 func(sealID flow.Identifier) (*flow.Seal, error)
 	SealsByHeightFunc        func(height uint64) ([]flow.Identifier, error)
+	ResultsByHeightFunc      func(height uint64) ([]*flow.TransactionResult, error)
 }
 
 func BaselineReader(t *testing.T) *Reader {
@@ -80,6 +83,12 @@ func BaselineReader(t *testing.T) *Reader {
 		HeightForTransactionFunc: func(blockID flow.Identifier) (uint64, error) {
 			return GenericHeight, nil
 		},
+		HeightForCollectionFunc: func(collID flow.Identifier) (uint64, error) {
+			return GenericHeight, nil
+		},
+		HeightForSealFunc: func(sealID flow.Identifier) (uint64, error) {
+			return GenericHeight, nil
+		},
 		TransactionsByHeightFunc: func(height uint64) ([]flow.Identifier, error) {
 			return GenericTransactionIDs(5), nil
 		},
@@ -92,6 +101,9 @@ func BaselineReader(t *testing.T) *Reader {
 		SealsByHeightFunc: func(height uint64) ([]flow.Identifier, error) {
 			return GenericSealIDs(5), nil
 		},
+		ResultsByHeightFunc: func(height uint64) ([]*flow.TransactionResult, error) {
+			return GenericResults(4), nil
+		},
 	}
 
 	return &r
@@ -145,6 +157,14 @@ func (r *Reader) HeightForTransaction(txID flow.Identifier) (uint64, error) {
 	return r.HeightForTransactionFunc(txID)
 }
 
+func (r *Reader) HeightForCollection(collID flow.Identifier) (uint64, error) {
+	return r.HeightForCollectionFunc(collID)
+}
+
+func (r *Reader) HeightForSeal(sealID flow.Identifier) (uint64, error) {
+	return r.HeightForSealFunc(sealID)
+}
+
 func (r *Reader) TransactionsByHeight(height uint64) ([]flow.Identifier, error) {
 	return r.TransactionsByHeightFunc(height)
 }
@@ -160,3 +180,7 @@ func (r *Reader) Seal(sealID flow.Identifier) (*flow.Seal, error) {
 func (r *Reader) SealsByHeight(height uint64) ([]flow.Identifier, error) {
 	return r.SealsByHeightFunc(height)
 }
+
+func (r *Reader) ResultsByHeight(height uint64) ([]*flow.TransactionResult, error) {
+	return r.ResultsByHeightFunc(height)
+}
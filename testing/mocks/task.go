@@ -0,0 +1,57 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package mocks
+
+import (
+	"testing"
+
+	"github.com/optakt/flow-dps/service/maintenance"
+)
+
+type Task struct {
+	CollectGarbageFunc func(ratio float64) (*maintenance.Result, error)
+	FlattenFunc        func(workers int) (*maintenance.Result, error)
+	ActivityFunc       func() int64
+}
+
+func BaselineTask(t *testing.T) *Task {
+	t.Helper()
+
+	m := Task{
+		CollectGarbageFunc: func(float64) (*maintenance.Result, error) {
+			return &maintenance.Result{}, nil
+		},
+		FlattenFunc: func(int) (*maintenance.Result, error) {
+			return &maintenance.Result{}, nil
+		},
+		ActivityFunc: func() int64 {
+			return 0
+		},
+	}
+
+	return &m
+}
+
+func (m *Task) CollectGarbage(ratio float64) (*maintenance.Result, error) {
+	return m.CollectGarbageFunc(ratio)
+}
+
+func (m *Task) Flatten(workers int) (*maintenance.Result, error) {
+	return m.FlattenFunc(workers)
+}
+
+func (m *Task) Activity() int64 {
+	return m.ActivityFunc()
+}